@@ -4,6 +4,7 @@ import (
 	"fmt"
 	stscache "github.com/timescale/tsbs/InfluxDB-client/memcache"
 	"log"
+	"sync/atomic"
 	"time"
 )
 
@@ -60,6 +61,8 @@ func TSCacheClient(conn Client, queryString string) (*Response, uint64, uint8) {
 
 			//remainValues := ResponseToByteArray(resp, queryString)
 			remainValues := ResponseToByteArrayWithParams(resp, datatypes, tags, metric, partialSegment)
+			byteLength = uint64(len(remainValues))
+			atomic.AddUint64(&DatabaseBytes, byteLength)
 
 			err = STsConnArr[CacheIndex].Set(&stscache.Item{Key: semanticSegment, Value: remainValues, Time_start: startTime, Time_end: endTime, NumOfTables: numOfTab})
 
@@ -81,6 +84,8 @@ func TSCacheClient(conn Client, queryString string) (*Response, uint64, uint8) {
 	} else { // 缓存部分命中或完全命中
 		/* 把查询结果从字节流转换成 Response 结构 */
 		convertedResponse, flagNum, flagArr, timeRangeArr, tagArr := ByteArrayToResponseWithDatatype(values, datatypes)
+		byteLength = uint64(len(values))
+		atomic.AddUint64(&CacheBytes, byteLength)
 
 		//convertedResponse, flagNum, _, _, _ := ByteArrayToResponseWithDatatype(values, datatypes)
 		//fmt.Println("\tconverted response:")
@@ -137,6 +142,8 @@ func TSCacheClient(conn Client, queryString string) (*Response, uint64, uint8) {
 			//remainByteArr := ResponseToByteArray(remainResp, queryString)
 			//todo
 			remainByteArr := RemainResponseToByteArrayWithParams(remainResp, datatypes, remainTags, metric, partialSegment)
+			byteLength += uint64(len(remainByteArr))
+			atomic.AddUint64(&DatabaseBytes, uint64(len(remainByteArr)))
 
 			//RemainResponseToByteArrayWithParams(remainResp, datatypes, remainTags, metric, partialSegment)
 
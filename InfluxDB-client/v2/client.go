@@ -11,6 +11,7 @@ import (
 	stscache "github.com/timescale/tsbs/InfluxDB-client/memcache"
 	"github.com/timescale/tsbs/InfluxDB-client/models"
 	"sync"
+	"sync/atomic"
 
 	//"github.com/influxdata/influxdb1-client/models"
 	"io"
@@ -81,6 +82,10 @@ type HTTPConfig struct {
 	// Password is the influxdb password, optional.
 	Password string
 
+	// Token is an InfluxDB 2.x API token, sent as 'Authorization: Token',
+	// optional. It is ignored if Username is set.
+	Token string
+
 	// UserAgent is the http User Agent, defaults to "InfluxDBClient".
 	UserAgent string
 
@@ -175,6 +180,7 @@ func NewHTTPClient(conf HTTPConfig) (Client, error) {
 		url:       *u,
 		username:  conf.Username,
 		password:  conf.Password,
+		token:     conf.Token,
 		useragent: conf.UserAgent,
 		httpClient: &http.Client{
 			Timeout:   conf.Timeout,
@@ -200,9 +206,7 @@ func (c *client) Ping(timeout time.Duration) (time.Duration, string, error) {
 
 	req.Header.Set("User-Agent", c.useragent)
 
-	if c.username != "" {
-		req.SetBasicAuth(c.username, c.password)
-	}
+	c.setAuth(req)
 
 	if timeout > 0 {
 		params := req.URL.Query()
@@ -231,6 +235,15 @@ func (c *client) Ping(timeout time.Duration) (time.Duration, string, error) {
 	return time.Since(now), version, nil
 }
 
+// setAuth adds the credentials of the client, if any, to req.
+func (c *client) setAuth(req *http.Request) {
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	} else if c.token != "" {
+		req.Header.Set("Authorization", "Token "+c.token)
+	}
+}
+
 // Close releases the client's resources.
 func (c *client) Close() error {
 	c.transport.CloseIdleConnections()
@@ -245,6 +258,7 @@ type client struct {
 	url        url.URL
 	username   string
 	password   string
+	token      string
 	useragent  string
 	httpClient *http.Client
 	transport  *http.Transport
@@ -469,9 +483,7 @@ func (c *client) Write(bp BatchPoints) error {
 	}
 	req.Header.Set("Content-Type", "")
 	req.Header.Set("User-Agent", c.useragent)
-	if c.username != "" {
-		req.SetBasicAuth(c.username, c.password)
-	}
+	c.setAuth(req)
 
 	params := req.URL.Query()
 	params.Set("db", bp.Database())
@@ -802,9 +814,7 @@ func (c *client) createDefaultRequest(q Query) (*http.Request, error) {
 	req.Header.Set("Content-Type", "")
 	req.Header.Set("User-Agent", c.useragent)
 
-	if c.username != "" {
-		req.SetBasicAuth(c.username, c.password)
-	}
+	c.setAuth(req)
 
 	params := req.URL.Query()
 	params.Set("q", q.Command)
@@ -921,6 +931,9 @@ var mtx sync.Mutex
 // var STsConnArr = InitStsConns()
 var STsConnArr []*stscache.Client
 
+// CacheBytes 和 DatabaseBytes 分别累计从 cache 和数据库获取的查询结果字节数，用 sync/atomic 读写
+var CacheBytes, DatabaseBytes uint64
+
 func InitStsConns() []*stscache.Client {
 	conns := make([]*stscache.Client, 0)
 	for i := 0; i < MaxThreadNum; i++ {
@@ -1018,6 +1031,8 @@ func STsCacheClient(conn Client, queryString string) (*Response, uint64, uint8)
 
 			//remainValues := ResponseToByteArray(resp, queryString)
 			remainValues := ResponseToByteArrayWithParams(resp, datatypes, tags, metric, partialSegment)
+			byteLength = uint64(len(remainValues))
+			atomic.AddUint64(&DatabaseBytes, byteLength)
 
 			err = STsConnArr[CacheIndex].Set(&stscache.Item{Key: starSegment, Value: remainValues, Time_start: startTime, Time_end: endTime, NumOfTables: numOfTab})
 
@@ -1058,6 +1073,8 @@ func STsCacheClient(conn Client, queryString string) (*Response, uint64, uint8)
 	} else { // 缓存部分命中或完全命中
 		/* 把查询结果从字节流转换成 Response 结构 */
 		convertedResponse, flagNum, flagArr, timeRangeArr, tagArr := ByteArrayToResponseWithDatatype(values, datatypes)
+		byteLength = uint64(len(values))
+		atomic.AddUint64(&CacheBytes, byteLength)
 
 		if flagNum == 0 { // 全部命中
 			//log.Printf("GET.")
@@ -1129,6 +1146,8 @@ func STsCacheClient(conn Client, queryString string) (*Response, uint64, uint8)
 
 			//remainByteArr := ResponseToByteArray(remainResp, queryString)
 			remainByteArr := RemainResponseToByteArrayWithParams(remainResp, datatypes, remainTags, metric, partialSegment)
+			byteLength += uint64(len(remainByteArr))
+			atomic.AddUint64(&DatabaseBytes, uint64(len(remainByteArr)))
 			//remainByteArr := ResponseToByteArrayWithParams(remainResp, datatypes, remainTags, metric, partialSegment)
 			//fmt.Println(remainQuery, "\nlen:", len(remainByteArr))
 
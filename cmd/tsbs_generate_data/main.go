@@ -7,6 +7,10 @@
 // MongoDB BSON format
 // TimescaleDB pseudo-CSV format (the same as for ClickHouse)
 // VictoriaMetrics bulk load format (the same as for InfluxDB)
+// Columnar binary format, readable by the InfluxDB loader (see pkg/data/columnar)
+//
+// With --real-time, the points are emitted as the wall clock reaches their
+// time, to stdout, the file or, with --write-url, straight to InfluxDB.
 
 // Supported use cases:
 // devops: scale is the number of hosts to simulate, with log messages
@@ -26,6 +30,9 @@ import (
 	"github.com/timescale/tsbs/internal/inputs"
 	"github.com/timescale/tsbs/internal/utils"
 	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"github.com/timescale/tsbs/pkg/targets"
+	"github.com/timescale/tsbs/pkg/targets/constants"
+	"github.com/timescale/tsbs/pkg/targets/influx"
 	"github.com/timescale/tsbs/pkg/targets/initializers"
 )
 
@@ -64,8 +71,38 @@ func main() {
 	if len(profileFile) > 0 {
 		defer startMemoryProfile(profileFile)()
 	}
-	target := initializers.GetTarget(config.Format)
+	// the columnar format is not specific to a target
+	var target targets.ImplementedTarget
+	if config.Format != constants.FormatColumnar {
+		var err error
+		target, err = initializers.GetTarget(config.Format)
+		if err != nil {
+			fmt.Printf("error: %v\n", err)
+			return
+		}
+	}
+	var stream *influx.StreamWriter
+	if config.WriteURL != "" {
+		stream = influx.NewStreamWriter(influx.NewHTTPWriter(influx.HTTPWriterConfig{
+			Host:       config.WriteURL,
+			Database:   config.WriteDB,
+			DebugInfo:  "tsbs_generate_data",
+			APIVersion: config.WriteAPIVersion,
+			Org:        config.WriteOrg,
+			Token:      config.WriteToken,
+			// the influx serializer writes nanosecond timestamps
+			Precision: "ns",
+		}, "all"))
+		dg.Out = stream
+	}
 	err := dg.Generate(config, target)
+	if stream != nil {
+		// close the stream even on error so that its buffered points are
+		// flushed, but report the generation error first
+		if closeErr := stream.Close(); err == nil {
+			err = closeErr
+		}
+	}
 	if err != nil {
 		fmt.Printf("error: %v\n", err)
 	}
@@ -23,6 +23,10 @@ type Core struct {
 
 	// Scale is the cardinality of the dataset in terms of devices/hosts
 	Scale int
+
+	// rand, if set, is the source of the random choices of the queries
+	// instead of the global source
+	rand *rand.Rand
 }
 
 // NewCore returns a new Core for the given time range and cardinality
@@ -35,6 +39,32 @@ func NewCore(start, end time.Time, scale int) (*Core, error) {
 	return &Core{Interval: ti, Scale: scale}, nil
 }
 
+// SetRand makes the queries of c draw their random choices, e.g. of hosts
+// and time windows, from r instead of the global source.
+func (c *Core) SetRand(r *rand.Rand) {
+	c.rand = r
+	c.Interval.SetRand(r)
+}
+
+// Intn returns a random number in [0, n) from the source of c.
+func (c *Core) Intn(n int) int {
+	if c.rand == nil {
+		return rand.Intn(n)
+	}
+	return c.rand.Intn(n)
+}
+
+// GetRandomSubsetPerm is GetRandomSubsetPerm drawing from the source of c.
+func (c *Core) GetRandomSubsetPerm(numItems int, totalItems int) ([]int, error) {
+	return getRandomSubsetPerm(c.Intn, numItems, totalItems)
+}
+
+// GetContinuousRandomSubset is GetContinuousRandomSubset drawing from the
+// source of c.
+func (c *Core) GetContinuousRandomSubset() ([]int, error) {
+	return getContinuousRandomSubset(c.Intn)
+}
+
 // PanicUnimplementedQuery generates a panic for the provided query distributionGenerator.
 func PanicUnimplementedQuery(dg utils.QueryGenerator) {
 	panic(fmt.Sprintf("database (%v) does not implement query", reflect.TypeOf(dg)))
@@ -46,6 +76,10 @@ func PanicUnimplementedQuery(dg utils.QueryGenerator) {
 // The subset of the permutation should have no duplicates and thus, can not be longer that original set
 // Ex.: 12, 7, 25 for numItems=3 and totalItems=30 (3 out of 30)
 func GetRandomSubsetPerm(numItems int, totalItems int) ([]int, error) {
+	return getRandomSubsetPerm(rand.Intn, numItems, totalItems)
+}
+
+func getRandomSubsetPerm(intn func(int) int, numItems int, totalItems int) ([]int, error) {
 	if numItems > totalItems {
 		// Cannot make a subset longer than the original set
 		return nil, fmt.Errorf(errMoreItemsThanScale)
@@ -55,7 +89,7 @@ func GetRandomSubsetPerm(numItems int, totalItems int) ([]int, error) {
 	res := make([]int, numItems)
 	for i := 0; i < numItems; i++ {
 		for {
-			n := rand.Intn(totalItems)
+			n := intn(totalItems)
 			// Keep iterating until a previously unseen int is found
 			if !seen[n] {
 				seen[n] = true
@@ -70,6 +104,10 @@ func GetRandomSubsetPerm(numItems int, totalItems int) ([]int, error) {
 var TruckScale string
 
 func GetContinuousRandomSubset() ([]int, error) {
+	return getContinuousRandomSubset(rand.Intn)
+}
+
+func getContinuousRandomSubset(intn func(int) int) ([]int, error) {
 	// 要取的结果数量
 	//resNum := (rand.Intn(4) + 1) * 10 // 生成 [0,4) 的一个整数｛0,1,2,3｝，加一变成 ｛1,2,3,4｝，乘十变成｛10,20,30，40｝
 	resNum := 0
@@ -91,7 +129,7 @@ func GetContinuousRandomSubset() ([]int, error) {
 	switch resNum {
 	case 10:
 		//section := rand.Intn(10) * 10 // 生成[0,10) 的一个整数，乘 10 变成{0,10,20....90}
-		section := rand.Intn(19) * 5 * 1 // 生成[0,20) 的一个整数，乘 5 变成{0,5,15....90}
+		section := intn(19) * 5 * 1 // 生成[0,20) 的一个整数，乘 5 变成{0,5,15....90}
 		fmt.Printf("result number: %d\tsection number: %d\n", resNum, section)
 		for i := 0; i < resNum; i++ {
 			result[i] = section
@@ -108,7 +146,7 @@ func GetContinuousRandomSubset() ([]int, error) {
 	//	break
 	// todo
 	case 30:
-		section := rand.Intn(19) * 5 * 3 // 生成[0,20) 的一个整数｛0,1,2｝，乘五变成{0,5,10}，乘三变成{0,15,30}
+		section := intn(19) * 5 * 3 // 生成[0,20) 的一个整数｛0,1,2｝，乘五变成{0,5,10}，乘三变成{0,15,30}
 		fmt.Printf("result number: %d\tsection number: %d\n", resNum, section)
 		for i := 0; i < resNum; i++ {
 			result[i] = section
@@ -124,7 +162,7 @@ func GetContinuousRandomSubset() ([]int, error) {
 	//	}
 	//	break
 	case 50:
-		section := rand.Intn(19) * 5 * 5 // 生成[0,20) 的一个整数｛0,1,2｝，乘五变成{0,5,10}，乘五变成{0,25,50}
+		section := intn(19) * 5 * 5 // 生成[0,20) 的一个整数｛0,1,2｝，乘五变成{0,5,10}，乘五变成{0,25,50}
 		fmt.Printf("result number: %d\tsection number: %d\n", resNum, section)
 		for i := 0; i < resNum; i++ {
 			result[i] = section
@@ -133,7 +171,7 @@ func GetContinuousRandomSubset() ([]int, error) {
 		break
 	default:
 		// todo 生成 50 个
-		section := rand.Intn(19) * 5 * 1 // 生成[0,20) 的一个整数，乘 25 变成{0,25,50....450}
+		section := intn(19) * 5 * 1 // 生成[0,20) 的一个整数，乘 25 变成{0,25,50....450}
 		fmt.Printf("result number: %d\tsection number: %d\n", resNum, section)
 		for i := 0; i < resNum; i++ {
 			result[i] = section
@@ -3,7 +3,6 @@ package iot
 import (
 	"fmt"
 	"github.com/timescale/tsbs/pkg/data/usecases/iot"
-	"math/rand"
 	"time"
 
 	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/uses/common"
@@ -78,7 +77,7 @@ type Core struct {
 
 // GetRandomFleet returns one of the fleet choices by random.
 func (c Core) GetRandomFleet() string {
-	return iot.FleetChoices[rand.Intn(len(iot.FleetChoices))]
+	return iot.FleetChoices[c.Intn(len(iot.FleetChoices))]
 }
 
 // NewCore returns a new Core for the given time range and cardinality
@@ -90,17 +89,18 @@ func NewCore(start, end time.Time, scale int) (*Core, error) {
 
 // GetRandomTrucks returns a random set of nTrucks from a given Core
 func (c *Core) GetRandomTrucks(nTrucks int) ([]string, error) {
-	return getRandomTrucks(nTrucks, c.Scale)
+	return getRandomTrucks(nTrucks, c.Scale, c.Core.GetRandomSubsetPerm)
 }
 
 func (c *Core) GetContinuousRandomTrucks() ([]string, error) {
-	return getContinuousRandomTrucks()
+	return getContinuousRandomTrucks(c.Core.GetContinuousRandomSubset)
 }
 
 // getRandomTruckNames returns a subset of numTrucks names of a permutation of truck names,
 // numbered from 0 to totalTrucks.
 // Ex.: truck_12, truck_7, truck_25 for numTrucks=3 and totalTrucks=30 (3 out of 30)
-func getRandomTrucks(numTrucks int, totalTrucks int) ([]string, error) {
+// The permutation is drawn with perm.
+func getRandomTrucks(numTrucks int, totalTrucks int, perm func(int, int) ([]int, error)) ([]string, error) {
 	if numTrucks < 1 {
 		return nil, fmt.Errorf("number of trucks cannot be < 1; got %d", numTrucks)
 	}
@@ -108,7 +108,7 @@ func getRandomTrucks(numTrucks int, totalTrucks int) ([]string, error) {
 		return nil, fmt.Errorf("number of trucks (%d) larger than total trucks. See --scale (%d)", numTrucks, totalTrucks)
 	}
 
-	randomNumbers, err := common.GetRandomSubsetPerm(numTrucks, totalTrucks)
+	randomNumbers, err := perm(numTrucks, totalTrucks)
 	if err != nil {
 		return nil, err
 	}
@@ -121,9 +121,9 @@ func getRandomTrucks(numTrucks int, totalTrucks int) ([]string, error) {
 	return truckNames, nil
 }
 
-func getContinuousRandomTrucks() ([]string, error) {
+func getContinuousRandomTrucks(subset func() ([]int, error)) ([]string, error) {
 
-	randomNumbers, err := common.GetContinuousRandomSubset()
+	randomNumbers, err := subset()
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,89 @@
+// tsbs_inspect_data reports statistics of a generated data file.
+//
+// It reads Influx line protocol, as generated by tsbs_generate_data, and
+// reports the number of points per measurement, the time range, the number of
+// series, the gaps and out-of-order points of the series, and the value ranges
+// of the fields. With --compare, it instead checks that two files have the
+// same series with the same points, in the same order within each series, and
+// exits with status 1 if they differ.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/blagojts/viper"
+	"github.com/spf13/pflag"
+	"github.com/timescale/tsbs/internal/utils"
+	"github.com/timescale/tsbs/pkg/inspect"
+)
+
+// Program option vars:
+var (
+	fileName    string
+	compareName string
+	interval    time.Duration
+)
+
+// Parse args:
+func init() {
+	pflag.String("file", "", "File name to read data from, stdin if empty")
+	pflag.String("compare", "", "File name of data to compare the data with, series by series")
+	pflag.Duration("interval", 0, "Expected time between the points of a series, 0 = the most common one of each measurement")
+
+	pflag.Parse()
+
+	err := utils.SetupConfigFile()
+
+	if err != nil {
+		panic(fmt.Errorf("fatal error config file: %s", err))
+	}
+
+	fileName = viper.GetString("file")
+	compareName = viper.GetString("compare")
+	interval = viper.GetDuration("interval")
+}
+
+func main() {
+	stats := readStats(fileName)
+	if compareName == "" {
+		if err := stats.Write(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	name := fileName
+	if name == "" {
+		name = "stdin"
+	}
+	diff := inspect.Compare(stats, readStats(compareName))
+	if err := diff.Write(os.Stdout, name, compareName); err != nil {
+		log.Fatal(err)
+	}
+	if !diff.Identical() {
+		os.Exit(1)
+	}
+}
+
+func readStats(fileName string) *inspect.Stats {
+	stats := inspect.NewStats(interval)
+	if err := stats.Read(getBufferedReader(fileName)); err != nil {
+		log.Fatalf("cannot read %s: %v", fileName, err)
+	}
+	return stats
+}
+
+func getBufferedReader(fileName string) *bufio.Reader {
+	if len(fileName) == 0 {
+		return bufio.NewReaderSize(os.Stdin, 4<<20)
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		log.Fatalf("cannot open file for read %s: %v", fileName, err)
+	}
+	return bufio.NewReaderSize(file, 4<<20)
+}
@@ -1,5 +1,13 @@
+// tsbs_load loads data into the targets implemented in this repository. To
+// load into a target maintained elsewhere, build a main package like this one
+// that also imports the package registering that target.
 package main
 
+import (
+	"github.com/timescale/tsbs/pkg/loadcmd"
+	_ "github.com/timescale/tsbs/pkg/targets/initializers" // targets in this repository
+)
+
 func main() {
-	rootCmd.Execute()
+	loadcmd.Main()
 }
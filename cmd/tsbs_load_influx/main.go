@@ -1,66 +1,55 @@
-// bulk_load_influx loads an InfluxDB daemon with data from stdin.
+// bulk_load_influx loads an InfluxDB daemon with data from stdin, a file, or
+// straight from the data simulator when --use-case is set.
 //
 // The caller is responsible for assuring that the database is empty before
 // bulk load.
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"fmt"
 	"log"
-	"strings"
-	"sync"
 	"time"
 
 	"github.com/blagojts/viper"
 	"github.com/spf13/pflag"
 	"github.com/timescale/tsbs/internal/utils"
 	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/data/source"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
 	"github.com/timescale/tsbs/pkg/targets"
 	"github.com/timescale/tsbs/pkg/targets/constants"
 	"github.com/timescale/tsbs/pkg/targets/initializers"
 )
 
-// Program option vars:
-var (
-	daemonURLs        []string
-	replicationFactor int
-	backoff           time.Duration
-	useGzip           bool
-	doAbortOnExist    bool
-	consistency       string
-)
-
 // Global vars
 var (
-	loader  load.BenchmarkRunner
-	config  load.BenchmarkRunnerConfig
-	bufPool sync.Pool
-	target  targets.ImplementedTarget
+	loader load.BenchmarkRunner
+	config load.BenchmarkRunnerConfig
+	target targets.ImplementedTarget
+	bench  targets.Benchmark
 )
 
-var consistencyChoices = map[string]struct{}{
-	"any":    {},
-	"one":    {},
-	"quorum": {},
-	"all":    {},
-}
-
-// allows for testing
-var fatal = log.Fatalf
-
 // Parse args:
 func init() {
-	target = initializers.GetTarget(constants.FormatInflux)
+	var err error
+	target, err = initializers.GetTarget(constants.FormatInflux)
+	if err != nil {
+		log.Fatal(err)
+	}
 	config = load.BenchmarkRunnerConfig{}
 	config.AddToFlagSet(pflag.CommandLine)
 	target.TargetSpecificFlags("", pflag.CommandLine)
-	var csvDaemonURLs string
+	pflag.String("use-case", "", "Generate the data of this use case while loading instead of reading it (as tsbs_generate_data, seeded by --seed)")
+	pflag.Uint64("scale", 1, "Scaling value specific to the use case, with --use-case")
+	pflag.Uint64("initial-scale", 0, "Initial scaling value specific to the use case, 0 = --scale, with --use-case")
+	pflag.String("timestamp-start", "2016-01-01T00:00:00Z", "Beginning timestamp (RFC3339), with --use-case")
+	pflag.String("timestamp-end", "2016-01-02T00:00:00Z", "Ending timestamp (RFC3339), with --use-case")
+	pflag.Duration("log-interval", 10*time.Second, "Duration between data points, with --use-case")
+	pflag.Uint64("max-metric-count", 100, "Max number of metric fields to generate per host in the devops-generic use case")
 
 	pflag.Parse()
 
-	err := utils.SetupConfigFile()
+	err = utils.SetupConfigFile()
 
 	if err != nil {
 		panic(fmt.Errorf("fatal error config file: %s", err))
@@ -70,52 +59,39 @@ func init() {
 		panic(fmt.Errorf("unable to decode config: %s", err))
 	}
 
-	csvDaemonURLs = viper.GetString("urls")
-	replicationFactor = viper.GetInt("replication-factor")
-	consistency = viper.GetString("consistency")
-	backoff = viper.GetDuration("backoff")
-	useGzip = viper.GetBool("gzip")
-
-	if _, ok := consistencyChoices[consistency]; !ok {
-		log.Fatalf("invalid consistency settings")
+	dataSource := &source.DataSourceConfig{
+		Type: source.FileDataSourceType,
+		File: &source.FileDataSourceConfig{Location: config.FileName},
 	}
-
-	daemonURLs = strings.Split(csvDaemonURLs, ",")
-	if len(daemonURLs) == 0 {
-		log.Fatal("missing 'urls' flag")
+	if useCase := viper.GetString("use-case"); useCase != "" {
+		if config.Resume && config.Seed == 0 {
+			log.Fatal("--resume with --use-case requires the --seed of the interrupted load")
+		}
+		dataSource = &source.DataSourceConfig{
+			Type: source.SimulatorDataSourceType,
+			Simulator: &common.DataGeneratorConfig{
+				BaseConfig: common.BaseConfig{
+					Format:    constants.FormatInflux,
+					Use:       useCase,
+					Scale:     viper.GetUint64("scale"),
+					TimeStart: viper.GetString("timestamp-start"),
+					TimeEnd:   viper.GetString("timestamp-end"),
+					Seed:      config.Seed,
+				},
+				InitialScale:          viper.GetUint64("initial-scale"),
+				LogInterval:           viper.GetDuration("log-interval"),
+				InterleavedNumGroups:  1,
+				MaxMetricCountPerHost: viper.GetUint64("max-metric-count"),
+			},
+		}
+	}
+	bench, err = target.Benchmark(config.DBName, dataSource, viper.GetViper())
+	if err != nil {
+		log.Fatal(err)
 	}
-	config.HashWorkers = false
 	loader = load.GetBenchmarkRunner(config)
 }
 
-type benchmark struct{}
-
-func (b *benchmark) GetDataSource() targets.DataSource {
-	return &fileDataSource{scanner: bufio.NewScanner(load.GetBufferedReader(config.FileName))}
-}
-
-func (b *benchmark) GetBatchFactory() targets.BatchFactory {
-	return &factory{}
-}
-
-func (b *benchmark) GetPointIndexer(_ uint) targets.PointIndexer {
-	return &targets.ConstantIndexer{}
-}
-
-func (b *benchmark) GetProcessor() targets.Processor {
-	return &processor{}
-}
-
-func (b *benchmark) GetDBCreator() targets.DBCreator {
-	return &dbCreator{}
-}
-
 func main() {
-	bufPool = sync.Pool{
-		New: func() interface{} {
-			return bytes.NewBuffer(make([]byte, 0, 4*1024*1024))
-		},
-	}
-
-	loader.RunBenchmark(&benchmark{})
+	loader.RunBenchmark(bench)
 }
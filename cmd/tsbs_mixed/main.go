@@ -0,0 +1,279 @@
+// tsbs_mixed runs a load and a query benchmark against the same InfluxDB in
+// one process, to measure how writes and cached queries affect each other.
+//
+// The load runner is configured with the tsbs_load_influx flags prefixed with
+// 'load.', the query runner with the tsbs_run_queries_influx flags prefixed
+// with 'query.'. Both share --db-name and --urls. The writes and queries are
+// collected in aligned time buckets and written as a single results JSON.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blagojts/viper"
+	"github.com/spf13/pflag"
+	client "github.com/timescale/tsbs/InfluxDB-client/v2"
+	"github.com/timescale/tsbs/internal/utils"
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/data/source"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"github.com/timescale/tsbs/pkg/query"
+	"github.com/timescale/tsbs/pkg/targets"
+	"github.com/timescale/tsbs/pkg/targets/constants"
+	"github.com/timescale/tsbs/pkg/targets/influx"
+	"github.com/timescale/tsbs/pkg/targets/initializers"
+	"github.com/timescale/tsbs/pkg/timeline"
+)
+
+const (
+	loadPrefix  = "load."
+	queryPrefix = "query."
+
+	// ResultFormatVersion is the version of the results JSON of tsbs_mixed
+	ResultFormatVersion = "0.1"
+)
+
+// Program option vars:
+var (
+	loadConfig  load.BenchmarkRunnerConfig
+	queryConfig query.BenchmarkRunnerConfig
+	daemonURLs  []string
+	bucketSize  time.Duration
+	queryDelay  time.Duration
+	resultsFile string
+)
+
+// Global vars:
+var (
+	loader      load.BenchmarkRunner
+	bench       targets.Benchmark
+	queryRunner *query.BenchmarkRunner
+	queryConns  []client.Client
+
+	queryProcessorConfig influx.QueryProcessorConfig
+)
+
+// MixedTestResult is the results JSON of a mixed benchmark.
+type MixedTestResult struct {
+	ResultFormatVersion string                      `json:"ResultFormatVersion"`
+	LoadConfig          load.BenchmarkRunnerConfig  `json:"LoadConfig"`
+	QueryConfig         query.BenchmarkRunnerConfig `json:"QueryConfig"`
+	StartTime           int64                       `json:"StartTime"`
+	EndTime             int64                       `json:"EndTime"`
+	DurationMillis      int64                       `json:"DurationMillis"`
+	// QueryStartMillis is when the query runner started, since StartTime.
+	QueryStartMillis int64             `json:"QueryStartMillis"`
+	BucketMillis     int64             `json:"BucketMillis"`
+	Buckets          []timeline.Bucket `json:"Buckets"`
+}
+
+// addPrefixedFlags adds the flags added by add to the command line with
+// prefix, except for db-name which is shared. It returns their unprefixed
+// names.
+func addPrefixedFlags(prefix string, add func(fs *pflag.FlagSet)) []string {
+	fs := pflag.NewFlagSet(prefix, pflag.ContinueOnError)
+	add(fs)
+	var names []string
+	fs.VisitAll(func(f *pflag.Flag) {
+		if f.Name == "db-name" {
+			return
+		}
+		prefixed := *f
+		prefixed.Name = prefix + f.Name
+		pflag.CommandLine.AddFlag(&prefixed)
+		names = append(names, f.Name)
+	})
+	return names
+}
+
+// unmarshalPrefixed decodes the prefixed flags names and the shared db-name
+// into config.
+func unmarshalPrefixed(prefix string, names []string, config interface{}) error {
+	v := viper.New()
+	for _, name := range names {
+		v.Set(name, viper.Get(prefix+name))
+	}
+	v.Set("db-name", viper.Get("db-name"))
+	return v.Unmarshal(config)
+}
+
+// Parse args:
+func init() {
+	target, err := initializers.GetTarget(constants.FormatInflux)
+	if err != nil {
+		log.Fatal(err)
+	}
+	loadFlags := addPrefixedFlags(loadPrefix, load.BenchmarkRunnerConfig{}.AddToFlagSet)
+	queryFlags := addPrefixedFlags(queryPrefix, query.BenchmarkRunnerConfig{}.AddToFlagSet)
+	target.TargetSpecificFlags("", pflag.CommandLine)
+
+	pflag.String("db-name", "benchmark", "Name of the database to load and query")
+	pflag.Uint64("chunk-response-size", 0, "Number of series to chunk query results into. 0 means no chunking.")
+	pflag.String("query-language", influx.QueryLanguageInfluxQL, "Language of the queries: influxql, sent to /query, or flux, sent to the /api/v2/query endpoint of InfluxDB 2.x (requires --query.use-cache=db)")
+	pflag.Duration("bucket", 10*time.Second, "Duration of the time buckets of the results")
+	pflag.Duration("query-delay", 0, "Time to load after the first batch was written before queries start")
+	pflag.String("results-file", "", "Write the mixed results json to this file")
+	pflag.String("use-case", "", "Generate the data of this use case while loading instead of reading --load.file (as tsbs_generate_data, seeded by --load.seed)")
+	pflag.Uint64("scale", 1, "Scaling value specific to the use case, with --use-case")
+	pflag.Uint64("initial-scale", 0, "Initial scaling value specific to the use case, 0 = --scale, with --use-case")
+	pflag.String("timestamp-start", "2016-01-01T00:00:00Z", "Beginning timestamp (RFC3339), with --use-case")
+	pflag.String("timestamp-end", "2016-01-02T00:00:00Z", "Ending timestamp (RFC3339), with --use-case")
+	pflag.Duration("log-interval", 10*time.Second, "Duration between data points, with --use-case")
+	pflag.Uint64("max-metric-count", 100, "Max number of metric fields to generate per host in the devops-generic use case")
+
+	pflag.Parse()
+
+	err = utils.SetupConfigFile()
+
+	if err != nil {
+		panic(fmt.Errorf("fatal error config file: %s", err))
+	}
+
+	if err := unmarshalPrefixed(loadPrefix, loadFlags, &loadConfig); err != nil {
+		panic(fmt.Errorf("unable to decode load config: %s", err))
+	}
+	if err := unmarshalPrefixed(queryPrefix, queryFlags, &queryConfig); err != nil {
+		panic(fmt.Errorf("unable to decode query config: %s", err))
+	}
+
+	daemonURLs = strings.Split(viper.GetString("urls"), ",")
+	bucketSize = viper.GetDuration("bucket")
+	queryDelay = viper.GetDuration("query-delay")
+	resultsFile = viper.GetString("results-file")
+	if bucketSize <= 0 {
+		log.Fatal("--bucket must be positive")
+	}
+	if queryConfig.FileName == "" {
+		log.Fatal("missing 'query.file' flag")
+	}
+
+	dataSource := &source.DataSourceConfig{
+		Type: source.FileDataSourceType,
+		File: &source.FileDataSourceConfig{Location: loadConfig.FileName},
+	}
+	if useCase := viper.GetString("use-case"); useCase != "" {
+		if loadConfig.Resume && loadConfig.Seed == 0 {
+			log.Fatal("--load.resume with --use-case requires the --load.seed of the interrupted load")
+		}
+		dataSource = &source.DataSourceConfig{
+			Type: source.SimulatorDataSourceType,
+			Simulator: &common.DataGeneratorConfig{
+				BaseConfig: common.BaseConfig{
+					Format:    constants.FormatInflux,
+					Use:       useCase,
+					Scale:     viper.GetUint64("scale"),
+					TimeStart: viper.GetString("timestamp-start"),
+					TimeEnd:   viper.GetString("timestamp-end"),
+					Seed:      loadConfig.Seed,
+				},
+				InitialScale:          viper.GetUint64("initial-scale"),
+				LogInterval:           viper.GetDuration("log-interval"),
+				InterleavedNumGroups:  1,
+				MaxMetricCountPerHost: viper.GetUint64("max-metric-count"),
+			},
+		}
+	} else if loadConfig.FileName == "" {
+		log.Fatal("missing 'use-case' or 'load.file' flag")
+	}
+	bench, err = target.Benchmark(loadConfig.DBName, dataSource, viper.GetViper())
+	if err != nil {
+		log.Fatal(err)
+	}
+	loader = load.GetBenchmarkRunner(loadConfig)
+
+	queryProcessorConfig = influx.QueryProcessorConfig{
+		URLs:                 daemonURLs,
+		ChunkSize:            viper.GetUint64("chunk-response-size"),
+		Database:             queryConfig.DBName,
+		Debug:                queryConfig.Debug,
+		PrettyPrintResponses: queryConfig.PrintResponses,
+		Language:             viper.GetString("query-language"),
+		Org:                  viper.GetString("org"),
+		Token:                viper.GetString("token"),
+	}
+	if err := queryProcessorConfig.Validate(queryConfig.UseCache); err != nil {
+		log.Fatal(err)
+	}
+	queryConns, err = influx.NewQueryConns(daemonURLs, queryProcessorConfig.Token)
+	if err != nil {
+		log.Fatal(err)
+	}
+	queryRunner = query.NewBenchmarkRunner(queryConfig)
+}
+
+func main() {
+	start := time.Now()
+	tl := timeline.New(start, bucketSize)
+
+	// queries start once the database was created and holds data
+	firstBatch := make(chan struct{})
+	var once sync.Once
+	loader.SetBatchListener(func(metricCount, rowCount uint64) {
+		tl.AddWrite(time.Now(), metricCount, rowCount)
+		once.Do(func() { close(firstBatch) })
+	})
+	queryRunner.SetQueryListener(func(_ string, latencyMillis float64, hitKind uint8) {
+		tl.AddQuery(time.Now(), time.Duration(latencyMillis*float64(time.Millisecond)), hitKind)
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	loadDone := make(chan struct{})
+	go func() {
+		defer wg.Done()
+		loader.RunBenchmark(bench)
+		close(loadDone)
+	}()
+	var queryStart time.Time
+	go func() {
+		defer wg.Done()
+		select {
+		case <-firstBatch:
+		case <-loadDone:
+		}
+		time.Sleep(queryDelay)
+		queryStart = time.Now()
+		if queryProcessorConfig.Language == influx.QueryLanguageInfluxQL {
+			influx.LoadQuerySchema(queryConns[0], queryConfig.DBName)
+		}
+		queryRunner.Run(&query.HTTPPool, influx.NewQueryProcessorCreate(queryProcessorConfig, queryConns))
+	}()
+	wg.Wait()
+	end := time.Now()
+
+	buckets := tl.Buckets()
+	fmt.Printf("\nmixed timeline (%v buckets):\n", bucketSize)
+	if err := timeline.WriteTable(os.Stdout, buckets); err != nil {
+		log.Fatal(err)
+	}
+
+	if resultsFile == "" {
+		return
+	}
+	res := MixedTestResult{
+		ResultFormatVersion: ResultFormatVersion,
+		LoadConfig:          loadConfig,
+		QueryConfig:         queryConfig,
+		StartTime:           start.UTC().UnixNano() / int64(time.Millisecond),
+		EndTime:             end.UTC().UnixNano() / int64(time.Millisecond),
+		DurationMillis:      end.Sub(start).Milliseconds(),
+		QueryStartMillis:    queryStart.Sub(start).Milliseconds(),
+		BucketMillis:        bucketSize.Milliseconds(),
+		Buckets:             buckets,
+	}
+	fmt.Printf("Saving results json file to %s\n", resultsFile)
+	file, err := json.MarshalIndent(res, "", " ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(resultsFile, file, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
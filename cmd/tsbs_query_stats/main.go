@@ -0,0 +1,105 @@
+// tsbs_query_stats characterizes the workload of a generated query file.
+//
+// It reads gob encoded Influx queries, as generated by tsbs_generate_queries,
+// and reports the time range lengths and their distance from the dataset end,
+// the number of series per query, the mix of query types, how queries reuse
+// the segments of earlier ones, and the fraction of bytes a perfect cache
+// could serve. No database or cache server is needed.
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/blagojts/viper"
+	"github.com/spf13/pflag"
+	"github.com/timescale/tsbs/internal/utils"
+	"github.com/timescale/tsbs/pkg/cachesim"
+	"github.com/timescale/tsbs/pkg/query"
+)
+
+// Program option vars:
+var (
+	fileName      string
+	datasetEnd    int64
+	pointInterval time.Duration
+	limit         uint64
+)
+
+// Parse args:
+func init() {
+	pflag.String("file", "", "File name to read queries from, stdin if empty")
+	pflag.String("timestamp-end", "", "End of the dataset the queries were generated for (RFC3339), defaults to the latest query end")
+	pflag.Duration("point-interval", 10*time.Second, "Time between rows of the dataset, used to size results of queries without GROUP BY time")
+	pflag.Uint64("max-queries", 0, "Limit the number of queries to read, 0 = no limit")
+
+	pflag.Parse()
+
+	err := utils.SetupConfigFile()
+
+	if err != nil {
+		panic(fmt.Errorf("fatal error config file: %s", err))
+	}
+
+	fileName = viper.GetString("file")
+	pointInterval = viper.GetDuration("point-interval")
+	limit = viper.GetUint64("max-queries")
+
+	if end := viper.GetString("timestamp-end"); end != "" {
+		t, err := utils.ParseUTCTime(end)
+		if err != nil {
+			log.Fatal(err)
+		}
+		datasetEnd = t.Unix()
+	}
+}
+
+func main() {
+	segmenter := cachesim.NewSegmenter(pointInterval)
+	workload := cachesim.NewWorkload(datasetEnd)
+
+	dec := gob.NewDecoder(getBufferedReader())
+	n, skipped := uint64(0), uint64(0)
+	for limit == 0 || n+skipped < limit {
+		q := query.NewHTTP()
+		err := dec.Decode(q)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		r, err := segmenter.Segment(string(q.HumanLabel), string(q.RawQuery))
+		q.Release()
+		if err != nil {
+			skipped++
+			continue
+		}
+		workload.Add(r)
+		n++
+	}
+
+	if skipped > 0 {
+		fmt.Printf("skipped %d queries without time range\n", skipped)
+	}
+	if err := workload.Write(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func getBufferedReader() *bufio.Reader {
+	if len(fileName) == 0 {
+		return bufio.NewReaderSize(os.Stdin, 4<<20)
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		log.Fatalf("cannot open file for read %s: %v", fileName, err)
+	}
+	return bufio.NewReaderSize(file, 4<<20)
+}
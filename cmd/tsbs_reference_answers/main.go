@@ -0,0 +1,159 @@
+// tsbs_reference_answers computes the expected answers of generated queries.
+//
+// It reads gob encoded Influx queries, as generated by tsbs_generate_queries,
+// and evaluates each of them in memory over the data they were generated for:
+// either a data file in Influx line protocol, or the data simulated from the
+// same flags as the ones given to tsbs_generate_data. It writes the expected
+// result of each query as a line of JSON, keyed by the ID the query runner
+// gives it, which tsbs_run_queries_influx --reference-answers checks the
+// results of the database or cache against. No database is needed.
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/blagojts/viper"
+	"github.com/spf13/pflag"
+	"github.com/timescale/tsbs/internal/inputs"
+	"github.com/timescale/tsbs/internal/utils"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"github.com/timescale/tsbs/pkg/query"
+	"github.com/timescale/tsbs/pkg/reference"
+	"github.com/timescale/tsbs/pkg/targets/constants"
+	"github.com/timescale/tsbs/pkg/targets/initializers"
+)
+
+// Program option vars:
+var (
+	dataFile    string
+	queriesFile string
+	outputFile  string
+	config      = &common.DataGeneratorConfig{}
+)
+
+// Parse args:
+func init() {
+	config.AddToFlagSet(pflag.CommandLine)
+
+	pflag.String("data-file", "", "File name to read the data from, in Influx line protocol; if empty the data is simulated from the data generation flags")
+	pflag.String("queries-file", "", "File name to read queries from, stdin if empty")
+	pflag.String("output", "", "File name to write the answers to, stdout if empty")
+
+	pflag.Parse()
+
+	err := utils.SetupConfigFile()
+
+	if err != nil {
+		panic(fmt.Errorf("fatal error config file: %s", err))
+	}
+
+	dataFile = viper.GetString("data-file")
+	queriesFile = viper.GetString("queries-file")
+	outputFile = viper.GetString("output")
+
+	if dataFile == "" {
+		if err := viper.Unmarshal(&config.BaseConfig); err != nil {
+			panic(fmt.Errorf("unable to decode base config: %s", err))
+		}
+		if err := viper.Unmarshal(&config); err != nil {
+			panic(fmt.Errorf("unable to decode config: %s", err))
+		}
+		// the generator would write the data to the file
+		if config.File != "" {
+			log.Fatal("--file is not supported, use --data-file to read a data file")
+		}
+	}
+}
+
+func main() {
+	dataset := reference.NewDataset()
+	var err error
+	if dataFile != "" {
+		err = dataset.Read(getBufferedReader(dataFile))
+	} else {
+		err = simulate(dataset)
+	}
+	if err != nil {
+		log.Fatalf("cannot read data: %v", err)
+	}
+
+	out := os.Stdout
+	if outputFile != "" {
+		out, err = os.Create(outputFile)
+		if err != nil {
+			log.Fatalf("cannot open file for write %s: %v", outputFile, err)
+		}
+	}
+	w := bufio.NewWriterSize(out, 4<<20)
+
+	dec := gob.NewDecoder(getBufferedReader(queriesFile))
+	n, unsupported := uint64(0), uint64(0)
+	for ; ; n++ {
+		q := query.NewHTTP()
+		err := dec.Decode(q)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// the query runner numbers the queries from 0 in the order of the file
+		answer := &reference.Answer{ID: n, Label: string(q.HumanLabel), Query: string(q.RawQuery)}
+		answer.Series, err = dataset.Evaluate(answer.Query)
+		if err != nil {
+			answer.Err = err.Error()
+			unsupported++
+		}
+		q.Release()
+		if err := reference.WriteAnswer(w, answer); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "%d queries, %d answered, %d unsupported\n", n, n-unsupported, unsupported)
+}
+
+// simulate adds the points tsbs_generate_data generates for config, in
+// Influx line protocol, to dataset.
+func simulate(dataset *reference.Dataset) error {
+	config.Format = constants.FormatInflux
+	// only the data is needed, as fast as possible
+	config.AnomalyLabels = ""
+	config.QualityReport = ""
+	config.StreamConfig = common.StreamConfig{}
+
+	target, err := initializers.GetTarget(config.Format)
+	if err != nil {
+		return err
+	}
+	r, w := io.Pipe()
+	dg := &inputs.DataGenerator{Out: w}
+	go func() {
+		w.CloseWithError(dg.Generate(config, target))
+	}()
+	err = dataset.Read(r)
+	r.Close()
+	return err
+}
+
+func getBufferedReader(fileName string) *bufio.Reader {
+	if len(fileName) == 0 {
+		return bufio.NewReaderSize(os.Stdin, 4<<20)
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		log.Fatalf("cannot open file for read %s: %v", fileName, err)
+	}
+	return bufio.NewReaderSize(file, 4<<20)
+}
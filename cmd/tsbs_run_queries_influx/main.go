@@ -9,18 +9,26 @@ import (
 	"fmt"
 	client "github.com/timescale/tsbs/InfluxDB-client/v2"
 	"log"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/blagojts/viper"
 	"github.com/spf13/pflag"
 	"github.com/timescale/tsbs/internal/utils"
 	"github.com/timescale/tsbs/pkg/query"
+	"github.com/timescale/tsbs/pkg/reference"
+	"github.com/timescale/tsbs/pkg/targets/influx"
 )
 
 // Program option vars:
 var (
 	daemonUrls []string
 	chunkSize  uint64
+	language   string
+	org        string
+	token      string
+	answers    *reference.Answers
 )
 
 // Global vars:
@@ -41,6 +49,10 @@ func init() {
 
 	pflag.String("urls", "http://localhost:8086", "Daemon URLs, comma-separated. Will be used in a round-robin fashion.")
 	pflag.Uint64("chunk-response-size", 0, "Number of series to chunk results into. 0 means no chunking.")
+	pflag.String("query-language", influx.QueryLanguageInfluxQL, "Language of the queries: influxql, sent to /query, or flux, sent to the /api/v2/query endpoint of InfluxDB 2.x (requires --use-cache=db)")
+	pflag.String("org", "", "Organization to run flux queries in")
+	pflag.String("token", "", "API token sent as 'Authorization: Token', e.g. for InfluxDB 2.x")
+	pflag.String("reference-answers", "", "File of expected answers written by tsbs_reference_answers to check the results of InfluxQL queries against")
 
 	pflag.Parse()
 
@@ -56,61 +68,66 @@ func init() {
 
 	csvDaemonUrls = viper.GetString("urls")
 	chunkSize = viper.GetUint64("chunk-response-size")
+	language = viper.GetString("query-language")
+	org = viper.GetString("org")
+	token = viper.GetString("token")
 	client.DB = viper.GetString("db-name")
+	if answersFile := viper.GetString("reference-answers"); answersFile != "" {
+		answers = readAnswers(answersFile)
+	}
 
 	daemonUrls = strings.Split(csvDaemonUrls, ",")
 	if len(daemonUrls) == 0 {
 		log.Fatal("missing 'urls' flag")
 	}
-	DBConn = make([]client.Client, len(daemonUrls))
 	// todo	多数据库
-	for i := range daemonUrls {
-		DBConn[i], _ = client.NewHTTPClient(client.HTTPConfig{Addr: daemonUrls[i]})
+	if err := processorConfig(config).Validate(config.UseCache); err != nil {
+		log.Fatal(err)
+	}
+	DBConn, err = influx.NewQueryConns(daemonUrls, token)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// the schema is only needed to split and merge InfluxQL results in the cache
+	if language == influx.QueryLanguageInfluxQL {
+		influx.LoadQuerySchema(DBConn[0], client.DB)
 	}
-	client.TagKV = client.GetTagKV(DBConn[0], client.DB)
-	client.Fields = client.GetFieldKeys(DBConn[0], client.DB)
 
 	runner = query.NewBenchmarkRunner(config)
 }
 
-func main() {
-	runner.Run(&query.HTTPPool, newProcessor)
-}
-
-type processor struct {
-	w    *HTTPClient
-	opts *HTTPClientDoOptions
+func processorConfig(config query.BenchmarkRunnerConfig) influx.QueryProcessorConfig {
+	return influx.QueryProcessorConfig{
+		URLs:                 daemonUrls,
+		ChunkSize:            chunkSize,
+		Database:             config.DBName,
+		Debug:                config.Debug,
+		PrettyPrintResponses: config.PrintResponses,
+		Language:             language,
+		Org:                  org,
+		Token:                token,
+		Answers:              answers,
+	}
 }
 
-func newProcessor() query.Processor { return &processor{} }
-
-func (p *processor) Init(workerNumber int) {
-	p.opts = &HTTPClientDoOptions{
-		Debug:                runner.DebugLevel(),
-		PrettyPrintResponses: runner.DoPrintResponses(),
-		chunkSize:            chunkSize,
-		database:             runner.DatabaseName(),
+// readAnswers reads the expected answers in fileName, whose time values are
+// in the seconds the queries are run with.
+func readAnswers(fileName string) *reference.Answers {
+	f, err := os.Open(fileName)
+	if err != nil {
+		log.Fatalf("cannot open file for read %s: %v", fileName, err)
+	}
+	defer f.Close()
+	a, err := reference.ReadAnswers(f, time.Second)
+	if err != nil {
+		log.Fatalf("cannot read reference answers %s: %v", fileName, err)
 	}
-	url := daemonUrls[workerNumber%len(daemonUrls)]
-	p.w = NewHTTPClient(url)
+	return a
 }
 
-func (p *processor) ProcessQuery(q query.Query, _ bool, workerNum int) ([]*query.Stat, error) {
-	hq := q.(*query.HTTP)
-
-	//println(string(hq.Path))
-	//println(len(string(hq.Path)))
-	//println(len(string(hq.RawQuery)))
-	//println(string(hq.RawQuery))
-
-	// todo
-	lag, byteLength, hitKind, err := p.w.Do(hq, p.opts, workerNum)
-
-	if err != nil {
-		return nil, err
+func main() {
+	runner.Run(&query.HTTPPool, influx.NewQueryProcessorCreate(processorConfig(runner.BenchmarkRunnerConfig), DBConn))
+	if answers != nil {
+		answers.WriteSummary(os.Stderr)
 	}
-	// todo
-	stat := query.GetStat()
-	stat.Init(q.HumanLabelName(), lag, byteLength, hitKind)
-	return []*query.Stat{stat}, nil
 }
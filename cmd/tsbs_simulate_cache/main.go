@@ -0,0 +1,195 @@
+// tsbs_simulate_cache estimates the hit ratios of semantic cache policies.
+//
+// It reads gob encoded Influx queries, as generated by tsbs_generate_queries,
+// reduces each of them to the segments and time range STsCache would look up,
+// and replays them against in-memory models of several cache policies under
+// the same byte budget. No database or cache server is needed.
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blagojts/viper"
+	"github.com/spf13/pflag"
+	"github.com/timescale/tsbs/internal/utils"
+	"github.com/timescale/tsbs/pkg/cachesim"
+	"github.com/timescale/tsbs/pkg/query"
+)
+
+// Program option vars:
+var (
+	fileName       string
+	policies       []string
+	cacheSize      int64
+	ttl            uint64
+	pointInterval  time.Duration
+	reportInterval uint64
+	limit          uint64
+	csvFileName    string
+)
+
+// Parse args:
+func init() {
+	pflag.String("file", "", "File name to read queries from, stdin if empty")
+	pflag.String("policies", strings.Join(cachesim.PolicyNames, ","), "Comma-separated cache policies to simulate: lru, lfu, arc, ttl, size")
+	pflag.String("cache-size", "1GB", "Byte budget of each simulated cache, e.g. 512MB, 2GB")
+	pflag.Uint64("ttl", 10000, "Number of queries an item is served for by the ttl policy")
+	pflag.Duration("point-interval", 10*time.Second, "Time between rows of the dataset, used to size results of queries without GROUP BY time")
+	pflag.Uint64("report-interval", 1000, "Print hit ratios after this many queries (0 to disable)")
+	pflag.Uint64("max-queries", 0, "Limit the number of queries to replay, 0 = no limit")
+	pflag.String("csv", "", "Write the hit ratios of every report interval to this CSV file")
+
+	pflag.Parse()
+
+	err := utils.SetupConfigFile()
+
+	if err != nil {
+		panic(fmt.Errorf("fatal error config file: %s", err))
+	}
+
+	fileName = viper.GetString("file")
+	ttl = viper.GetUint64("ttl")
+	pointInterval = viper.GetDuration("point-interval")
+	reportInterval = viper.GetUint64("report-interval")
+	limit = viper.GetUint64("max-queries")
+	csvFileName = viper.GetString("csv")
+
+	cacheSize, err = utils.ParseByteSize(viper.GetString("cache-size"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, p := range strings.Split(viper.GetString("policies"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			policies = append(policies, p)
+		}
+	}
+	if len(policies) == 0 {
+		log.Fatal("missing 'policies' flag")
+	}
+}
+
+func main() {
+	caches := make([]*cachesim.Cache, 0, len(policies)+1)
+	for _, p := range append(policies, cachesim.PolicyOracle) {
+		c, err := cachesim.NewCache(p, cacheSize, ttl)
+		if err != nil {
+			log.Fatal(err)
+		}
+		caches = append(caches, c)
+	}
+
+	var csvFile *os.File
+	var csvOut *csv.Writer
+	if csvFileName != "" {
+		var err error
+		csvFile, err = os.Create(csvFileName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		csvOut = csv.NewWriter(csvFile)
+		csvOut.Write([]string{"queries", "policy", "full_hit_ratio", "partial_hit_ratio", "byte_hit_ratio",
+			"total_full_hit_ratio", "total_partial_hit_ratio", "total_byte_hit_ratio", "used_bytes"})
+	}
+
+	segmenter := cachesim.NewSegmenter(pointInterval)
+	last := make([]cachesim.Stats, len(caches))
+	report := func(n uint64) {
+		fmt.Printf("after %d queries:\n", n)
+		for i, c := range caches {
+			total := c.Stats()
+			window := total.Sub(last[i])
+			last[i] = total
+			printStats(c.Name, window, total)
+			if csvOut != nil {
+				csvOut.Write([]string{
+					strconv.FormatUint(n, 10), c.Name,
+					formatRatio(window.FullHitRatio()), formatRatio(window.PartialHitRatio()), formatRatio(window.ByteHitRatio()),
+					formatRatio(total.FullHitRatio()), formatRatio(total.PartialHitRatio()), formatRatio(total.ByteHitRatio()),
+					strconv.FormatInt(c.UsedBytes(), 10),
+				})
+			}
+		}
+	}
+
+	dec := gob.NewDecoder(getBufferedReader())
+	n, skipped := uint64(0), uint64(0)
+	for limit == 0 || n < limit {
+		q := query.NewHTTP()
+		err := dec.Decode(q)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		r, err := segmenter.Segment(string(q.HumanLabel), string(q.RawQuery))
+		q.Release()
+		if err != nil {
+			skipped++
+			continue
+		}
+		for _, c := range caches {
+			c.Access(r)
+		}
+		n++
+
+		if reportInterval > 0 && n%reportInterval == 0 {
+			report(n)
+		}
+	}
+	if reportInterval == 0 || n%reportInterval != 0 {
+		report(n)
+	}
+
+	fmt.Printf("\nreplayed %d queries (%d skipped without time range) with a cache size of %d bytes\n", n, skipped, cacheSize)
+	fmt.Printf("%-8s %10s %10s %10s %14s %10s\n", "policy", "full hit", "partial", "byte hit", "used bytes", "evictions")
+	for _, c := range caches {
+		s := c.Stats()
+		fmt.Printf("%-8s %9.2f%% %9.2f%% %9.2f%% %14d %10d\n", c.Name,
+			100*s.FullHitRatio(), 100*s.PartialHitRatio(), 100*s.ByteHitRatio(), c.UsedBytes(), s.Evictions)
+	}
+
+	if csvOut != nil {
+		// the writer keeps the first error of Write and Flush
+		csvOut.Flush()
+		if err := csvOut.Error(); err != nil {
+			log.Fatalf("cannot write CSV file %s: %v", csvFileName, err)
+		}
+		if err := csvFile.Close(); err != nil {
+			log.Fatalf("cannot close CSV file %s: %v", csvFileName, err)
+		}
+	}
+}
+
+func printStats(name string, window, total cachesim.Stats) {
+	fmt.Printf("  %-8s full hit %6.2f%% (%6.2f%% total), partial %6.2f%% (%6.2f%% total), byte hit %6.2f%% (%6.2f%% total)\n",
+		name,
+		100*window.FullHitRatio(), 100*total.FullHitRatio(),
+		100*window.PartialHitRatio(), 100*total.PartialHitRatio(),
+		100*window.ByteHitRatio(), 100*total.ByteHitRatio())
+}
+
+func formatRatio(r float64) string {
+	return strconv.FormatFloat(r, 'f', 6, 64)
+}
+
+func getBufferedReader() *bufio.Reader {
+	if len(fileName) == 0 {
+		return bufio.NewReaderSize(os.Stdin, 4<<20)
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		log.Fatalf("cannot open file for read %s: %v", fileName, err)
+	}
+	return bufio.NewReaderSize(file, 4<<20)
+}
@@ -0,0 +1,168 @@
+package inputs
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	queryUtils "github.com/timescale/tsbs/cmd/tsbs_generate_queries/utils"
+	internalUtils "github.com/timescale/tsbs/internal/utils"
+	"github.com/timescale/tsbs/pkg/query"
+	"github.com/timescale/tsbs/pkg/query/config"
+)
+
+const (
+	errNoDashboardFitsFmt = "no dashboard range fits between %v and %v"
+)
+
+// dashboard is one simulated dashboard: a fixed set of panels whose queries
+// cover the last rng of a virtual clock, re-issued every refresh period.
+type dashboard struct {
+	id     int
+	rng    time.Duration
+	panels []string
+	// seeds fix the random choices (hosts, trucks, metrics) of each panel so
+	// that successive refreshes only differ in their time range.
+	seeds []int64
+	// rand draws the random choices of a panel, reseeded with its seed
+	rand *rand.Rand
+	next time.Time
+}
+
+// dashboardPanels returns the query types shown on every dashboard.
+func dashboardPanels(c *config.QueryGeneratorConfig) []string {
+	if c.DashboardPanels == "" {
+		return []string{c.QueryType}
+	}
+	return splitList(c.DashboardPanels)
+}
+
+func splitList(s string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// newDashboards creates the configured dashboards. Ranges are assigned
+// round-robin and the first refresh of each dashboard happens once its range
+// fits in the dataset, shifted by a random phase within one refresh period so
+// that dashboards do not all refresh at the same instant.
+func newDashboards(c *config.QueryGeneratorConfig, start time.Time, r *rand.Rand) ([]*dashboard, error) {
+	ranges := splitList(c.DashboardRanges)
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf(config.ErrEmptyDashboardRanges)
+	}
+	rngs := make([]time.Duration, len(ranges))
+	for i, s := range ranges {
+		d, err := internalUtils.ParseRelativeRange(s)
+		if err != nil {
+			return nil, err
+		}
+		rngs[i] = d
+	}
+
+	panels := dashboardPanels(c)
+	phaseSecs := int64(c.DashboardRefresh / time.Second)
+	if phaseSecs < 1 {
+		phaseSecs = 1
+	}
+
+	dashboards := make([]*dashboard, c.Dashboards)
+	for i := range dashboards {
+		d := &dashboard{
+			id:     i,
+			rng:    rngs[i%len(rngs)],
+			panels: panels,
+			seeds:  make([]int64, len(panels)),
+			rand:   rand.New(rand.NewSource(0)),
+		}
+		for j := range d.seeds {
+			d.seeds[j] = r.Int63()
+		}
+		d.next = start.Add(d.rng).Add(time.Duration(r.Int63n(phaseSecs)) * time.Second)
+		dashboards[i] = d
+	}
+	return dashboards, nil
+}
+
+// fillPanel fills q with the whole interval of the filler's generator. Query
+// types with a fixed window panic in MustRandWindow when the window does not
+// fit in the interval, e.g. the dashboard range; that panic is reported as an
+// error instead, any other one is propagated.
+func fillPanel(q query.Query, filler queryUtils.QueryFiller, zipNum int64) (filled query.Query, err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		msg, ok := r.(string)
+		if !ok || !strings.HasPrefix(msg, internalUtils.ErrWindowTooLarge) {
+			panic(r)
+		}
+		q.Release()
+		err = fmt.Errorf("%s", msg)
+	}()
+	return filler.Fill(q, zipNum, 0, internalUtils.WholeInterval), nil
+}
+
+// nextDashboard returns the dashboard with the earliest pending refresh,
+// preferring the lowest id on ties.
+func nextDashboard(dashboards []*dashboard) *dashboard {
+	var next *dashboard
+	for _, d := range dashboards {
+		if next == nil || d.next.Before(next.next) {
+			next = d
+		}
+	}
+	return next
+}
+
+// runDashboardGeneration emits the queries of the configured dashboards as a
+// virtual clock advances from the start to the end of the dataset. Each
+// refresh issues one query per panel covering [now-range, now], so the windows
+// of consecutive refreshes overlap and slide forward by the refresh period.
+// Generation stops at the end of the dataset or after Limit queries.
+func (g *QueryGenerator) runDashboardGeneration(c *config.QueryGeneratorConfig) error {
+	defer g.bufOut.Flush()
+
+	w, err := g.newQueryWriter(c)
+	if err != nil {
+		return err
+	}
+	dashboards, err := newDashboards(c, g.tsStart, rand.New(rand.NewSource(c.Seed)))
+	if err != nil {
+		return err
+	}
+
+	count := uint64(0)
+	fits := false
+	for d := nextDashboard(dashboards); !d.next.After(g.tsEnd); d = nextDashboard(dashboards) {
+		fits = true
+		for i, panel := range d.panels {
+			d.rand.Seed(d.seeds[i])
+			q, err := g.fillRange(c, panel, d.next.Add(-d.rng), d.next, d.rand)
+			if err != nil {
+				return err
+			}
+			if err := w.write(q); err != nil {
+				return err
+			}
+
+			count++
+			if c.Limit > 0 && count >= c.Limit {
+				return g.printQueryStats(w.stats)
+			}
+		}
+		d.next = d.next.Add(c.DashboardRefresh)
+	}
+
+	if !fits {
+		return fmt.Errorf(errNoDashboardFitsFmt, g.tsStart, g.tsEnd)
+	}
+	return g.printQueryStats(w.stats)
+}
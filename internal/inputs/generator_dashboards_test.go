@@ -0,0 +1,366 @@
+package inputs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/databases/influx"
+	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/uses/devops"
+	queryUtils "github.com/timescale/tsbs/cmd/tsbs_generate_queries/utils"
+	internalUtils "github.com/timescale/tsbs/internal/utils"
+	"github.com/timescale/tsbs/pkg/query"
+	"github.com/timescale/tsbs/pkg/query/config"
+)
+
+const testRefresh = 30 * time.Minute
+
+// windowFiller fills a query with the whole interval of its generator and
+// one random host, so that tests can check the window and the random choices
+// of every dashboard panel.
+type windowFiller struct {
+	core  queryUtils.QueryGenerator
+	label string
+}
+
+func newWindowFiller(label string) queryUtils.QueryFillerMaker {
+	return func(core queryUtils.QueryGenerator) queryUtils.QueryFiller {
+		return &windowFiller{core: core, label: label}
+	}
+}
+
+func (f *windowFiller) Fill(q query.Query, zipNum int64, latestNum int64, newOrOld int) query.Query {
+	d := f.core.(*influx.Devops)
+	hosts, err := d.GetRandomHosts(1)
+	if err != nil {
+		panic(err.Error())
+	}
+	hq := q.(*query.HTTP)
+	hq.HumanLabel = []byte(f.label)
+	hq.HumanDescription = []byte(d.Interval.StartString() + "," + d.Interval.EndString())
+	hq.Path = []byte(hosts[0])
+	return q
+}
+
+// panelQuery is a decoded query of windowFiller.
+type panelQuery struct {
+	panel      string
+	start, end time.Time
+	host       string
+}
+
+func getTestDashboardConfigAndGenerator() (*config.QueryGeneratorConfig, *QueryGenerator) {
+	c, g := getTestConfigAndGenerator()
+	c.Limit = 0
+	c.QueryType = ""
+	c.Dashboards = 2
+	c.DashboardPanels = "panel-a,panel-b"
+	c.DashboardRanges = "now-2h,now-4h"
+	c.DashboardRefresh = testRefresh
+	m := g.useCaseMatrix[c.Use]
+	m["panel-a"] = newWindowFiller("panel-a")
+	m["panel-b"] = newWindowFiller("panel-b")
+	m["panel-12h"] = devops.NewSingleGroupby(1, 1, 12)
+	return c, g
+}
+
+// runTestDashboards runs the dashboard generation of c and returns the
+// decoded queries.
+func runTestDashboards(t *testing.T, c *config.QueryGeneratorConfig, g *QueryGenerator) []panelQuery {
+	if err := g.init(c); err != nil {
+		t.Fatalf("unexpected error initializing: got %v", err)
+	}
+	var buf bytes.Buffer
+	g.bufOut = bufio.NewWriter(&buf)
+	g.DebugOut = ioutil.Discard
+	if err := g.runDashboardGeneration(c); err != nil {
+		t.Fatalf("unexpected error: got %v", err)
+	}
+
+	decoder := gob.NewDecoder(&buf)
+	queries := make([]panelQuery, 0)
+	for {
+		q := &query.HTTP{}
+		err := decoder.Decode(q)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("unexpected error while decoding: got %v", err)
+		}
+		bounds := strings.Split(string(q.HumanDescription), ",")
+		start, err := time.Parse(time.RFC3339, bounds[0])
+		if err != nil {
+			t.Fatalf("could not parse start: %v", err)
+		}
+		end, err := time.Parse(time.RFC3339, bounds[1])
+		if err != nil {
+			t.Fatalf("could not parse end: %v", err)
+		}
+		queries = append(queries, panelQuery{string(q.HumanLabel), start, end, string(q.Path)})
+	}
+	return queries
+}
+
+func TestNewDashboards(t *testing.T) {
+	c, _ := getTestDashboardConfigAndGenerator()
+	c.Dashboards = 3
+	start := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	dashboards, err := newDashboards(c, start, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("unexpected error: got %v", err)
+	}
+	if got := len(dashboards); got != 3 {
+		t.Fatalf("incorrect number of dashboards: got %d want %d", got, 3)
+	}
+	wantRanges := []time.Duration{2 * time.Hour, 4 * time.Hour, 2 * time.Hour}
+	for i, d := range dashboards {
+		if d.id != i {
+			t.Errorf("incorrect id: got %d want %d", d.id, i)
+		}
+		if d.rng != wantRanges[i] {
+			t.Errorf("dashboard %d: incorrect range: got %v want %v", i, d.rng, wantRanges[i])
+		}
+		if got := strings.Join(d.panels, ","); got != c.DashboardPanels {
+			t.Errorf("dashboard %d: incorrect panels: got %s want %s", i, got, c.DashboardPanels)
+		}
+		if got := len(d.seeds); got != len(d.panels) {
+			t.Errorf("dashboard %d: incorrect number of seeds: got %d want %d", i, got, len(d.panels))
+		}
+		phase := d.next.Sub(start.Add(d.rng))
+		if phase < 0 || phase >= testRefresh || phase%time.Second != 0 {
+			t.Errorf("dashboard %d: incorrect phase: got %v", i, phase)
+		}
+	}
+
+	// Dashboards are deterministic for a given source
+	again, _ := newDashboards(c, start, rand.New(rand.NewSource(1)))
+	for i, d := range again {
+		if !d.next.Equal(dashboards[i].next) || d.seeds[0] != dashboards[i].seeds[0] {
+			t.Errorf("dashboard %d differs for the same source", i)
+		}
+	}
+}
+
+func TestNewDashboardsErrors(t *testing.T) {
+	c, _ := getTestDashboardConfigAndGenerator()
+	start := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	c.DashboardRanges = " , "
+	_, err := newDashboards(c, start, rand.New(rand.NewSource(1)))
+	if err == nil {
+		t.Errorf("unexpected lack of error for empty ranges")
+	} else if got := err.Error(); got != config.ErrEmptyDashboardRanges {
+		t.Errorf("incorrect error: got %s want %s", got, config.ErrEmptyDashboardRanges)
+	}
+
+	c.DashboardRanges = "now-2h,now-x"
+	_, err = newDashboards(c, start, rand.New(rand.NewSource(1)))
+	if err == nil {
+		t.Errorf("unexpected lack of error for bad range")
+	}
+}
+
+func TestDashboardPanels(t *testing.T) {
+	c := &config.QueryGeneratorConfig{QueryType: "single-groupby-1-1-1"}
+	if got := strings.Join(dashboardPanels(c), ","); got != c.QueryType {
+		t.Errorf("incorrect default panels: got %s want %s", got, c.QueryType)
+	}
+	c.DashboardPanels = " a, ,b "
+	if got := strings.Join(dashboardPanels(c), ","); got != "a,b" {
+		t.Errorf("incorrect panels: got %s want %s", got, "a,b")
+	}
+}
+
+func TestNextDashboard(t *testing.T) {
+	start := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+	dashboards := []*dashboard{
+		{id: 0, next: start.Add(2 * time.Hour)},
+		{id: 1, next: start.Add(time.Hour)},
+		{id: 2, next: start.Add(time.Hour)},
+	}
+	if got := nextDashboard(dashboards).id; got != 1 {
+		t.Errorf("incorrect next dashboard: got %d want %d", got, 1)
+	}
+	dashboards[1].next = start.Add(3 * time.Hour)
+	if got := nextDashboard(dashboards).id; got != 2 {
+		t.Errorf("incorrect next dashboard: got %d want %d", got, 2)
+	}
+}
+
+type panicFiller struct {
+	msg string
+}
+
+func (f *panicFiller) Fill(q query.Query, zipNum int64, latestNum int64, newOrOld int) query.Query {
+	panic(f.msg)
+}
+
+func TestFillPanel(t *testing.T) {
+	tooLarge := internalUtils.ErrWindowTooLarge + ": window 12h0m0s, interval 2h0m0s"
+	q, err := fillPanel(query.NewHTTP(), &panicFiller{msg: tooLarge}, 0)
+	if err == nil {
+		t.Errorf("unexpected lack of error for too large window")
+	} else if got := err.Error(); got != tooLarge {
+		t.Errorf("incorrect error: got %s want %s", got, tooLarge)
+	}
+	if q != nil {
+		t.Errorf("query was not nil")
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("unexpected lack of panic for other panics")
+		} else if got := r.(string); got != "other" {
+			t.Errorf("incorrect panic: got %s want %s", got, "other")
+		}
+	}()
+	fillPanel(query.NewHTTP(), &panicFiller{msg: "other"}, 0)
+}
+
+func TestQueryGeneratorRunDashboardGeneration(t *testing.T) {
+	c, g := getTestDashboardConfigAndGenerator()
+	queries := runTestDashboards(t, c, g)
+	if len(queries) == 0 {
+		t.Fatalf("no queries generated")
+	}
+
+	type key struct {
+		rng   time.Duration
+		panel string
+	}
+	lastEnd := map[key]time.Time{}
+	hosts := map[key]string{}
+	for i, q := range queries {
+		rng := q.end.Sub(q.start)
+		if rng != 2*time.Hour && rng != 4*time.Hour {
+			t.Fatalf("query %d: window is not a dashboard range: got %v", i, rng)
+		}
+		if q.start.Before(g.tsStart) || q.end.After(g.tsEnd) {
+			t.Errorf("query %d: window %v-%v outside of the dataset", i, q.start, q.end)
+		}
+
+		// Panels of one refresh are issued one after another over one window
+		wantPanel := "panel-a"
+		if i%2 == 1 {
+			wantPanel = "panel-b"
+			prev := queries[i-1]
+			if !prev.start.Equal(q.start) || !prev.end.Equal(q.end) {
+				t.Errorf("query %d: panels of a refresh have different windows", i)
+			}
+		}
+		if q.panel != wantPanel {
+			t.Errorf("query %d: incorrect panel: got %s want %s", i, q.panel, wantPanel)
+		}
+
+		// Refreshes come in the order of the virtual clock
+		if i > 0 && q.end.Before(queries[i-1].end) {
+			t.Errorf("query %d: refresh at %v before the previous one at %v", i, q.end, queries[i-1].end)
+		}
+
+		// Windows of a dashboard slide by the refresh period and keep the
+		// random choices of the panel
+		k := key{rng, q.panel}
+		if prev, ok := lastEnd[k]; !ok {
+			phase := q.end.Sub(g.tsStart.Add(rng))
+			if phase < 0 || phase >= testRefresh {
+				t.Errorf("query %d: incorrect first refresh phase: got %v", i, phase)
+			}
+			hosts[k] = q.host
+		} else {
+			if got := q.end.Sub(prev); got != testRefresh {
+				t.Errorf("query %d: incorrect slide: got %v want %v", i, got, testRefresh)
+			}
+			if q.host != hosts[k] {
+				t.Errorf("query %d: random choice changed between refreshes: got %s want %s", i, q.host, hosts[k])
+			}
+		}
+		lastEnd[k] = q.end
+	}
+	if got := len(lastEnd); got != 4 {
+		t.Errorf("incorrect number of dashboard panels: got %d want %d", got, 4)
+	}
+	for k, end := range lastEnd {
+		if end.Add(testRefresh).Before(g.tsEnd) || end.Add(testRefresh).Equal(g.tsEnd) {
+			t.Errorf("%v: generation stopped at %v before the end of the dataset", k, end)
+		}
+	}
+
+	// Generation is deterministic for a given seed
+	c2, g2 := getTestDashboardConfigAndGenerator()
+	again := runTestDashboards(t, c2, g2)
+	if len(again) != len(queries) {
+		t.Fatalf("incorrect number of queries for the same seed: got %d want %d", len(again), len(queries))
+	}
+	for i := range again {
+		if again[i] != queries[i] {
+			t.Errorf("query %d differs for the same seed: got %v want %v", i, again[i], queries[i])
+		}
+	}
+
+	// Limit stops in the middle of a refresh
+	c3, g3 := getTestDashboardConfigAndGenerator()
+	c3.Limit = 3
+	limited := runTestDashboards(t, c3, g3)
+	if len(limited) != 3 {
+		t.Fatalf("incorrect number of queries with limit: got %d want %d", len(limited), 3)
+	}
+	for i := range limited {
+		if limited[i] != queries[i] {
+			t.Errorf("query %d differs with limit: got %v want %v", i, limited[i], queries[i])
+		}
+	}
+
+	// Interleaved groups split the queries of all dashboards
+	c4, g4 := getTestDashboardConfigAndGenerator()
+	c4.InterleavedNumGroups = 3
+	c4.InterleavedGroupID = 1
+	grouped := runTestDashboards(t, c4, g4)
+	wantGrouped := make([]panelQuery, 0)
+	for i := 1; i < len(queries); i += 3 {
+		wantGrouped = append(wantGrouped, queries[i])
+	}
+	if len(grouped) != len(wantGrouped) {
+		t.Fatalf("incorrect number of interleaved queries: got %d want %d", len(grouped), len(wantGrouped))
+	}
+	for i := range grouped {
+		if grouped[i] != wantGrouped[i] {
+			t.Errorf("interleaved query %d: got %v want %v", i, grouped[i], wantGrouped[i])
+		}
+	}
+}
+
+func TestQueryGeneratorRunDashboardGenerationErrors(t *testing.T) {
+	c, g := getTestDashboardConfigAndGenerator()
+	c.DashboardRanges = "now-48h"
+	if err := g.init(c); err != nil {
+		t.Fatalf("unexpected error initializing: got %v", err)
+	}
+	g.bufOut = bufio.NewWriter(ioutil.Discard)
+	want := fmt.Sprintf(errNoDashboardFitsFmt, g.tsStart, g.tsEnd)
+	if err := g.runDashboardGeneration(c); err == nil {
+		t.Errorf("unexpected lack of error when no dashboard fits")
+	} else if got := err.Error(); got != want {
+		t.Errorf("incorrect error:\ngot\n%s\nwant\n%s", got, want)
+	}
+
+	c, g = getTestDashboardConfigAndGenerator()
+	c.DashboardPanels = "panel-a,panel-12h"
+	if err := g.init(c); err != nil {
+		t.Fatalf("unexpected error initializing: got %v", err)
+	}
+	g.bufOut = bufio.NewWriter(ioutil.Discard)
+	want = fmt.Sprintf(errQueryNotFilledFmt, "panel-12h", 2*time.Hour, internalUtils.ErrWindowTooLarge)
+	if err := g.runDashboardGeneration(c); err == nil {
+		t.Errorf("unexpected lack of error for a panel longer than its range")
+	} else if got := err.Error(); !strings.HasPrefix(got, want) {
+		t.Errorf("incorrect error:\ngot\n%s\nwant prefix\n%s", got, want)
+	}
+}
@@ -2,13 +2,16 @@ package inputs
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
-	"math/rand"
 	"os"
 	"sort"
+	"time"
 
+	internalUtils "github.com/timescale/tsbs/internal/utils"
 	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/columnar"
 	"github.com/timescale/tsbs/pkg/data/serialize"
 	"github.com/timescale/tsbs/pkg/data/usecases"
 	"github.com/timescale/tsbs/pkg/data/usecases/common"
@@ -20,6 +23,9 @@ import (
 const (
 	ErrNoConfig          = "no GeneratorConfig provided"
 	ErrInvalidDataConfig = "invalid config: DataGenerator needs a DataGeneratorConfig"
+
+	errCannotWriteLabelsFmt = "cannot write anomaly labels to '%s': %v"
+	errCannotWriteReportFmt = "cannot write quality report to '%s': %v"
 )
 
 // DataGenerator is a type of Generator for creating data that will be consumed
@@ -36,6 +42,13 @@ type DataGenerator struct {
 	// bufOut represents the buffered writer that should actually be passed to
 	// any operations that write out data.
 	bufOut *bufio.Writer
+
+	// pacer paces a real-time generation, nil otherwise
+	pacer *pacer
+	// now and sleep are the wall clock of a real-time generation, time.Now
+	// and time.Sleep if nil
+	now   func() time.Time
+	sleep func(time.Duration)
 }
 
 func (g *DataGenerator) init(config common.GeneratorConfig) error {
@@ -71,28 +84,179 @@ func (g *DataGenerator) Generate(config common.GeneratorConfig, target targets.I
 		return err
 	}
 
-	rand.Seed(g.config.Seed)
+	if g.config.RealTime {
+		if err := g.startNow(); err != nil {
+			return err
+		}
+	}
 
-	scfg, err := usecases.GetSimulatorConfig(g.config)
+	patterns := g.config.NewPatterns()
+	scfg, err := usecases.GetSimulatorConfigWithPatterns(g.config, patterns)
 	if err != nil {
 		return err
 	}
 
 	sim := scfg.NewSimulator(g.config.LogInterval, g.config.Limit)
-	serializer, err := g.getSerializer(sim, target)
+	if g.config.Format == constants.FormatColumnar {
+		err = g.runColumnar(sim)
+	} else {
+		err = g.run(sim, target)
+	}
 	if err != nil {
 		return err
 	}
 
+	if patterns != nil && patterns.Log != nil {
+		if err := writeAnomalyLabels(patterns.Log, g.config.AnomalyLabels); err != nil {
+			return err
+		}
+	}
+	if r, ok := sim.(common.QualityReporter); ok && g.config.QualityReport != "" {
+		return writeQualityReport(r.QualityReport(), g.config.QualityReport)
+	}
+	return nil
+}
+
+// run simulates sim and serializes its points for target.
+func (g *DataGenerator) run(sim common.Simulator, target targets.ImplementedTarget) error {
+	serializer, err := g.getSerializer(sim, target)
+	if err != nil {
+		return err
+	}
+	if p, ok := sim.(common.Partitioner); ok && g.config.Workers > 1 {
+		// the points of a real-time generation are paced in the order of sim
+		if g.pacer != nil {
+			return g.runSimulator(common.NewParallelSimulator(p, int(g.config.Workers)), serializer, g.config)
+		}
+		return g.runPartitions(p, target, int(g.config.Workers))
+	}
 	return g.runSimulator(sim, serializer, g.config)
 }
 
+// startNow moves the time range of a real-time generation to start at the
+// current time, truncated to the log interval, keeping its length, and sets
+// up its pacer.
+func (g *DataGenerator) startNow() error {
+	start, err := internalUtils.ParseUTCTime(g.config.TimeStart)
+	if err != nil {
+		return fmt.Errorf(errCannotParseTimeFmt, g.config.TimeStart, err)
+	}
+	end, err := internalUtils.ParseUTCTime(g.config.TimeEnd)
+	if err != nil {
+		return fmt.Errorf(errCannotParseTimeFmt, g.config.TimeEnd, err)
+	}
+	if g.now == nil {
+		g.now = time.Now
+	}
+	if g.sleep == nil {
+		g.sleep = time.Sleep
+	}
+
+	now := g.now().UTC()
+	first := now.Truncate(g.config.LogInterval)
+	g.config.TimeStart = first.Format(time.RFC3339Nano)
+	g.config.TimeEnd = first.Add(end.Sub(start)).Format(time.RFC3339Nano)
+	g.pacer = &pacer{start: now, acceleration: g.config.Acceleration, now: g.now, sleep: g.sleep}
+	return nil
+}
+
+// pacer holds the points of a real-time generation back until the wall clock
+// reaches their time, with the data clock running acceleration times as fast
+// as the wall clock from start, at which both clocks agree.
+type pacer struct {
+	start        time.Time
+	acceleration float64
+	// latest is the latest time waited for
+	latest time.Time
+	now    func() time.Time
+	sleep  func(time.Duration)
+}
+
+// due reports whether t is after the latest time waited for, so the points
+// before it are complete.
+func (p *pacer) due(t time.Time) bool {
+	return t.After(p.latest)
+}
+
+// wait waits until the wall clock reaches the time t.
+func (p *pacer) wait(t time.Time) {
+	p.latest = t
+	wall := p.start.Add(time.Duration(float64(t.Sub(p.start)) / p.acceleration))
+	if d := wall.Sub(p.now()); d > 0 {
+		p.sleep(d)
+	}
+}
+
+// runColumnar simulates sim and writes its points in the columnar format,
+// which is written by a single writer, so the partitions of sim are only
+// simulated in parallel.
+func (g *DataGenerator) runColumnar(sim common.Simulator) error {
+	if p, ok := sim.(common.Partitioner); ok && g.config.Workers > 1 {
+		sim = common.NewParallelSimulator(p, int(g.config.Workers))
+	}
+	w, err := columnar.NewWriter(g.bufOut, sim.Headers())
+	if err != nil {
+		return err
+	}
+	if err := g.runSimulator(sim, columnarSerializer{w}, g.config); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return g.bufOut.Flush()
+}
+
+// columnarSerializer adds the points to a columnar.Writer, which writes them
+// to its own io.Writer.
+type columnarSerializer struct {
+	w *columnar.Writer
+}
+
+func (s columnarSerializer) Serialize(p *data.Point, _ io.Writer) error {
+	return s.w.Write(p)
+}
+
+// writeQualityReport writes the summary of the disorder of report to the
+// file at path.
+func writeQualityReport(report *common.QualityReport, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf(errCannotWriteReportFmt, path, err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := report.Write(w); err != nil {
+		return fmt.Errorf(errCannotWriteReportFmt, path, err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf(errCannotWriteReportFmt, path, err)
+	}
+	return nil
+}
+
+// writeAnomalyLabels writes the anomalies of log to the CSV file at path.
+func writeAnomalyLabels(log *common.AnomalyLog, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf(errCannotWriteLabelsFmt, path, err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := log.WriteCSV(w); err != nil {
+		return fmt.Errorf(errCannotWriteLabelsFmt, path, err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf(errCannotWriteLabelsFmt, path, err)
+	}
+	return nil
+}
+
 func (g *DataGenerator) CreateSimulator(config *common.DataGeneratorConfig) (common.Simulator, error) {
 	err := g.init(config)
 	if err != nil {
 		return nil, err
 	}
-	rand.Seed(g.config.Seed)
 	scfg, err := usecases.GetSimulatorConfig(g.config)
 	if err != nil {
 		return nil, err
@@ -103,6 +267,10 @@ func (g *DataGenerator) CreateSimulator(config *common.DataGeneratorConfig) (com
 
 func (g *DataGenerator) runSimulator(sim common.Simulator, serializer serialize.PointSerializer, dgc *common.DataGeneratorConfig) error {
 	defer g.bufOut.Flush()
+	if s, ok := sim.(common.Stopper); ok {
+		// the simulation may be abandoned on an error
+		defer s.Stop()
+	}
 
 	currGroupID := uint(0)
 	point := data.NewPoint()
@@ -113,6 +281,15 @@ func (g *DataGenerator) runSimulator(sim common.Simulator, serializer serialize.
 			continue
 		}
 
+		if g.pacer != nil && g.pacer.due(*point.Timestamp()) {
+			// the points before are complete, so they are emitted before
+			// waiting for the next ones
+			if err := g.bufOut.Flush(); err != nil {
+				return err
+			}
+			g.pacer.wait(*point.Timestamp())
+		}
+
 		// in the default case this is always true
 		if currGroupID == dgc.InterleavedGroupID {
 			err := serializer.Serialize(point, g.bufOut)
@@ -124,7 +301,58 @@ func (g *DataGenerator) runSimulator(sim common.Simulator, serializer serialize.
 
 		currGroupID = (currGroupID + 1) % dgc.InterleavedNumGroups
 	}
-	return nil
+	return g.bufOut.Flush()
+}
+
+// serializedStep is the output of a step of a partition of the simulation.
+type serializedStep struct {
+	data []byte
+	err  error
+}
+
+// runPartitions simulates and serializes the partitions of sim in workers
+// goroutines, each with its own serializer, and writes their steps in the
+// order of sim, so the output is the same as the one of runSimulator.
+func (g *DataGenerator) runPartitions(sim common.Partitioner, target targets.ImplementedTarget, workers int) error {
+	defer g.bufOut.Flush()
+
+	steps := common.RunPartitions(sim, workers, func() common.StepFunc {
+		return serializeStep(target.Serializer())
+	})
+	defer steps.Stop()
+
+	for {
+		next, ok := steps.Next()
+		if !ok {
+			return nil
+		}
+		step := next.(serializedStep)
+		if step.err != nil {
+			return step.err
+		}
+		if _, err := g.bufOut.Write(step.data); err != nil {
+			return err
+		}
+	}
+}
+
+// serializeStep returns the common.StepFunc of a partition, which serializes
+// the points of each step with serializer.
+func serializeStep(serializer serialize.PointSerializer) common.StepFunc {
+	point := data.NewPoint()
+	return func(sim common.Simulator, stepSize int) interface{} {
+		var buf bytes.Buffer
+		var err error
+		for i := 0; i < stepSize && !sim.Finished() && err == nil; i++ {
+			if sim.Next(point) {
+				if err = serializer.Serialize(point, &buf); err != nil {
+					err = fmt.Errorf("can not serialize point: %s", err)
+				}
+			}
+			point.Reset()
+		}
+		return serializedStep{data: buf.Bytes(), err: err}
+	}
 }
 
 func (g *DataGenerator) getSerializer(sim common.Simulator, target targets.ImplementedTarget) (serialize.PointSerializer, error) {
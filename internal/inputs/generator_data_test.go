@@ -5,13 +5,17 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/blagojts/viper"
 	"github.com/spf13/pflag"
 	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/columnar"
 	"github.com/timescale/tsbs/pkg/data/serialize"
 	"github.com/timescale/tsbs/pkg/data/source"
 	"github.com/timescale/tsbs/pkg/data/usecases"
@@ -77,6 +81,118 @@ func TestDataGeneratorInit(t *testing.T) {
 	} else if dg.Out != &buf {
 		t.Errorf("Out not set to explicit io.Writer")
 	}
+
+	// Test that the custom use case fails without a schema
+	c.Use = common.UseCaseCustom
+	if err = dg.init(c); err == nil {
+		t.Errorf("unexpected lack of error with custom use case without schema")
+	}
+	c.Use = common.UseCaseDevops
+
+	// Test that invalid patterns fail
+	patternErrors := []common.PatternConfig{
+		{AnomalyLabels: "labels.csv"},
+		{Patterns: true, DailyAmplitude: -1, AnomalyDuration: 1},
+		{Patterns: true, TrendStep: -time.Second, AnomalyDuration: 1},
+		{Patterns: true, SpikeRate: 0.6, FlatLineRate: 0.6, AnomalyDuration: 1},
+		{Patterns: true, SpikeRate: -0.1, AnomalyDuration: 1},
+		{Patterns: true},
+	}
+	for _, pc := range patternErrors {
+		c.PatternConfig = pc
+		if err = dg.init(c); err == nil {
+			t.Errorf("unexpected lack of error with patterns %+v", pc)
+		}
+	}
+	c.PatternConfig = common.PatternConfig{Patterns: true, SpikeRate: 0.1, AnomalyDuration: 1}
+	if err = dg.init(c); err != nil {
+		t.Errorf("unexpected error with valid patterns: got %v", err)
+	}
+
+	// Test that invalid churn fails
+	churnErrors := []common.ChurnConfig{
+		{EntityLifetime: -time.Hour},
+		{TagChangeRate: 1.5},
+		{TagChangeRate: -0.1},
+		{CardinalityValues: 1000},
+	}
+	for _, cc := range churnErrors {
+		c.ChurnConfig = cc
+		if err = dg.init(c); err == nil {
+			t.Errorf("unexpected lack of error with churn %+v", cc)
+		}
+	}
+	c.ChurnConfig = common.ChurnConfig{EntityLifetime: time.Hour, TagChangeRate: 0.1, CardinalityTag: "pod", CardinalityValues: 1000}
+	if err = dg.init(c); err != nil {
+		t.Errorf("unexpected error with valid churn: got %v", err)
+	}
+	c.Use = common.UseCaseDevopsGeneric
+	c.MaxMetricCountPerHost = 1
+	if err = dg.init(c); err == nil {
+		t.Errorf("unexpected lack of error with churn in use case %s", c.Use)
+	}
+	c.Use = common.UseCaseDevops
+	c.ChurnConfig = common.ChurnConfig{}
+
+	// Test that invalid data quality fails
+	qualityErrors := []common.QualityConfig{
+		{MissingBatchChance: 1.5},
+		{ZeroFieldChance: -0.1},
+		{LateChance: 0.1, LateDelay: -time.Second},
+		{LateChance: 0.1, LateDelayDistribution: "normal"},
+		{OutageInterval: time.Hour},
+		{LateChance: 0.1},
+		{QualityReport: "report.txt"},
+	}
+	for _, qc := range qualityErrors {
+		c.QualityConfig = qc
+		if err = dg.init(c); err == nil {
+			t.Errorf("unexpected lack of error with data quality %+v", qc)
+		}
+	}
+	c.Use = common.UseCaseIoT
+	c.QualityConfig = common.QualityConfig{LateChance: 0.1, LateDelay: time.Minute, OutageInterval: time.Hour, OutageDuration: time.Minute}
+	if err = dg.init(c); err != nil {
+		t.Errorf("unexpected error with valid data quality: got %v", err)
+	}
+	c.QualityConfig = common.QualityConfig{}
+
+	// Test that invalid streaming fails
+	streamErrors := []struct {
+		format string
+		file   string
+		sc     common.StreamConfig
+	}{
+		{constants.FormatInflux, "", common.StreamConfig{Acceleration: -1}},
+		{constants.FormatColumnar, "", common.StreamConfig{RealTime: true}},
+		{constants.FormatTimescaleDB, "", common.StreamConfig{WriteURL: "http://localhost:8086"}},
+		{constants.FormatInflux, "data.txt", common.StreamConfig{WriteURL: "http://localhost:8086"}},
+		{constants.FormatInflux, "", common.StreamConfig{WriteURL: "http://localhost:8086", WriteAPIVersion: 3}},
+		{constants.FormatInflux, "", common.StreamConfig{WriteURL: "http://localhost:8086", WriteAPIVersion: 2, WriteToken: "t"}},
+		{constants.FormatInflux, "", common.StreamConfig{WriteURL: "http://localhost:8086", WriteAPIVersion: 2, WriteOrg: "o"}},
+	}
+	for _, se := range streamErrors {
+		c.Format = se.format
+		c.File = se.file
+		c.StreamConfig = se.sc
+		if err = dg.init(c); err == nil {
+			t.Errorf("unexpected lack of error with format %s, file '%s' and streaming %+v", se.format, se.file, se.sc)
+		}
+	}
+	c.Format = constants.FormatInflux
+	c.File = ""
+	c.StreamConfig = common.StreamConfig{RealTime: true, WriteURL: "http://localhost:8086"}
+	if err = dg.init(c); err != nil {
+		t.Errorf("unexpected error with valid streaming: got %v", err)
+	} else if c.Acceleration != 1 {
+		t.Errorf("unexpected default acceleration: got %v want 1", c.Acceleration)
+	} else if c.WriteAPIVersion != 1 {
+		t.Errorf("unexpected default write api version: got %d want 1", c.WriteAPIVersion)
+	}
+	c.StreamConfig = common.StreamConfig{WriteURL: "http://localhost:8086", WriteAPIVersion: 2, WriteOrg: "o", WriteToken: "t"}
+	if err = dg.init(c); err != nil {
+		t.Errorf("unexpected error with valid api version 2 streaming: got %v", err)
+	}
 }
 
 func TestDataGeneratorGenerate(t *testing.T) {
@@ -118,6 +234,284 @@ func TestDataGeneratorGenerate(t *testing.T) {
 	}
 }
 
+// pointStringSerializer serializes all values of a point, and is safe to
+// use concurrently.
+type pointStringSerializer struct{}
+
+func (s *pointStringSerializer) Serialize(p *data.Point, w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s %s %v %v %v\n", p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldValues(), p.TimestampInUnixMs())
+	return err
+}
+
+func TestDataGeneratorGenerateWorkers(t *testing.T) {
+	generate := func(use string, workers uint) string {
+		c := &common.DataGeneratorConfig{
+			BaseConfig: common.BaseConfig{
+				Seed:      123,
+				Format:    constants.FormatInflux,
+				Use:       use,
+				Scale:     10,
+				TimeStart: defaultTimeStart,
+				TimeEnd:   "2016-01-01T00:10:00Z",
+			},
+			Limit:                 2000,
+			LogInterval:           defaultLogInterval,
+			InterleavedNumGroups:  1,
+			MaxMetricCountPerHost: 10,
+			Workers:               workers,
+		}
+		var buf bytes.Buffer
+		dg := &DataGenerator{Out: &buf}
+		target := &mockTarget{name: constants.FormatInflux, serializer: &pointStringSerializer{}}
+		if err := dg.Generate(c, target); err != nil {
+			t.Fatalf("unexpected error when generating %s with %d workers: got %v", use, workers, err)
+		}
+		return buf.String()
+	}
+
+	for _, use := range []string{common.UseCaseCPUOnly, common.UseCaseDevops, common.UseCaseIoT} {
+		want := generate(use, 1)
+		if want == "" {
+			t.Errorf("%s: no points generated", use)
+		}
+		for _, workers := range []uint{2, 3, 16} {
+			if got := generate(use, workers); got != want {
+				t.Errorf("%s: output of %d workers differs from the one of a single worker", use, workers)
+			}
+		}
+	}
+
+	c := &common.DataGeneratorConfig{
+		BaseConfig: common.BaseConfig{
+			Format: constants.FormatInflux,
+			Use:    common.UseCaseDevops,
+			Scale:  1,
+		},
+		LogInterval:          time.Second,
+		InterleavedNumGroups: 2,
+		Workers:              2,
+	}
+	if err := (&DataGenerator{}).init(c); err == nil {
+		t.Errorf("unexpected lack of error with workers and interleaved groups")
+	}
+}
+
+func TestDataGeneratorGenerateRealTime(t *testing.T) {
+	for _, workers := range []uint{1, 2} {
+		c := &common.DataGeneratorConfig{
+			BaseConfig: common.BaseConfig{
+				Seed:      123,
+				Format:    constants.FormatInflux,
+				Use:       common.UseCaseCPUOnly,
+				Scale:     2,
+				TimeStart: defaultTimeStart,
+				TimeEnd:   "2016-01-01T00:01:00Z",
+			},
+			LogInterval:          defaultLogInterval,
+			InterleavedNumGroups: 1,
+			Workers:              workers,
+			StreamConfig:         common.StreamConfig{RealTime: true, Acceleration: 10},
+		}
+		var buf bytes.Buffer
+		// the wall clock only moves when sleeping
+		wall := time.Date(2020, 5, 4, 3, 2, 1, 500, time.UTC)
+		var slept time.Duration
+		var flushed []int
+		dg := &DataGenerator{
+			Out: &buf,
+			now: func() time.Time { return wall },
+			sleep: func(d time.Duration) {
+				slept += d
+				wall = wall.Add(d)
+				flushed = append(flushed, strings.Count(buf.String(), "\n"))
+			},
+		}
+		target := &mockTarget{name: constants.FormatInflux, serializer: &pointStringSerializer{}}
+		if err := dg.Generate(c, target); err != nil {
+			t.Fatalf("unexpected error when generating in real time with %d workers: got %v", workers, err)
+		}
+
+		first := time.Date(2020, 5, 4, 3, 2, 0, 0, time.UTC)
+		if c.TimeStart != first.Format(time.RFC3339Nano) || c.TimeEnd != first.Add(time.Minute).Format(time.RFC3339Nano) {
+			t.Errorf("%d workers: unexpected time range: got %s to %s", workers, c.TimeStart, c.TimeEnd)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if got := lines[0][strings.LastIndex(lines[0], " ")+1:]; got != fmt.Sprint(first.UnixNano()/1e6) {
+			t.Errorf("%d workers: unexpected first timestamp: got %s want %d", workers, got, first.UnixNano()/1e6)
+		}
+		// the interval at 03:02:00 is already due, the 5 others are waited for
+		want := []int{2, 4, 6, 8, 10}
+		if fmt.Sprint(flushed) != fmt.Sprint(want) {
+			t.Errorf("%d workers: unexpected lines written before sleeping: got %v want %v", workers, flushed, want)
+		}
+		if wantSlept := (50*time.Second - 1*time.Second - 500) / 10; slept != wantSlept {
+			t.Errorf("%d workers: unexpected time slept: got %v want %v", workers, slept, wantSlept)
+		}
+	}
+}
+
+func TestDataGeneratorGenerateColumnar(t *testing.T) {
+	generate := func(use, format string, workers uint) []byte {
+		c := &common.DataGeneratorConfig{
+			BaseConfig: common.BaseConfig{
+				Seed:      123,
+				Format:    format,
+				Use:       use,
+				Scale:     10,
+				TimeStart: defaultTimeStart,
+				TimeEnd:   "2016-01-01T00:10:00Z",
+			},
+			Limit:                 2000,
+			LogInterval:           defaultLogInterval,
+			InterleavedNumGroups:  1,
+			MaxMetricCountPerHost: 10,
+			Workers:               workers,
+		}
+		var buf bytes.Buffer
+		dg := &DataGenerator{Out: &buf}
+		var target targets.ImplementedTarget
+		if format != constants.FormatColumnar {
+			target = &mockTarget{name: format, serializer: &pointStringSerializer{}}
+		}
+		if err := dg.Generate(c, target); err != nil {
+			t.Fatalf("unexpected error when generating %s in %s: got %v", use, format, err)
+		}
+		return buf.Bytes()
+	}
+
+	for _, use := range []string{common.UseCaseDevops, common.UseCaseIoT} {
+		want := string(generate(use, constants.FormatInflux, 1))
+		for _, workers := range []uint{1, 4} {
+			ds, err := columnar.NewDataSource(bytes.NewReader(generate(use, constants.FormatColumnar, workers)), &pointStringSerializer{})
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", use, err)
+			}
+			if ds.Headers() == nil {
+				t.Errorf("%s: columnar data without headers", use)
+			}
+			var got strings.Builder
+			for p := ds.NextItem(); p.Data != nil; p = ds.NextItem() {
+				got.Write(p.Data.([]byte))
+				got.WriteByte('\n')
+			}
+			if ds.Err() != nil {
+				t.Fatalf("%s: unexpected error: %v", use, ds.Err())
+			}
+			if got.String() != want {
+				t.Errorf("%s: points of columnar data with %d workers differ from the generated ones", use, workers)
+			}
+		}
+	}
+}
+
+func TestDataGeneratorGenerateAnomalyLabels(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-labels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	generate := func(use string, workers uint) (string, string) {
+		path := filepath.Join(dir, fmt.Sprintf("%s-%d.csv", use, workers))
+		c := &common.DataGeneratorConfig{
+			BaseConfig: common.BaseConfig{
+				Seed:      123,
+				Format:    constants.FormatInflux,
+				Use:       use,
+				Scale:     10,
+				TimeStart: defaultTimeStart,
+				TimeEnd:   "2016-01-02T00:00:00Z",
+			},
+			LogInterval:          time.Hour,
+			InterleavedNumGroups: 1,
+			Workers:              workers,
+			PatternConfig: common.PatternConfig{
+				Patterns:         true,
+				DailyAmplitude:   0.2,
+				SpikeRate:        0.01,
+				LevelShiftRate:   0.01,
+				FlatLineRate:     0.01,
+				AnomalyMagnitude: 0.5,
+				AnomalyDuration:  3,
+				AnomalyLabels:    path,
+			},
+		}
+		var buf bytes.Buffer
+		dg := &DataGenerator{Out: &buf}
+		target := &mockTarget{name: constants.FormatInflux, serializer: &pointStringSerializer{}}
+		if err := dg.Generate(c, target); err != nil {
+			t.Fatalf("unexpected error when generating %s with %d workers: got %v", use, workers, err)
+		}
+		labels, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("cannot read anomaly labels: %v", err)
+		}
+		return buf.String(), string(labels)
+	}
+
+	for _, use := range []string{common.UseCaseCPUOnly, common.UseCaseDevops, common.UseCaseIoT} {
+		data, labels := generate(use, 1)
+		lines := strings.Split(strings.TrimSpace(labels), "\n")
+		if lines[0] != "start,end,measurement,entity,field,kind" {
+			t.Errorf("%s: incorrect header: got %s", use, lines[0])
+		}
+		if len(lines) < 2 {
+			t.Errorf("%s: no anomaly labels", use)
+		}
+		if data2, labels2 := generate(use, 3); data2 != data || labels2 != labels {
+			t.Errorf("%s: output of 3 workers differs from the one of a single worker", use)
+		}
+	}
+}
+
+func TestDataGeneratorGenerateQualityReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-quality")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "report.txt")
+	c := &common.DataGeneratorConfig{
+		BaseConfig: common.BaseConfig{
+			Seed:      123,
+			Format:    constants.FormatInflux,
+			Use:       common.UseCaseIoT,
+			Scale:     10,
+			TimeStart: defaultTimeStart,
+			TimeEnd:   "2016-01-02T00:00:00Z",
+		},
+		LogInterval:          time.Minute,
+		InterleavedNumGroups: 1,
+		QualityConfig: common.QualityConfig{
+			DuplicateChance: 0.1,
+			OutageInterval:  6 * time.Hour,
+			OutageDuration:  time.Hour,
+			OutageBackfill:  true,
+			QualityReport:   path,
+		},
+	}
+	var buf bytes.Buffer
+	dg := &DataGenerator{Out: &buf}
+	target := &mockTarget{name: constants.FormatInflux, serializer: &pointStringSerializer{}}
+	if err := dg.Generate(c, target); err != nil {
+		t.Fatalf("unexpected error when generating: got %v", err)
+	}
+	report, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cannot read quality report: %v", err)
+	}
+	want := fmt.Sprintf("entries sent:         %d\n", strings.Count(buf.String(), "\n"))
+	if !strings.HasPrefix(string(report), want) {
+		t.Errorf("incorrect quality report: got\n%s\nwant prefix\n%s", report, want)
+	}
+	for _, s := range []string{"duplicates:", "entries backfilled", "fleet="} {
+		if !strings.Contains(string(report), s) {
+			t.Errorf("quality report does not contain %q: got\n%s", s, report)
+		}
+	}
+}
+
 var keyIteration = []byte("iteration")
 
 type testSimulator struct {
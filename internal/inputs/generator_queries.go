@@ -17,6 +17,7 @@ import (
 	queryUtils "github.com/timescale/tsbs/cmd/tsbs_generate_queries/utils"
 	internalUtils "github.com/timescale/tsbs/internal/utils"
 	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"github.com/timescale/tsbs/pkg/query"
 	"github.com/timescale/tsbs/pkg/query/config"
 	"github.com/timescale/tsbs/pkg/query/factories"
 )
@@ -32,6 +33,12 @@ const (
 	errUnknownUseCaseFmt        = "use case '%s' is undefined"
 	errCannotParseTimeFmt       = "cannot parse time from string '%s': %v"
 	errBadUseFmt                = "invalid use case specified: '%v'"
+	errQueryNotFilledFmt        = "cannot fill query type '%s' over %v: %v"
+	errNoLocalRandFmt           = "queries of format '%s' cannot draw from a local random source"
+
+	// timeNow is the timestamp of the current time, e.g. the end of the data
+	// generated in real time
+	timeNow = "now"
 )
 
 // DevopsGeneratorMaker creates a query distributionGenerator for devops use case
@@ -64,6 +71,9 @@ type QueryGenerator struct {
 	factories map[string]interface{}
 	tsStart   time.Time
 	tsEnd     time.Time
+	// now returns the current time that timeNow stands for. If nil, it is
+	// time.Now.
+	now func() time.Time
 
 	// bufOut represents the buffered writer that should actually be passed to
 	// any operations that write out data.
@@ -86,6 +96,13 @@ func (g *QueryGenerator) Generate(config common.GeneratorConfig) error {
 		return err
 	}
 
+	if g.conf.Dashboards > 0 {
+		return g.runDashboardGeneration(g.conf)
+	}
+	if g.conf.Last > 0 {
+		return g.runLastGeneration(g.conf)
+	}
+
 	useGen, err := g.getUseCaseGenerator(g.conf)
 	if err != nil {
 		return err
@@ -120,15 +137,25 @@ func (g *QueryGenerator) init(conf common.GeneratorConfig) error {
 		return fmt.Errorf(errBadUseFmt, g.conf.Use)
 	}
 
-	if _, ok := g.useCaseMatrix[g.conf.Use][g.conf.QueryType]; !ok {
+	if g.conf.Dashboards > 0 {
+		for _, panel := range dashboardPanels(g.conf) {
+			if _, ok := g.useCaseMatrix[g.conf.Use][panel]; !ok {
+				return fmt.Errorf(errBadQueryTypeFmt, g.conf.Use, panel)
+			}
+		}
+	} else if _, ok := g.useCaseMatrix[g.conf.Use][g.conf.QueryType]; !ok {
 		return fmt.Errorf(errBadQueryTypeFmt, g.conf.Use, g.conf.QueryType)
 	}
 
-	g.tsStart, err = internalUtils.ParseUTCTime(g.conf.TimeStart)
+	if g.now == nil {
+		g.now = time.Now
+	}
+	now := g.now().UTC().Truncate(time.Second)
+	g.tsStart, err = parseQueryTime(g.conf.TimeStart, now)
 	if err != nil {
 		return fmt.Errorf(errCannotParseTimeFmt, g.conf.TimeStart, err)
 	}
-	g.tsEnd, err = internalUtils.ParseUTCTime(g.conf.TimeEnd)
+	g.tsEnd, err = parseQueryTime(g.conf.TimeEnd, now)
 	if err != nil {
 		return fmt.Errorf(errCannotParseTimeFmt, g.conf.TimeEnd, err)
 	}
@@ -148,6 +175,14 @@ func (g *QueryGenerator) init(conf common.GeneratorConfig) error {
 	return nil
 }
 
+// parseQueryTime parses the timestamp s, which is now if it is timeNow.
+func parseQueryTime(s string, now time.Time) (time.Time, error) {
+	if s == timeNow {
+		return now, nil
+	}
+	return internalUtils.ParseUTCTime(s)
+}
+
 func (g *QueryGenerator) initFactories() error {
 	factoryMap := factories.InitQueryFactories(g.conf)
 	for db, fac := range factoryMap {
@@ -176,6 +211,12 @@ func (g *QueryGenerator) addFactory(database string, factory interface{}) error
 }
 
 func (g *QueryGenerator) getUseCaseGenerator(c *config.QueryGeneratorConfig) (queryUtils.QueryGenerator, error) {
+	return g.newUseCaseGenerator(c, g.tsStart, g.tsEnd)
+}
+
+// newUseCaseGenerator returns the use case generator of the configured format
+// whose queries fall between start and end.
+func (g *QueryGenerator) newUseCaseGenerator(c *config.QueryGeneratorConfig, start, end time.Time) (queryUtils.QueryGenerator, error) {
 	scale := int(c.Scale) // TODO: make all the Devops constructors use a uint64
 	var factory interface{}
 	var ok bool
@@ -192,32 +233,27 @@ func (g *QueryGenerator) getUseCaseGenerator(c *config.QueryGeneratorConfig) (qu
 			return nil, fmt.Errorf(errUseCaseNotImplementedFmt, c.Use, c.Format)
 		}
 
-		return iotFactory.NewIoT(g.tsStart, g.tsEnd, scale)
+		return iotFactory.NewIoT(start, end, scale)
 	case common.UseCaseDevops, common.UseCaseCPUOnly, common.UseCaseCPUSingle:
 		devopsFactory, ok := factory.(DevopsGeneratorMaker)
 		if !ok {
 			return nil, fmt.Errorf(errUseCaseNotImplementedFmt, c.Use, c.Format)
 		}
 
-		return devopsFactory.NewDevops(g.tsStart, g.tsEnd, scale)
+		return devopsFactory.NewDevops(start, end, scale)
 	default:
 		return nil, fmt.Errorf(errUnknownUseCaseFmt, c.Use)
 	}
 }
 
 func (g *QueryGenerator) runQueryGeneration(useGen queryUtils.QueryGenerator, filler queryUtils.QueryFiller, c *config.QueryGeneratorConfig) error {
-	stats := make(map[string]int64)
-	currentGroup := uint(0)
-	enc := gob.NewEncoder(g.bufOut)
 	defer g.bufOut.Flush()
 
 	rand.Seed(g.conf.Seed)
 	//fmt.Println(g.config.Seed)
-	if g.conf.Debug > 0 {
-		_, err := fmt.Fprintf(g.DebugOut, "using random seed %d\n", g.conf.Seed)
-		if err != nil {
-			return fmt.Errorf(errCouldNotDebugFmt, err)
-		}
+	w, err := g.newQueryWriter(c)
+	if err != nil {
+		return err
 	}
 
 	// 加入两个分布，用于生成随机时间范围
@@ -277,34 +313,8 @@ func (g *QueryGenerator) runQueryGeneration(useGen queryUtils.QueryGenerator, fi
 		// todo 生成后输出
 		//fmt.Println(q.String())
 
-		if currentGroup == c.InterleavedGroupID {
-			err := enc.Encode(q)
-			if err != nil {
-				return fmt.Errorf(errCouldNotEncodeQueryFmt, err)
-			}
-			stats[string(q.HumanLabelName())]++
-
-			if c.Debug > 0 {
-				var debugMsg string
-				if c.Debug == 1 {
-					debugMsg = string(q.HumanLabelName())
-				} else if c.Debug == 2 {
-					debugMsg = string(q.HumanDescriptionName())
-				} else if c.Debug >= 3 {
-					debugMsg = q.String()
-				}
-
-				_, err = fmt.Fprintf(g.DebugOut, debugMsg+"\n")
-				if err != nil {
-					return fmt.Errorf(errCouldNotDebugFmt, err)
-				}
-			}
-		}
-		q.Release()
-
-		currentGroup++
-		if currentGroup == c.InterleavedNumGroups {
-			currentGroup = 0
+		if err := w.write(q); err != nil {
+			return err
 		}
 	}
 
@@ -312,7 +322,118 @@ func (g *QueryGenerator) runQueryGeneration(useGen queryUtils.QueryGenerator, fi
 	fmt.Println("random: ", influx.RandomTag)
 	fmt.Printf("tag num:\t%d\n", influx.TagNum)
 
-	// Print stats:
+	return g.printQueryStats(w.stats)
+}
+
+// runLastGeneration generates Limit queries that all cover the last Last of
+// the time range, e.g. of data generated in real time, with the other random
+// choices, such as hosts, drawn from a source seeded with Seed.
+func (g *QueryGenerator) runLastGeneration(c *config.QueryGeneratorConfig) error {
+	defer g.bufOut.Flush()
+
+	w, err := g.newQueryWriter(c)
+	if err != nil {
+		return err
+	}
+	r := rand.New(rand.NewSource(c.Seed))
+	for i := uint64(0); i < c.Limit; i++ {
+		q, err := g.fillRange(c, c.QueryType, g.tsEnd.Add(-c.Last), g.tsEnd, r)
+		if err != nil {
+			return err
+		}
+		if err := w.write(q); err != nil {
+			return err
+		}
+	}
+	return g.printQueryStats(w.stats)
+}
+
+// randSetter is implemented by the use case generators whose random choices
+// can be drawn from a given source.
+type randSetter interface {
+	SetRand(r *rand.Rand)
+}
+
+// fillRange fills a query of queryType over [start, end], with its other
+// random choices, such as hosts, drawn from r.
+func (g *QueryGenerator) fillRange(c *config.QueryGeneratorConfig, queryType string, start, end time.Time, r *rand.Rand) (query.Query, error) {
+	useGen, err := g.newUseCaseGenerator(c, start, end)
+	if err != nil {
+		return nil, err
+	}
+	rs, ok := useGen.(randSetter)
+	if !ok {
+		return nil, fmt.Errorf(errNoLocalRandFmt, c.Format)
+	}
+	rs.SetRand(r)
+	filler := g.useCaseMatrix[c.Use][queryType](useGen)
+
+	q, err := fillPanel(useGen.GenerateEmptyQuery(), filler, internalUtils.ZipNumForDuration(end.Sub(start)))
+	if err != nil {
+		return nil, fmt.Errorf(errQueryNotFilledFmt, queryType, end.Sub(start), err)
+	}
+	return q, nil
+}
+
+// queryWriter encodes the generated queries of the configured interleaved
+// group to the output and counts them by label.
+type queryWriter struct {
+	g     *QueryGenerator
+	c     *config.QueryGeneratorConfig
+	enc   *gob.Encoder
+	stats map[string]int64
+	group uint
+}
+
+// newQueryWriter returns a queryWriter to the output of g, and writes the
+// seed of c to DebugOut if debugging.
+func (g *QueryGenerator) newQueryWriter(c *config.QueryGeneratorConfig) (*queryWriter, error) {
+	if c.Debug > 0 {
+		_, err := fmt.Fprintf(g.DebugOut, "using random seed %d\n", c.Seed)
+		if err != nil {
+			return nil, fmt.Errorf(errCouldNotDebugFmt, err)
+		}
+	}
+	return &queryWriter{g: g, c: c, enc: gob.NewEncoder(g.bufOut), stats: make(map[string]int64)}, nil
+}
+
+// write writes q if it belongs to the interleaved group of the generator,
+// and releases it.
+func (w *queryWriter) write(q query.Query) error {
+	defer q.Release()
+	group := w.group
+	w.group = (w.group + 1) % w.c.InterleavedNumGroups
+	if group != w.c.InterleavedGroupID {
+		return nil
+	}
+
+	err := w.enc.Encode(q)
+	if err != nil {
+		return fmt.Errorf(errCouldNotEncodeQueryFmt, err)
+	}
+	w.stats[string(q.HumanLabelName())]++
+
+	if w.c.Debug > 0 {
+		var debugMsg string
+		if w.c.Debug == 1 {
+			debugMsg = string(q.HumanLabelName())
+		} else if w.c.Debug == 2 {
+			debugMsg = string(q.HumanDescriptionName())
+		} else if w.c.Debug >= 3 {
+			debugMsg = q.String()
+		}
+
+		_, err = fmt.Fprintf(w.g.DebugOut, "%s\n", debugMsg)
+		if err != nil {
+			return fmt.Errorf(errCouldNotDebugFmt, err)
+		}
+	}
+	return nil
+}
+
+// printQueryStats writes the number of generated queries per label, sorted by
+// label, to DebugOut.
+func (g *QueryGenerator) printQueryStats(stats map[string]int64) error {
 	keys := []string{}
 	for k := range stats {
 		keys = append(keys, k)
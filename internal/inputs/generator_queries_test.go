@@ -13,14 +13,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/databases/cassandra"
-	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/databases/clickhouse"
-	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/databases/cratedb"
 	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/databases/influx"
-	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/databases/mongo"
-	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/databases/questdb"
-	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/databases/siridb"
-	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/databases/timescaledb"
 	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/uses/devops"
 	queryUtils "github.com/timescale/tsbs/cmd/tsbs_generate_queries/utils"
 	internalUtils "github.com/timescale/tsbs/internal/utils"
@@ -265,20 +258,6 @@ func TestGetUseCaseGenerator(t *testing.T) {
 		return useGen
 	}
 
-	bc := cassandra.BaseGenerator{}
-	cass, err := bc.NewDevops(tsStart, tsEnd, scale)
-	if err != nil {
-		t.Fatalf("Error creating cassandra query distributionGenerator")
-	}
-	checkType(constants.FormatCassandra, cass)
-
-	bcr := cratedb.BaseGenerator{}
-	crate, err := bcr.NewDevops(tsStart, tsEnd, scale)
-	if err != nil {
-		t.Errorf("Error creating cratedb query distributionGenerator")
-	}
-	checkType(constants.FormatCrateDB, crate)
-
 	bi := influx.BaseGenerator{}
 	indb, err := bi.NewDevops(tsStart, tsEnd, scale)
 	if err != nil {
@@ -286,81 +265,6 @@ func TestGetUseCaseGenerator(t *testing.T) {
 	}
 	checkType(constants.FormatInflux, indb)
 
-	bs := siridb.BaseGenerator{}
-	siri, err := bs.NewDevops(tsStart, tsEnd, scale)
-	if err != nil {
-		t.Fatalf("Error creating siridb query distributionGenerator")
-	}
-	checkType(constants.FormatSiriDB, siri)
-
-	bm := mongo.BaseGenerator{}
-	mongodb, err := bm.NewDevops(tsStart, tsEnd, scale)
-	if err != nil {
-		t.Fatalf("Error creating mongodb query distributionGenerator")
-	}
-	checkType(constants.FormatMongo, mongodb)
-
-	bm.UseNaive = true
-	nmongo, err := bm.NewDevops(tsStart, tsEnd, scale)
-	if err != nil {
-		t.Fatalf("Error creating naive mongodb query distributionGenerator")
-	}
-	g.conf.MongoUseNaive = true
-	checkType(constants.FormatMongo, nmongo)
-
-	bcc := clickhouse.BaseGenerator{}
-	clickh, err := bcc.NewDevops(tsStart, tsEnd, scale)
-	if err != nil {
-		t.Fatalf("Error creating clickhouse query distributionGenerator")
-	}
-	checkType(constants.FormatClickhouse, clickh)
-
-	bq := questdb.BaseGenerator{}
-	qdb, err := bq.NewDevops(tsStart, tsEnd, scale)
-	if err != nil {
-		t.Fatalf("Error creating questdb query distributionGenerator")
-	}
-	checkType(constants.FormatQuestDB, qdb)
-
-	bcc.UseTags = true
-	clickt, err := bcc.NewDevops(tsStart, tsEnd, scale)
-	checkType(constants.FormatClickhouse, clickt)
-	if got := clickt.(*clickhouse.Devops).UseTags; got != bcc.UseTags {
-		t.Errorf("clickhous3 UseTags not set correctly: got %v want %v", got, bcc.UseTags)
-	}
-
-	bt := timescaledb.BaseGenerator{}
-	ts, err := bt.NewDevops(tsStart, tsEnd, scale)
-
-	checkType(constants.FormatTimescaleDB, ts)
-	if got := ts.(*timescaledb.Devops).UseTags; got != c.TimescaleUseTags {
-		t.Errorf("timescaledb UseTags not set correctly: got %v want %v", got, c.TimescaleUseTags)
-	}
-	if got := ts.(*timescaledb.Devops).UseJSON; got != c.TimescaleUseJSON {
-		t.Errorf("timescaledb UseJSON not set correctly: got %v want %v", got, c.TimescaleUseJSON)
-	}
-	if got := ts.(*timescaledb.Devops).UseTimeBucket; got != c.TimescaleUseTimeBucket {
-		t.Errorf("timescaledb UseTimeBucket not set correctly: got %v want %v", got, c.TimescaleUseTimeBucket)
-	}
-
-	bt.UseJSON = true
-	bt.UseTags = true
-	bt.UseTimeBucket = true
-	tts, err := bt.NewDevops(tsStart, tsEnd, scale)
-	g.conf.TimescaleUseJSON = true
-	g.conf.TimescaleUseTags = true
-	g.conf.TimescaleUseTimeBucket = true
-	checkType(constants.FormatTimescaleDB, tts)
-	if got := tts.(*timescaledb.Devops).UseTags; got != c.TimescaleUseTags {
-		t.Errorf("timescaledb UseTags not set correctly: got %v want %v", got, c.TimescaleUseTags)
-	}
-	if got := tts.(*timescaledb.Devops).UseJSON; got != c.TimescaleUseJSON {
-		t.Errorf("timescaledb UseJSON not set correctly: got %v want %v", got, c.TimescaleUseJSON)
-	}
-	if got := tts.(*timescaledb.Devops).UseTimeBucket; got != c.TimescaleUseTimeBucket {
-		t.Errorf("timescaledb UseTimeBucket not set correctly: got %v want %v", got, c.TimescaleUseTimeBucket)
-	}
-
 	// Test error condition
 	c.Format = "bad format"
 	useGen, err := g.getUseCaseGenerator(c)
@@ -373,40 +277,6 @@ func TestGetUseCaseGenerator(t *testing.T) {
 	}
 }
 
-// Decoded previously
-var wantQueries = []query.TimescaleDB{
-	{
-		Hypertable:       []byte("cpu"),
-		HumanLabel:       []byte("TimescaleDB 1 cpu metric(s), random    1 hosts, random 1h0m0s by 1m"),
-		HumanDescription: []byte("TimescaleDB 1 cpu metric(s), random    1 hosts, random 1h0m0s by 1m: 2016-01-01T02:17:08Z"),
-		SqlQuery: []byte(`SELECT time_bucket('60 seconds', time) AS minute,
-        max(usage_user) as max_usage_user
-        FROM cpu
-        WHERE tags_id IN (SELECT id FROM tags WHERE hostname IN ('host_9')) AND time >= '2016-01-01 02:17:08.646325 +0000' AND time < '2016-01-01 03:17:08.646325 +0000'
-        GROUP BY minute ORDER BY minute ASC`),
-	},
-	{
-		Hypertable:       []byte("cpu"),
-		HumanLabel:       []byte("TimescaleDB 1 cpu metric(s), random    1 hosts, random 1h0m0s by 1m"),
-		HumanDescription: []byte("TimescaleDB 1 cpu metric(s), random    1 hosts, random 1h0m0s by 1m: 2016-01-01T14:03:26Z"),
-		SqlQuery: []byte(`SELECT time_bucket('60 seconds', time) AS minute,
-        max(usage_user) as max_usage_user
-        FROM cpu
-        WHERE tags_id IN (SELECT id FROM tags WHERE hostname IN ('host_5')) AND time >= '2016-01-01 14:03:26.894865 +0000' AND time < '2016-01-01 15:03:26.894865 +0000'
-        GROUP BY minute ORDER BY minute ASC`),
-	},
-	{
-		Hypertable:       []byte("cpu"),
-		HumanLabel:       []byte("TimescaleDB 1 cpu metric(s), random    1 hosts, random 1h0m0s by 1m"),
-		HumanDescription: []byte("TimescaleDB 1 cpu metric(s), random    1 hosts, random 1h0m0s by 1m: 2016-01-01T09:11:43Z"),
-		SqlQuery: []byte(`SELECT time_bucket('60 seconds', time) AS minute,
-        max(usage_user) as max_usage_user
-        FROM cpu
-        WHERE tags_id IN (SELECT id FROM tags WHERE hostname IN ('host_9')) AND time >= '2016-01-01 09:11:43.311177 +0000' AND time < '2016-01-01 10:11:43.311177 +0000'
-        GROUP BY minute ORDER BY minute ASC`),
-	},
-}
-
 func getTestConfigAndGenerator() (*config.QueryGeneratorConfig, *QueryGenerator) {
 	const scale = 10
 	tsStart, _ := internalUtils.ParseUTCTime(defaultTimeStart)
@@ -414,18 +284,16 @@ func getTestConfigAndGenerator() (*config.QueryGeneratorConfig, *QueryGenerator)
 	tsEnd = tsEnd.Add(time.Second)
 	c := &config.QueryGeneratorConfig{
 		BaseConfig: common.BaseConfig{
-			Format:    constants.FormatTimescaleDB,
+			Format:    constants.FormatInflux,
 			Use:       common.UseCaseCPUOnly,
 			Scale:     scale,
 			TimeStart: defaultTimeStart,
 			TimeEnd:   strings.Replace(defaultTimeEnd, ":00Z", ":01Z", 1),
 			Seed:      123,
 		},
-		Limit:                  3,
-		QueryType:              "single-groupby-1-1-1",
-		TimescaleUseTimeBucket: true,
-		TimescaleUseTags:       true,
-		InterleavedNumGroups:   1,
+		Limit:                3,
+		QueryType:            "single-groupby-1-1-1",
+		InterleavedNumGroups: 1,
 	}
 	g := &QueryGenerator{
 		useCaseMatrix: map[string]map[string]queryUtils.QueryFillerMaker{
@@ -443,70 +311,49 @@ func getTestConfigAndGenerator() (*config.QueryGeneratorConfig, *QueryGenerator)
 	return c, g
 }
 
-func checkGeneratedOutput(t *testing.T, buf *bytes.Buffer) {
+// wantLabel is the label of the queries of getTestConfigAndGenerator.
+const wantLabel = "Influx 1 cpu metric(s), random    1 hosts, random 1h0m0s by 1m"
+
+// checkGeneratedOutput checks that buf holds the 3 queries of
+// getTestConfigAndGenerator, whose time ranges are random, and returns them.
+func checkGeneratedOutput(t *testing.T, buf *bytes.Buffer) []*query.HTTP {
 	r := bufio.NewReader(buf)
 	decoder := gob.NewDecoder(r)
-	i := 0
+	queries := make([]*query.HTTP, 0)
 	for {
-		var q query.TimescaleDB
-		err := decoder.Decode(&q)
+		q := &query.HTTP{}
+		err := decoder.Decode(q)
 		if err == io.EOF {
 			break
 		} else if err != nil {
 			t.Fatalf("unexpected error while decoding: got %v", err)
 		}
-		want := string(wantQueries[i].SqlQuery)
-		if got := string(q.SqlQuery); got != want {
-			t.Errorf("incorrect query:\ngot\n%s\nwant\n%s", got, want)
+		if got := string(q.HumanLabel); got != wantLabel {
+			t.Errorf("incorrect query label: got %s want %s", got, wantLabel)
 		}
-		i++
+		if !strings.Contains(string(q.Path), "host_") {
+			t.Errorf("query does not filter on a host: %s", q.Path)
+		}
+		queries = append(queries, q)
 	}
-	if i != len(wantQueries) {
-		t.Errorf("incorrect number of queries: got %d want %d", i, len(wantQueries))
+	if len(queries) != 3 {
+		t.Errorf("incorrect number of queries: got %d want %d", len(queries), 3)
 	}
+	return queries
 }
 
 func TestQueryGeneratorRunQueryGeneration(t *testing.T) {
 	seedLine := "using random seed 123"
-	summaryLine := "TimescaleDB 1 cpu metric(s), random    1 hosts, random 1h0m0s by 1m: 3 points"
+	summaryLine := wantLabel + ": 3 points"
 	cases := []struct {
-		level     int
-		wantDebug []string
+		level int
+		// debug returns the debug line of a query
+		debug func(q *query.HTTP) string
 	}{
-		{
-			level:     0,
-			wantDebug: []string{summaryLine},
-		},
-		{
-			level: 1,
-			wantDebug: []string{
-				seedLine,
-				string(wantQueries[0].HumanLabelName()),
-				string(wantQueries[1].HumanLabelName()),
-				string(wantQueries[2].HumanLabelName()),
-				summaryLine,
-			},
-		},
-		{
-			level: 2,
-			wantDebug: []string{
-				seedLine,
-				string(wantQueries[0].HumanDescriptionName()),
-				string(wantQueries[1].HumanDescriptionName()),
-				string(wantQueries[2].HumanDescriptionName()),
-				summaryLine,
-			},
-		},
-		{
-			level: 3,
-			wantDebug: []string{
-				seedLine,
-				wantQueries[0].String(),
-				wantQueries[1].String(),
-				wantQueries[2].String(),
-				summaryLine,
-			},
-		},
+		{level: 0},
+		{level: 1, debug: func(q *query.HTTP) string { return string(q.HumanLabelName()) }},
+		{level: 2, debug: func(q *query.HTTP) string { return string(q.HumanDescriptionName()) }},
+		{level: 3, debug: func(q *query.HTTP) string { return q.String() }},
 	}
 
 	for _, c := range cases {
@@ -533,10 +380,18 @@ func TestQueryGeneratorRunQueryGeneration(t *testing.T) {
 			t.Errorf("unexpected error: got %v", err)
 		}
 
-		checkGeneratedOutput(t, &buf)
+		queries := checkGeneratedOutput(t, &buf)
 
 		// Check that the proper debug output was written
-		wantDebug := strings.TrimSpace(strings.Join(c.wantDebug, "\n"))
+		lines := []string{summaryLine}
+		if c.debug != nil {
+			lines = []string{seedLine}
+			for _, q := range queries {
+				lines = append(lines, c.debug(q))
+			}
+			lines = append(lines, summaryLine)
+		}
+		wantDebug := strings.TrimSpace(strings.Join(lines, "\n"))
 		if got := strings.TrimSpace(debug.String()); got != wantDebug {
 			t.Errorf("incorrect line for debug level %d:\ngot\n%s\nwant\n%s", c.level, got, wantDebug)
 		}
@@ -623,3 +478,73 @@ func TestQueryGeneratorGenerate(t *testing.T) {
 	}
 	checkGeneratedOutput(t, &buf)
 }
+
+func TestParseQueryTime(t *testing.T) {
+	now := time.Date(2020, time.June, 1, 12, 30, 0, 0, time.UTC)
+	got, err := parseQueryTime(timeNow, now)
+	if err != nil {
+		t.Errorf("unexpected error for now: got %v", err)
+	} else if !got.Equal(now) {
+		t.Errorf("incorrect time for now: got %v want %v", got, now)
+	}
+
+	got, err = parseQueryTime(defaultTimeStart, now)
+	want := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if err != nil {
+		t.Errorf("unexpected error for timestamp: got %v", err)
+	} else if !got.Equal(want) {
+		t.Errorf("incorrect time for timestamp: got %v want %v", got, want)
+	}
+
+	if _, err = parseQueryTime("yesterday", now); err == nil {
+		t.Errorf("unexpected lack of error for bad time")
+	}
+}
+
+func TestQueryGeneratorRunLastGeneration(t *testing.T) {
+	now := time.Date(2020, time.June, 1, 12, 30, 45, 500, time.UTC)
+	wantEnd := now.Truncate(time.Second)
+	wantStart := wantEnd.Add(-2 * time.Hour)
+
+	c, g := getTestDashboardConfigAndGenerator()
+	c.Dashboards = 0
+	c.QueryType = "panel-a"
+	c.TimeStart = defaultTimeStart
+	c.TimeEnd = timeNow
+	c.Last = 2 * time.Hour
+	c.Limit = 3
+	g.now = func() time.Time { return now }
+	if err := g.init(c); err != nil {
+		t.Fatalf("unexpected error initializing: got %v", err)
+	}
+	if !g.tsEnd.Equal(wantEnd) {
+		t.Errorf("incorrect end for now: got %v want %v", g.tsEnd, wantEnd)
+	}
+
+	var buf bytes.Buffer
+	g.bufOut = bufio.NewWriter(&buf)
+	g.DebugOut = ioutil.Discard
+	if err := g.runLastGeneration(c); err != nil {
+		t.Fatalf("unexpected error: got %v", err)
+	}
+
+	decoder := gob.NewDecoder(&buf)
+	count := 0
+	for {
+		q := &query.HTTP{}
+		err := decoder.Decode(q)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("unexpected error while decoding: got %v", err)
+		}
+		count++
+		want := wantStart.Format(time.RFC3339) + "," + wantEnd.Format(time.RFC3339)
+		if got := string(q.HumanDescription); got != want {
+			t.Errorf("incorrect window: got %s want %s", got, want)
+		}
+	}
+	if count != 3 {
+		t.Errorf("incorrect number of queries: got %d want %d", count, 3)
+	}
+}
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -23,6 +25,64 @@ func ParseUTCTime(s string) (time.Time, error) {
 	return t.UTC(), nil
 }
 
+const errBadRelativeRangeFmt = "cannot parse relative range '%s': want e.g. now-6h, now-7d"
+
+// ParseRelativeRange parses a dashboard style relative range such as "now-6h"
+// into the duration it spans. The "now-" prefix is optional and, besides the
+// units known to time.ParseDuration, "d" (days) and "w" (weeks) are accepted.
+func ParseRelativeRange(s string) (time.Duration, error) {
+	r := strings.TrimPrefix(strings.TrimSpace(s), "now-")
+	var d time.Duration
+	var err error
+	switch {
+	case strings.HasSuffix(r, "d"), strings.HasSuffix(r, "w"):
+		unit := 24 * time.Hour
+		if strings.HasSuffix(r, "w") {
+			unit *= 7
+		}
+		var n int64
+		n, err = strconv.ParseInt(r[:len(r)-1], 10, 64)
+		d = time.Duration(n) * unit
+	default:
+		d, err = time.ParseDuration(r)
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf(errBadRelativeRangeFmt, s)
+	}
+	return d, nil
+}
+
+const errBadByteSizeFmt = "cannot parse byte size '%s': want e.g. 512MB, 2GiB"
+
+var byteSizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ParseByteSize parses a size such as "512MB" or "2GiB" into bytes. Units are
+// powers of 1024 and case-insensitive; a plain number is a count of bytes.
+func ParseByteSize(s string) (int64, error) {
+	r := strings.ToUpper(strings.TrimSpace(s))
+	unit := int64(1)
+	for _, u := range byteSizeUnits {
+		if strings.HasSuffix(r, u.suffix) {
+			r = strings.TrimSpace(strings.TrimSuffix(r, u.suffix))
+			unit = u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(r, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf(errBadByteSizeFmt, s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
 const (
 	errInvalidGroupsFmt = "incorrect interleaved groups configuration: id %d >= total groups %d"
 	errTotalGroupsZero  = "incorrect interleaved groups configuration: total groups = 0"
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestValidateGroups(t *testing.T) {
@@ -47,3 +48,62 @@ func TestValidateGroups(t *testing.T) {
 		}
 	}
 }
+
+func TestParseRelativeRange(t *testing.T) {
+	cases := []struct {
+		in     string
+		want   time.Duration
+		errMsg string
+	}{
+		{in: "now-6h", want: 6 * time.Hour},
+		{in: "now-30m", want: 30 * time.Minute},
+		{in: "12h", want: 12 * time.Hour},
+		{in: "now-7d", want: 7 * 24 * time.Hour},
+		{in: "now-2w", want: 14 * 24 * time.Hour},
+		{in: "now-", errMsg: fmt.Sprintf(errBadRelativeRangeFmt, "now-")},
+		{in: "now-xd", errMsg: fmt.Sprintf(errBadRelativeRangeFmt, "now-xd")},
+		{in: "now--1h", errMsg: fmt.Sprintf(errBadRelativeRangeFmt, "now--1h")},
+	}
+	for _, c := range cases {
+		got, err := ParseRelativeRange(c.in)
+		if c.errMsg == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.in, err)
+		} else if c.errMsg != "" && err == nil {
+			t.Errorf("%s: unexpected lack of error", c.in)
+		} else if err != nil && err.Error() != c.errMsg {
+			t.Errorf("%s: incorrect error: got %s want %s", c.in, err.Error(), c.errMsg)
+		} else if got != c.want {
+			t.Errorf("%s: incorrect duration: got %v want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	cases := []struct {
+		in     string
+		want   int64
+		errMsg string
+	}{
+		{in: "1024", want: 1024},
+		{in: "10B", want: 10},
+		{in: "512MB", want: 512 << 20},
+		{in: "2GiB", want: 2 << 30},
+		{in: "1.5g", want: 3 << 29},
+		{in: " 4 kb ", want: 4 << 10},
+		{in: "", errMsg: fmt.Sprintf(errBadByteSizeFmt, "")},
+		{in: "GB", errMsg: fmt.Sprintf(errBadByteSizeFmt, "GB")},
+		{in: "-1MB", errMsg: fmt.Sprintf(errBadByteSizeFmt, "-1MB")},
+	}
+	for _, c := range cases {
+		got, err := ParseByteSize(c.in)
+		if c.errMsg == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.in, err)
+		} else if c.errMsg != "" && err == nil {
+			t.Errorf("%s: unexpected lack of error", c.in)
+		} else if err != nil && err.Error() != c.errMsg {
+			t.Errorf("%s: incorrect error: got %s want %s", c.in, err.Error(), c.errMsg)
+		} else if got != c.want {
+			t.Errorf("%s: incorrect size: got %d want %d", c.in, got, c.want)
+		}
+	}
+}
@@ -11,7 +11,10 @@ const (
 	// would be before its start.
 	ErrEndBeforeStart = "end time before start time"
 
-	errWindowTooLargeFmt = "random window equal to or larger than TimeInterval: window %v, interval %v"
+	// ErrWindowTooLarge is the prefix of the error, and of the panic of
+	// MustRandWindow, for a random window that does not fit in a TimeInterval.
+	ErrWindowTooLarge    = "random window equal to or larger than TimeInterval"
+	errWindowTooLargeFmt = ErrWindowTooLarge + ": window %v, interval %v"
 
 	minute = time.Minute
 	hour   = time.Hour
@@ -24,6 +27,11 @@ var ZipFianTimeDuration = []time.Duration{
 	6 * hour, 12 * hour, 1 * day, 2 * day, 3 * day, 5 * day, 1 * week, 2 * week, 3 * week, 1 * month,
 }
 
+// WholeInterval is the newOrOld value that makes DistributionRandWithOldData
+// return the TimeInterval itself instead of a window drawn from it. Generators
+// built over an exact query window, such as dashboard panels, use it.
+const WholeInterval = 2
+
 //var ZipFianTimeDuration = []time.Duration{
 //	day, 12 * hour, 6 * hour, 2 * hour, hour, 2 * day, 3 * day, 4 * day, 5 * day, week,
 //}
@@ -34,6 +42,9 @@ var ZipFianTimeDuration = []time.Duration{
 type TimeInterval struct {
 	start time.Time
 	end   time.Time
+
+	// rand, if set, is the source of RandWindow instead of the global source
+	rand *rand.Rand
 }
 
 // NewTimeInterval creates a new TimeInterval for a given start and end. If end
@@ -42,7 +53,13 @@ func NewTimeInterval(start, end time.Time) (*TimeInterval, error) {
 	if end.Before(start) {
 		return nil, fmt.Errorf(ErrEndBeforeStart)
 	}
-	return &TimeInterval{start.UTC(), end.UTC()}, nil
+	return &TimeInterval{start: start.UTC(), end: end.UTC()}, nil
+}
+
+// SetRand makes RandWindow draw the start of the windows from r instead of
+// the global source.
+func (ti *TimeInterval) SetRand(r *rand.Rand) {
+	ti.rand = r
 }
 
 // Duration returns the time.Duration of the TimeInterval.
@@ -92,7 +109,12 @@ func (ti *TimeInterval) RandWindow(window time.Duration) (*TimeInterval, error)
 
 	}
 
-	start := lower + rand.Int63n(upper-lower)
+	var start int64
+	if ti.rand == nil {
+		start = lower + rand.Int63n(upper-lower)
+	} else {
+		start = lower + ti.rand.Int63n(upper-lower)
+	}
 	end := start + window.Nanoseconds()
 
 	x, err := NewTimeInterval(time.Unix(0, start), time.Unix(0, end))
@@ -146,6 +168,10 @@ func (ti *TimeInterval) DistributionRand(zipNum int64, latestNum int64) *TimeInt
 }
 
 func (ti *TimeInterval) DistributionRandWithOldData(zipNum int64, latestNum int64, newOrOld int) *TimeInterval {
+	if newOrOld == WholeInterval {
+		return &TimeInterval{start: ti.start, end: ti.end}
+	}
+
 	duration := ZipFianTimeDuration[zipNum].Nanoseconds() // Zipfian分布生成时间区间
 	// 启动项参数中设置的整体查询的 起始时间 和 结束时间
 
@@ -205,6 +231,18 @@ func (ti *TimeInterval) DistributionRandWithOldData(zipNum int64, latestNum int6
 
 }
 
+// ZipNumForDuration returns the index of the shortest ZipFianTimeDuration that
+// covers d, or the last index if d is longer than all of them. Query fillers
+// pick their GROUP BY granularity from this index.
+func ZipNumForDuration(d time.Duration) int64 {
+	for i, zd := range ZipFianTimeDuration {
+		if zd >= d {
+			return int64(i)
+		}
+	}
+	return int64(len(ZipFianTimeDuration) - 1)
+}
+
 // Start returns the starting time in UTC.
 func (ti *TimeInterval) Start() time.Time {
 	return ti.start
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -291,6 +292,35 @@ func TestTimeIntervalRandWindow(t *testing.T) {
 	}
 }
 
+func TestTimeIntervalRandWindowSetRand(t *testing.T) {
+	start := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2016, time.January, 1, 1, 0, 0, 0, time.UTC)
+	ti, err := NewTimeInterval(start, end)
+	if err != nil {
+		t.Fatalf("unexpected error creating TimeInterval: got %v", err)
+	}
+
+	windows := func(seed int64) []time.Time {
+		ti.SetRand(rand.New(rand.NewSource(seed)))
+		ret := make([]time.Time, 0, 10)
+		for i := 0; i < 10; i++ {
+			x := ti.MustRandWindow(time.Minute)
+			ret = append(ret, x.Start())
+		}
+		return ret
+	}
+	want := windows(1)
+	got := windows(1)
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("window %d differs with the same source: got %v want %v", i, got[i], want[i])
+		}
+	}
+	if other := windows(2); other[0].Equal(want[0]) && other[1].Equal(want[1]) {
+		t.Errorf("windows do not depend on the source")
+	}
+}
+
 func TestTimeIntervalMustRandWindow(t *testing.T) {
 	start := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
 	end := time.Date(2016, time.January, 1, 1, 0, 0, 0, time.UTC)
@@ -318,3 +348,42 @@ func TestTimeIntervalMustRandWindow(t *testing.T) {
 		})
 	}
 }
+
+func TestTimeIntervalDistributionRandWholeInterval(t *testing.T) {
+	start := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2016, time.January, 1, 6, 0, 0, 0, time.UTC)
+	ti, err := NewTimeInterval(start, end)
+	if err != nil {
+		t.Fatalf("unexpected error creating TimeInterval: got %v", err)
+	}
+
+	x := ti.DistributionRandWithOldData(ZipNumForDuration(ti.Duration()), 0, WholeInterval)
+	if x == ti {
+		t.Errorf("returned TimeInterval should be a copy")
+	}
+	if got := x.Start(); got != start {
+		t.Errorf("incorrect start: got %v want %v", got, start)
+	}
+	if got := x.End(); got != end {
+		t.Errorf("incorrect end: got %v want %v", got, end)
+	}
+}
+
+func TestZipNumForDuration(t *testing.T) {
+	cases := []struct {
+		d    time.Duration
+		want int64
+	}{
+		{d: time.Hour, want: 0},
+		{d: 6 * hour, want: 0},
+		{d: 7 * hour, want: 1},
+		{d: day, want: 2},
+		{d: week, want: 6},
+		{d: 2 * month, want: int64(len(ZipFianTimeDuration) - 1)},
+	}
+	for _, c := range cases {
+		if got := ZipNumForDuration(c.d); got != c.want {
+			t.Errorf("%v: incorrect index: got %d want %d", c.d, got, c.want)
+		}
+	}
+}
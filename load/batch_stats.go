@@ -0,0 +1,175 @@
+package load
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+const (
+	// batch latencies are recorded in microseconds, up to an hour
+	maxBatchLatencyMicros = int64(time.Hour / time.Microsecond)
+	batchLatencySigFigs   = 3
+
+	batchStatsCSVHeader = "time,batches,bytes,p50_ms,p95_ms,p99_ms,max_ms\n"
+)
+
+// LatencySummary summarizes the latencies of a set of batch writes, in
+// milliseconds.
+type LatencySummary struct {
+	Batches int64   `json:"batches"`
+	Mean    float64 `json:"mean"`
+	P50     float64 `json:"p50"`
+	P95     float64 `json:"p95"`
+	P99     float64 `json:"p99"`
+	Max     float64 `json:"max"`
+}
+
+// BatchStatsResult holds the batch write statistics of a load, for processors
+// that implement targets.ProcessorWithStats.
+type BatchStatsResult struct {
+	BytesSent uint64           `json:"bytesSent"`
+	Latency   LatencySummary   `json:"latency"`
+	Workers   []LatencySummary `json:"workers"`
+}
+
+func newLatencyHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(1, maxBatchLatencyMicros, batchLatencySigFigs)
+}
+
+func summarizeLatencies(h *hdrhistogram.Histogram) LatencySummary {
+	ms := func(micros int64) float64 { return float64(micros) / 1e3 }
+	return LatencySummary{
+		Batches: h.TotalCount(),
+		Mean:    h.Mean() / 1e3,
+		P50:     ms(h.ValueAtQuantile(50)),
+		P95:     ms(h.ValueAtQuantile(95)),
+		P99:     ms(h.ValueAtQuantile(99)),
+		Max:     ms(h.Max()),
+	}
+}
+
+// batchStats keeps HDR histograms of the batch write latencies per worker
+// and for the current reporting period. Its methods do nothing on a nil
+// *batchStats.
+type batchStats struct {
+	mu       sync.Mutex
+	workers  []*hdrhistogram.Histogram
+	bytes    uint64
+	interval *hdrhistogram.Histogram
+	// bytes sent in the current reporting period
+	intervalBytes uint64
+
+	csvFile *os.File
+	csv     *bufio.Writer
+}
+
+func newBatchStats(workers uint) *batchStats {
+	s := &batchStats{
+		workers:  make([]*hdrhistogram.Histogram, workers),
+		interval: newLatencyHistogram(),
+	}
+	for i := range s.workers {
+		s.workers[i] = newLatencyHistogram()
+	}
+	return s
+}
+
+// writeCSV makes every call to intervalSummary also write a row to the CSV
+// file at path.
+func (s *batchStats) writeCSV(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	s.csvFile = f
+	s.csv = bufio.NewWriter(f)
+	_, err = s.csv.WriteString(batchStatsCSVHeader)
+	return err
+}
+
+func (s *batchStats) record(worker uint, stats targets.BatchStats) {
+	if s == nil {
+		return
+	}
+	lat := stats.Latency.Microseconds()
+	if lat < 1 {
+		lat = 1
+	} else if lat > maxBatchLatencyMicros {
+		lat = maxBatchLatencyMicros
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// RecordValue only fails for values out of range, which are clamped above
+	_ = s.workers[worker].RecordValue(lat)
+	_ = s.interval.RecordValue(lat)
+	s.bytes += stats.Bytes
+	s.intervalBytes += stats.Bytes
+}
+
+// intervalSummary returns the statistics of the batches written since the
+// last call and starts a new period, writing a CSV row at now if enabled.
+func (s *batchStats) intervalSummary(now time.Time) (LatencySummary, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summary, bytes := summarizeLatencies(s.interval), s.intervalBytes
+	s.interval.Reset()
+	s.intervalBytes = 0
+	if s.csv != nil {
+		row := make([]byte, 0, 128)
+		row = strconv.AppendInt(row, now.Unix(), 10)
+		row = append(row, ',')
+		row = strconv.AppendInt(row, summary.Batches, 10)
+		row = append(row, ',')
+		row = strconv.AppendUint(row, bytes, 10)
+		for _, v := range []float64{summary.P50, summary.P95, summary.P99, summary.Max} {
+			row = append(row, ',')
+			row = strconv.AppendFloat(row, v, 'f', 3, 64)
+		}
+		row = append(row, '\n')
+		_, _ = s.csv.Write(row)
+	}
+	return summary, bytes
+}
+
+// close writes the CSV row of the last, partial period and closes the file.
+func (s *batchStats) close(now time.Time) error {
+	if s == nil || s.csv == nil {
+		return nil
+	}
+	s.intervalSummary(now)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.csv.Flush()
+	if cerr := s.csvFile.Close(); err == nil {
+		err = cerr
+	}
+	s.csv = nil
+	return err
+}
+
+// result returns the statistics of the whole load, or nil if no batch
+// statistics were recorded.
+func (s *batchStats) result() *BatchStatsResult {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all := newLatencyHistogram()
+	res := &BatchStatsResult{BytesSent: s.bytes}
+	for _, h := range s.workers {
+		all.Merge(h)
+		res.Workers = append(res.Workers, summarizeLatencies(h))
+	}
+	if all.TotalCount() == 0 {
+		return nil
+	}
+	res.Latency = summarizeLatencies(all)
+	return res
+}
@@ -0,0 +1,80 @@
+package load
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+func TestBatchStats(t *testing.T) {
+	s := newBatchStats(2)
+	if s.result() != nil {
+		t.Errorf("result without batches is not nil")
+	}
+	for i := 1; i <= 100; i++ {
+		s.record(uint(i%2), targets.BatchStats{Latency: time.Duration(i) * time.Millisecond, Bytes: 10})
+	}
+
+	lat, bytes := s.intervalSummary(time.Now())
+	if lat.Batches != 100 || bytes != 1000 {
+		t.Errorf("incorrect interval: got %d batches, %d bytes", lat.Batches, bytes)
+	}
+	if lat.P50 < 49.9 || lat.P50 > 50.1 || lat.Max < 99.9 || lat.Max > 100.1 {
+		t.Errorf("incorrect interval latencies: %+v", lat)
+	}
+	if lat, bytes := s.intervalSummary(time.Now()); lat.Batches != 0 || bytes != 0 {
+		t.Errorf("interval not reset: got %d batches, %d bytes", lat.Batches, bytes)
+	}
+
+	res := s.result()
+	if res.BytesSent != 1000 || res.Latency.Batches != 100 || len(res.Workers) != 2 {
+		t.Fatalf("incorrect result: %+v", res)
+	}
+	if res.Workers[0].Batches != 50 || res.Workers[0].Max < 99.9 || res.Workers[1].Max > 99.1 {
+		t.Errorf("incorrect worker latencies: %+v", res.Workers)
+	}
+	if res.Latency.P99 < 98.9 || res.Latency.P99 > 99.1 {
+		t.Errorf("incorrect p99: got %f want 99", res.Latency.P99)
+	}
+}
+
+func TestBatchStatsNil(t *testing.T) {
+	var s *batchStats
+	s.record(0, targets.BatchStats{Latency: time.Second})
+	if s.result() != nil {
+		t.Errorf("nil batch stats has a result")
+	}
+	if err := s.close(time.Now()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBatchStatsCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.csv")
+	s := newBatchStats(1)
+	if err := s.writeCSV(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.record(0, targets.BatchStats{Latency: 2 * time.Millisecond, Bytes: 100})
+	s.intervalSummary(time.Unix(10, 0))
+	s.record(0, targets.BatchStats{Latency: time.Millisecond, Bytes: 50})
+	if err := s.close(time.Unix(20, 0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// periods after close are not written
+	s.intervalSummary(time.Unix(30, 0))
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := batchStatsCSVHeader +
+		"10,1,100,2.000,2.000,2.000,2.000\n" +
+		"20,1,50,1.000,1.000,1.000,1.000\n"
+	if string(got) != want {
+		t.Errorf("incorrect CSV:\ngot\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package load
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+const (
+	defaultCheckpointInterval   = 10 * time.Second
+	errResumeNoCheckpointFile   = "--resume requires --checkpoint-file"
+	errCheckpointNoFlowControl  = "checkpoints require flow control: --checkpoint-file cannot be used with --no-flow-control"
+	errCheckpointReadFmt        = "cannot read checkpoint file %s: %v"
+	errCheckpointWriteFmt       = "cannot write checkpoint file %s: %v"
+	checkpointTempFileExtension = ".tmp"
+)
+
+// checkpoint is the content of a checkpoint file.
+type checkpoint struct {
+	// Items is the number of items of the data source, counted from its
+	// start, that were all acknowledged by the workers.
+	Items   uint64    `json:"items"`
+	Updated time.Time `json:"updated"`
+}
+
+// readCheckpoint returns the checkpoint stored at path, or nil if there is
+// no file at path.
+func readCheckpoint(path string) (*checkpoint, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	cp := &checkpoint{}
+	if err := json.Unmarshal(b, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// writeCheckpoint replaces the checkpoint file at path with cp. The file is
+// written next to path and renamed, so a crash never leaves a partial file.
+func writeCheckpoint(path string, cp checkpoint) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+checkpointTempFileExtension)
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// checkpointer tracks which items of the data source were loaded, i.e. are
+// in batches a worker acknowledged, and periodically writes the number of
+// items up to the first one that was not loaded yet to a checkpoint file.
+// It is only used by the scanner goroutine. Its methods do nothing on a nil
+// *checkpointer.
+type checkpointer struct {
+	path     string
+	interval time.Duration
+	nowFn    func() time.Time
+	last     time.Time
+
+	// items read from the data source so far, including skipped ones
+	read uint64
+	// number of the first item of every batch not acknowledged yet, counted
+	// from 1
+	first map[targets.Batch]uint64
+}
+
+// newCheckpointer returns a checkpointer writing to path every interval,
+// for a data source whose first skipped items were loaded before.
+func newCheckpointer(path string, interval time.Duration, skipped uint64) *checkpointer {
+	return &checkpointer{
+		path:     path,
+		interval: interval,
+		nowFn:    time.Now,
+		last:     time.Now(),
+		read:     skipped,
+		first:    map[targets.Batch]uint64{},
+	}
+}
+
+// appended records that the next item of the data source was appended to b.
+func (c *checkpointer) appended(b targets.Batch) {
+	if c == nil {
+		return
+	}
+	c.read++
+	if _, ok := c.first[b]; !ok {
+		c.first[b] = c.read
+	}
+}
+
+// acked records that a worker loaded b, and writes a checkpoint if the last
+// one is older than the interval.
+func (c *checkpointer) acked(b targets.Batch) error {
+	if c == nil {
+		return nil
+	}
+	delete(c.first, b)
+	if now := c.nowFn(); now.Sub(c.last) >= c.interval {
+		return c.save()
+	}
+	return nil
+}
+
+// loaded returns the number of items up to the first item that is in a
+// batch not acknowledged yet.
+func (c *checkpointer) loaded() uint64 {
+	loaded := c.read
+	for _, first := range c.first {
+		if first-1 < loaded {
+			loaded = first - 1
+		}
+	}
+	return loaded
+}
+
+// save writes a checkpoint of the items loaded so far.
+func (c *checkpointer) save() error {
+	if c == nil {
+		return nil
+	}
+	c.last = c.nowFn()
+	return writeCheckpoint(c.path, checkpoint{Items: c.loaded(), Updated: c.last.UTC()})
+}
+
+// skipItems reads n items of ds, which were loaded before, and returns how
+// many it read, which is less than n if ds ends.
+func skipItems(ds targets.DataSource, n uint64) uint64 {
+	var skipped uint64
+	for ; skipped < n; skipped++ {
+		if item := ds.NextItem(); item.Data == nil {
+			break
+		}
+	}
+	return skipped
+}
+
+// checkpointDataSource skips the items of ds loaded before when resuming. It
+// returns the checkpointer of the load, nil without --checkpoint-file, the
+// limit of items left to read, and false if --limit items were loaded before.
+func (l *CommonBenchmarkRunner) checkpointDataSource(ds targets.DataSource) (*checkpointer, uint64, bool) {
+	if l.CheckpointFile == "" {
+		return nil, l.Limit, true
+	}
+	skipped := uint64(0)
+	if l.resumeItems > 0 {
+		start := time.Now()
+		skipped = skipItems(ds, l.resumeItems)
+		printFn("skipped %d items loaded before in %0.3fsec\n", skipped, time.Since(start).Seconds())
+	}
+	cp := newCheckpointer(l.CheckpointFile, l.CheckpointInterval, skipped)
+	if l.Limit == 0 {
+		return cp, 0, true
+	}
+	if skipped >= l.Limit {
+		return cp, 0, false
+	}
+	return cp, l.Limit - skipped, true
+}
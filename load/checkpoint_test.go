@@ -0,0 +1,190 @@
+package load
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+func TestCheckpointer(t *testing.T) {
+	now := time.Unix(0, 0)
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	c := newCheckpointer(path, time.Second, 10)
+	c.nowFn = func() time.Time { return now }
+	c.last = now
+
+	// items 11 and 13 go to b1, 12 and 14 to b2, 15 to b3
+	b1, b2, b3 := &testBatch{}, &testBatch{}, &testBatch{}
+	for _, b := range []targets.Batch{b1, b2, b1, b2, b3} {
+		c.appended(b)
+	}
+	if got := c.loaded(); got != 10 {
+		t.Errorf("incorrect loaded items before acks: got %d want 10", got)
+	}
+	if err := c.acked(b2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := c.loaded(); got != 10 {
+		t.Errorf("incorrect loaded items after out of order ack: got %d want 10", got)
+	}
+	if cp, _ := readCheckpoint(path); cp != nil {
+		t.Errorf("checkpoint written before the interval: %+v", cp)
+	}
+
+	now = now.Add(time.Second)
+	if err := c.acked(b1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := c.loaded(); got != 14 {
+		t.Errorf("incorrect loaded items: got %d want 14", got)
+	}
+	cp, err := readCheckpoint(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cp == nil || cp.Items != 14 || !cp.Updated.Equal(now) {
+		t.Errorf("incorrect checkpoint: got %+v want 14 items at %v", cp, now)
+	}
+
+	if err := c.acked(b3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := c.loaded(); got != 15 {
+		t.Errorf("incorrect loaded items after all acks: got %d want 15", got)
+	}
+}
+
+func TestCheckpointerNil(t *testing.T) {
+	var c *checkpointer
+	c.appended(&testBatch{})
+	if err := c.acked(&testBatch{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := c.save(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestReadCheckpointMissing(t *testing.T) {
+	cp, err := readCheckpoint(filepath.Join(t.TempDir(), "missing"))
+	if cp != nil || err != nil {
+		t.Errorf("missing checkpoint file: got %v, %v want nil, nil", cp, err)
+	}
+}
+
+func TestScanWithCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	testData := []byte{0x00, 0x01, 0x02, 0x03, 0x04}
+	ds := &testDataSource{br: bufio.NewReader(bytes.NewReader(testData))}
+	channels := []*duplexChannel{newDuplexChannel(1), newDuplexChannel(1)}
+	for _, c := range channels {
+		go _boringWorker(c)
+	}
+	cp := newCheckpointer(path, time.Hour, 3)
+	read := scanWithFlowControl(channels, 2, 0, ds, &testFactory{}, &modIndexer{mod: 2}, cp)
+	if read != 5 {
+		t.Errorf("incorrect items read: got %d want 5", read)
+	}
+	got, err := readCheckpoint(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got == nil || got.Items != 8 {
+		t.Errorf("incorrect checkpoint: got %+v want 8 items", got)
+	}
+}
+
+func TestCheckpointDataSource(t *testing.T) {
+	cases := []struct {
+		desc        string
+		file        string
+		resumeItems uint64
+		limit       uint64
+		wantSkipped uint64
+		wantLimit   uint64
+		wantMore    bool
+	}{
+		{desc: "no checkpoint file", limit: 4, wantLimit: 4, wantMore: true},
+		{desc: "new load", file: "cp", limit: 4, wantLimit: 4, wantMore: true},
+		{desc: "resume", file: "cp", resumeItems: 2, wantSkipped: 2, wantMore: true},
+		{desc: "resume with limit", file: "cp", resumeItems: 2, limit: 4, wantSkipped: 2, wantLimit: 2, wantMore: true},
+		{desc: "resume after limit", file: "cp", resumeItems: 4, limit: 4, wantSkipped: 4},
+		{desc: "resume after end of data", file: "cp", resumeItems: 10, wantSkipped: 5, wantMore: true},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			oldPrintFn := printFn
+			printFn = func(string, ...interface{}) (int, error) { return 0, nil }
+			defer func() { printFn = oldPrintFn }()
+
+			ds := &testDataSource{br: bufio.NewReader(bytes.NewReader([]byte{0, 1, 2, 3, 4}))}
+			l := &CommonBenchmarkRunner{
+				BenchmarkRunnerConfig: BenchmarkRunnerConfig{CheckpointFile: c.file, Limit: c.limit},
+				resumeItems:           c.resumeItems,
+			}
+			cp, limit, more := l.checkpointDataSource(ds)
+			if ds.called != c.wantSkipped {
+				t.Errorf("incorrect items skipped: got %d want %d", ds.called, c.wantSkipped)
+			}
+			if limit != c.wantLimit || more != c.wantMore {
+				t.Errorf("incorrect limit: got %d, %v want %d, %v", limit, more, c.wantLimit, c.wantMore)
+			}
+			if (cp == nil) != (c.file == "") {
+				t.Errorf("incorrect checkpointer: got %v", cp)
+			} else if cp != nil && cp.read != c.wantSkipped {
+				t.Errorf("incorrect items of checkpointer: got %d want %d", cp.read, c.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestUseDBCreatorResume(t *testing.T) {
+	r := &CommonBenchmarkRunner{
+		BenchmarkRunnerConfig: BenchmarkRunnerConfig{
+			DoLoad:         true,
+			DoCreateDB:     true,
+			DoAbortOnExist: true,
+		},
+		resumeItems: 100,
+	}
+	core := &testCreator{exists: true}
+	r.useDBCreator(core)()
+	if core.removeCalled || core.createCalled {
+		t.Errorf("existing DB replaced on resume")
+	}
+
+	core = &testCreator{exists: false}
+	r.useDBCreator(core)()
+	if !core.createCalled {
+		t.Errorf("missing DB not created on resume")
+	}
+}
+
+func TestGetBenchmarkRunnerCheckpoint(t *testing.T) {
+	cases := []struct {
+		desc        string
+		conf        BenchmarkRunnerConfig
+		shouldPanic bool
+	}{
+		{desc: "checkpoint", conf: BenchmarkRunnerConfig{CheckpointFile: "cp", Resume: true}},
+		{desc: "resume without file", conf: BenchmarkRunnerConfig{Resume: true}, shouldPanic: true},
+		{desc: "no flow control", conf: BenchmarkRunnerConfig{CheckpointFile: "cp", NoFlowControl: true}, shouldPanic: true},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			defer func() {
+				if re := recover(); (re != nil) != c.shouldPanic {
+					t.Errorf("incorrect panic: got %v want panic %v", re, c.shouldPanic)
+				}
+			}()
+			r := GetBenchmarkRunner(c.conf).(*CommonBenchmarkRunner)
+			if r.CheckpointInterval != defaultCheckpointInterval {
+				t.Errorf("incorrect default interval: got %v", r.CheckpointInterval)
+			}
+		})
+	}
+}
@@ -4,18 +4,19 @@ import "github.com/timescale/tsbs/pkg/targets"
 
 // duplexChannel acts as a two-way channel for communicating from a scan routine
 // to a worker goroutine. The toWorker channel sends data to the worker for it
-// to process and the toScan channel allows the worker to acknowledge completion.
+// to process and the toScan channel allows the worker to acknowledge completion
+// by sending the processed batch back.
 // Using this we can accomplish better flow control between the scanner and workers.
 type duplexChannel struct {
 	toWorker  chan targets.Batch
-	toScanner chan bool
+	toScanner chan targets.Batch
 }
 
 // newDuplexChannel returns a duplexChannel with specified buffer sizes
 func newDuplexChannel(queueLen int) *duplexChannel {
 	return &duplexChannel{
 		toWorker:  make(chan targets.Batch, queueLen),
-		toScanner: make(chan bool, queueLen),
+		toScanner: make(chan targets.Batch, queueLen),
 	}
 }
 
@@ -24,9 +25,9 @@ func (dc *duplexChannel) sendToWorker(b targets.Batch) {
 	dc.toWorker <- b
 }
 
-// sendToScanner passes an acknowledge to the scanner from the worker
-func (dc *duplexChannel) sendToScanner() {
-	dc.toScanner <- true
+// sendToScanner passes an acknowledge of b to the scanner from the worker
+func (dc *duplexChannel) sendToScanner(b targets.Batch) {
+	dc.toScanner <- b
 }
 
 // close closes down the duplexChannel
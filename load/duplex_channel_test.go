@@ -44,9 +44,10 @@ func TestSendToWorker(t *testing.T) {
 
 func TestSendToScanner(t *testing.T) {
 	ch := newDuplexChannel(1)
-	ch.sendToScanner()
-	if res, ok := <-ch.toScanner; !res || !ok {
-		t.Errorf("sendToScanner did not send 'true', sent %v", res)
+	b := &testBatch{}
+	ch.sendToScanner(b)
+	if res, ok := <-ch.toScanner; res != b || !ok {
+		t.Errorf("sendToScanner did not send the batch, sent %v", res)
 	}
 }
 
@@ -0,0 +1,177 @@
+package insertstrategy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	rateStepSeparator  = ":"
+	rateCreditWindow   = time.Second
+	maxRateWait        = 100 * time.Millisecond
+	rateScheduleFormat = "rate schedule could not be parsed: '%s'. Required: 'rate' or 'offset:rate,...' with increasing offsets, e.g. '0s:1000,5m:5000' | rate is a non-negative number"
+)
+
+type ratePoint struct {
+	offset time.Duration
+	rate   float64
+}
+
+// RateSchedule is a target rate per second that can change over the course of
+// a load. Between two points the rate changes linearly, after the last point
+// it stays constant.
+type RateSchedule []ratePoint
+
+// ParseRateSchedule parses a constant rate, e.g. '500000', or a ramp of
+// comma-separated offset:rate points, where offsets are durations from the
+// start of the load in increasing order. E.g. '0s:100000,5m:500000' starts at
+// 100000 per second and ramps up linearly to 500000 per second over the first
+// five minutes, and '0s:100000,5m:100000,5m:500000' steps up after five minutes.
+func ParseRateSchedule(schedule string) (RateSchedule, error) {
+	var s RateSchedule
+	for _, step := range strings.Split(schedule, intervalSeparator) {
+		step = strings.TrimSpace(step)
+		var p ratePoint
+		var err error
+		rate := step
+		if i := strings.Index(step, rateStepSeparator); i >= 0 {
+			if p.offset, err = time.ParseDuration(step[:i]); err != nil {
+				return nil, fmt.Errorf(rateScheduleFormat, schedule)
+			}
+			rate = step[i+1:]
+		} else if len(s) > 0 {
+			return nil, fmt.Errorf(rateScheduleFormat, schedule)
+		}
+		if p.rate, err = strconv.ParseFloat(rate, 64); err != nil || p.rate < 0 {
+			return nil, fmt.Errorf(rateScheduleFormat, schedule)
+		}
+		if len(s) > 0 && p.offset < s[len(s)-1].offset {
+			return nil, fmt.Errorf(rateScheduleFormat, schedule)
+		}
+		s = append(s, p)
+	}
+	if len(s) == 0 {
+		return nil, fmt.Errorf(rateScheduleFormat, schedule)
+	}
+	return s, nil
+}
+
+// RateAt returns the target rate at elapsed time since the start of the load.
+func (s RateSchedule) RateAt(elapsed time.Duration) float64 {
+	// first point after elapsed
+	i := sort.Search(len(s), func(i int) bool { return s[i].offset > elapsed })
+	if i == 0 {
+		return s[0].rate
+	}
+	if i == len(s) {
+		return s[len(s)-1].rate
+	}
+	prev, next := s[i-1], s[i]
+	frac := float64(elapsed-prev.offset) / float64(next.offset-prev.offset)
+	return prev.rate + frac*(next.rate-prev.rate)
+}
+
+// Amount returns the target amount between from and to, both elapsed times
+// since the start of the load.
+func (s RateSchedule) Amount(from, to time.Duration) float64 {
+	amount := 0.0
+	for from < to {
+		end := to
+		for _, p := range s {
+			if p.offset > from && p.offset < end {
+				end = p.offset
+				break
+			}
+		}
+		// the rate is linear between from and end, so its mean is the rate
+		// half way, which unlike the rates at the ends is not affected by steps
+		amount += s.RateAt(from+(end-from)/2) * (end - from).Seconds()
+		from = end
+	}
+	return amount
+}
+
+// RateRegulator is a token bucket shared by all load workers, which holds
+// them back so that together they follow a RateSchedule, measured in metrics,
+// rows or bytes.
+type RateRegulator struct {
+	schedule RateSchedule
+	nowFn    nowProviderFn
+	sleepFn  func(time.Duration)
+
+	mu        sync.Mutex
+	start     time.Time
+	last      time.Time
+	allowance float64
+
+	total uint64
+}
+
+// NewRateRegulator returns a RateRegulator following schedule from now on.
+func NewRateRegulator(schedule RateSchedule) *RateRegulator {
+	return newRateRegulator(schedule, time.Now, time.Sleep)
+}
+
+func newRateRegulator(schedule RateSchedule, nowFn nowProviderFn, sleepFn func(time.Duration)) *RateRegulator {
+	now := nowFn()
+	return &RateRegulator{
+		schedule: schedule,
+		nowFn:    nowFn,
+		sleepFn:  sleepFn,
+		start:    now,
+		last:     now,
+	}
+}
+
+// Wait takes n tokens for a batch a worker inserted. While the workers are
+// ahead of the schedule, i.e. the tokens taken so far were not refilled yet,
+// the calling goroutine sleeps. Unused tokens are kept for up to a second of
+// the target rate, so the rate can catch up after a slow insert.
+func (r *RateRegulator) Wait(n uint64) {
+	for {
+		r.mu.Lock()
+		now := r.nowFn()
+		r.refill(now)
+		if r.allowance >= 0 {
+			r.allowance -= float64(n)
+			r.mu.Unlock()
+			atomic.AddUint64(&r.total, n)
+			return
+		}
+		wait := maxRateWait
+		if rate := r.schedule.RateAt(now.Sub(r.start)); rate > 0 {
+			if d := time.Duration(-r.allowance / rate * float64(time.Second)); d < wait {
+				wait = d
+			}
+		}
+		r.mu.Unlock()
+		r.sleepFn(wait)
+	}
+}
+
+func (r *RateRegulator) refill(now time.Time) {
+	if !now.After(r.last) {
+		return
+	}
+	r.allowance += r.schedule.Amount(r.last.Sub(r.start), now.Sub(r.start))
+	if limit := r.schedule.RateAt(now.Sub(r.start)) * rateCreditWindow.Seconds(); r.allowance > limit {
+		r.allowance = limit
+	}
+	r.last = now
+}
+
+// Total returns the amount taken by Wait so far.
+func (r *RateRegulator) Total() uint64 {
+	return atomic.LoadUint64(&r.total)
+}
+
+// Target returns the amount the schedule targets between from and to, both
+// elapsed times since the regulator was created.
+func (r *RateRegulator) Target(from, to time.Duration) float64 {
+	return r.schedule.Amount(from, to)
+}
@@ -0,0 +1,168 @@
+package insertstrategy
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseRateSchedule(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		schedule  string
+		want      RateSchedule
+		expectErr bool
+	}{
+		{
+			desc:     "constant rate",
+			schedule: "500",
+			want:     RateSchedule{{rate: 500}},
+		}, {
+			desc:     "ramp",
+			schedule: "0s:100, 1m:200",
+			want:     RateSchedule{{rate: 100}, {offset: time.Minute, rate: 200}},
+		}, {
+			desc:     "step",
+			schedule: "1m:100,1m:200",
+			want:     RateSchedule{{offset: time.Minute, rate: 100}, {offset: time.Minute, rate: 200}},
+		}, {
+			desc:      "empty",
+			schedule:  "",
+			expectErr: true,
+		}, {
+			desc:      "not a number",
+			schedule:  "a",
+			expectErr: true,
+		}, {
+			desc:      "negative rate",
+			schedule:  "0s:-1",
+			expectErr: true,
+		}, {
+			desc:      "bad offset",
+			schedule:  "1x:10",
+			expectErr: true,
+		}, {
+			desc:      "decreasing offsets",
+			schedule:  "1m:10,0s:20",
+			expectErr: true,
+		}, {
+			desc:      "second point without offset",
+			schedule:  "10,20",
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ParseRateSchedule(tc.schedule)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %v want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("point %d: got %v want %v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRateSchedule(t *testing.T) {
+	s, err := ParseRateSchedule("10s:100,20s:200,30s:200,30s:400")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rates := map[time.Duration]float64{
+		0:                100,
+		10 * time.Second: 100,
+		15 * time.Second: 150,
+		20 * time.Second: 200,
+		30 * time.Second: 400,
+		time.Hour:        400,
+	}
+	for elapsed, want := range rates {
+		if got := s.RateAt(elapsed); got != want {
+			t.Errorf("rate at %v: got %f want %f", elapsed, got, want)
+		}
+	}
+
+	amounts := []struct {
+		from, to time.Duration
+		want     float64
+	}{
+		{from: 0, to: 10 * time.Second, want: 1000},
+		{from: 10 * time.Second, to: 20 * time.Second, want: 1500},
+		{from: 5 * time.Second, to: 15 * time.Second, want: 500 + 625},
+		{from: 25 * time.Second, to: 35 * time.Second, want: 1000 + 2000},
+		{from: 0, to: 0, want: 0},
+	}
+	for _, a := range amounts {
+		if got := s.Amount(a.from, a.to); math.Abs(got-a.want) > 1e-6 {
+			t.Errorf("amount from %v to %v: got %f want %f", a.from, a.to, got, a.want)
+		}
+	}
+}
+
+func TestRateRegulatorWait(t *testing.T) {
+	s, err := ParseRateSchedule("1000")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Unix(0, 0)
+	slept := time.Duration(0)
+	r := newRateRegulator(s, func() time.Time { return now }, func(d time.Duration) {
+		slept += d
+		now = now.Add(d)
+	})
+
+	// the first batch is not held back
+	r.Wait(500)
+	if slept != 0 {
+		t.Errorf("first batch slept %v", slept)
+	}
+	// the second has to wait for the 500 tokens of the first
+	r.Wait(500)
+	if slept != 500*time.Millisecond {
+		t.Errorf("second batch slept %v want %v", slept, 500*time.Millisecond)
+	}
+	// an idle period gives at most a second of credit
+	now = now.Add(time.Minute)
+	slept = 0
+	r.Wait(500)
+	r.Wait(1000)
+	r.Wait(1000)
+	if slept != 500*time.Millisecond {
+		t.Errorf("after idle period slept %v want %v", slept, 500*time.Millisecond)
+	}
+	if got := r.Total(); got != 3500 {
+		t.Errorf("incorrect total: got %d want %d", got, 3500)
+	}
+	if got := r.Target(0, 2*time.Second); got != 2000 {
+		t.Errorf("incorrect target: got %f want %d", got, 2000)
+	}
+}
+
+func TestRateRegulatorWaitZeroRate(t *testing.T) {
+	s, err := ParseRateSchedule("0s:0,1s:0,1s:100")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Unix(0, 0)
+	r := newRateRegulator(s, func() time.Time { return now }, func(d time.Duration) {
+		now = now.Add(d)
+	})
+	r.Wait(10)
+	r.Wait(10)
+	// 10 tokens at 100 per second after the first second
+	if want := time.Unix(1, 100*int64(time.Millisecond)); now.Sub(want).Abs() > time.Millisecond {
+		t.Errorf("second batch waited until %v want %v", now, want)
+	}
+}
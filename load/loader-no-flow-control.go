@@ -54,10 +54,16 @@ func (l *noFlowBenchmarkRunner) work(b targets.Benchmark, wg *sync.WaitGroup, c
 	// Process batches coming from the incoming queue (c)
 	for batch := range c {
 		startedWorkAt := time.Now()
-		metricCnt, rowCnt := proc.ProcessBatch(batch, l.DoLoad)
+		l.metrics.startBatch()
+		metricCnt, rowCnt, stats := l.processBatch(proc, batch, workerNum)
 		atomic.AddUint64(&l.metricCnt, metricCnt)
 		atomic.AddUint64(&l.rowCnt, rowCnt)
+		l.metrics.endBatch(startedWorkAt)
+		if l.batchListener != nil {
+			l.batchListener(metricCnt, rowCnt)
+		}
 		l.timeToSleep(workerNum, startedWorkAt)
+		l.waitForRate(metricCnt, rowCnt, stats)
 	}
 
 	// Close proc if necessary
@@ -65,6 +71,7 @@ func (l *noFlowBenchmarkRunner) work(b targets.Benchmark, wg *sync.WaitGroup, c
 	case targets.ProcessorCloser:
 		c.Close(l.DoLoad)
 	}
+	l.addRejected(proc)
 
 	wg.Done()
 }
@@ -13,6 +13,7 @@ import (
 
 	"github.com/spf13/pflag"
 	"github.com/timescale/tsbs/load/insertstrategy"
+	"github.com/timescale/tsbs/pkg/metrics"
 )
 
 const (
@@ -21,6 +22,12 @@ const (
 	DefaultChannelCapacityFlagVal   = 0
 	defaultChannelCapacityPerWorker = 5
 	errDBExistsFmt                  = "database \"%s\" exists: aborting."
+	errInvalidRateUnitFmt           = "invalid rate unit '%s', must be one of: metrics, rows, bytes"
+	errRateUnitNoBytes              = "rate unit bytes is not supported: the target does not report the bytes it sends"
+
+	rateUnitMetrics = "metrics"
+	rateUnitRows    = "rows"
+	rateUnitBytes   = "bytes"
 )
 
 // change for more useful testing
@@ -44,6 +51,16 @@ type BenchmarkRunnerConfig struct {
 	ChannelCapacity uint          `yaml:"channel-capacity" mapstructure:"channel-capacity" json:"channel-capacity"`
 	InsertIntervals string        `yaml:"insert-intervals" mapstructure:"insert-intervals" json:"insert-intervals"`
 	ResultsFile     string        `yaml:"results-file" mapstructure:"results-file" json:"results-file"`
+	MetricsAddr     string        `yaml:"metrics-addr" mapstructure:"metrics-addr" json:"metrics-addr"`
+	BatchStatsCSV   string        `yaml:"batch-stats-csv" mapstructure:"batch-stats-csv" json:"batch-stats-csv"`
+	RateLimit       string        `yaml:"rate-limit" mapstructure:"rate-limit" json:"rate-limit"`
+	RateUnit        string        `yaml:"rate-unit" mapstructure:"rate-unit" json:"rate-unit"`
+
+	// checkpointing of the items loaded, see checkpoint.go
+	CheckpointFile     string        `yaml:"checkpoint-file" mapstructure:"checkpoint-file" json:"checkpoint-file"`
+	CheckpointInterval time.Duration `yaml:"checkpoint-interval" mapstructure:"checkpoint-interval" json:"checkpoint-interval"`
+	Resume             bool          `yaml:"resume" mapstructure:"resume" json:"resume"`
+
 	// deprecated, should not be used in other places other than tsbs_load_xx commands
 	FileName string `yaml:"file" mapstructure:"file" json:"file"`
 	Seed     int64  `yaml:"seed" mapstructure:"seed" json:"seed"`
@@ -64,13 +81,27 @@ func (c BenchmarkRunnerConfig) AddToFlagSet(fs *pflag.FlagSet) {
 	fs.String("insert-intervals", "", "Time to wait between each insert, default '' => all workers insert ASAP. '1,2' = worker 1 waits 1s between inserts, worker 2 and others wait 2s")
 	fs.Bool("hash-workers", false, "Whether to consistently hash insert data to the same workers (i.e., the data for a particular host always goes to the same worker)")
 	fs.String("results-file", "", "Write the test results summary json to this file")
+	fs.String("metrics-addr", "", "Serve Prometheus metrics on /metrics of this address, e.g. ':9100' (empty to disable)")
+	fs.String("batch-stats-csv", "", "Write the batch latency percentiles and bytes sent of every reporting period to this CSV file")
+	fs.String("rate-limit", "", "Target insert rate per second of all workers together, in --rate-unit: a constant, e.g. '500000', or a ramp of offset:rate points, e.g. '0s:100000,5m:500000' (empty = as fast as possible)")
+	fs.String("rate-unit", rateUnitMetrics, "Unit of --rate-limit: metrics, rows or bytes")
+	fs.String("checkpoint-file", "", "Periodically write the number of items of the input that were loaded to this file")
+	fs.Duration("checkpoint-interval", defaultCheckpointInterval, "Period to write the checkpoint file")
+	fs.Bool("resume", false, "Skip the items loaded according to --checkpoint-file and keep the existing database, if the file exists")
 }
 
 type BenchmarkRunner interface {
 	DatabaseName() string
 	RunBenchmark(b targets.Benchmark)
+	// SetBatchListener makes the runner call fn for every processed batch.
+	// It must be called before RunBenchmark.
+	SetBatchListener(fn BatchListener)
 }
 
+// BatchListener is notified of every batch a load worker processed, e.g. to
+// build a timeline of a load. It is called concurrently by the workers.
+type BatchListener func(metricCount, rowCount uint64)
+
 // CommonBenchmarkRunner is responsible for initializing and storing common
 // flags across all database systems and ultimately running a supplied Benchmark
 type CommonBenchmarkRunner struct {
@@ -79,6 +110,18 @@ type CommonBenchmarkRunner struct {
 	rowCnt         uint64
 	initialRand    *rand.Rand
 	sleepRegulator insertstrategy.SleepRegulator
+	metrics        *loadMetrics
+	batchStats     *batchStats
+	rateSchedule   insertstrategy.RateSchedule
+	rateRegulator  *insertstrategy.RateRegulator
+	batchListener  BatchListener
+
+	// metrics and rows the database refused, see targets.ProcessorRejecter
+	rejectedMetricCnt uint64
+	rejectedRowCnt    uint64
+
+	// items loaded before according to the checkpoint file, with --resume
+	resumeItems uint64
 }
 
 // GetBenchmarkRunnerWithBatchSize returns the singleton CommonBenchmarkRunner for use in a benchmark program
@@ -102,9 +145,31 @@ func GetBenchmarkRunner(c BenchmarkRunnerConfig) BenchmarkRunner {
 			panic(fmt.Sprintf("could not initialize BenchmarkRunner: %v", err))
 		}
 	}
+	if c.RateLimit != "" {
+		loader.rateSchedule, err = insertstrategy.ParseRateSchedule(c.RateLimit)
+		if err != nil {
+			panic(fmt.Sprintf("could not initialize BenchmarkRunner: %v", err))
+		}
+		switch loader.RateUnit {
+		case "":
+			loader.RateUnit = rateUnitMetrics
+		case rateUnitMetrics, rateUnitRows, rateUnitBytes:
+		default:
+			panic(fmt.Sprintf("could not initialize BenchmarkRunner: "+errInvalidRateUnitFmt, loader.RateUnit))
+		}
+	}
+	if c.Resume && c.CheckpointFile == "" {
+		panic("could not initialize BenchmarkRunner: " + errResumeNoCheckpointFile)
+	}
+	if loader.CheckpointInterval <= 0 {
+		loader.CheckpointInterval = defaultCheckpointInterval
+	}
 	if !c.NoFlowControl {
 		return &loader
 	}
+	if c.CheckpointFile != "" {
+		panic("could not initialize BenchmarkRunner: " + errCheckpointNoFlowControl)
+	}
 
 	if c.ChannelCapacity == DefaultChannelCapacityFlagVal {
 		if c.HashWorkers {
@@ -117,21 +182,53 @@ func GetBenchmarkRunner(c BenchmarkRunnerConfig) BenchmarkRunner {
 	return &noFlowBenchmarkRunner{loader}
 }
 
+// SetBatchListener makes the runner call fn for every processed batch
+func (l *CommonBenchmarkRunner) SetBatchListener(fn BatchListener) {
+	l.batchListener = fn
+}
+
 // DatabaseName returns the value of the --db-name flag (name of the database to store data)
 func (l *CommonBenchmarkRunner) DatabaseName() string {
 	return l.DBName
 }
 
 func (l *CommonBenchmarkRunner) preRun(b targets.Benchmark) (*sync.WaitGroup, *time.Time) {
+	if l.Resume {
+		cp, err := readCheckpoint(l.CheckpointFile)
+		if err != nil {
+			fatal(errCheckpointReadFmt, l.CheckpointFile, err)
+		} else if cp != nil {
+			l.resumeItems = cp.Items
+			printFn("resuming from checkpoint %s: %d items were loaded before %s\n", l.CheckpointFile, cp.Items, cp.Updated.Format(time.RFC3339))
+		}
+	}
+
 	// Create required DB
 	if b.GetDBCreator() != nil {
 		cleanupFn := l.useDBCreator(b.GetDBCreator())
 		defer cleanupFn()
 	}
 
+	if l.DoLoad {
+		l.batchStats = newBatchStats(l.Workers)
+		if len(l.BatchStatsCSV) > 0 {
+			if err := l.batchStats.writeCSV(l.BatchStatsCSV); err != nil {
+				fatal("cannot write batch stats to %s: %v", l.BatchStatsCSV, err)
+			}
+		}
+	}
+	if l.rateSchedule != nil {
+		l.rateRegulator = insertstrategy.NewRateRegulator(l.rateSchedule)
+	}
 	if l.ReportingPeriod.Nanoseconds() > 0 {
 		go l.report(l.ReportingPeriod)
 	}
+	if len(l.MetricsAddr) > 0 {
+		l.metrics = newLoadMetrics(metrics.DefaultRegistry, l)
+		if err := metrics.Serve(l.MetricsAddr); err != nil {
+			fatal("cannot serve metrics on %s: %v", l.MetricsAddr, err)
+		}
+	}
 	wg := &sync.WaitGroup{}
 	wg.Add(int(l.Workers))
 	start := time.Now()
@@ -143,6 +240,9 @@ func (l *CommonBenchmarkRunner) postRun(wg *sync.WaitGroup, start *time.Time) {
 	wg.Wait()
 	end := time.Now()
 	took := end.Sub(*start)
+	if err := l.batchStats.close(end); err != nil {
+		fatal("cannot write batch stats to %s: %v", l.BatchStatsCSV, err)
+	}
 	l.summary(took)
 	if l.BenchmarkRunnerConfig.ResultsFile != "" {
 		metricRate := float64(l.metricCnt) / took.Seconds()
@@ -157,6 +257,13 @@ func (l *CommonBenchmarkRunner) saveTestResult(took time.Duration, start time.Ti
 	if l.rowCnt > 0 {
 		totals["rowRate"] = rowRate
 	}
+	totals["rejectedMetrics"] = l.rejectedMetricCnt
+	totals["rejectedRows"] = l.rejectedRowCnt
+	if l.rateRegulator != nil {
+		totals["rateUnit"] = l.RateUnit
+		totals["targetRate"] = l.rateRegulator.Target(0, took) / took.Seconds()
+		totals["achievedRate"] = float64(l.rateRegulator.Total()) / took.Seconds()
+	}
 
 	testResult := LoaderTestResult{
 		ResultFormatVersion: LoaderTestResultVersion,
@@ -165,6 +272,7 @@ func (l *CommonBenchmarkRunner) saveTestResult(took time.Duration, start time.Ti
 		EndTime:             end.Unix(),
 		DurationMillis:      took.Milliseconds(),
 		Totals:              totals,
+		BatchStats:          l.batchStats.result(),
 	}
 
 	_, _ = fmt.Printf("Saving results json file to %s\n", l.BenchmarkRunnerConfig.ResultsFile)
@@ -199,7 +307,11 @@ func (l *CommonBenchmarkRunner) RunBenchmark(b targets.Benchmark) {
 	}
 
 	// Start scan process - actual data read process
-	scanWithFlowControl(channels, l.BatchSize, l.Limit, b.GetDataSource(), b.GetBatchFactory(), b.GetPointIndexer(uint(len(channels))))
+	ds := b.GetDataSource()
+	cp, limit, more := l.checkpointDataSource(ds)
+	if more {
+		scanWithFlowControl(channels, l.BatchSize, limit, ds, b.GetBatchFactory(), b.GetPointIndexer(uint(len(channels))), cp)
+	}
 	// After scan process completed (no more data to come) - begin shutdown process
 
 	// Close all communication channels to/from workers
@@ -227,14 +339,16 @@ func (l *CommonBenchmarkRunner) useDBCreator(dbc targets.DBCreator) func() {
 		}
 
 		// Check whether required DB already exists
+		// When resuming, an existing DB holds the items loaded before
 		exists := dbc.DBExists(l.DBName)
-		if exists && l.DoAbortOnExist {
+		resuming := exists && l.resumeItems > 0
+		if exists && l.DoAbortOnExist && !resuming {
 			panic(fmt.Sprintf(errDBExistsFmt, l.DBName))
 		}
 
 		// Create required DB if need be
 		// In case DB already exists - delete it
-		if l.DoCreateDB {
+		if l.DoCreateDB && !resuming {
 			if exists {
 				err := dbc.RemoveOldDB(l.DBName)
 				if err != nil {
@@ -282,11 +396,17 @@ func (l *CommonBenchmarkRunner) work(b targets.Benchmark, wg *sync.WaitGroup, c
 	// and send ACKs into duplexChannel.toScanner queue
 	for batch := range c.toWorker {
 		startedWorkAt := time.Now()
-		metricCnt, rowCnt := proc.ProcessBatch(batch, l.DoLoad)
+		l.metrics.startBatch()
+		metricCnt, rowCnt, stats := l.processBatch(proc, batch, workerNum)
 		atomic.AddUint64(&l.metricCnt, metricCnt)
 		atomic.AddUint64(&l.rowCnt, rowCnt)
-		c.sendToScanner()
+		l.metrics.endBatch(startedWorkAt)
+		if l.batchListener != nil {
+			l.batchListener(metricCnt, rowCnt)
+		}
+		c.sendToScanner(batch)
 		l.timeToSleep(workerNum, startedWorkAt)
+		l.waitForRate(metricCnt, rowCnt, stats)
 	}
 
 	// Close proc if necessary
@@ -294,10 +414,53 @@ func (l *CommonBenchmarkRunner) work(b targets.Benchmark, wg *sync.WaitGroup, c
 	case targets.ProcessorCloser:
 		c.Close(l.DoLoad)
 	}
+	l.addRejected(proc)
 
 	wg.Done()
 }
 
+// processBatch has proc process b. If proc is a targets.ProcessorWithStats,
+// the write statistics of b are recorded and returned, otherwise they are nil.
+func (l *CommonBenchmarkRunner) processBatch(proc targets.Processor, b targets.Batch, workerNum uint) (uint64, uint64, *targets.BatchStats) {
+	if p, ok := proc.(targets.ProcessorWithStats); ok {
+		metricCnt, rowCnt, stats := p.ProcessBatchWithStats(b, l.DoLoad)
+		l.batchStats.record(workerNum, stats)
+		return metricCnt, rowCnt, &stats
+	}
+	metricCnt, rowCnt := proc.ProcessBatch(b, l.DoLoad)
+	return metricCnt, rowCnt, nil
+}
+
+// waitForRate holds the worker back while the workers are ahead of
+// --rate-limit
+func (l *CommonBenchmarkRunner) waitForRate(metricCnt, rowCnt uint64, stats *targets.BatchStats) {
+	if l.rateRegulator == nil {
+		return
+	}
+	n := metricCnt
+	switch l.RateUnit {
+	case rateUnitRows:
+		n = rowCnt
+	case rateUnitBytes:
+		if stats == nil {
+			fatal(errRateUnitNoBytes)
+			return
+		}
+		n = stats.Bytes
+	}
+	l.rateRegulator.Wait(n)
+}
+
+// addRejected adds the metrics and rows proc rejected to the totals, if proc
+// is a targets.ProcessorRejecter
+func (l *CommonBenchmarkRunner) addRejected(proc targets.Processor) {
+	if r, ok := proc.(targets.ProcessorRejecter); ok {
+		metricCnt, rowCnt := r.Rejected()
+		atomic.AddUint64(&l.rejectedMetricCnt, metricCnt)
+		atomic.AddUint64(&l.rejectedRowCnt, rowCnt)
+	}
+}
+
 func (l *CommonBenchmarkRunner) timeToSleep(workerNum uint, startedWorkAt time.Time) {
 	if l.sleepRegulator != nil {
 		l.sleepRegulator.Sleep(int(workerNum), startedWorkAt)
@@ -313,6 +476,26 @@ func (l *CommonBenchmarkRunner) summary(took time.Duration) {
 		rowRate := float64(l.rowCnt) / float64(took.Seconds())
 		printFn("loaded %d rows in %0.3fsec with %d workers (mean rate %0.2f rows/sec)\n", l.rowCnt, took.Seconds(), l.Workers, rowRate)
 	}
+	if l.rejectedMetricCnt > 0 || l.rejectedRowCnt > 0 {
+		printFn("rejected %d metrics (%d rows)\n", l.rejectedMetricCnt, l.rejectedRowCnt)
+	}
+	if l.rateRegulator != nil {
+		target := l.rateRegulator.Target(0, took) / took.Seconds()
+		achieved := float64(l.rateRegulator.Total()) / took.Seconds()
+		printFn("target rate %0.2f %s/sec, achieved %0.2f %s/sec\n", target, l.RateUnit, achieved, l.RateUnit)
+	}
+	if res := l.batchStats.result(); res != nil {
+		printFn("sent %d bytes in %0.3fsec (mean rate %0.2f MB/sec)\n", res.BytesSent, took.Seconds(), float64(res.BytesSent)/1e6/took.Seconds())
+		printFn("batch latency: %s\n", formatLatencies(res.Latency))
+		for i, w := range res.Workers {
+			printFn("worker %d batch latency: %s\n", i, formatLatencies(w))
+		}
+	}
+}
+
+// formatLatencies formats s for report and summary
+func formatLatencies(s LatencySummary) string {
+	return fmt.Sprintf("p50 %0.2fms, p95 %0.2fms, p99 %0.2fms, max %0.2fms over %d batches", s.P50, s.P95, s.P99, s.Max, s.Batches)
 }
 
 // report handles periodic reporting of loading stats
@@ -321,6 +504,7 @@ func (l *CommonBenchmarkRunner) report(period time.Duration) {
 	prevTime := start
 	prevColCount := uint64(0)
 	prevRowCount := uint64(0)
+	prevRateTotal := uint64(0)
 
 	printFn("time,per. metric/s,metric total,overall metric/s,per. row/s,row total,overall row/s\n")
 	for now := range time.NewTicker(period).C {
@@ -339,6 +523,20 @@ func (l *CommonBenchmarkRunner) report(period time.Duration) {
 			printFn("%d,%0.2f,%E,%0.2f,-,-,-\n", now.Unix(), colrate, float64(cCount), overallColRate)
 		}
 
+		if l.batchStats != nil {
+			if lat, bytes := l.batchStats.intervalSummary(now); lat.Batches > 0 {
+				printFn("batch latency: %s, %0.2f MB/sec\n", formatLatencies(lat), float64(bytes)/1e6/took.Seconds())
+			}
+		}
+
+		if l.rateRegulator != nil {
+			rateTotal := l.rateRegulator.Total()
+			target := l.rateRegulator.Target(prevTime.Sub(start), sinceStart) / took.Seconds()
+			achieved := float64(rateTotal-prevRateTotal) / took.Seconds()
+			printFn("target rate %0.2f %s/sec, achieved %0.2f %s/sec\n", target, l.RateUnit, achieved, l.RateUnit)
+			prevRateTotal = rateTotal
+		}
+
 		prevColCount = cCount
 		prevRowCount = rCount
 		prevTime = now
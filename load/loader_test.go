@@ -3,6 +3,7 @@ package load
 import (
 	"bytes"
 	"fmt"
+	"github.com/timescale/tsbs/load/insertstrategy"
 	"github.com/timescale/tsbs/pkg/targets"
 	"strings"
 	"sync"
@@ -307,6 +308,8 @@ func TestCreateChannelsAndPartitions(t *testing.T) {
 
 func TestWork(t *testing.T) {
 	br := &CommonBenchmarkRunner{}
+	var listened uint64
+	br.SetBatchListener(func(metricCount, _ uint64) { atomic.AddUint64(&listened, metricCount) })
 	b := &testBenchmark{}
 	for i := 0; i < 2; i++ {
 		b.processors = append(b.processors, &testProcessor{})
@@ -336,6 +339,10 @@ func TestWork(t *testing.T) {
 		t.Errorf("TestWork: invalid metric count: got %d want %d", got, 2)
 	}
 
+	if got := atomic.LoadUint64(&listened); got != 2 {
+		t.Errorf("TestWork: invalid metric count of batch listener: got %d want %d", got, 2)
+	}
+
 	if !b.processors[0].closed {
 		t.Errorf("TestWork: processor 0 not closed")
 	}
@@ -380,11 +387,12 @@ func TestWorkWithSleep(t *testing.T) {
 
 func TestSummary(t *testing.T) {
 	cases := []struct {
-		desc    string
-		metrics uint64
-		rows    uint64
-		took    time.Duration
-		want    string
+		desc     string
+		metrics  uint64
+		rows     uint64
+		rejected uint64
+		took     time.Duration
+		want     string
 	}{
 		{
 			desc:    "10 metrics, 0 rows, 1 second",
@@ -414,12 +422,21 @@ func TestSummary(t *testing.T) {
 			took:    time.Second,
 			want:    "\nSummary:\nloaded 10 metrics in 1.000sec with 0 workers (mean rate 10.00 metrics/sec)\nloaded 1 rows in 1.000sec with 0 workers (mean rate 1.00 rows/sec)\n",
 		},
+		{
+			desc:     "include rejected: 10 metrics, 0 rows, 4 rejected, 1 second",
+			metrics:  10,
+			rejected: 4,
+			took:     time.Second,
+			want:     "\nSummary:\nloaded 10 metrics in 1.000sec with 0 workers (mean rate 10.00 metrics/sec)\nrejected 4 metrics (2 rows)\n",
+		},
 	}
 
 	for _, c := range cases {
 		br := &CommonBenchmarkRunner{}
 		br.metricCnt = c.metrics
 		br.rowCnt = c.rows
+		br.rejectedMetricCnt = c.rejected
+		br.rejectedRowCnt = c.rejected / 2
 		var b bytes.Buffer
 		printFn = func(s string, args ...interface{}) (n int, err error) {
 			return fmt.Fprintf(&b, s, args...)
@@ -479,3 +496,56 @@ func TestReport(t *testing.T) {
 		t.Errorf("TestReport: row report ends in -")
 	}
 }
+
+func TestGetBenchmarkRunnerRateLimit(t *testing.T) {
+	br := GetBenchmarkRunner(BenchmarkRunnerConfig{Workers: 1, RateLimit: "0s:10,1m:20"}).(*CommonBenchmarkRunner)
+	if len(br.rateSchedule) != 2 || br.RateUnit != rateUnitMetrics {
+		t.Errorf("incorrect rate limit: %v %s", br.rateSchedule, br.RateUnit)
+	}
+
+	for _, c := range []BenchmarkRunnerConfig{
+		{Workers: 1, RateLimit: "fast"},
+		{Workers: 1, RateLimit: "10", RateUnit: "points"},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("%+v: expected a panic", c)
+				}
+			}()
+			GetBenchmarkRunner(c)
+		}()
+	}
+}
+
+func TestWaitForRate(t *testing.T) {
+	schedule, err := insertstrategy.ParseRateSchedule("1000000")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cases := []struct {
+		unit      string
+		stats     *targets.BatchStats
+		want      uint64
+		wantFatal bool
+	}{
+		{unit: rateUnitMetrics, want: 10},
+		{unit: rateUnitRows, want: 2},
+		{unit: rateUnitBytes, stats: &targets.BatchStats{Bytes: 300}, want: 300},
+		{unit: rateUnitBytes, wantFatal: true},
+	}
+	for _, c := range cases {
+		fatalCalled := false
+		fatal = func(string, ...interface{}) { fatalCalled = true }
+		br := &CommonBenchmarkRunner{}
+		br.RateUnit = c.unit
+		br.rateRegulator = insertstrategy.NewRateRegulator(schedule)
+		br.waitForRate(10, 2, c.stats)
+		if fatalCalled != c.wantFatal {
+			t.Errorf("%s: fatal called %v want %v", c.unit, fatalCalled, c.wantFatal)
+		}
+		if got := br.rateRegulator.Total(); !c.wantFatal && got != c.want {
+			t.Errorf("%s: incorrect total: got %d want %d", c.unit, got, c.want)
+		}
+	}
+}
@@ -17,4 +17,8 @@ type LoaderTestResult struct {
 
 	// Totals
 	Totals map[string]interface{} `json:"Totals"`
+
+	// BatchStats holds the batch latencies and bytes sent, if the target
+	// reports them
+	BatchStats *BatchStatsResult `json:"BatchStats,omitempty"`
 }
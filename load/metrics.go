@@ -0,0 +1,46 @@
+package load
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/metrics"
+)
+
+// loadMetrics exposes the progress of a load benchmark as Prometheus metrics.
+// Its methods do nothing on a nil *loadMetrics, so runners without
+// --metrics-addr need no checks.
+type loadMetrics struct {
+	batches       *metrics.Counter
+	batchDuration *metrics.Histogram
+	busyWorkers   *metrics.Gauge
+}
+
+func newLoadMetrics(reg *metrics.Registry, l *CommonBenchmarkRunner) *loadMetrics {
+	reg.CounterFunc("tsbs_load_metrics_total", "Metric values inserted.", func() float64 {
+		return float64(atomic.LoadUint64(&l.metricCnt))
+	})
+	reg.CounterFunc("tsbs_load_rows_total", "Rows inserted.", func() float64 {
+		return float64(atomic.LoadUint64(&l.rowCnt))
+	})
+	reg.Gauge("tsbs_load_workers", "Configured insert workers.").Set(float64(l.Workers))
+	return &loadMetrics{
+		batches:       reg.Counter("tsbs_load_batches_total", "Batches processed."),
+		batchDuration: reg.Histogram("tsbs_load_batch_duration_seconds", "Time to process a batch, including backoff.", metrics.LatencyBuckets),
+		busyWorkers:   reg.Gauge("tsbs_load_workers_busy", "Workers processing a batch."),
+	}
+}
+
+func (m *loadMetrics) startBatch() {
+	if m != nil {
+		m.busyWorkers.Add(1)
+	}
+}
+
+func (m *loadMetrics) endBatch(startedAt time.Time) {
+	if m != nil {
+		m.busyWorkers.Add(-1)
+		m.batches.Inc()
+		m.batchDuration.Observe(time.Since(startedAt).Seconds())
+	}
+}
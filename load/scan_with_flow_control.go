@@ -42,9 +42,10 @@ func sendOrQueueBatch(ch *duplexChannel, count *int, batch targets.Batch, unsent
 // which are then dispatched to workers (duplexChannel chosen by PointIndexer).
 // Scan does flow control to make sure workers are not left idle for too long
 // and also that the scanning process does not starve them of CPU.
+// If cp is not nil, the acknowledged batches are checkpointed with it.
 func scanWithFlowControl(
 	channels []*duplexChannel, batchSize uint, limit uint64,
-	ds targets.DataSource, factory targets.BatchFactory, indexer targets.PointIndexer,
+	ds targets.DataSource, factory targets.BatchFactory, indexer targets.PointIndexer, cp *checkpointer,
 ) uint64 {
 	var itemsRead uint64
 	numChannels := len(channels)
@@ -107,9 +108,10 @@ func scanWithFlowControl(
 		}
 
 		// Only receive an 'ok' when it's from a channel, default does not return 'ok'
-		chosen, _, ok := reflect.Select(cases[:caseLimit])
+		chosen, recv, ok := reflect.Select(cases[:caseLimit])
 		if ok {
 			unsentBatches[chosen] = ackAndMaybeSend(channels[chosen], &ocnt, unsentBatches[chosen])
+			checkpointAck(cp, recv)
 		}
 
 		// Prepare new batch - decode new item and append it to batch
@@ -124,6 +126,7 @@ func scanWithFlowControl(
 		// Append new item to batch
 		idx := indexer.GetIndex(item)
 		fillingBatches[idx].Append(item)
+		cp.appended(fillingBatches[idx])
 
 		if fillingBatches[idx].Len() >= batchSize {
 			// Batch is full (contains at least batchSize items) - ready to be sent to worker,
@@ -152,11 +155,28 @@ func scanWithFlowControl(
 		}
 
 		// Try to send batches to workers
-		chosen, _, ok := reflect.Select(cases[:len(cases)-1])
+		chosen, recv, ok := reflect.Select(cases[:len(cases)-1])
 		if ok {
 			unsentBatches[chosen] = ackAndMaybeSend(channels[chosen], &ocnt, unsentBatches[chosen])
+			checkpointAck(cp, recv)
 		}
 	}
 
+	// All batches are acknowledged, so the checkpoint covers all items read
+	if err := cp.save(); err != nil {
+		fatal(errCheckpointWriteFmt, cp.path, err)
+	}
+
 	return itemsRead
 }
+
+// checkpointAck records the batch acknowledged by a worker, received from
+// its duplexChannel.toScanner, with cp.
+func checkpointAck(cp *checkpointer, recv reflect.Value) {
+	if cp == nil {
+		return
+	}
+	if err := cp.acked(recv.Interface().(targets.Batch)); err != nil {
+		fatal(errCheckpointWriteFmt, cp.path, err)
+	}
+}
@@ -203,8 +203,8 @@ func _checkScan(t *testing.T, desc string, called, read, want uint64) {
 }
 
 func _boringWorker(c *duplexChannel) {
-	for range c.toWorker {
-		c.sendToScanner()
+	for b := range c.toWorker {
+		c.sendToScanner(b)
 	}
 }
 
@@ -262,12 +262,12 @@ func TestScanWithIndexer(t *testing.T) {
 						t.Errorf("%s: did not panic when should", c.desc)
 					}
 				}()
-				scanWithFlowControl(channels, c.batchSize, c.limit, testDataSource, &testFactory{}, indexer)
+				scanWithFlowControl(channels, c.batchSize, c.limit, testDataSource, &testFactory{}, indexer, nil)
 			}()
 			continue
 		} else {
 			go _boringWorker(channels[0])
-			read := scanWithFlowControl(channels, c.batchSize, c.limit, testDataSource, &testFactory{}, indexer)
+			read := scanWithFlowControl(channels, c.batchSize, c.limit, testDataSource, &testFactory{}, indexer, nil)
 			_checkScan(t, c.desc, testDataSource.called, read, c.wantCalls)
 		}
 	}
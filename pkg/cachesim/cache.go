@@ -0,0 +1,250 @@
+package cachesim
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const errUnknownPolicyFmt = "unknown cache policy '%s'"
+
+// Kinds of cache hit, numbered like the hit kinds of the query runner's stats.
+const (
+	Miss       uint8 = 0
+	PartialHit uint8 = 1
+	FullHit    uint8 = 2
+)
+
+// Stats counts the requests a Cache served and how much of them it could
+// answer.
+type Stats struct {
+	Queries        uint64
+	FullHits       uint64
+	PartialHits    uint64
+	RequestedBytes float64
+	HitBytes       float64
+	Evictions      uint64
+}
+
+// Sub returns the difference between s and an earlier snapshot of the same
+// counters.
+func (s Stats) Sub(earlier Stats) Stats {
+	return Stats{
+		Queries:        s.Queries - earlier.Queries,
+		FullHits:       s.FullHits - earlier.FullHits,
+		PartialHits:    s.PartialHits - earlier.PartialHits,
+		RequestedBytes: s.RequestedBytes - earlier.RequestedBytes,
+		HitBytes:       s.HitBytes - earlier.HitBytes,
+		Evictions:      s.Evictions - earlier.Evictions,
+	}
+}
+
+// FullHitRatio is the fraction of queries answered entirely from the cache.
+func (s Stats) FullHitRatio() float64 {
+	return ratio(float64(s.FullHits), float64(s.Queries))
+}
+
+// PartialHitRatio is the fraction of queries answered partly from the cache.
+func (s Stats) PartialHitRatio() float64 {
+	return ratio(float64(s.PartialHits), float64(s.Queries))
+}
+
+// ByteHitRatio is the fraction of the requested bytes served by the cache.
+func (s Stats) ByteHitRatio() float64 {
+	return ratio(s.HitBytes, s.RequestedBytes)
+}
+
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
+
+// Cache models a semantic cache holding time ranges of segments under a byte
+// budget. Like STsCache, a request is answered with the cached parts of its
+// range and the remainder is fetched and cached.
+type Cache struct {
+	Name   string
+	budget int64
+	used   int64
+	now    uint64
+	policy policy
+	// segments holds the cached items of each segment, sorted by start time
+	// and not overlapping.
+	segments map[string][]*item
+	stats    Stats
+}
+
+// NewCache returns a Cache using the named policy within budget bytes. ttl is
+// the number of requests an item is served for by the ttl policy. The oracle
+// policy ignores budget.
+func NewCache(policyName string, budget int64, ttl uint64) (*Cache, error) {
+	var p policy
+	switch policyName {
+	case PolicyLRU:
+		p = newLRUPolicy()
+	case PolicyLFU:
+		p = newLFUPolicy()
+	case PolicyARC:
+		p = newARCPolicy(budget)
+	case PolicyTTL:
+		p = newTTLPolicy(ttl)
+	case PolicySize:
+		p = newSizePolicy()
+	case PolicyOracle:
+		p = newLRUPolicy()
+		budget = math.MaxInt64
+	default:
+		return nil, fmt.Errorf(errUnknownPolicyFmt, policyName)
+	}
+	return &Cache{
+		Name:     policyName,
+		budget:   budget,
+		policy:   p,
+		segments: make(map[string][]*item),
+	}, nil
+}
+
+// Stats returns the counters accumulated so far.
+func (c *Cache) Stats() Stats {
+	return c.stats
+}
+
+// UsedBytes returns the size of the cached items.
+func (c *Cache) UsedBytes() int64 {
+	return c.used
+}
+
+// Access serves r and returns the kind of hit it was.
+func (c *Cache) Access(r *Request) uint8 {
+	c.now++
+	requested := int64(len(r.Segments)) * r.Duration()
+	covered := int64(0)
+	for _, seg := range r.Segments {
+		covered += c.lookup(seg, r.Start, r.End)
+		for _, gap := range c.gaps(seg, r.Start, r.End) {
+			c.insert(seg, gap[0], gap[1], r.BytesPerSecond)
+		}
+	}
+
+	c.stats.Queries++
+	c.stats.RequestedBytes += float64(requested) * r.BytesPerSecond
+	c.stats.HitBytes += float64(covered) * r.BytesPerSecond
+	switch {
+	case covered >= requested && requested > 0:
+		c.stats.FullHits++
+		return FullHit
+	case covered > 0:
+		c.stats.PartialHits++
+		return PartialHit
+	default:
+		return Miss
+	}
+}
+
+// lookup drops expired items of seg, records hits on the ones overlapping
+// [start, end) and returns how many seconds of the range they cover.
+func (c *Cache) lookup(seg string, start, end int64) int64 {
+	covered := int64(0)
+	for _, it := range c.overlapping(seg, start, end) {
+		if c.policy.expired(it, c.now) {
+			c.policy.remove(it)
+			c.drop(it)
+			continue
+		}
+		covered += minInt64(it.end, end) - maxInt64(it.start, start)
+		it.lastAccess = c.now
+		c.policy.hit(it, c.now)
+	}
+	return covered
+}
+
+// overlapping returns the items of seg that overlap [start, end).
+func (c *Cache) overlapping(seg string, start, end int64) []*item {
+	items := c.segments[seg]
+	i := sort.Search(len(items), func(i int) bool { return items[i].end > start })
+	var result []*item
+	for ; i < len(items) && items[i].start < end; i++ {
+		result = append(result, items[i])
+	}
+	return result
+}
+
+// gaps returns the parts of [start, end) that seg has no items for.
+func (c *Cache) gaps(seg string, start, end int64) [][2]int64 {
+	var result [][2]int64
+	cur := start
+	for _, it := range c.overlapping(seg, start, end) {
+		if it.start > cur {
+			result = append(result, [2]int64{cur, it.start})
+		}
+		cur = maxInt64(cur, it.end)
+	}
+	if cur < end {
+		result = append(result, [2]int64{cur, end})
+	}
+	return result
+}
+
+// insert caches [start, end) of seg, evicting items until it fits. Ranges
+// larger than the whole budget are not cached.
+func (c *Cache) insert(seg string, start, end int64, bytesPerSecond float64) {
+	size := int64(math.Ceil(float64(end-start) * bytesPerSecond))
+	if size < 1 {
+		size = 1
+	}
+	if size > c.budget {
+		return
+	}
+	for c.used+size > c.budget {
+		victim := c.policy.victim()
+		if victim == nil {
+			return
+		}
+		c.drop(victim)
+		c.stats.Evictions++
+	}
+
+	it := &item{key: seg, start: start, end: end, size: size, inserted: c.now, lastAccess: c.now}
+	items := c.segments[seg]
+	i := sort.Search(len(items), func(i int) bool { return items[i].start >= start })
+	items = append(items, nil)
+	copy(items[i+1:], items[i:])
+	items[i] = it
+	c.segments[seg] = items
+	c.used += size
+	c.policy.admit(it, c.now)
+}
+
+// drop removes it from the segment index. The policy must already have
+// forgotten it.
+func (c *Cache) drop(it *item) {
+	items := c.segments[it.key]
+	for i := range items {
+		if items[i] == it {
+			items = append(items[:i], items[i+1:]...)
+			break
+		}
+	}
+	if len(items) == 0 {
+		delete(c.segments, it.key)
+	} else {
+		c.segments[it.key] = items
+	}
+	c.used -= it.size
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
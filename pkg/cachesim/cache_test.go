@@ -0,0 +1,110 @@
+package cachesim
+
+import (
+	"testing"
+)
+
+func testRequest(segments []string, start, end int64) *Request {
+	return &Request{Label: "test", Segments: segments, Start: start, End: end, BytesPerSecond: 1}
+}
+
+func TestCacheHitKinds(t *testing.T) {
+	c, err := NewCache(PolicyLRU, 1000, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	segs := []string{"a", "b"}
+	cases := []struct {
+		desc       string
+		start, end int64
+		want       uint8
+		wantHit    float64
+	}{
+		{desc: "cold", start: 0, end: 100, want: Miss},
+		{desc: "same range", start: 0, end: 100, want: FullHit, wantHit: 200},
+		{desc: "contained", start: 10, end: 20, want: FullHit, wantHit: 20},
+		{desc: "sliding", start: 50, end: 150, want: PartialHit, wantHit: 100},
+		{desc: "remainder cached", start: 0, end: 150, want: FullHit, wantHit: 300},
+		{desc: "disjoint", start: 200, end: 210, want: Miss},
+	}
+	for _, tc := range cases {
+		before := c.Stats()
+		if got := c.Access(testRequest(segs, tc.start, tc.end)); got != tc.want {
+			t.Errorf("%s: incorrect hit kind: got %d want %d", tc.desc, got, tc.want)
+		}
+		if got := c.Stats().Sub(before).HitBytes; got != tc.wantHit {
+			t.Errorf("%s: incorrect hit bytes: got %v want %v", tc.desc, got, tc.wantHit)
+		}
+	}
+
+	s := c.Stats()
+	if s.Queries != 6 || s.FullHits != 3 || s.PartialHits != 1 {
+		t.Errorf("incorrect stats: %+v", s)
+	}
+	if got := c.UsedBytes(); got != 320 {
+		t.Errorf("incorrect used bytes: got %d want %d", got, 320)
+	}
+}
+
+func TestCacheBudget(t *testing.T) {
+	c, err := NewCache(PolicyLRU, 100, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Access(testRequest([]string{"a"}, 0, 60))
+	c.Access(testRequest([]string{"b"}, 0, 60))
+	if got := c.UsedBytes(); got != 60 {
+		t.Errorf("incorrect used bytes: got %d want %d", got, 60)
+	}
+	if got := c.Stats().Evictions; got != 1 {
+		t.Errorf("incorrect evictions: got %d want %d", got, 1)
+	}
+	if got := c.Access(testRequest([]string{"a"}, 0, 60)); got != Miss {
+		t.Errorf("evicted range should miss: got %d", got)
+	}
+
+	// Ranges larger than the budget are never cached.
+	c.Access(testRequest([]string{"c"}, 0, 200))
+	if got := c.Access(testRequest([]string{"c"}, 0, 200)); got != Miss {
+		t.Errorf("oversized range should miss: got %d", got)
+	}
+}
+
+func TestCacheOracle(t *testing.T) {
+	c, err := NewCache(PolicyOracle, 1, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		c.Access(testRequest([]string{"a", "b", "c"}, 0, 1000))
+	}
+	if got := c.Stats().FullHits; got != 2 {
+		t.Errorf("oracle should only miss once: got %d full hits", got)
+	}
+}
+
+func TestNewCacheUnknownPolicy(t *testing.T) {
+	if _, err := NewCache("fifo", 100, 0); err == nil {
+		t.Errorf("expected error for unknown policy")
+	}
+}
+
+func TestStatsRatios(t *testing.T) {
+	s := Stats{Queries: 10, FullHits: 5, PartialHits: 2, RequestedBytes: 400, HitBytes: 100}
+	if got := s.FullHitRatio(); got != 0.5 {
+		t.Errorf("incorrect full hit ratio: got %v", got)
+	}
+	if got := s.PartialHitRatio(); got != 0.2 {
+		t.Errorf("incorrect partial hit ratio: got %v", got)
+	}
+	if got := s.ByteHitRatio(); got != 0.25 {
+		t.Errorf("incorrect byte hit ratio: got %v", got)
+	}
+	if got := (Stats{}).ByteHitRatio(); got != 0 {
+		t.Errorf("empty stats should have ratio 0: got %v", got)
+	}
+	w := s.Sub(Stats{Queries: 4, FullHits: 1, RequestedBytes: 100})
+	if w.Queries != 6 || w.FullHits != 4 || w.RequestedBytes != 300 {
+		t.Errorf("incorrect window: %+v", w)
+	}
+}
@@ -0,0 +1,391 @@
+package cachesim
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// Names of the supported cache policies.
+const (
+	PolicyLRU  = "lru"
+	PolicyLFU  = "lfu"
+	PolicyARC  = "arc"
+	PolicyTTL  = "ttl"
+	PolicySize = "size"
+	// PolicyOracle is a cache without a byte budget. Only compulsory misses
+	// remain, which bounds the hit ratios of every other policy.
+	PolicyOracle = "oracle"
+)
+
+// PolicyNames lists the policies that run under a byte budget.
+var PolicyNames = []string{PolicyLRU, PolicyLFU, PolicyARC, PolicyTTL, PolicySize}
+
+// item is a cached time range of one segment.
+type item struct {
+	key   string
+	start int64
+	end   int64
+	size  int64
+
+	inserted   uint64
+	lastAccess uint64
+	freq       uint64
+	priority   float64
+
+	elem  *list.Element
+	index int
+	arcT2 bool
+}
+
+// policy decides which cached item to evict next. now is the number of
+// requests the cache has served, which serves as its clock.
+type policy interface {
+	// admit records a newly cached item.
+	admit(it *item, now uint64)
+	// hit records a read of a cached item.
+	hit(it *item, now uint64)
+	// victim removes and returns the next item to evict, or nil if there is
+	// none.
+	victim() *item
+	// remove forgets an item that is dropped for another reason than eviction.
+	remove(it *item)
+	// expired reports whether it may no longer be served.
+	expired(it *item, now uint64) bool
+}
+
+// lruPolicy evicts the least recently used item.
+type lruPolicy struct {
+	l *list.List
+}
+
+func newLRUPolicy() *lruPolicy {
+	return &lruPolicy{l: list.New()}
+}
+
+func (p *lruPolicy) admit(it *item, now uint64) {
+	it.elem = p.l.PushFront(it)
+}
+
+func (p *lruPolicy) hit(it *item, now uint64) {
+	p.l.MoveToFront(it.elem)
+}
+
+func (p *lruPolicy) victim() *item {
+	e := p.l.Back()
+	if e == nil {
+		return nil
+	}
+	return p.l.Remove(e).(*item)
+}
+
+func (p *lruPolicy) remove(it *item) {
+	p.l.Remove(it.elem)
+}
+
+func (p *lruPolicy) expired(*item, uint64) bool {
+	return false
+}
+
+// ttlPolicy serves items for ttl requests after they were cached and evicts
+// the oldest item first when space is needed.
+type ttlPolicy struct {
+	lruPolicy
+	ttl uint64
+}
+
+func newTTLPolicy(ttl uint64) *ttlPolicy {
+	return &ttlPolicy{lruPolicy: *newLRUPolicy(), ttl: ttl}
+}
+
+// hit does not extend the life of an item.
+func (p *ttlPolicy) hit(*item, uint64) {}
+
+func (p *ttlPolicy) expired(it *item, now uint64) bool {
+	return now-it.inserted >= p.ttl
+}
+
+// itemHeap is a min-heap of items ordered by less.
+type itemHeap struct {
+	items []*item
+	less  func(a, b *item) bool
+}
+
+func (h *itemHeap) Len() int           { return len(h.items) }
+func (h *itemHeap) Less(i, j int) bool { return h.less(h.items[i], h.items[j]) }
+func (h *itemHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *itemHeap) Push(x interface{}) {
+	it := x.(*item)
+	it.index = len(h.items)
+	h.items = append(h.items, it)
+}
+
+func (h *itemHeap) Pop() interface{} {
+	n := len(h.items)
+	it := h.items[n-1]
+	h.items[n-1] = nil
+	h.items = h.items[:n-1]
+	it.index = -1
+	return it
+}
+
+// lfuPolicy evicts the least frequently used item, the least recently used
+// one among equals.
+type lfuPolicy struct {
+	h *itemHeap
+}
+
+func newLFUPolicy() *lfuPolicy {
+	return &lfuPolicy{h: &itemHeap{less: func(a, b *item) bool {
+		if a.freq != b.freq {
+			return a.freq < b.freq
+		}
+		return a.lastAccess < b.lastAccess
+	}}}
+}
+
+func (p *lfuPolicy) admit(it *item, now uint64) {
+	it.freq = 1
+	heap.Push(p.h, it)
+}
+
+func (p *lfuPolicy) hit(it *item, now uint64) {
+	it.freq++
+	heap.Fix(p.h, it.index)
+}
+
+func (p *lfuPolicy) victim() *item {
+	if p.h.Len() == 0 {
+		return nil
+	}
+	return heap.Pop(p.h).(*item)
+}
+
+func (p *lfuPolicy) remove(it *item) {
+	heap.Remove(p.h, it.index)
+}
+
+func (p *lfuPolicy) expired(*item, uint64) bool {
+	return false
+}
+
+// sizePolicy is GreedyDual-Size-Frequency: it evicts the item with the lowest
+// frequency per byte, aged so that items that were popular long ago are
+// eventually evicted too. It favours keeping many small results over a few
+// large ones.
+type sizePolicy struct {
+	h *itemHeap
+	// age is the priority of the last victim.
+	age float64
+}
+
+func newSizePolicy() *sizePolicy {
+	return &sizePolicy{h: &itemHeap{less: func(a, b *item) bool {
+		if a.priority != b.priority {
+			return a.priority < b.priority
+		}
+		return a.lastAccess < b.lastAccess
+	}}}
+}
+
+func (p *sizePolicy) prioritize(it *item) {
+	it.priority = p.age + float64(it.freq)/float64(it.size)
+}
+
+func (p *sizePolicy) admit(it *item, now uint64) {
+	it.freq = 1
+	p.prioritize(it)
+	heap.Push(p.h, it)
+}
+
+func (p *sizePolicy) hit(it *item, now uint64) {
+	it.freq++
+	p.prioritize(it)
+	heap.Fix(p.h, it.index)
+}
+
+func (p *sizePolicy) victim() *item {
+	if p.h.Len() == 0 {
+		return nil
+	}
+	it := heap.Pop(p.h).(*item)
+	p.age = it.priority
+	return it
+}
+
+func (p *sizePolicy) remove(it *item) {
+	heap.Remove(p.h, it.index)
+}
+
+func (p *sizePolicy) expired(*item, uint64) bool {
+	return false
+}
+
+// ghostKey identifies an evicted item in the ARC ghost lists.
+type ghostKey struct {
+	key        string
+	start, end int64
+}
+
+// ghostList is an ARC ghost list: the keys and sizes of recently evicted
+// items, most recent first.
+type ghostList struct {
+	l     *list.List
+	elems map[ghostKey]*list.Element
+	size  int64
+}
+
+type ghost struct {
+	k    ghostKey
+	size int64
+}
+
+func newGhostList() *ghostList {
+	return &ghostList{l: list.New(), elems: make(map[ghostKey]*list.Element)}
+}
+
+// push adds k as the most recent key, replacing any earlier entry of k.
+func (g *ghostList) push(k ghostKey, size int64) {
+	g.take(k)
+	g.elems[k] = g.l.PushFront(&ghost{k: k, size: size})
+	g.size += size
+}
+
+// take removes k and reports whether it was in the list.
+func (g *ghostList) take(k ghostKey) bool {
+	e, ok := g.elems[k]
+	if !ok {
+		return false
+	}
+	g.removeElem(e)
+	return true
+}
+
+func (g *ghostList) dropOldest() {
+	if e := g.l.Back(); e != nil {
+		g.removeElem(e)
+	}
+}
+
+func (g *ghostList) removeElem(e *list.Element) {
+	gh := g.l.Remove(e).(*ghost)
+	delete(g.elems, gh.k)
+	g.size -= gh.size
+}
+
+// arcPolicy is the Adaptive Replacement Cache, weighted by item size. Items
+// seen once live in t1 and items hit again in t2. The split between them, p,
+// adapts to hits in the ghost lists of items recently evicted from each.
+type arcPolicy struct {
+	budget int64
+	p      float64
+	t1, t2 *list.List
+	t1Size int64
+	t2Size int64
+	b1, b2 *ghostList
+}
+
+func newARCPolicy(budget int64) *arcPolicy {
+	return &arcPolicy{
+		budget: budget,
+		t1:     list.New(),
+		t2:     list.New(),
+		b1:     newGhostList(),
+		b2:     newGhostList(),
+	}
+}
+
+func (p *arcPolicy) admit(it *item, now uint64) {
+	k := ghostKey{it.key, it.start, it.end}
+	size := float64(it.size)
+	switch {
+	case p.b1.take(k):
+		delta := size
+		if p.b1.size > 0 && p.b2.size > p.b1.size {
+			delta *= float64(p.b2.size) / float64(p.b1.size)
+		}
+		p.p = minFloat(p.p+delta, float64(p.budget))
+		p.pushT2(it)
+	case p.b2.take(k):
+		delta := size
+		if p.b2.size > 0 && p.b1.size > p.b2.size {
+			delta *= float64(p.b1.size) / float64(p.b2.size)
+		}
+		p.p = maxFloat(p.p-delta, 0)
+		p.pushT2(it)
+	default:
+		it.arcT2 = false
+		it.elem = p.t1.PushFront(it)
+		p.t1Size += it.size
+	}
+}
+
+func (p *arcPolicy) pushT2(it *item) {
+	it.arcT2 = true
+	it.elem = p.t2.PushFront(it)
+	p.t2Size += it.size
+}
+
+func (p *arcPolicy) hit(it *item, now uint64) {
+	if it.arcT2 {
+		p.t2.MoveToFront(it.elem)
+		return
+	}
+	p.remove(it)
+	p.pushT2(it)
+}
+
+func (p *arcPolicy) victim() *item {
+	var it *item
+	if p.t1.Len() > 0 && (float64(p.t1Size) > p.p || p.t2.Len() == 0) {
+		it = p.t1.Back().Value.(*item)
+		p.remove(it)
+		p.b1.push(ghostKey{it.key, it.start, it.end}, it.size)
+	} else if p.t2.Len() > 0 {
+		it = p.t2.Back().Value.(*item)
+		p.remove(it)
+		p.b2.push(ghostKey{it.key, it.start, it.end}, it.size)
+	} else {
+		return nil
+	}
+	for p.b1.size+p.b2.size > p.budget {
+		if p.b1.size > p.b2.size {
+			p.b1.dropOldest()
+		} else {
+			p.b2.dropOldest()
+		}
+	}
+	return it
+}
+
+func (p *arcPolicy) remove(it *item) {
+	if it.arcT2 {
+		p.t2.Remove(it.elem)
+		p.t2Size -= it.size
+	} else {
+		p.t1.Remove(it.elem)
+		p.t1Size -= it.size
+	}
+}
+
+func (p *arcPolicy) expired(*item, uint64) bool {
+	return false
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,107 @@
+package cachesim
+
+import (
+	"testing"
+)
+
+// accessAll runs ranges [0, 10) of the given segments through c, in order.
+func accessAll(c *Cache, segs ...string) {
+	for _, seg := range segs {
+		c.Access(testRequest([]string{seg}, 0, 10))
+	}
+}
+
+func cached(c *Cache, seg string) bool {
+	return len(c.segments[seg]) > 0
+}
+
+func checkCached(t *testing.T, c *Cache, want map[string]bool) {
+	t.Helper()
+	for seg, w := range want {
+		if got := cached(c, seg); got != w {
+			t.Errorf("%s: segment %s cached: got %v want %v", c.Name, seg, got, w)
+		}
+	}
+}
+
+func TestPolicyLRU(t *testing.T) {
+	c, _ := NewCache(PolicyLRU, 30, 0)
+	accessAll(c, "a", "b", "c", "a", "d")
+	checkCached(t, c, map[string]bool{"a": true, "b": false, "c": true, "d": true})
+}
+
+func TestPolicyLFU(t *testing.T) {
+	c, _ := NewCache(PolicyLFU, 30, 0)
+	accessAll(c, "a", "a", "b", "b", "c", "d")
+	checkCached(t, c, map[string]bool{"a": true, "b": true, "c": false, "d": true})
+}
+
+func TestPolicyTTL(t *testing.T) {
+	c, _ := NewCache(PolicyTTL, 1000, 3)
+	accessAll(c, "a", "b")
+	if got := c.Access(testRequest([]string{"a"}, 0, 10)); got != FullHit {
+		t.Errorf("item within ttl should hit: got %d", got)
+	}
+	accessAll(c, "b")
+	if got := c.Access(testRequest([]string{"a"}, 0, 10)); got != Miss {
+		t.Errorf("expired item should miss: got %d", got)
+	}
+
+	// Without expiry, the oldest item is evicted first even if it was hit.
+	c, _ = NewCache(PolicyTTL, 20, 100)
+	accessAll(c, "a", "b", "a", "c")
+	checkCached(t, c, map[string]bool{"a": false, "b": true, "c": true})
+}
+
+func TestPolicySize(t *testing.T) {
+	c, _ := NewCache(PolicySize, 95, 0)
+	c.Access(testRequest([]string{"big"}, 0, 80))
+	accessAll(c, "a", "b")
+	checkCached(t, c, map[string]bool{"big": false, "a": true, "b": true})
+
+	// A frequently used item outlives a less used one of the same size.
+	c, _ = NewCache(PolicySize, 20, 0)
+	accessAll(c, "a", "a", "a", "b", "c")
+	checkCached(t, c, map[string]bool{"a": true, "b": false, "c": true})
+}
+
+func TestPolicyARC(t *testing.T) {
+	c, _ := NewCache(PolicyARC, 30, 0)
+	// a is used twice and moves to t2, so a scan of one-off segments evicts
+	// the others first.
+	accessAll(c, "a", "a", "b", "c", "d", "e")
+	checkCached(t, c, map[string]bool{"a": true, "b": false, "c": false, "d": true, "e": true})
+
+	// Re-requesting a segment recently evicted from t1 grows t1's share.
+	arc := c.policy.(*arcPolicy)
+	before := arc.p
+	accessAll(c, "b")
+	if arc.p <= before {
+		t.Errorf("ghost hit in b1 should increase p: got %v, was %v", arc.p, before)
+	}
+	if !cached(c, "b") || !c.segments["b"][0].arcT2 {
+		t.Errorf("item re-admitted from a ghost list should be in t2")
+	}
+	if used := arc.t1Size + arc.t2Size; used != c.UsedBytes() {
+		t.Errorf("policy and cache disagree on size: %d vs %d", used, c.UsedBytes())
+	}
+	if ghosts := arc.b1.size + arc.b2.size; ghosts > 30 {
+		t.Errorf("ghost lists exceed the budget: %d", ghosts)
+	}
+}
+
+func TestGhostListPushTwice(t *testing.T) {
+	g := newGhostList()
+	a := ghostKey{key: "a", end: 10}
+	g.push(a, 10)
+	g.push(ghostKey{key: "b", end: 10}, 5)
+	g.push(a, 20)
+	if g.l.Len() != 2 || len(g.elems) != 2 || g.size != 25 {
+		t.Fatalf("pushing a key twice should replace it: got %d elements, %d keys, size %d", g.l.Len(), len(g.elems), g.size)
+	}
+	// b is now the oldest
+	g.dropOldest()
+	if !g.take(a) || g.size != 0 || g.l.Len() != 0 {
+		t.Errorf("unexpected ghost list after dropping b and taking a: size %d, %d elements", g.size, g.l.Len())
+	}
+}
@@ -0,0 +1,153 @@
+// Package cachesim replays InfluxQL queries against in-memory models of
+// semantic cache policies to estimate their hit ratios without a database.
+package cachesim
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	client "github.com/timescale/tsbs/InfluxDB-client/v2"
+	"github.com/timescale/tsbs/internal/utils"
+)
+
+const (
+	// bytesPerValue is the size of one encoded timestamp or field value.
+	bytesPerValue = 8
+
+	errNoTimeRangeFmt = "query has no time range: %s"
+	errNoMetricFmt    = "query has no measurement: %s"
+)
+
+// Request is a query reduced to what a semantic cache sees: the series
+// segments it reads and the time range it reads each of them over.
+type Request struct {
+	Label    string
+	Segments []string
+	// Start and End are in seconds, End is exclusive.
+	Start int64
+	End   int64
+	// BytesPerSecond is the estimated size of the result of one segment per
+	// second of time range.
+	BytesPerSecond float64
+}
+
+// Duration returns the length of the time range in seconds.
+func (r *Request) Duration() int64 {
+	return r.End - r.Start
+}
+
+// Bytes returns the estimated size of the whole result.
+func (r *Request) Bytes() float64 {
+	return float64(len(r.Segments)) * float64(r.Duration()) * r.BytesPerSecond
+}
+
+// segmentInfo is the part of a Request shared by all queries of a template.
+type segmentInfo struct {
+	partialSegment string
+	metric         string
+	bytesPerSecond float64
+}
+
+// Segmenter turns query strings into Requests with the segment functions the
+// STsCache client uses. Tag keys are learned from the queries themselves, so
+// no database is needed. It registers them in the client's global TagKV and
+// is therefore not safe for concurrent use.
+type Segmenter struct {
+	// PointInterval is the time between rows of queries without GROUP BY time.
+	PointInterval time.Duration
+
+	templates map[string]*segmentInfo
+}
+
+// NewSegmenter returns a Segmenter for data written every pointInterval.
+func NewSegmenter(pointInterval time.Duration) *Segmenter {
+	return &Segmenter{
+		PointInterval: pointInterval,
+		templates:     make(map[string]*segmentInfo),
+	}
+}
+
+// Segment returns the Request for queryString.
+func (s *Segmenter) Segment(label, queryString string) (*Request, error) {
+	template, start, end, tags := client.GetQueryTemplate(queryString)
+	if template == "" {
+		// Queries without a tag filter are their own template.
+		template = queryString
+		start, end = client.GetQueryTimeRange(queryString)
+	}
+	if start <= 0 || end <= start {
+		return nil, fmt.Errorf(errNoTimeRangeFmt, queryString)
+	}
+
+	info, ok := s.templates[template]
+	if !ok {
+		var err error
+		info, err = s.newSegmentInfo(queryString, tags)
+		if err != nil {
+			return nil, err
+		}
+		s.templates[template] = info
+	}
+
+	return &Request{
+		Label:          label,
+		Segments:       client.GetSingleSegment(info.metric, info.partialSegment, tags),
+		Start:          start,
+		End:            end,
+		BytesPerSecond: info.bytesPerSecond,
+	}, nil
+}
+
+func (s *Segmenter) newSegmentInfo(queryString string, tags []string) (*segmentInfo, error) {
+	metric := client.GetMetricName(queryString)
+	if metric == "" {
+		return nil, fmt.Errorf(errNoMetricFmt, queryString)
+	}
+	registerTagKeys(metric, tags)
+
+	partialSegment, fields, metric := client.GetPartialSegmentAndFields(queryString)
+	step := s.PointInterval
+	if interval := client.GetInterval(queryString); interval != "empty" {
+		if d, err := utils.ParseRelativeRange(interval); err == nil {
+			step = d
+		}
+	}
+	stepSecs := math.Max(step.Seconds(), 1)
+	rowBytes := float64(bytesPerValue * (1 + strings.Count(fields, "[")))
+
+	return &segmentInfo{
+		partialSegment: partialSegment,
+		metric:         metric,
+		bytesPerSecond: rowBytes / stepSecs,
+	}, nil
+}
+
+// registerTagKeys adds the keys of tags, given as "key=value", to the tag keys
+// the client knows for metric, so that they are told apart from field
+// predicates when building segments.
+func registerTagKeys(metric string, tags []string) {
+	if client.TagKV.Measurement == nil {
+		client.TagKV.Measurement = make(map[string][]client.TagKeyMap)
+	}
+	known := make(map[string]bool)
+	for _, tkm := range client.TagKV.Measurement[metric] {
+		for k := range tkm.Tag {
+			known[k] = true
+		}
+	}
+	for _, tag := range tags {
+		idx := strings.IndexAny(tag, "!=")
+		if idx <= 0 {
+			continue
+		}
+		key := tag[:idx]
+		if known[key] {
+			continue
+		}
+		known[key] = true
+		client.TagKV.Measurement[metric] = append(client.TagKV.Measurement[metric],
+			client.TagKeyMap{Tag: map[string]client.TagValues{key: {}}})
+	}
+}
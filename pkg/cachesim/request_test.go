@@ -0,0 +1,73 @@
+package cachesim
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSegmenterSegment(t *testing.T) {
+	s := NewSegmenter(10 * time.Second)
+	q := `SELECT mean(usage_user),mean(usage_idle) FROM "cpu" WHERE ("hostname" = 'host_1' or "hostname" = 'host_0') ` +
+		`AND TIME >= '2016-01-01T00:00:00Z' AND TIME < '2016-01-01T01:00:00Z' GROUP BY "hostname",time(1m)`
+
+	r, err := s.Segment("lbl", q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Label != "lbl" {
+		t.Errorf("incorrect label: got %s", r.Label)
+	}
+	if got := r.Duration(); got != 3600 {
+		t.Errorf("incorrect duration: got %d want %d", got, 3600)
+	}
+	if got := len(r.Segments); got != 2 {
+		t.Fatalf("incorrect number of segments: got %d want %d", got, 2)
+	}
+	if !strings.HasPrefix(r.Segments[0], "{(cpu.hostname=host_0)}") || !strings.HasPrefix(r.Segments[1], "{(cpu.hostname=host_1)}") {
+		t.Errorf("incorrect segments: %v", r.Segments)
+	}
+	// The tag filter is not part of the segment's predicates.
+	if strings.Contains(r.Segments[0], "'host_0'") {
+		t.Errorf("tag condition left in predicates: %s", r.Segments[0])
+	}
+	// One row of a timestamp and two values per minute.
+	if want := 24.0 / 60; r.BytesPerSecond != want {
+		t.Errorf("incorrect bytes per second: got %v want %v", r.BytesPerSecond, want)
+	}
+	if want := 2 * 3600 * 24.0 / 60; r.Bytes() != want {
+		t.Errorf("incorrect bytes: got %v want %v", r.Bytes(), want)
+	}
+
+	// Queries of the same template share segments.
+	q2 := strings.Replace(q, "01:00:00Z", "02:00:00Z", 1)
+	r2, err := s.Segment("lbl", q2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r2.Segments[0] != r.Segments[0] || r2.Duration() != 7200 {
+		t.Errorf("incorrect request for the same template: %+v", r2)
+	}
+}
+
+func TestSegmenterRawQuery(t *testing.T) {
+	s := NewSegmenter(10 * time.Second)
+	q := `SELECT usage_user FROM "cpu" WHERE TIME >= '2016-01-01T00:00:00Z' AND TIME < '2016-01-01T00:01:00Z'`
+	r, err := s.Segment("raw", q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := len(r.Segments); got != 1 {
+		t.Errorf("incorrect number of segments: got %d want %d", got, 1)
+	}
+	if want := 16.0 / 10; r.BytesPerSecond != want {
+		t.Errorf("incorrect bytes per second: got %v want %v", r.BytesPerSecond, want)
+	}
+}
+
+func TestSegmenterNoTimeRange(t *testing.T) {
+	s := NewSegmenter(10 * time.Second)
+	if _, err := s.Segment("x", `SELECT usage_user FROM "cpu"`); err == nil {
+		t.Errorf("expected error for a query without time range")
+	}
+}
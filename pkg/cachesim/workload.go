@@ -0,0 +1,266 @@
+package cachesim
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// durationBounds are the upper bounds of the time range histograms.
+var durationBounds = []time.Duration{
+	time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 3 * time.Hour, 6 * time.Hour,
+	12 * time.Hour, 24 * time.Hour, 3 * 24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour,
+}
+
+// overlapBounds are the upper bounds, in percent, of the overlap histogram.
+var overlapBounds = []float64{0, 25, 50, 75, 99.999, 100}
+
+// histogram counts occurrences per labelled bucket.
+type histogram struct {
+	labels []string
+	counts []uint64
+	total  uint64
+}
+
+func newHistogram(labels []string) *histogram {
+	return &histogram{labels: labels, counts: make([]uint64, len(labels))}
+}
+
+func (h *histogram) inc(bucket int) {
+	h.counts[bucket]++
+	h.total++
+}
+
+func (h *histogram) write(w io.Writer, title string) error {
+	if _, err := fmt.Fprintf(w, "%s:\n", title); err != nil {
+		return err
+	}
+	for i, label := range h.labels {
+		if h.counts[i] == 0 {
+			continue
+		}
+		_, err := fmt.Fprintf(w, "  %-16s %10d %6.2f%%\n", label, h.counts[i], 100*ratio(float64(h.counts[i]), float64(h.total)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func durationLabels() []string {
+	labels := make([]string, 0, len(durationBounds)+1)
+	for _, b := range durationBounds {
+		labels = append(labels, "<= "+formatDuration(b))
+	}
+	return append(labels, "> "+formatDuration(durationBounds[len(durationBounds)-1]))
+}
+
+func durationBucket(secs int64) int {
+	d := time.Duration(secs) * time.Second
+	for i, b := range durationBounds {
+		if d <= b {
+			return i
+		}
+	}
+	return len(durationBounds)
+}
+
+// formatDuration prints whole days as such instead of as hours.
+func formatDuration(d time.Duration) string {
+	if d >= 24*time.Hour && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// Workload characterizes a stream of Requests: the lengths of their time
+// ranges and how far those are from the end of the dataset, how many series
+// each reads, the mix of query types, how soon and how much queries reuse the
+// segments of earlier ones, and how much a cache without a byte budget could
+// serve.
+type Workload struct {
+	// DatasetEnd is the end of the dataset in seconds. If 0, the latest end
+	// of any request is used.
+	DatasetEnd int64
+
+	queries     uint64
+	labels      map[string]uint64
+	ranges      *histogram
+	ends        []int64
+	cardinality map[int]uint64
+	reuse       map[int]uint64
+	firstUses   uint64
+	lastUse     map[string]uint64
+	overlap     *histogram
+	oracle      *Cache
+}
+
+// NewWorkload returns an empty Workload for a dataset ending at datasetEnd.
+func NewWorkload(datasetEnd int64) *Workload {
+	overlapLabels := []string{"0%", "(0%, 25%]", "(25%, 50%]", "(50%, 75%]", "(75%, 100%)", "100%"}
+	oracle, _ := NewCache(PolicyOracle, 0, 0)
+	return &Workload{
+		DatasetEnd:  datasetEnd,
+		labels:      make(map[string]uint64),
+		ranges:      newHistogram(durationLabels()),
+		cardinality: make(map[int]uint64),
+		reuse:       make(map[int]uint64),
+		lastUse:     make(map[string]uint64),
+		overlap:     newHistogram(overlapLabels),
+		oracle:      oracle,
+	}
+}
+
+// Add records r as the next request of the workload.
+func (w *Workload) Add(r *Request) {
+	w.queries++
+	w.labels[r.Label]++
+	w.ranges.inc(durationBucket(r.Duration()))
+	w.ends = append(w.ends, r.End)
+	w.cardinality[len(r.Segments)]++
+
+	for _, seg := range r.Segments {
+		if last, ok := w.lastUse[seg]; ok {
+			w.reuse[reuseBucket(w.queries-last)]++
+		} else {
+			w.firstUses++
+		}
+		w.lastUse[seg] = w.queries
+	}
+
+	before := w.oracle.Stats()
+	w.oracle.Access(r)
+	d := w.oracle.Stats().Sub(before)
+	w.overlap.inc(overlapBucket(100 * d.ByteHitRatio()))
+}
+
+// reuseBucket returns the index of the power of two bucket that holds d.
+func reuseBucket(d uint64) int {
+	return int(math.Ceil(math.Log2(float64(d))))
+}
+
+func overlapBucket(pct float64) int {
+	for i, b := range overlapBounds {
+		if pct <= b {
+			return i
+		}
+	}
+	return len(overlapBounds) - 1
+}
+
+// PerfectCacheStats returns the stats of a cache without a byte budget, whose
+// byte hit ratio is the fraction of bytes any cache could serve.
+func (w *Workload) PerfectCacheStats() Stats {
+	return w.oracle.Stats()
+}
+
+// Write prints the report of the workload to out.
+func (w *Workload) Write(out io.Writer) error {
+	if _, err := fmt.Fprintf(out, "queries: %d\n\n", w.queries); err != nil {
+		return err
+	}
+
+	// Query-type mix, most frequent first.
+	labels := make([]string, 0, len(w.labels))
+	for l := range w.labels {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if w.labels[labels[i]] != w.labels[labels[j]] {
+			return w.labels[labels[i]] > w.labels[labels[j]]
+		}
+		return labels[i] < labels[j]
+	})
+	mix := newHistogram(labels)
+	for i, l := range labels {
+		mix.counts[i] = w.labels[l]
+		mix.total += w.labels[l]
+	}
+	if err := mix.write(out, "query types"); err != nil {
+		return err
+	}
+
+	if err := w.ranges.write(out, "\ntime range length"); err != nil {
+		return err
+	}
+
+	datasetEnd := w.DatasetEnd
+	if datasetEnd == 0 {
+		for _, e := range w.ends {
+			if e > datasetEnd {
+				datasetEnd = e
+			}
+		}
+	}
+	distance := newHistogram(append([]string{"0s"}, durationLabels()...))
+	for _, e := range w.ends {
+		if e >= datasetEnd {
+			distance.inc(0)
+		} else {
+			distance.inc(1 + durationBucket(datasetEnd-e))
+		}
+	}
+	title := fmt.Sprintf("\ndistance of range end from dataset end (%s)", time.Unix(datasetEnd, 0).UTC().Format(time.RFC3339))
+	if err := distance.write(out, title); err != nil {
+		return err
+	}
+
+	if err := writeIntHistogram(out, "\nseries per query", w.cardinality, func(k int) string {
+		return fmt.Sprintf("%d", k)
+	}); err != nil {
+		return err
+	}
+
+	reuse := make(map[int]uint64, len(w.reuse)+1)
+	for k, v := range w.reuse {
+		reuse[k] = v
+	}
+	if w.firstUses > 0 {
+		reuse[-1] = w.firstUses
+	}
+	if err := writeIntHistogram(out, "\nsegment reuse distance (queries since the previous use)", reuse, func(k int) string {
+		switch k {
+		case -1:
+			return "first use"
+		case 0:
+			return "1"
+		}
+		return fmt.Sprintf("(%d, %d]", 1<<(k-1), 1<<k)
+	}); err != nil {
+		return err
+	}
+
+	if err := w.overlap.write(out, "\nrange already requested by earlier queries of the same segments"); err != nil {
+		return err
+	}
+
+	s := w.PerfectCacheStats()
+	_, err := fmt.Fprintf(out, "\nperfect cache: full hit %.2f%%, partial hit %.2f%%, byte hit %.2f%% of %.0f bytes\n",
+		100*s.FullHitRatio(), 100*s.PartialHitRatio(), 100*s.ByteHitRatio(), s.RequestedBytes)
+	return err
+}
+
+func writeIntHistogram(out io.Writer, title string, counts map[int]uint64, label func(int) string) error {
+	keys := make([]int, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	h := newHistogram(make([]string, len(keys)))
+	for i, k := range keys {
+		h.labels[i] = label(k)
+		h.counts[i] = counts[k]
+		h.total += counts[k]
+	}
+	return h.write(out, title)
+}
@@ -0,0 +1,82 @@
+package cachesim
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatDuration(t *testing.T) {
+	cases := map[time.Duration]string{
+		time.Minute:        "1m",
+		15 * time.Minute:   "15m",
+		time.Hour:          "1h",
+		90 * time.Minute:   "1h30m",
+		24 * time.Hour:     "1d",
+		7 * 24 * time.Hour: "7d",
+		30 * time.Second:   "30s",
+	}
+	for d, want := range cases {
+		if got := formatDuration(d); got != want {
+			t.Errorf("%v: incorrect format: got %s want %s", d, got, want)
+		}
+	}
+}
+
+func TestReuseBucket(t *testing.T) {
+	cases := map[uint64]int{1: 0, 2: 1, 3: 2, 4: 2, 5: 3, 1024: 10, 1025: 11}
+	for d, want := range cases {
+		if got := reuseBucket(d); got != want {
+			t.Errorf("%d: incorrect bucket: got %d want %d", d, got, want)
+		}
+	}
+}
+
+func TestWorkload(t *testing.T) {
+	w := NewWorkload(0)
+	w.Add(&Request{Label: "a", Segments: []string{"x", "y"}, Start: 0, End: 3600, BytesPerSecond: 1})
+	w.Add(&Request{Label: "b", Segments: []string{"x"}, Start: 1800, End: 5400, BytesPerSecond: 1})
+	w.Add(&Request{Label: "a", Segments: []string{"z"}, Start: 7200, End: 7260, BytesPerSecond: 1})
+	w.Add(&Request{Label: "a", Segments: []string{"x", "y"}, Start: 0, End: 3600, BytesPerSecond: 1})
+
+	if got := w.labels["a"]; got != 3 {
+		t.Errorf("incorrect label count: got %d want %d", got, 3)
+	}
+	if got := w.cardinality[2]; got != 2 {
+		t.Errorf("incorrect cardinality count: got %d want %d", got, 2)
+	}
+	if w.firstUses != 3 {
+		t.Errorf("incorrect first uses: got %d want %d", w.firstUses, 3)
+	}
+	// x reused after 1 and 2 queries, y after 3.
+	if w.reuse[0] != 1 || w.reuse[1] != 1 || w.reuse[2] != 1 {
+		t.Errorf("incorrect reuse distances: %v", w.reuse)
+	}
+	// Overlaps: 0%, 50%, 0%, 100%.
+	if got := w.overlap.counts; got[0] != 2 || got[2] != 1 || got[5] != 1 {
+		t.Errorf("incorrect overlaps: %v", got)
+	}
+	s := w.PerfectCacheStats()
+	if s.HitBytes != 1800+7200 || s.RequestedBytes != 7200+3600+60+7200 {
+		t.Errorf("incorrect perfect cache stats: %+v", s)
+	}
+
+	var b bytes.Buffer
+	if err := w.Write(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"queries: 4",
+		"  a                         3  75.00%",
+		"distance of range end from dataset end (1970-01-01T02:01:00Z)",
+		"  <= 3h                     2  50.00%",
+		"  first use                 3  50.00%",
+		"perfect cache: full hit 25.00%, partial hit 25.00%, byte hit 49.83% of 18060 bytes",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report does not contain %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package columnar
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+)
+
+func testPoint(m string, tags []string, tagValues []interface{}, fields []string, fieldValues []interface{}, ts time.Time) *data.Point {
+	p := data.NewPoint()
+	p.SetMeasurementName([]byte(m))
+	for i, k := range tags {
+		p.AppendTag([]byte(k), tagValues[i])
+	}
+	for i, k := range fields {
+		p.AppendField([]byte(k), fieldValues[i])
+	}
+	p.SetTimestamp(&ts)
+	return p
+}
+
+// testPoints returns points of several schemas, with unordered timestamps,
+// nil values and values of several types.
+func testPoints() []*data.Point {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	var points []*data.Point
+	for i := 0; i < 25; i++ {
+		ts := start.Add(time.Duration(i%7) * time.Second).Add(time.Duration(i) * time.Nanosecond)
+		host := fmt.Sprintf("host_%d", i%3)
+		points = append(points, testPoint("cpu",
+			[]string{"hostname", "region"}, []interface{}{host, "eu-west-1"},
+			[]string{"usage_user", "usage_system"}, []interface{}{float64(i) / 3, int64(i)}, ts))
+		if i%2 == 0 {
+			var load interface{}
+			if i%4 == 0 {
+				load = float32(i)
+			}
+			points = append(points, testPoint("load",
+				[]string{"hostname"}, []interface{}{host},
+				[]string{"load1", "up"}, []interface{}{load, i%3 == 0}, ts))
+		}
+		if i%5 == 0 {
+			var mixed interface{} = i
+			switch i % 3 {
+			case 1:
+				mixed = "text"
+			case 2:
+				mixed = nil
+			}
+			points = append(points, testPoint("event",
+				[]string{"name", "code"}, []interface{}{nil, i % 2},
+				[]string{"mixed", "payload"}, []interface{}{mixed, []byte(host)}, ts))
+		}
+	}
+	return points
+}
+
+func writeTestPoints(t *testing.T, points []*data.Point, headers *common.GeneratedDataHeaders, segmentRows int) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, headers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.SegmentRows = segmentRows
+	for _, p := range points {
+		if err := w.Write(p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func readTestPoints(b []byte) ([]*data.Point, *common.GeneratedDataHeaders, error) {
+	r, err := NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, nil, err
+	}
+	var points []*data.Point
+	for {
+		p := data.NewPoint()
+		ok, err := r.Next(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			return points, r.Headers(), nil
+		}
+		points = append(points, p)
+	}
+}
+
+func TestWriterReaderRoundTrip(t *testing.T) {
+	headers := &common.GeneratedDataHeaders{
+		TagKeys:   []string{"hostname", "region"},
+		TagTypes:  []string{"string", "string"},
+		FieldKeys: map[string][]string{"cpu": {"usage_user", "usage_system"}, "load": {"load1", "up"}},
+	}
+	points := testPoints()
+	for _, segmentRows := range []int{1, 4, DefaultSegmentRows} {
+		for _, h := range []*common.GeneratedDataHeaders{nil, headers} {
+			got, gotHeaders, err := readTestPoints(writeTestPoints(t, points, h, segmentRows))
+			if err != nil {
+				t.Fatalf("segment rows %d: unexpected error: %v", segmentRows, err)
+			}
+			if !reflect.DeepEqual(gotHeaders, h) {
+				t.Errorf("segment rows %d: incorrect headers: got %v want %v", segmentRows, gotHeaders, h)
+			}
+			if len(got) != len(points) {
+				t.Fatalf("segment rows %d: incorrect number of points: got %d want %d", segmentRows, len(got), len(points))
+			}
+			for i, p := range points {
+				if !reflect.DeepEqual(got[i], p) {
+					t.Errorf("segment rows %d: incorrect point %d: got %+v want %+v", segmentRows, i, got[i], p)
+				}
+			}
+		}
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	got, _, err := readTestPoints(writeTestPoints(t, nil, nil, DefaultSegmentRows))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("incorrect number of points: got %d want 0", len(got))
+	}
+}
+
+func TestWriterSmallerThanText(t *testing.T) {
+	var text bytes.Buffer
+	points := testPoints()
+	for _, p := range points {
+		fmt.Fprintf(&text, "%s %v %v %d\n", p.MeasurementName(), p.TagValues(), p.FieldValues(), p.Timestamp().UnixNano())
+	}
+	if b := writeTestPoints(t, points, nil, DefaultSegmentRows); len(b) >= text.Len() {
+		t.Errorf("columnar data not smaller than text: %d bytes, text %d bytes", len(b), text.Len())
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	w, err := NewWriter(io.Discard, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Write(data.NewPoint()); err == nil || err.Error() != errNoTimestamp {
+		t.Errorf("incorrect error for point without timestamp: got %v", err)
+	}
+	p := testPoint("cpu", nil, nil, []string{"f"}, []interface{}{uint8(1)}, time.Now())
+	if err := w.Write(p); err == nil || err.Error() != fmt.Sprintf(errUnknownTypeFmt, uint8(1)) {
+		t.Errorf("incorrect error for unsupported type: got %v", err)
+	}
+}
+
+func TestReaderCorrupt(t *testing.T) {
+	b := writeTestPoints(t, testPoints(), nil, 8)
+
+	if _, _, err := readTestPoints([]byte("cpu,hostname=host_0 value=1 140\n")); err == nil || err.Error() != errNotColumnar {
+		t.Errorf("incorrect error for line protocol: got %v", err)
+	}
+	// every truncation of the data is an error, not a short read
+	for n := len(Magic); n < len(b); n++ {
+		if _, _, err := readTestPoints(b[:n]); err == nil || !strings.HasPrefix(err.Error(), "corrupt columnar data") {
+			t.Fatalf("incorrect error for data truncated to %d bytes: got %v", n, err)
+		}
+	}
+	// corrupt bytes may decode to other values, but must not panic
+	for i := len(Magic); i < len(b); i++ {
+		c := append([]byte(nil), b...)
+		c[i] ^= 0xff
+		readTestPoints(c)
+	}
+}
+
+// lineSerializer serializes points as a line of their values.
+type lineSerializer struct{}
+
+func (lineSerializer) Serialize(p *data.Point, w io.Writer) error {
+	if string(p.MeasurementName()) == "event" {
+		// like targets that skip some points
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "%s %v %v %d\n", p.MeasurementName(), p.TagValues(), p.FieldValues(), p.Timestamp().UnixNano())
+	return err
+}
+
+func TestDataSource(t *testing.T) {
+	points := testPoints()
+	var want []string
+	for _, p := range points {
+		var buf bytes.Buffer
+		lineSerializer{}.Serialize(p, &buf)
+		if buf.Len() > 0 {
+			want = append(want, strings.TrimSuffix(buf.String(), "\n"))
+		}
+	}
+
+	ds, err := NewDataSource(bytes.NewReader(writeTestPoints(t, points, nil, 4)), lineSerializer{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for p := ds.NextItem(); p.Data != nil; p = ds.NextItem() {
+		got = append(got, string(p.Data.([]byte)))
+	}
+	if ds.Err() != nil {
+		t.Fatalf("unexpected error: %v", ds.Err())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect items: got\n%v\nwant\n%v", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+package columnar
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+)
+
+// DataSource is a targets.DataSource of columnar data, which serializes each
+// point to the format of a target as it is read.
+type DataSource struct {
+	r          *Reader
+	serializer serialize.PointSerializer
+	point      *data.Point
+	buf        bytes.Buffer
+	err        error
+}
+
+// NewDataSource returns a DataSource of the columnar data of r, serialized by
+// serializer.
+func NewDataSource(r io.Reader, serializer serialize.PointSerializer) (*DataSource, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &DataSource{r: reader, serializer: serializer, point: data.NewPoint()}, nil
+}
+
+// NextItem returns the next point, serialized without its trailing newline,
+// or an empty LoadedPoint at the end of the data or on error.
+func (d *DataSource) NextItem() data.LoadedPoint {
+	for d.err == nil {
+		d.point.Reset()
+		ok, err := d.r.Next(d.point)
+		if err != nil || !ok {
+			d.err = err
+			break
+		}
+		d.buf.Reset()
+		if d.err = d.serializer.Serialize(d.point, &d.buf); d.err != nil {
+			break
+		}
+		// the LoadedPoint outlives the buffer, which is reused
+		line := bytes.TrimSuffix(d.buf.Bytes(), []byte("\n"))
+		if len(line) > 0 {
+			return data.NewLoadedPoint(append([]byte(nil), line...))
+		}
+	}
+	return data.LoadedPoint{}
+}
+
+// Err returns the error that ended the data, if any.
+func (d *DataSource) Err() error {
+	return d.err
+}
+
+// Headers returns the headers of the data.
+func (d *DataSource) Headers() *common.GeneratedDataHeaders {
+	return d.r.Headers()
+}
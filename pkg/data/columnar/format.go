@@ -0,0 +1,225 @@
+// Package columnar implements a compact binary format for generated data,
+// which stores data.Points column-wise.
+//
+// A file starts with Magic and the headers of the generated data, followed by
+// segments of consecutive points and an empty segment:
+//
+//	file    = Magic headers segment* uvarint(0)
+//	segment = uvarint(rows) uvarint(len(payload)) payload
+//	payload = uvarint(count) block*
+//
+// The points of a segment are stored in a block per schema, i.e. per
+// measurement, tag keys and field keys, each with the positions of its points
+// in the segment, so the points are read back in the order they were written:
+//
+//	block = measurement uvarint(rows) positions tags timestamps fields
+//	tags  = uvarint(count) (key dictionary indexes)*
+//	fields = uvarint(count) (key column)*
+//
+// Positions are delta-encoded, timestamps are a varint of the first
+// UnixNano and the zig-zag varint deltas of the next ones. Tag values are
+// stored once per block in a dictionary. A field column has the type of its
+// values and a bitmap of the rows without value, or is a column of typed
+// values if its values are of several types. Every integer is a varint and
+// every string, key and []byte is prefixed by its length.
+package columnar
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Magic starts every columnar file, the last byte being the version of the
+// format.
+const Magic = "TSBSCOL\x01"
+
+// Types of the values of the columns.
+const (
+	typeNil byte = iota
+	typeBool
+	typeInt
+	typeInt64
+	typeFloat32
+	typeFloat64
+	typeString
+	typeBytes
+	// typeMixed is the type of a field column whose values are typed
+	// values of several types
+	typeMixed byte = 0xff
+)
+
+const (
+	errUnknownTypeFmt = "unsupported value type %T"
+	errCorruptFmt     = "corrupt columnar data: %s"
+	errNotColumnar    = "not columnar data: bad magic"
+	errNoTimestamp    = "cannot write a point without timestamp"
+)
+
+// valueType returns the type of v.
+func valueType(v interface{}) (byte, error) {
+	switch v.(type) {
+	case nil:
+		return typeNil, nil
+	case bool:
+		return typeBool, nil
+	case int:
+		return typeInt, nil
+	case int64:
+		return typeInt64, nil
+	case float32:
+		return typeFloat32, nil
+	case float64:
+		return typeFloat64, nil
+	case string:
+		return typeString, nil
+	case []byte:
+		return typeBytes, nil
+	default:
+		return 0, fmt.Errorf(errUnknownTypeFmt, v)
+	}
+}
+
+// appendValue appends v, of type t, to b.
+func appendValue(b []byte, t byte, v interface{}) []byte {
+	switch t {
+	case typeBool:
+		if v.(bool) {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case typeInt:
+		return binary.AppendVarint(b, int64(v.(int)))
+	case typeInt64:
+		return binary.AppendVarint(b, v.(int64))
+	case typeFloat32:
+		return binary.LittleEndian.AppendUint32(b, math.Float32bits(v.(float32)))
+	case typeFloat64:
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(v.(float64)))
+	case typeString:
+		return appendBytes(b, []byte(v.(string)))
+	case typeBytes:
+		return appendBytes(b, v.([]byte))
+	default:
+		return b
+	}
+}
+
+// appendTypedValue appends the type of v and v to b.
+func appendTypedValue(b []byte, v interface{}) ([]byte, error) {
+	t, err := valueType(v)
+	if err != nil {
+		return nil, err
+	}
+	return appendValue(append(b, t), t, v), nil
+}
+
+func appendBytes(b, s []byte) []byte {
+	return append(binary.AppendUvarint(b, uint64(len(s))), s...)
+}
+
+// decoder reads the encoded values of a segment, remembering the first error.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) fail(what string) {
+	if d.err == nil {
+		d.err = fmt.Errorf(errCorruptFmt, what)
+	}
+	d.b = nil
+}
+
+func (d *decoder) byte() byte {
+	if len(d.b) < 1 {
+		d.fail("truncated byte")
+		return 0
+	}
+	c := d.b[0]
+	d.b = d.b[1:]
+	return c
+}
+
+func (d *decoder) next(n int) []byte {
+	if n < 0 || len(d.b) < n {
+		d.fail("truncated value")
+		return nil
+	}
+	s := d.b[:n:n]
+	d.b = d.b[n:]
+	return s
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.fail("bad uvarint")
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.fail("bad varint")
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+// count reads a number of items, each encoded in at least min bytes, so
+// that a corrupt count does not allocate more than the segment.
+func (d *decoder) count(min int) int {
+	n := d.uvarint()
+	if n > uint64(len(d.b)/min) {
+		d.fail("bad count")
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) bytes() []byte {
+	return d.next(d.count(1))
+}
+
+// value reads a value of type t.
+func (d *decoder) value(t byte) interface{} {
+	switch t {
+	case typeNil:
+		return nil
+	case typeBool:
+		return d.byte() != 0
+	case typeInt:
+		return int(d.varint())
+	case typeInt64:
+		return d.varint()
+	case typeFloat32:
+		b := d.next(4)
+		if b == nil {
+			return nil
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b))
+	case typeFloat64:
+		b := d.next(8)
+		if b == nil {
+			return nil
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	case typeString:
+		return string(d.bytes())
+	case typeBytes:
+		return d.bytes()
+	default:
+		d.fail(fmt.Sprintf("unknown type %d", t))
+		return nil
+	}
+}
+
+// typedValue reads the type of a value and the value.
+func (d *decoder) typedValue() interface{} {
+	return d.value(d.byte())
+}
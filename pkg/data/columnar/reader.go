@@ -0,0 +1,280 @@
+package columnar
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+)
+
+// Reader reads data.Points written by a Writer, in the order they were
+// written.
+type Reader struct {
+	r       *bufio.Reader
+	headers *common.GeneratedDataHeaders
+	done    bool
+
+	// rows are the block and row in the block of each point of the current
+	// segment, and next the index of the next point to read
+	rows    []blockRow
+	next    int
+	segment []byte
+}
+
+type blockRow struct {
+	block *block
+	row   int
+}
+
+// block is a decoded block of a segment.
+type block struct {
+	measurement []byte
+	tagKeys     [][]byte
+	// tags are the tag values of each tag, by row
+	tags       [][]interface{}
+	timestamps []int64
+	fieldKeys  [][]byte
+	fields     [][]interface{}
+}
+
+// IsColumnar tells whether b, the first bytes of some data, starts with
+// Magic.
+func IsColumnar(b []byte) bool {
+	return len(b) >= len(Magic) && string(b[:len(Magic)]) == Magic
+}
+
+// NewReader returns a Reader of r, after reading the headers of the data.
+func NewReader(r io.Reader) (*Reader, error) {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	magic := make([]byte, len(Magic))
+	if _, err := io.ReadFull(br, magic); err != nil || !IsColumnar(magic) {
+		return nil, errors.New(errNotColumnar)
+	}
+	headers, err := readHeaders(br)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{r: br, headers: headers}, nil
+}
+
+func readHeaders(r *bufio.Reader) (*common.GeneratedDataHeaders, error) {
+	present, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf(errCorruptFmt, "truncated headers")
+	}
+	if present == 0 {
+		return nil, nil
+	}
+	h := &common.GeneratedDataHeaders{FieldKeys: make(map[string][]string)}
+	if h.TagKeys, err = readStrings(r); err != nil {
+		return nil, err
+	}
+	if h.TagTypes, err = readStrings(r); err != nil {
+		return nil, err
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf(errCorruptFmt, "truncated headers")
+	}
+	for i := uint64(0); i < n; i++ {
+		m, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		if h.FieldKeys[m], err = readStrings(r); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+func readString(r *bufio.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", fmt.Errorf(errCorruptFmt, "truncated headers")
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", fmt.Errorf(errCorruptFmt, "truncated headers")
+	}
+	return string(b), nil
+}
+
+func readStrings(r *bufio.Reader) ([]string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf(errCorruptFmt, "truncated headers")
+	}
+	var s []string
+	for i := uint64(0); i < n; i++ {
+		x, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, x)
+	}
+	return s, nil
+}
+
+// Headers returns the headers of the data, or nil if it has none.
+func (r *Reader) Headers() *common.GeneratedDataHeaders {
+	return r.headers
+}
+
+// Next populates p with the next point. It returns false at the end of the
+// data.
+func (r *Reader) Next(p *data.Point) (bool, error) {
+	for r.next == len(r.rows) {
+		if r.done {
+			return false, nil
+		}
+		if err := r.readSegment(); err != nil {
+			return false, err
+		}
+	}
+	br := r.rows[r.next]
+	r.next++
+
+	b, i := br.block, br.row
+	p.SetMeasurementName(b.measurement)
+	for j, key := range b.tagKeys {
+		p.AppendTag(key, b.tags[j][i])
+	}
+	for j, key := range b.fieldKeys {
+		p.AppendField(key, b.fields[j][i])
+	}
+	ts := time.Unix(0, b.timestamps[i]).UTC()
+	p.SetTimestamp(&ts)
+	return true, nil
+}
+
+// readSegment reads and decodes the next segment.
+func (r *Reader) readSegment() error {
+	rows, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return fmt.Errorf(errCorruptFmt, "truncated data")
+	}
+	r.rows = r.rows[:0]
+	r.next = 0
+	if rows == 0 {
+		r.done = true
+		return nil
+	}
+	size, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return fmt.Errorf(errCorruptFmt, "truncated segment")
+	}
+	if uint64(cap(r.segment)) < size {
+		r.segment = make([]byte, size)
+	}
+	r.segment = r.segment[:size]
+	if _, err := io.ReadFull(r.r, r.segment); err != nil {
+		return fmt.Errorf(errCorruptFmt, "truncated segment")
+	}
+	// the decoded values must not refer to the buffer, which is reused
+	d := &decoder{b: append([]byte(nil), r.segment...)}
+	if rows > uint64(len(d.b)) {
+		return fmt.Errorf(errCorruptFmt, "bad number of points")
+	}
+	r.rows = make([]blockRow, rows)
+
+	blocks := d.count(1)
+	for i := 0; i < blocks && d.err == nil; i++ {
+		decodeBlock(d, r.rows)
+	}
+	if d.err != nil {
+		return d.err
+	}
+	for _, row := range r.rows {
+		if row.block == nil {
+			return fmt.Errorf(errCorruptFmt, "missing points")
+		}
+	}
+	return nil
+}
+
+// decodeBlock decodes a block, setting the rows of its points.
+func decodeBlock(d *decoder, rows []blockRow) {
+	b := &block{measurement: d.bytes()}
+	n := d.count(1)
+	pos := -1
+	for i := 0; i < n && d.err == nil; i++ {
+		pos += int(d.uvarint())
+		if pos < 0 || pos >= len(rows) || rows[pos].block != nil {
+			d.fail("bad position")
+			return
+		}
+		rows[pos] = blockRow{block: b, row: i}
+	}
+
+	tags := d.count(1)
+	b.tagKeys = make([][]byte, tags)
+	b.tags = make([][]interface{}, tags)
+	for j := 0; j < tags && d.err == nil; j++ {
+		b.tagKeys[j] = d.bytes()
+		dict := make([]interface{}, d.count(1))
+		for k := range dict {
+			dict[k] = d.typedValue()
+		}
+		b.tags[j] = make([]interface{}, n)
+		for i := range b.tags[j] {
+			index := d.uvarint()
+			if index >= uint64(len(dict)) {
+				d.fail("bad dictionary index")
+				return
+			}
+			b.tags[j][i] = dict[index]
+		}
+	}
+
+	b.timestamps = make([]int64, n)
+	for i := range b.timestamps {
+		b.timestamps[i] = d.varint()
+		if i > 0 {
+			b.timestamps[i] += b.timestamps[i-1]
+		}
+	}
+
+	fields := d.count(1)
+	b.fieldKeys = make([][]byte, fields)
+	b.fields = make([][]interface{}, fields)
+	for j := 0; j < fields && d.err == nil; j++ {
+		b.fieldKeys[j] = d.bytes()
+		b.fields[j] = decodeColumn(d, n)
+	}
+}
+
+// decodeColumn decodes a field column of n values.
+func decodeColumn(d *decoder, n int) []interface{} {
+	values := make([]interface{}, n)
+	t := d.byte()
+	switch t {
+	case typeNil:
+		return values
+	case typeMixed:
+		for i := range values {
+			values[i] = d.typedValue()
+		}
+		return values
+	}
+
+	var bitmap []byte
+	if d.byte() != 0 {
+		bitmap = d.next((n + 7) / 8)
+	}
+	for i := range values {
+		if bitmap != nil && bitmap[i/8]&(1<<(i%8)) != 0 {
+			continue
+		}
+		values[i] = d.value(t)
+	}
+	return values
+}
@@ -0,0 +1,304 @@
+package columnar
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+)
+
+// DefaultSegmentRows is the number of points of the segments of a Writer,
+// which bounds the memory used to write and read them.
+const DefaultSegmentRows = 16384
+
+// Writer writes data.Points in the columnar format.
+type Writer struct {
+	w io.Writer
+	// SegmentRows is the number of points of a segment
+	SegmentRows int
+
+	rows   int
+	blocks []*blockBuilder
+	// schemas are the blocks of the segment by schema
+	schemas map[string]*blockBuilder
+	buf     []byte
+}
+
+// NewWriter returns a Writer to w, after writing the headers of the data to
+// it. headers may be nil.
+func NewWriter(w io.Writer, headers *common.GeneratedDataHeaders) (*Writer, error) {
+	cw := &Writer{
+		w:           w,
+		SegmentRows: DefaultSegmentRows,
+		schemas:     make(map[string]*blockBuilder),
+	}
+	b := appendHeaders([]byte(Magic), headers)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func appendHeaders(b []byte, h *common.GeneratedDataHeaders) []byte {
+	if h == nil {
+		return append(b, 0)
+	}
+	b = append(b, 1)
+	b = appendStrings(b, h.TagKeys)
+	b = appendStrings(b, h.TagTypes)
+	measurements := make([]string, 0, len(h.FieldKeys))
+	for m := range h.FieldKeys {
+		measurements = append(measurements, m)
+	}
+	sort.Strings(measurements)
+	b = binary.AppendUvarint(b, uint64(len(measurements)))
+	for _, m := range measurements {
+		b = appendBytes(b, []byte(m))
+		b = appendStrings(b, h.FieldKeys[m])
+	}
+	return b
+}
+
+func appendStrings(b []byte, s []string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	for _, x := range s {
+		b = appendBytes(b, []byte(x))
+	}
+	return b
+}
+
+// Write adds p to the current segment, writing the segment once it is full.
+func (w *Writer) Write(p *data.Point) error {
+	if p.Timestamp() == nil {
+		return errors.New(errNoTimestamp)
+	}
+	schema := schemaKey(p)
+	block, ok := w.schemas[schema]
+	if !ok {
+		block = newBlockBuilder(p)
+		w.schemas[schema] = block
+		w.blocks = append(w.blocks, block)
+	}
+	if err := block.add(w.rows, p); err != nil {
+		return err
+	}
+	w.rows++
+	if w.rows >= w.SegmentRows {
+		return w.flush()
+	}
+	return nil
+}
+
+// Close writes the last segment and the end of the data. It does not close
+// the underlying io.Writer.
+func (w *Writer) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	_, err := w.w.Write([]byte{0})
+	return err
+}
+
+// flush writes the current segment, if not empty.
+func (w *Writer) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	payload := binary.AppendUvarint(w.buf[:0], uint64(len(w.blocks)))
+	for _, block := range w.blocks {
+		payload = block.append(payload)
+	}
+	header := binary.AppendUvarint(nil, uint64(w.rows))
+	header = binary.AppendUvarint(header, uint64(len(payload)))
+	w.buf = payload
+
+	w.rows = 0
+	w.blocks = w.blocks[:0]
+	w.schemas = make(map[string]*blockBuilder)
+	if _, err := w.w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.w.Write(payload)
+	return err
+}
+
+// schemaKey returns the measurement, tag keys and field keys of p.
+func schemaKey(p *data.Point) string {
+	var sb strings.Builder
+	sb.Write(p.MeasurementName())
+	for _, k := range p.TagKeys() {
+		sb.WriteByte(0)
+		sb.Write(k)
+	}
+	sb.WriteByte(1)
+	for _, k := range p.FieldKeys() {
+		sb.WriteByte(0)
+		sb.Write(k)
+	}
+	return sb.String()
+}
+
+// blockBuilder gathers the points of a schema of a segment.
+type blockBuilder struct {
+	measurement []byte
+	tagKeys     [][]byte
+	fieldKeys   [][]byte
+
+	positions  []int
+	timestamps []int64
+	// tags are the dictionary indexes of the tag values of each tag
+	tags [][]uint64
+	// dicts are the encoded dictionaries of each tag, and dictIndexes the
+	// index of each encoded value in them
+	dicts       [][]byte
+	dictSizes   []int
+	dictIndexes []map[string]uint64
+	fields      [][]interface{}
+}
+
+func newBlockBuilder(p *data.Point) *blockBuilder {
+	b := &blockBuilder{
+		measurement: append([]byte(nil), p.MeasurementName()...),
+		tagKeys:     copyKeys(p.TagKeys()),
+		fieldKeys:   copyKeys(p.FieldKeys()),
+		tags:        make([][]uint64, len(p.TagKeys())),
+		dicts:       make([][]byte, len(p.TagKeys())),
+		dictSizes:   make([]int, len(p.TagKeys())),
+		dictIndexes: make([]map[string]uint64, len(p.TagKeys())),
+		fields:      make([][]interface{}, len(p.FieldKeys())),
+	}
+	for i := range b.dictIndexes {
+		b.dictIndexes[i] = make(map[string]uint64)
+	}
+	return b
+}
+
+func copyKeys(keys [][]byte) [][]byte {
+	c := make([][]byte, len(keys))
+	for i, k := range keys {
+		c[i] = append([]byte(nil), k...)
+	}
+	return c
+}
+
+// add adds p, the point at position of the segment.
+func (b *blockBuilder) add(position int, p *data.Point) error {
+	// check the values first, so that the block is left as is on error
+	for _, values := range [][]interface{}{p.TagValues(), p.FieldValues()} {
+		for _, v := range values {
+			if _, err := valueType(v); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, v := range p.TagValues() {
+		encoded, _ := appendTypedValue(nil, v)
+		index, ok := b.dictIndexes[i][string(encoded)]
+		if !ok {
+			index = uint64(b.dictSizes[i])
+			b.dictIndexes[i][string(encoded)] = index
+			b.dicts[i] = append(b.dicts[i], encoded...)
+			b.dictSizes[i]++
+		}
+		b.tags[i] = append(b.tags[i], index)
+	}
+	for i, v := range p.FieldValues() {
+		if s, ok := v.([]byte); ok {
+			v = append([]byte(nil), s...)
+		}
+		b.fields[i] = append(b.fields[i], v)
+	}
+	b.positions = append(b.positions, position)
+	b.timestamps = append(b.timestamps, p.Timestamp().UnixNano())
+	return nil
+}
+
+// append appends the encoded block to dst.
+func (b *blockBuilder) append(dst []byte) []byte {
+	dst = appendBytes(dst, b.measurement)
+	dst = binary.AppendUvarint(dst, uint64(len(b.positions)))
+	prev := -1
+	for _, pos := range b.positions {
+		dst = binary.AppendUvarint(dst, uint64(pos-prev))
+		prev = pos
+	}
+
+	dst = binary.AppendUvarint(dst, uint64(len(b.tagKeys)))
+	for i, key := range b.tagKeys {
+		dst = appendBytes(dst, key)
+		dst = binary.AppendUvarint(dst, uint64(b.dictSizes[i]))
+		dst = append(dst, b.dicts[i]...)
+		for _, index := range b.tags[i] {
+			dst = binary.AppendUvarint(dst, index)
+		}
+	}
+
+	for i, ts := range b.timestamps {
+		if i == 0 {
+			dst = binary.AppendVarint(dst, ts)
+			continue
+		}
+		dst = binary.AppendVarint(dst, ts-b.timestamps[i-1])
+	}
+
+	dst = binary.AppendUvarint(dst, uint64(len(b.fieldKeys)))
+	for i, key := range b.fieldKeys {
+		dst = appendBytes(dst, key)
+		dst = appendColumn(dst, b.fields[i])
+	}
+	return dst
+}
+
+// appendColumn appends the type of the values, a bitmap of the nil values if
+// any, and the values that are not nil. If the values are of several types,
+// it appends typeMixed and the typed values.
+func appendColumn(dst []byte, values []interface{}) []byte {
+	t := typeNil
+	nulls := false
+	for _, v := range values {
+		vt, _ := valueType(v)
+		switch {
+		case vt == typeNil:
+			nulls = true
+		case t == typeNil:
+			t = vt
+		case t != vt:
+			t = typeMixed
+		}
+	}
+
+	if t == typeMixed {
+		dst = append(dst, typeMixed)
+		for _, v := range values {
+			dst, _ = appendTypedValue(dst, v)
+		}
+		return dst
+	}
+	dst = append(dst, t)
+	if t == typeNil {
+		return dst
+	}
+	if !nulls {
+		dst = append(dst, 0)
+	} else {
+		dst = append(dst, 1)
+		bitmap := make([]byte, (len(values)+7)/8)
+		for i, v := range values {
+			if v == nil {
+				bitmap[i/8] |= 1 << (i % 8)
+			}
+		}
+		dst = append(dst, bitmap...)
+	}
+	for _, v := range values {
+		if v != nil {
+			dst = appendValue(dst, t, v)
+		}
+	}
+	return dst
+}
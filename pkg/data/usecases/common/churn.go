@@ -0,0 +1,114 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const cardinalityValueFmt = "%s_%d"
+
+// Churn are the changes of the simulated entities (hosts, trucks...) over
+// time, which increase the number of series of the simulation.
+type Churn struct {
+	// Lifetime is the mean lifetime of an entity, after which it is
+	// replaced by a new entity with a new id, or 0 for entities that live
+	// forever
+	Lifetime time.Duration
+	// TagChangeRate is the probability that the tags of an entity change
+	// at a reading
+	TagChangeRate float64
+	// CardinalityTag is the key of an extra tag of every entity, whose
+	// value is one of CardinalityValues values and is drawn again whenever
+	// the tags of the entity change. There is no extra tag if
+	// CardinalityValues is 0.
+	CardinalityTag    []byte
+	CardinalityValues uint64
+}
+
+// TagChanger is implemented by entities whose tags change over time, e.g.
+// the driver of a truck.
+type TagChanger interface {
+	// ChangeTags changes the tags of the entity, drawing from r.
+	ChangeTags(r *rand.Rand)
+}
+
+// EntityChurn applies a Churn to the entities of a simulator. Every entity
+// is in a slot, which keeps its place among the entities of the simulator
+// when it is replaced. The entity replacing the one with id in the slot has
+// the id id+n, n being the number of slots, and its PRNG is seeded from the
+// seed of the simulation and its id, so the entities of a slot do not depend
+// on the other slots. Tick, Replace and Tag of a slot only use the state of
+// that slot, so the partitions of a simulator, which have distinct slots,
+// churn their entities in separate goroutines.
+type EntityChurn struct {
+	*Churn
+
+	seed        int64
+	ids         []int
+	cardinality []string
+}
+
+// NewEntityChurn returns an EntityChurn of the entities whose PRNGs are
+// rands, which are used to draw the values of their extra tag.
+func NewEntityChurn(c *Churn, seed int64, rands []*rand.Rand) *EntityChurn {
+	ec := &EntityChurn{
+		Churn: c,
+		seed:  seed,
+		ids:   make([]int, len(rands)),
+	}
+	if c.CardinalityValues > 0 {
+		ec.cardinality = make([]string, len(rands))
+	}
+	for i, r := range rands {
+		ec.ids[i] = i
+		ec.drawCardinality(i, r)
+	}
+	return ec
+}
+
+// Tick tells whether the entity in slot i is replaced at a reading interval
+// after the previous one, drawing from r, the PRNG of the entity. If it is
+// not replaced, its tags, changed by tags if not nil, and the value of its
+// extra tag may change instead.
+func (c *EntityChurn) Tick(i int, interval time.Duration, r *rand.Rand, tags TagChanger) bool {
+	if c.Lifetime > 0 {
+		// the lifetimes are exponentially distributed
+		if r.Float64() < 1-math.Exp(-float64(interval)/float64(c.Lifetime)) {
+			return true
+		}
+	}
+	if c.TagChangeRate > 0 && r.Float64() < c.TagChangeRate {
+		if tags != nil {
+			tags.ChangeTags(r)
+		}
+		c.drawCardinality(i, r)
+	}
+	return false
+}
+
+// Replace replaces the entity in slot i by a new one, which is created by
+// construct with its id and PRNG. It returns the PRNG of the new entity.
+func (c *EntityChurn) Replace(i int, construct func(id int, r *rand.Rand)) *rand.Rand {
+	c.ids[i] += len(c.ids)
+	r := NewRand(EntitySeed(c.seed, c.ids[i]))
+	construct(c.ids[i], r)
+	c.drawCardinality(i, r)
+	return r
+}
+
+// Tag returns the extra tag of the entity in slot i, if any. c may be nil.
+func (c *EntityChurn) Tag(i int) (Tag, bool) {
+	if c == nil || c.cardinality == nil {
+		return Tag{}, false
+	}
+	return Tag{Key: c.CardinalityTag, Value: c.cardinality[i]}, true
+}
+
+func (c *EntityChurn) drawCardinality(i int, r *rand.Rand) {
+	if c.cardinality == nil {
+		return
+	}
+	c.cardinality[i] = fmt.Sprintf(cardinalityValueFmt, c.CardinalityTag, r.Int63n(int64(c.CardinalityValues)))
+}
@@ -0,0 +1,110 @@
+package common
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+type countingTagChanger struct {
+	changes int
+}
+
+func (c *countingTagChanger) ChangeTags(_ *rand.Rand) {
+	c.changes++
+}
+
+func newTestRands(n int) []*rand.Rand {
+	rands := make([]*rand.Rand, n)
+	for i := range rands {
+		rands[i] = NewRand(EntitySeed(123, i))
+	}
+	return rands
+}
+
+func TestEntityChurnReplace(t *testing.T) {
+	rands := newTestRands(3)
+	c := NewEntityChurn(&Churn{Lifetime: time.Nanosecond}, 123, rands)
+	if _, ok := c.Tag(0); ok {
+		t.Errorf("unexpected extra tag without cardinality values")
+	}
+
+	// a lifetime much shorter than the interval replaces every entity
+	if !c.Tick(1, time.Second, rands[1], nil) {
+		t.Fatalf("entity not replaced")
+	}
+	var gotID int
+	r := c.Replace(1, func(id int, r *rand.Rand) { gotID = id })
+	if gotID != 4 {
+		t.Errorf("incorrect id of replacing entity: got %d want 4", gotID)
+	}
+	if want := NewRand(EntitySeed(123, 4)); r.Int63() != want.Int63() {
+		t.Errorf("PRNG of replacing entity not seeded from its id")
+	}
+	c.Replace(1, func(id int, r *rand.Rand) { gotID = id })
+	if gotID != 7 {
+		t.Errorf("incorrect id of second replacing entity: got %d want 7", gotID)
+	}
+
+	c = NewEntityChurn(&Churn{}, 123, rands)
+	for i := 0; i < 100; i++ {
+		if c.Tick(0, time.Second, rands[0], nil) {
+			t.Fatalf("entity replaced without lifetime")
+		}
+	}
+}
+
+func TestEntityChurnLifetime(t *testing.T) {
+	rands := newTestRands(1)
+	c := NewEntityChurn(&Churn{Lifetime: time.Hour}, 123, rands)
+	replaced := 0
+	const ticks = 100000
+	for i := 0; i < ticks; i++ {
+		if c.Tick(0, time.Minute, rands[0], nil) {
+			replaced++
+		}
+	}
+	// about one replacement every 60 ticks
+	if want := ticks / 60; replaced < want*9/10 || replaced > want*11/10 {
+		t.Errorf("incorrect number of replacements: got %d want ~%d", replaced, want)
+	}
+}
+
+func TestEntityChurnTags(t *testing.T) {
+	rands := newTestRands(2)
+	c := NewEntityChurn(&Churn{TagChangeRate: 1, CardinalityTag: []byte("pod"), CardinalityValues: 1000000}, 123, rands)
+	tag, ok := c.Tag(1)
+	if !ok {
+		t.Fatalf("no extra tag with cardinality values")
+	}
+	if string(tag.Key) != "pod" || !strings.HasPrefix(tag.Value.(string), "pod_") {
+		t.Errorf("incorrect extra tag: got %s=%v", tag.Key, tag.Value)
+	}
+
+	changer := &countingTagChanger{}
+	values := map[interface{}]bool{}
+	for i := 0; i < 10; i++ {
+		if c.Tick(1, time.Second, rands[1], changer) {
+			t.Fatalf("entity replaced without lifetime")
+		}
+		tag, _ := c.Tag(1)
+		values[tag.Value] = true
+	}
+	if changer.changes != 10 {
+		t.Errorf("incorrect number of tag changes: got %d want 10", changer.changes)
+	}
+	if len(values) < 2 {
+		t.Errorf("extra tag not drawn again when the tags change")
+	}
+
+	var nilChurn *EntityChurn
+	if _, ok := nilChurn.Tag(0); ok {
+		t.Errorf("unexpected extra tag without churn")
+	}
+}
+
+func TestChurnConfigYAMLNames(t *testing.T) {
+	checkYAMLMatchesMapstructure(t, ChurnConfig{})
+	checkInlinedInYAML(t, "ChurnConfig")
+}
@@ -2,19 +2,19 @@ package common
 
 import "math/rand"
 
-// RandomStringSliceChoice returns a random string from the provided slice of string slices.
-func RandomStringSliceChoice(s []string) string {
-	return s[rand.Intn(len(s))]
+// RandomStringSliceChoice returns a random string, drawn from r, from the provided slice of string slices.
+func RandomStringSliceChoice(s []string, r *rand.Rand) string {
+	return s[r.Intn(len(s))]
 }
 
-// RandomByteStringSliceChoice returns a random byte string slice from the provided slice of byte string slices.
-func RandomByteStringSliceChoice(s [][]byte) []byte {
-	return s[rand.Intn(len(s))]
+// RandomByteStringSliceChoice returns a random byte string slice, drawn from r, from the provided slice of byte string slices.
+func RandomByteStringSliceChoice(s [][]byte, r *rand.Rand) []byte {
+	return s[r.Intn(len(s))]
 }
 
-// RandomInt64SliceChoice returns a random int64 from an int64 slice.
-func RandomInt64SliceChoice(s []int64) int64 {
-	return s[rand.Intn(len(s))]
+// RandomInt64SliceChoice returns a random int64, drawn from r, from an int64 slice.
+func RandomInt64SliceChoice(s []int64, r *rand.Rand) int64 {
+	return s[r.Intn(len(s))]
 }
 
 const (
@@ -24,6 +24,7 @@ const (
 	UseCaseDevops        = "devops"
 	UseCaseIoT           = "iot"
 	UseCaseDevopsGeneric = "devops-generic"
+	UseCaseCustom        = "custom"
 )
 
 var UseCaseChoices = []string{
@@ -32,4 +33,5 @@ var UseCaseChoices = []string{
 	UseCaseDevops,
 	UseCaseIoT,
 	UseCaseDevopsGeneric,
+	UseCaseCustom,
 }
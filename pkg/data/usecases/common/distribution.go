@@ -6,11 +6,29 @@ import (
 )
 
 // Distribution provides an interface to model a statistical distribution.
+// Advance draws its random numbers from r, which is the PRNG of the
+// simulated entity the distribution belongs to.
 type Distribution interface {
-	Advance()
+	Advance(r *rand.Rand)
 	Get() float64 // should be idempotent
 }
 
+// Sampler is implemented by stateless distributions. A Sampler can be shared
+// by the distributions of several entities, which may be advanced in
+// separate goroutines, as long as they take their steps from it with sample.
+type Sampler interface {
+	Sample(r *rand.Rand) float64
+}
+
+// sample returns the next value of d, without changing d if it is a Sampler.
+func sample(d Distribution, r *rand.Rand) float64 {
+	if s, ok := d.(Sampler); ok {
+		return s.Sample(r)
+	}
+	d.Advance(r)
+	return d.Get()
+}
+
 // NormalDistribution models a normal distribution (stateless).
 type NormalDistribution struct {
 	Mean   float64
@@ -29,8 +47,13 @@ func ND(mean, stddev float64) *NormalDistribution {
 
 // Advance advances this distribution. Since the distribution is
 // stateless, this just overwrites the internal cache value.
-func (d *NormalDistribution) Advance() {
-	d.value = rand.NormFloat64()*d.StdDev + d.Mean
+func (d *NormalDistribution) Advance(r *rand.Rand) {
+	d.value = d.Sample(r)
+}
+
+// Sample returns a new value of this distribution.
+func (d *NormalDistribution) Sample(r *rand.Rand) float64 {
+	return r.NormFloat64()*d.StdDev + d.Mean
 }
 
 // Get returns the last computed value for this distribution.
@@ -56,11 +79,16 @@ func UD(low, high float64) *UniformDistribution {
 
 // Advance advances this distribution. Since the distribution is
 // stateless, this just overwrites the internal cache value.
-func (d *UniformDistribution) Advance() {
-	x := rand.Float64() // uniform
+func (d *UniformDistribution) Advance(r *rand.Rand) {
+	d.value = d.Sample(r)
+}
+
+// Sample returns a new value of this distribution.
+func (d *UniformDistribution) Sample(r *rand.Rand) float64 {
+	x := r.Float64() // uniform
 	x *= d.High - d.Low
 	x += d.Low
-	d.value = x
+	return x
 }
 
 // Get returns the last computed value for this distribution.
@@ -85,9 +113,8 @@ func WD(step Distribution, state float64) *RandomWalkDistribution {
 }
 
 // Advance computes the next value of this distribution and stores it.
-func (d *RandomWalkDistribution) Advance() {
-	d.Step.Advance()
-	d.State += d.Step.Get()
+func (d *RandomWalkDistribution) Advance(r *rand.Rand) {
+	d.State += sample(d.Step, r)
 }
 
 // Get returns the last computed value for this distribution.
@@ -118,9 +145,8 @@ func CWD(step Distribution, min, max, state float64) *ClampedRandomWalkDistribut
 }
 
 // Advance computes the next value of this distribution and stores it.
-func (d *ClampedRandomWalkDistribution) Advance() {
-	d.Step.Advance()
-	d.State += d.Step.Get()
+func (d *ClampedRandomWalkDistribution) Advance(r *rand.Rand) {
+	d.State += sample(d.Step, r)
 	if d.State > d.Max {
 		d.State = d.Max
 	}
@@ -144,9 +170,8 @@ type MonotonicRandomWalkDistribution struct {
 }
 
 // Advance computes the next value of this distribution and stores it.
-func (d *MonotonicRandomWalkDistribution) Advance() {
-	d.Step.Advance()
-	d.State += math.Abs(d.Step.Get())
+func (d *MonotonicRandomWalkDistribution) Advance(r *rand.Rand) {
+	d.State += math.Abs(sample(d.Step, r))
 }
 
 // Get returns the last computed value for this distribution.
@@ -168,7 +193,7 @@ type ConstantDistribution struct {
 }
 
 // Advance does nothing in a constant distribution
-func (d *ConstantDistribution) Advance() {
+func (d *ConstantDistribution) Advance(_ *rand.Rand) {
 }
 
 // Get returns the last computed value for this distribution.
@@ -183,8 +208,8 @@ type FloatPrecision struct {
 }
 
 // Advance calls the underlying distribution Advance method.
-func (f *FloatPrecision) Advance() {
-	f.step.Advance()
+func (f *FloatPrecision) Advance(r *rand.Rand) {
+	f.step.Advance(r)
 }
 
 // Get returns the value from the underlying distribution with adjusted float value precision.
@@ -214,6 +239,8 @@ type LazyDistribution struct {
 	motive    Distribution
 	step      Distribution
 	threshold float64
+
+	value float64
 }
 
 // LD returns a new LazyDistribution that returns a new value from "dist", if the "motavation" distribution,
@@ -223,19 +250,19 @@ func LD(motive, dist Distribution, threshold float64) *LazyDistribution {
 		step:      dist,
 		motive:    motive,
 		threshold: threshold,
+		value:     dist.Get(),
 	}
 }
 
 // Advance computes the next value of this distribution.
-func (d *LazyDistribution) Advance() {
-	d.motive.Advance()
-	if d.motive.Get() < d.threshold {
+func (d *LazyDistribution) Advance(r *rand.Rand) {
+	if sample(d.motive, r) < d.threshold {
 		return
 	}
-	d.step.Advance()
+	d.value = sample(d.step, r)
 }
 
 // Get returns the last computed value for this distribution.
 func (d *LazyDistribution) Get() float64 {
-	return d.step.Get()
+	return d.value
 }
@@ -2,6 +2,7 @@ package common
 
 import (
 	"math"
+	"math/rand"
 	"testing"
 )
 
@@ -10,7 +11,7 @@ type mockDistribution struct {
 	ReturnValue   float64
 }
 
-func (m *mockDistribution) Advance() {
+func (m *mockDistribution) Advance(_ *rand.Rand) {
 	m.AdvanceCalled = true
 }
 
@@ -24,7 +25,7 @@ func TestFloatPrecisionAdvance(t *testing.T) {
 
 	fp := FP(dist, 1)
 
-	fp.Advance()
+	fp.Advance(nil)
 
 	if !dist.AdvanceCalled {
 		t.Errorf("FloatPrecision Advance call did not call underlying distribution Advance method")
@@ -143,12 +144,8 @@ func TestLazyDistribution(t *testing.T) {
 
 	for _, testCase := range testCases {
 		t.Run(testCase.desc, func(t *testing.T) {
-			ld := &LazyDistribution{
-				motive:    testCase.motive,
-				step:      testCase.step,
-				threshold: testCase.threshold,
-			}
-			ld.Advance()
+			ld := LD(testCase.motive, testCase.step, testCase.threshold)
+			ld.Advance(nil)
 			if !ld.motive.(*mockDistribution).AdvanceCalled {
 				t.Errorf("advance not called on saddle distribution")
 			}
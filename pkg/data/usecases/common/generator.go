@@ -5,6 +5,8 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/timescale/tsbs/internal/utils"
 	"github.com/timescale/tsbs/pkg/targets/constants"
+	"math"
+	"math/rand"
 	"strings"
 	"time"
 )
@@ -16,7 +18,9 @@ var Ratio []int = []int{8, 1}
 type Generator interface {
 	Measurements() []SimulatedMeasurement
 	Tags() []Tag
-	TickAll(d time.Duration)
+	// TickAll advances the measurements by d, drawing from the PRNG r of
+	// the entity.
+	TickAll(d time.Duration, r *rand.Rand)
 }
 
 // Tag is a key-value pair of information which is used to tag a distributionGenerator
@@ -26,9 +30,32 @@ type Tag struct {
 }
 
 const (
-	errMaxMetricCountValue = "max metric count per host has to be greater than 0"
-	errLogIntervalZero     = "cannot have log interval of 0"
-	defaultLogInterval     = 10 * time.Second
+	errMaxMetricCountValue     = "max metric count per host has to be greater than 0"
+	errLogIntervalZero         = "cannot have log interval of 0"
+	errWorkersInterleaved      = "cannot use more than 1 worker with more than 1 interleaved generation group"
+	errNoCustomSchema          = "custom use case requires a schema file (--custom-schema)"
+	errAnomalyLabelsNoPatterns = "anomaly labels require patterns (--patterns)"
+	errNegativeAmplitude       = "pattern amplitudes and anomaly magnitude cannot be negative"
+	errNegativeTrendStep       = "trend step cannot be negative"
+	errBadAnomalyRates         = "anomaly rates must be non-negative with a sum of at most 1"
+	errAnomalyDurationZero     = "anomaly duration must be at least 1"
+	errNegativeLifetime        = "entity lifetime cannot be negative"
+	errBadTagChangeRate        = "tag change rate must be between 0 and 1"
+	errNoCardinalityTag        = "cardinality tag cannot be empty"
+	errChurnUseCaseFmt         = "entity churn is not supported in use case '%s'"
+	errBadChanceFmt            = "%s must be between 0 and 1"
+	errNegativeDelay           = "late delay and outage durations cannot be negative"
+	errBadDelayDistributionFmt = "invalid late delay distribution '%s', must be one of %v"
+	errOutageDurationZero      = "outages require an outage duration (--outage-duration)"
+	errQualityUseCaseFmt       = "late arrivals, duplicates, outages and quality reports are not supported in use case '%s'"
+	errBadAcceleration         = "acceleration must be positive"
+	errRealTimeFormatFmt       = "real-time generation is not supported in format '%s'"
+	errWriteURLFormatFmt       = "writing to InfluxDB requires format '%s'"
+	errWriteURLFile            = "cannot write to both a file and InfluxDB"
+	errWriteAPIVersionFmt      = "invalid write-api-version %d, must be 1 or 2"
+	errWriteAPIv2NoOrg         = "write-api-version 2 requires a write-org"
+	errWriteAPIv2NoToken       = "write-api-version 2 requires a write-token"
+	defaultLogInterval         = 10 * time.Second
 )
 
 // DataGeneratorConfig is the GeneratorConfig that should be used with a
@@ -43,6 +70,331 @@ type DataGeneratorConfig struct {
 	InterleavedGroupID    uint          `yaml:"interleaved-generation-group-id" mapstructure:"interleaved-generation-group-id"`
 	InterleavedNumGroups  uint          `yaml:"interleaved-generation-groups" mapstructure:"interleaved-generation-groups"`
 	MaxMetricCountPerHost uint64        `yaml:"max-metric-count" mapstructure:"max-metric-count"`
+	Workers               uint          `yaml:"workers" mapstructure:"workers"`
+	CustomSchema          string        `yaml:"custom-schema" mapstructure:"custom-schema"`
+	PatternConfig         `yaml:",inline" mapstructure:",squash"`
+	ChurnConfig           `yaml:",inline" mapstructure:",squash"`
+	QualityConfig         `yaml:",inline" mapstructure:",squash"`
+	StreamConfig          `yaml:",inline" mapstructure:",squash"`
+}
+
+// StreamConfig are the options of the real-time generation, in which the
+// points are emitted as the wall clock reaches their time.
+type StreamConfig struct {
+	RealTime bool `yaml:"real-time" mapstructure:"real-time"`
+	// Acceleration is how much faster than the wall clock the data clock
+	// runs, 1 if 0
+	Acceleration float64 `yaml:"acceleration" mapstructure:"acceleration"`
+	// WriteURL, if set, is the InfluxDB the points are written to instead of
+	// the output, in the database WriteDB
+	WriteURL string `yaml:"write-url" mapstructure:"write-url"`
+	WriteDB  string `yaml:"write-db" mapstructure:"write-db"`
+	// WriteAPIVersion selects the HTTP API of the server, 1 or 2. With 2,
+	// WriteDB is the bucket of the organization WriteOrg.
+	WriteAPIVersion uint   `yaml:"write-api-version" mapstructure:"write-api-version"`
+	WriteOrg        string `yaml:"write-org" mapstructure:"write-org"`
+	// WriteToken, if set, is sent as 'Authorization: Token' with every request.
+	WriteToken string `yaml:"write-token" mapstructure:"write-token"`
+}
+
+// Validate checks that the values of the StreamConfig are reasonable for the
+// format and output file.
+func (c *StreamConfig) Validate(format, file string) error {
+	if c.Acceleration < 0 || math.IsNaN(c.Acceleration) || math.IsInf(c.Acceleration, 0) {
+		return fmt.Errorf(errBadAcceleration)
+	}
+	if c.Acceleration == 0 {
+		c.Acceleration = 1
+	}
+	// columnar segments are only written once they are full
+	if c.RealTime && format == constants.FormatColumnar {
+		return fmt.Errorf(errRealTimeFormatFmt, format)
+	}
+	if c.WriteURL != "" && format != constants.FormatInflux {
+		return fmt.Errorf(errWriteURLFormatFmt, constants.FormatInflux)
+	}
+	if c.WriteURL != "" && file != "" {
+		return fmt.Errorf(errWriteURLFile)
+	}
+	if c.WriteAPIVersion == 0 {
+		c.WriteAPIVersion = 1
+	}
+	switch c.WriteAPIVersion {
+	case 1:
+	case 2:
+		if c.WriteURL != "" && c.WriteOrg == "" {
+			return fmt.Errorf(errWriteAPIv2NoOrg)
+		}
+		if c.WriteURL != "" && c.WriteToken == "" {
+			return fmt.Errorf(errWriteAPIv2NoToken)
+		}
+	default:
+		return fmt.Errorf(errWriteAPIVersionFmt, c.WriteAPIVersion)
+	}
+	return nil
+}
+
+func (c *StreamConfig) AddToFlagSet(fs *pflag.FlagSet) {
+	fs.Bool("real-time", false,
+		"Emit the points as the wall clock reaches their time, with the time range moved to start now")
+	fs.Float64("acceleration", 1, "How much faster than the wall clock the data clock runs. Used only with --real-time")
+	fs.String("write-url", "", "Write the points to the InfluxDB at this URL instead of the output. Requires the influx format")
+	fs.String("write-db", "benchmark", "Database to write the points to. Used only with --write-url")
+	fs.Uint("write-api-version", 1, "HTTP API version of the InfluxDB at --write-url, 1 or 2. With 2, --write-db is the bucket")
+	fs.String("write-org", "", "Organization of the bucket. Required with --write-api-version=2")
+	fs.String("write-token", "", "Token sent in the Authorization header. Required with --write-api-version=2")
+}
+
+// QualityConfig are the options of the disorder injected in the IoT data.
+type QualityConfig struct {
+	BatchSize                 uint          `yaml:"iot-batch-size" mapstructure:"iot-batch-size"`
+	MissingBatchChance        float64       `yaml:"missing-batch-chance" mapstructure:"missing-batch-chance"`
+	OutOfOrderBatchChance     float64       `yaml:"out-of-order-batch-chance" mapstructure:"out-of-order-batch-chance"`
+	InsertPreviousBatchChance float64       `yaml:"insert-previous-batch-chance" mapstructure:"insert-previous-batch-chance"`
+	MissingEntryChance        float64       `yaml:"missing-entry-chance" mapstructure:"missing-entry-chance"`
+	OutOfOrderEntryChance     float64       `yaml:"out-of-order-entry-chance" mapstructure:"out-of-order-entry-chance"`
+	InsertPreviousEntryChance float64       `yaml:"insert-previous-entry-chance" mapstructure:"insert-previous-entry-chance"`
+	ZeroTagChance             float64       `yaml:"zero-tag-chance" mapstructure:"zero-tag-chance"`
+	ZeroFieldChance           float64       `yaml:"zero-field-chance" mapstructure:"zero-field-chance"`
+	LateChance                float64       `yaml:"late-chance" mapstructure:"late-chance"`
+	LateDelay                 time.Duration `yaml:"late-delay" mapstructure:"late-delay"`
+	LateDelayDistribution     string        `yaml:"late-delay-distribution" mapstructure:"late-delay-distribution"`
+	DuplicateChance           float64       `yaml:"duplicate-chance" mapstructure:"duplicate-chance"`
+	OutageInterval            time.Duration `yaml:"outage-interval" mapstructure:"outage-interval"`
+	OutageDuration            time.Duration `yaml:"outage-duration" mapstructure:"outage-duration"`
+	OutageBackfill            bool          `yaml:"outage-backfill" mapstructure:"outage-backfill"`
+	QualityReport             string        `yaml:"quality-report" mapstructure:"quality-report"`
+}
+
+// Validate checks that the values of the QualityConfig are reasonable for the
+// use case.
+func (c *QualityConfig) Validate(use string) error {
+	chances := []struct {
+		name  string
+		value float64
+	}{
+		{"missing batch chance", c.MissingBatchChance},
+		{"out-of-order batch chance", c.OutOfOrderBatchChance},
+		{"insert previous batch chance", c.InsertPreviousBatchChance},
+		{"missing entry chance", c.MissingEntryChance},
+		{"out-of-order entry chance", c.OutOfOrderEntryChance},
+		{"insert previous entry chance", c.InsertPreviousEntryChance},
+		{"zero tag chance", c.ZeroTagChance},
+		{"zero field chance", c.ZeroFieldChance},
+		{"late chance", c.LateChance},
+		{"duplicate chance", c.DuplicateChance},
+	}
+	for _, chance := range chances {
+		if chance.value < 0 || chance.value > 1 {
+			return fmt.Errorf(errBadChanceFmt, chance.name)
+		}
+	}
+	if c.LateDelay < 0 || c.OutageInterval < 0 || c.OutageDuration < 0 {
+		return fmt.Errorf(errNegativeDelay)
+	}
+	if c.LateDelayDistribution != "" && !utils.IsIn(c.LateDelayDistribution, DelayDistributionChoices) {
+		return fmt.Errorf(errBadDelayDistributionFmt, c.LateDelayDistribution, DelayDistributionChoices)
+	}
+	if c.OutageInterval > 0 && c.OutageDuration == 0 {
+		return fmt.Errorf(errOutageDurationZero)
+	}
+	q := c.NewQuality()
+	if (q.Disordered() || c.QualityReport != "") && use != UseCaseIoT {
+		return fmt.Errorf(errQualityUseCaseFmt, use)
+	}
+	return nil
+}
+
+// NewQuality returns the Quality of the config, or DefaultQuality if the
+// config is not filled in, e.g. in a DataGeneratorConfig built in code.
+func (c *QualityConfig) NewQuality() *Quality {
+	if *c == (QualityConfig{}) {
+		q := DefaultQuality
+		return &q
+	}
+	return &Quality{
+		BatchSize:                 c.BatchSize,
+		MissingBatchChance:        c.MissingBatchChance,
+		OutOfOrderBatchChance:     c.OutOfOrderBatchChance,
+		InsertPreviousBatchChance: c.InsertPreviousBatchChance,
+		MissingEntryChance:        c.MissingEntryChance,
+		OutOfOrderEntryChance:     c.OutOfOrderEntryChance,
+		InsertPreviousEntryChance: c.InsertPreviousEntryChance,
+		ZeroTagChance:             c.ZeroTagChance,
+		ZeroFieldChance:           c.ZeroFieldChance,
+		LateChance:                c.LateChance,
+		LateDelay:                 Delay{Distribution: c.LateDelayDistribution, Mean: c.LateDelay},
+		DuplicateChance:           c.DuplicateChance,
+		OutageInterval:            c.OutageInterval,
+		OutageDuration:            c.OutageDuration,
+		OutageBackfill:            c.OutageBackfill,
+	}
+}
+
+func (c *QualityConfig) AddToFlagSet(fs *pflag.FlagSet) {
+	d := DefaultQuality
+	fs.Uint("iot-batch-size", d.BatchSize,
+		"Number of entries of the batches in which missing, out-of-order and zero values are injected. 0 disables them. Used only in iot use-case")
+	fs.Float64("missing-batch-chance", d.MissingBatchChance, "Probability that a batch is missing. Used only in iot use-case")
+	fs.Float64("out-of-order-batch-chance", d.OutOfOrderBatchChance, "Probability that a batch is sent later out of order. Used only in iot use-case")
+	fs.Float64("insert-previous-batch-chance", d.InsertPreviousBatchChance,
+		"Probability that an out-of-order batch is sent instead of the next one. Used only in iot use-case")
+	fs.Float64("missing-entry-chance", d.MissingEntryChance, "Probability that an entry of a batch is missing. Used only in iot use-case")
+	fs.Float64("out-of-order-entry-chance", d.OutOfOrderEntryChance,
+		"Probability that an entry of a batch is sent later out of order. Used only in iot use-case")
+	fs.Float64("insert-previous-entry-chance", d.InsertPreviousEntryChance,
+		"Probability that an out-of-order entry is sent before an entry of a batch. Used only in iot use-case")
+	fs.Float64("zero-tag-chance", d.ZeroTagChance, "Probability that an entry has a tag without value. Used only in iot use-case")
+	fs.Float64("zero-field-chance", d.ZeroFieldChance, "Probability that an entry has a field without value. Used only in iot use-case")
+	fs.Float64("late-chance", d.LateChance,
+		"Probability that an entry arrives late, after a delay in simulated time (--late-delay). Used only in iot use-case")
+	fs.Duration("late-delay", d.LateDelay.Mean, "Mean delay of the late entries and the duplicates. Used only in iot use-case")
+	fs.String("late-delay-distribution", d.LateDelay.Distribution,
+		fmt.Sprintf("Distribution of the delays of the late entries and the duplicates, one of %v. Used only in iot use-case", DelayDistributionChoices))
+	fs.Float64("duplicate-chance", d.DuplicateChance, "Probability that an entry is sent twice. Duplicates are not counted in --max-data-points. Used only in iot use-case")
+	fs.Duration("outage-interval", d.OutageInterval,
+		"Mean time between the outages of a fleet, during which its trucks send nothing. 0 means no outages. Used only in iot use-case")
+	fs.Duration("outage-duration", d.OutageDuration, "Duration of the outages of the fleets. Used only in iot use-case")
+	fs.Bool("outage-backfill", d.OutageBackfill,
+		"Send the entries held back by an outage at its end, instead of losing them. Used only in iot use-case")
+	fs.String("quality-report", "", "Write a summary of the injected disorder to this file. Used only in iot use-case")
+}
+
+// ChurnConfig are the options of the changes of the simulated entities over
+// time.
+type ChurnConfig struct {
+	EntityLifetime    time.Duration `yaml:"entity-lifetime" mapstructure:"entity-lifetime"`
+	TagChangeRate     float64       `yaml:"tag-change-rate" mapstructure:"tag-change-rate"`
+	CardinalityTag    string        `yaml:"cardinality-tag" mapstructure:"cardinality-tag"`
+	CardinalityValues uint64        `yaml:"cardinality-values" mapstructure:"cardinality-values"`
+}
+
+// churnUseCases are the use cases whose entities can churn.
+var churnUseCases = []string{UseCaseCPUOnly, UseCaseCPUSingle, UseCaseDevops, UseCaseIoT}
+
+// Validate checks that the values of the ChurnConfig are reasonable for the
+// use case.
+func (c *ChurnConfig) Validate(use string) error {
+	if c.EntityLifetime < 0 {
+		return fmt.Errorf(errNegativeLifetime)
+	}
+	if c.TagChangeRate < 0 || c.TagChangeRate > 1 {
+		return fmt.Errorf(errBadTagChangeRate)
+	}
+	if c.CardinalityValues > 0 && c.CardinalityTag == "" {
+		return fmt.Errorf(errNoCardinalityTag)
+	}
+	if c.enabled() && !utils.IsIn(use, churnUseCases) {
+		return fmt.Errorf(errChurnUseCaseFmt, use)
+	}
+	return nil
+}
+
+func (c *ChurnConfig) enabled() bool {
+	return c.EntityLifetime > 0 || c.TagChangeRate > 0 || c.CardinalityValues > 0
+}
+
+// NewChurn returns the Churn of the config, or nil if the entities do not
+// change.
+func (c *ChurnConfig) NewChurn() *Churn {
+	if !c.enabled() {
+		return nil
+	}
+	return &Churn{
+		Lifetime:          c.EntityLifetime,
+		TagChangeRate:     c.TagChangeRate,
+		CardinalityTag:    []byte(c.CardinalityTag),
+		CardinalityValues: c.CardinalityValues,
+	}
+}
+
+func (c *ChurnConfig) AddToFlagSet(fs *pflag.FlagSet) {
+	fs.Duration("entity-lifetime", 0,
+		"Mean lifetime of a host (or truck), after which it is replaced by a new one with a new id. 0 means forever")
+	fs.Float64("tag-change-rate", 0,
+		"Probability that the tags of a host (service version) or truck (driver) change at a reading")
+	fs.String("cardinality-tag", "pod", "Key of the extra high-cardinality tag. Used only with --cardinality-values")
+	fs.Uint64("cardinality-values", 0,
+		"Number of distinct values of an extra tag of every host (or truck), drawn again when its tags change. 0 means no extra tag")
+}
+
+// PatternConfig are the options of the seasonality, trends and anomalies of
+// the generated data.
+type PatternConfig struct {
+	Patterns         bool          `yaml:"patterns" mapstructure:"patterns"`
+	DailyAmplitude   float64       `yaml:"daily-amplitude" mapstructure:"daily-amplitude"`
+	WeeklyAmplitude  float64       `yaml:"weekly-amplitude" mapstructure:"weekly-amplitude"`
+	Trend            float64       `yaml:"trend" mapstructure:"trend"`
+	TrendStep        time.Duration `yaml:"trend-step" mapstructure:"trend-step"`
+	SpikeRate        float64       `yaml:"spike-rate" mapstructure:"spike-rate"`
+	LevelShiftRate   float64       `yaml:"level-shift-rate" mapstructure:"level-shift-rate"`
+	FlatLineRate     float64       `yaml:"flat-line-rate" mapstructure:"flat-line-rate"`
+	AnomalyMagnitude float64       `yaml:"anomaly-magnitude" mapstructure:"anomaly-magnitude"`
+	AnomalyDuration  int           `yaml:"anomaly-duration" mapstructure:"anomaly-duration"`
+	AnomalyLabels    string        `yaml:"anomaly-labels" mapstructure:"anomaly-labels"`
+}
+
+// Validate checks that the values of the PatternConfig are reasonable.
+func (c *PatternConfig) Validate() error {
+	if !c.Patterns {
+		if c.AnomalyLabels != "" {
+			return fmt.Errorf(errAnomalyLabelsNoPatterns)
+		}
+		return nil
+	}
+	if c.DailyAmplitude < 0 || c.WeeklyAmplitude < 0 || c.AnomalyMagnitude < 0 {
+		return fmt.Errorf(errNegativeAmplitude)
+	}
+	if c.TrendStep < 0 {
+		return fmt.Errorf(errNegativeTrendStep)
+	}
+	if c.SpikeRate < 0 || c.LevelShiftRate < 0 || c.FlatLineRate < 0 || c.SpikeRate+c.LevelShiftRate+c.FlatLineRate > 1 {
+		return fmt.Errorf(errBadAnomalyRates)
+	}
+	if c.AnomalyDuration < 1 {
+		return fmt.Errorf(errAnomalyDurationZero)
+	}
+	return nil
+}
+
+// NewPatterns returns the Patterns of the config, with an AnomalyLog if the
+// anomaly labels are written, or nil if the patterns are disabled.
+func (c *PatternConfig) NewPatterns() *Patterns {
+	if !c.Patterns {
+		return nil
+	}
+	p := &Patterns{
+		DailyAmplitude:  c.DailyAmplitude,
+		WeeklyAmplitude: c.WeeklyAmplitude,
+		Trend:           c.Trend,
+		TrendStep:       c.TrendStep,
+		Anomalies: AnomalyConfig{
+			SpikeRate:      c.SpikeRate,
+			LevelShiftRate: c.LevelShiftRate,
+			FlatLineRate:   c.FlatLineRate,
+			Magnitude:      c.AnomalyMagnitude,
+			Duration:       c.AnomalyDuration,
+		},
+	}
+	if c.AnomalyLabels != "" {
+		p.Log = &AnomalyLog{}
+	}
+	return p
+}
+
+func (c *PatternConfig) AddToFlagSet(fs *pflag.FlagSet) {
+	fs.Bool("patterns", false,
+		"Add daily and weekly seasonality, a trend and anomalies to the devops CPU and IoT readings fields")
+	fs.Float64("daily-amplitude", 0.2, "Amplitude of the daily cycle, as a fraction of the range of each field. Used only with --patterns")
+	fs.Float64("weekly-amplitude", 0.05, "Amplitude of the weekly cycle, as a fraction of the range of each field. Used only with --patterns")
+	fs.Float64("trend", 0, "Change per day (or per --trend-step) as a fraction of the range of each field. Used only with --patterns")
+	fs.Duration("trend-step", 0, "Change the trend in steps of this duration instead of continuously. Used only with --patterns")
+	fs.Float64("spike-rate", 0.001, "Probability that a spike starts at a reading. Used only with --patterns")
+	fs.Float64("level-shift-rate", 0.0002, "Probability that a level shift starts at a reading. Used only with --patterns")
+	fs.Float64("flat-line-rate", 0.0002, "Probability that a flat-line starts at a reading. Used only with --patterns")
+	fs.Float64("anomaly-magnitude", 0.5, "Size of spikes (level shifts are half of it) as a fraction of the range of each field. Used only with --patterns")
+	fs.Int("anomaly-duration", 30, "Number of readings of level shifts and flat-lines. Used only with --patterns")
+	fs.String("anomaly-labels", "", "Write the injected anomalies to this CSV file. Used only with --patterns")
 }
 
 // Validate checks that the values of the DataGeneratorConfig are reasonable.
@@ -62,10 +414,38 @@ func (c *DataGeneratorConfig) Validate() error {
 
 	err = utils.ValidateGroups(c.InterleavedGroupID, c.InterleavedNumGroups)
 
+	if c.Workers == 0 {
+		c.Workers = 1
+	}
+
+	if c.Workers > 1 && c.InterleavedNumGroups > 1 {
+		return fmt.Errorf(errWorkersInterleaved)
+	}
+
 	if c.Use == UseCaseDevopsGeneric && c.MaxMetricCountPerHost < 1 {
 		return fmt.Errorf(errMaxMetricCountValue)
 	}
 
+	if c.Use == UseCaseCustom && c.CustomSchema == "" {
+		return fmt.Errorf(errNoCustomSchema)
+	}
+
+	if err := c.PatternConfig.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.ChurnConfig.Validate(c.Use); err != nil {
+		return err
+	}
+
+	if err := c.QualityConfig.Validate(c.Use); err != nil {
+		return err
+	}
+
+	if err := c.StreamConfig.Validate(c.Format, c.File); err != nil {
+		return err
+	}
+
 	return err
 }
 
@@ -80,6 +460,13 @@ func (c *DataGeneratorConfig) AddToFlagSet(fs *pflag.FlagSet) {
 	fs.Uint("interleaved-generation-groups", 1,
 		"The number of round-robin serialization groups. Use this to scale up data generation to multiple processes.")
 	fs.Uint64("max-metric-count", 100, "Max number of metric fields to generate per host. Used only in devops-generic use-case")
+	fs.Uint("workers", 1,
+		"The number of goroutines simulating and serializing the hosts (or trucks). The output does not depend on it.")
+	c.PatternConfig.AddToFlagSet(fs)
+	c.ChurnConfig.AddToFlagSet(fs)
+	c.QualityConfig.AddToFlagSet(fs)
+	c.StreamConfig.AddToFlagSet(fs)
+	fs.String("custom-schema", "", "YAML file describing the entities, tags and measurements of the custom use case. Used only in custom use-case")
 }
 
 const defaultTimeStart = "2016-01-01T00:00:00Z"
@@ -0,0 +1,8 @@
+package common
+
+import "testing"
+
+func TestStreamConfigYAMLNames(t *testing.T) {
+	checkYAMLMatchesMapstructure(t, StreamConfig{})
+	checkInlinedInYAML(t, "StreamConfig")
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"github.com/timescale/tsbs/pkg/data"
+	"math/rand"
 	"time"
 )
 
@@ -20,20 +21,20 @@ func NewSubsystemMeasurement(start time.Time, numDistributions int) *SubsystemMe
 }
 
 // NewSubsystemMeasurementWithDistributionMakers creates a new SubsystemMeasurement with start time and distribution makers
-// which are used to create the necessary distributions.
-func NewSubsystemMeasurementWithDistributionMakers(start time.Time, makers []LabeledDistributionMaker) *SubsystemMeasurement {
+// which are used to create the necessary distributions, drawing their initial states from r.
+func NewSubsystemMeasurementWithDistributionMakers(start time.Time, makers []LabeledDistributionMaker, r *rand.Rand) *SubsystemMeasurement {
 	m := NewSubsystemMeasurement(start, len(makers))
 	for i := 0; i < len(makers); i++ {
-		m.Distributions[i] = makers[i].DistributionMaker()
+		m.Distributions[i] = makers[i].DistributionMaker(r)
 	}
 	return m
 }
 
 // Tick advances all the distributions for the SubsystemMeasurement.
-func (m *SubsystemMeasurement) Tick(d time.Duration) {
+func (m *SubsystemMeasurement) Tick(d time.Duration, r *rand.Rand) {
 	m.Timestamp = m.Timestamp.Add(d)
 	for i := range m.Distributions {
-		m.Distributions[i].Advance()
+		m.Distributions[i].Advance(r)
 	}
 }
 
@@ -63,5 +64,5 @@ func (m *SubsystemMeasurement) ToPointAllInt64(p *data.Point, measurementName []
 // LabeledDistributionMaker combines a distribution maker with a label.
 type LabeledDistributionMaker struct {
 	Label             []byte
-	DistributionMaker func() Distribution
+	DistributionMaker func(r *rand.Rand) Distribution
 }
@@ -3,6 +3,7 @@ package common
 import (
 	"github.com/timescale/tsbs/pkg/data"
 	"math"
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -21,7 +22,7 @@ type monotonicDistribution struct {
 	state float64
 }
 
-func (d *monotonicDistribution) Advance() {
+func (d *monotonicDistribution) Advance(_ *rand.Rand) {
 	d.state++
 }
 
@@ -62,11 +63,11 @@ func TestNewSubsystemMeasurement(t *testing.T) {
 
 func TestNewSubsystemMeasurementWithDistributionMakers(t *testing.T) {
 	makers := []LabeledDistributionMaker{
-		{[]byte("foo"), func() Distribution { return &monotonicDistribution{state: 0.0} }},
-		{[]byte("bar"), func() Distribution { return &monotonicDistribution{state: 1.0} }},
+		{[]byte("foo"), func(_ *rand.Rand) Distribution { return &monotonicDistribution{state: 0.0} }},
+		{[]byte("bar"), func(_ *rand.Rand) Distribution { return &monotonicDistribution{state: 1.0} }},
 	}
 	now := time.Now()
-	m := NewSubsystemMeasurementWithDistributionMakers(now, makers)
+	m := NewSubsystemMeasurementWithDistributionMakers(now, makers, nil)
 	if !m.Timestamp.Equal(now) {
 		t.Errorf("incorrect timestamp set: got %v want %v", m.Timestamp, now)
 	}
@@ -90,7 +91,7 @@ func TestSubsytemMeasurementTick(t *testing.T) {
 	for i := 0; i < numDistros; i++ {
 		m.Distributions[i] = &monotonicDistribution{state: float64(i)}
 	}
-	m.Tick(time.Nanosecond)
+	m.Tick(time.Nanosecond, nil)
 	if got := m.Timestamp.UnixNano(); got != now.UnixNano()+1 {
 		t.Errorf("tick did not increase timestamp correct: got %d want %d", got, now.UnixNano()+1)
 	}
@@ -125,10 +126,10 @@ func TestToPointAllInt64(t *testing.T) {
 
 func setupToPoint(start time.Time) (*SubsystemMeasurement, []LabeledDistributionMaker) {
 	makers := []LabeledDistributionMaker{
-		{[]byte(toPointFieldLabel), func() Distribution { return &monotonicDistribution{state: toPointState} }},
+		{[]byte(toPointFieldLabel), func(_ *rand.Rand) Distribution { return &monotonicDistribution{state: toPointState} }},
 	}
-	m := NewSubsystemMeasurementWithDistributionMakers(start, makers)
-	m.Tick(time.Nanosecond)
+	m := NewSubsystemMeasurementWithDistributionMakers(start, makers, nil)
+	m.Tick(time.Nanosecond, nil)
 	return m, makers
 }
 
@@ -0,0 +1,204 @@
+package common
+
+import (
+	"github.com/timescale/tsbs/pkg/data"
+)
+
+// parallelChunkCapacity is the number of steps a partition may simulate ahead
+// of the one being read.
+const parallelChunkCapacity = 4
+
+// Partitioner is implemented by Simulators whose generators (hosts, trucks...)
+// can be simulated in separate goroutines. Every generator draws from its own
+// PRNG, so the points of a generator do not depend on how the generators are
+// partitioned.
+type Partitioner interface {
+	Simulator
+	// Generators returns the number of generators of the simulator.
+	Generators() int
+	// Partition returns a Simulator of the generators [lo, hi) only, which
+	// shares them with the partitioned simulator. It must be called before
+	// the first call to Next, and the partitioned simulator must not be used
+	// to simulate points afterwards. Every step, i.e. every measurement of
+	// every epoch, Next of the partition returns the points of its
+	// generators in order, hi-lo points, until the partition is Finished.
+	// Finished is true once all points before the next one of the partition
+	// reach the points limit of the simulator.
+	Partition(lo, hi int) Simulator
+}
+
+// PartitionBounds splits n generators into at most parts contiguous ranges of
+// nearly equal size. The range i is [bounds[i], bounds[i+1]).
+func PartitionBounds(n, parts int) []int {
+	if parts > n {
+		parts = n
+	}
+	if parts < 1 {
+		parts = 1
+	}
+	bounds := make([]int, parts+1)
+	for i := range bounds {
+		bounds[i] = i * n / parts
+	}
+	return bounds
+}
+
+// StepFunc simulates the next step of the partition s, i.e. at most stepSize
+// points, and returns the result read for the step. It is called in the
+// goroutine of the partition.
+type StepFunc func(s Simulator, stepSize int) interface{}
+
+// PartitionSteps runs the partitions of a Partitioner in separate goroutines
+// and returns the results of their steps in the order of the partitioned
+// simulator.
+type PartitionSteps struct {
+	steps   []chan interface{}
+	next    int
+	done    chan struct{}
+	stopped bool
+}
+
+// RunPartitions runs at most workers partitions of s in separate goroutines,
+// each simulating its steps with the StepFunc newStep returns for it, until
+// the partition is Finished or the PartitionSteps are stopped.
+func RunPartitions(s Partitioner, workers int, newStep func() StepFunc) *PartitionSteps {
+	bounds := PartitionBounds(s.Generators(), workers)
+	r := &PartitionSteps{
+		steps: make([]chan interface{}, len(bounds)-1),
+		done:  make(chan struct{}),
+	}
+	for i := range r.steps {
+		r.steps[i] = make(chan interface{}, parallelChunkCapacity)
+		go runPartition(s.Partition(bounds[i], bounds[i+1]), bounds[i+1]-bounds[i], newStep(), r.steps[i], r.done)
+	}
+	return r
+}
+
+func runPartition(s Simulator, stepSize int, step StepFunc, out chan<- interface{}, done <-chan struct{}) {
+	defer close(out)
+	for !s.Finished() {
+		select {
+		case out <- step(s, stepSize):
+		case <-done:
+			return
+		}
+	}
+}
+
+// Next returns the result of the next step, taking a step from each
+// partition in turn, or false once all partitions are Finished.
+func (r *PartitionSteps) Next() (interface{}, bool) {
+	for len(r.steps) > 0 {
+		step, ok := <-r.steps[r.next]
+		if !ok {
+			// the partition is done, and so are the ones after it
+			r.steps = append(r.steps[:r.next], r.steps[r.next+1:]...)
+			if r.next == len(r.steps) {
+				r.next = 0
+			}
+			continue
+		}
+		r.next = (r.next + 1) % len(r.steps)
+		return step, true
+	}
+	return nil, false
+}
+
+// Stop ends the goroutines of the partitions, even if they are not Finished.
+// Next must not be called afterwards.
+func (r *PartitionSteps) Stop() {
+	if !r.stopped {
+		r.stopped = true
+		close(r.done)
+	}
+}
+
+// Stopper is implemented by Simulators that simulate in goroutines of their
+// own. Stop ends them, so the simulation can be abandoned before it is
+// Finished; the Simulator must not be used afterwards.
+type Stopper interface {
+	Stop()
+}
+
+// ParallelPoint is a point simulated by a partition.
+type ParallelPoint struct {
+	Point *data.Point
+	Write bool
+}
+
+// simulateStep is the StepFunc of a ParallelSimulator, which returns the
+// points of the step as a []ParallelPoint.
+func simulateStep(s Simulator, stepSize int) interface{} {
+	step := make([]ParallelPoint, 0, stepSize)
+	for i := 0; i < stepSize && !s.Finished(); i++ {
+		p := data.NewPoint()
+		write := s.Next(p)
+		// Measurements may point the timestamp at their own state,
+		// which the next tick advances before p is read.
+		if ts := p.Timestamp(); ts != nil {
+			t := *ts
+			p.SetTimestamp(&t)
+		}
+		step = append(step, ParallelPoint{Point: p, Write: write})
+	}
+	return step
+}
+
+// ParallelSimulator is a Simulator that simulates the partitions of a
+// Partitioner in separate goroutines and returns their points in the order
+// of the partitioned simulator, i.e. the same points as it would.
+type ParallelSimulator struct {
+	Partitioner
+
+	workers int
+	steps   *PartitionSteps
+	step    []ParallelPoint
+}
+
+// NewParallelSimulator returns a ParallelSimulator of s, which starts the
+// workers goroutines when the first point is requested.
+func NewParallelSimulator(s Partitioner, workers int) *ParallelSimulator {
+	return &ParallelSimulator{Partitioner: s, workers: workers}
+}
+
+// Finished tells whether all partitions simulated all their points.
+func (s *ParallelSimulator) Finished() bool {
+	return !s.fill()
+}
+
+// Next copies the next point of the simulation to p.
+func (s *ParallelSimulator) Next(p *data.Point) bool {
+	if !s.fill() {
+		return false
+	}
+	next := s.step[0]
+	s.step = s.step[1:]
+	// a point that is not written may not have been populated at all
+	if next.Point.Timestamp() != nil {
+		p.Copy(next.Point)
+	}
+	return next.Write
+}
+
+// Stop ends the goroutines simulating the partitions.
+func (s *ParallelSimulator) Stop() {
+	if s.steps != nil {
+		s.steps.Stop()
+	}
+}
+
+// fill reads the next step, if the current one was read, and returns false
+// if there are no more points.
+func (s *ParallelSimulator) fill() bool {
+	if s.steps == nil {
+		s.steps = RunPartitions(s.Partitioner, s.workers, func() StepFunc { return simulateStep })
+	}
+	for len(s.step) == 0 {
+		step, ok := s.steps.Next()
+		if !ok {
+			return false
+		}
+		s.step = step.([]ParallelPoint)
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+package common
+
+import (
+	"fmt"
+	"github.com/timescale/tsbs/pkg/data"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPartitionBounds(t *testing.T) {
+	cases := []struct {
+		n, parts int
+		want     []int
+	}{
+		{n: 10, parts: 1, want: []int{0, 10}},
+		{n: 10, parts: 3, want: []int{0, 3, 6, 10}},
+		{n: 10, parts: 0, want: []int{0, 10}},
+		{n: 2, parts: 4, want: []int{0, 1, 2}},
+	}
+	for _, c := range cases {
+		if got := PartitionBounds(c.n, c.parts); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("incorrect bounds of %d generators in %d parts: got %v want %v", c.n, c.parts, got, c.want)
+		}
+	}
+}
+
+// randomMeasurement is a measurement whose value depends on the PRNG it is
+// advanced with.
+type randomMeasurement struct {
+	timestamp time.Time
+	value     float64
+}
+
+func (m *randomMeasurement) Tick(d time.Duration, r *rand.Rand) {
+	m.timestamp = m.timestamp.Add(d)
+	m.value = r.Float64()
+}
+
+func (m *randomMeasurement) ToPoint(p *data.Point) {
+	p.SetMeasurementName(dummyMeasurementName)
+	p.SetTimestamp(&m.timestamp)
+	p.AppendField(dummyFieldLabel, m.value)
+}
+
+type randomGenerator struct {
+	id           int
+	measurements []SimulatedMeasurement
+}
+
+func (g *randomGenerator) Measurements() []SimulatedMeasurement {
+	return g.measurements
+}
+
+func (g *randomGenerator) Tags() []Tag {
+	return []Tag{{Key: []byte("id"), Value: g.id}}
+}
+
+func (g *randomGenerator) TickAll(d time.Duration, r *rand.Rand) {
+	for _, m := range g.measurements {
+		m.Tick(d, r)
+	}
+}
+
+func randomGeneratorConstructor(i int, start time.Time, r *rand.Rand) Generator {
+	return &randomGenerator{
+		id:           i,
+		measurements: []SimulatedMeasurement{&randomMeasurement{timestamp: start}, &randomMeasurement{timestamp: start}},
+	}
+}
+
+func TestParallelSimulator(t *testing.T) {
+	conf := &BaseSimulatorConfig{
+		Start:                testTime,
+		End:                  testTime.Add(10 * time.Second),
+		InitGeneratorScale:   4,
+		GeneratorScale:       11,
+		GeneratorConstructor: randomGeneratorConstructor,
+		Seed:                 123,
+	}
+	points := func(s Simulator) []string {
+		ret := make([]string, 0)
+		p := data.NewPoint()
+		for !s.Finished() {
+			write := s.Next(p)
+			ret = append(ret, fmt.Sprintf("%t %v %v %v", write, p.TagValues(), p.FieldValues(), p.TimestampInUnixMs()))
+			p.Reset()
+		}
+		return ret
+	}
+	for _, limit := range []uint64{0, 1, 50, 97} {
+		want := points(conf.NewSimulator(time.Second, limit))
+		for workers := 1; workers <= 12; workers++ {
+			s := conf.NewSimulator(time.Second, limit).(Partitioner)
+			if got := points(NewParallelSimulator(s, workers)); !reflect.DeepEqual(got, want) {
+				t.Errorf("limit %d, %d workers: incorrect points: got\n%v\nwant\n%v", limit, workers, got, want)
+			}
+		}
+	}
+}
+
+func TestBaseSimulatorChurn(t *testing.T) {
+	conf := &BaseSimulatorConfig{
+		Start:                testTime,
+		End:                  testTime.Add(100 * time.Second),
+		InitGeneratorScale:   5,
+		GeneratorScale:       5,
+		GeneratorConstructor: randomGeneratorConstructor,
+		Seed:                 123,
+		Churn: &Churn{
+			Lifetime:          10 * time.Second,
+			CardinalityTag:    []byte("pod"),
+			CardinalityValues: 100,
+		},
+	}
+	points := func(s Simulator) []string {
+		ret := make([]string, 0)
+		p := data.NewPoint()
+		for !s.Finished() {
+			s.Next(p)
+			ret = append(ret, fmt.Sprintf("%v %v %v", p.TagValues(), p.FieldValues(), p.TimestampInUnixMs()))
+			p.Reset()
+		}
+		return ret
+	}
+
+	s := conf.NewSimulator(time.Second, 0)
+	if got := s.TagKeys(); !reflect.DeepEqual(got, []string{"id", "pod"}) {
+		t.Errorf("incorrect tag keys: got %v", got)
+	}
+	want := points(s)
+	ids := map[interface{}]bool{}
+	p := data.NewPoint()
+	s = conf.NewSimulator(time.Second, 0)
+	for slot := 0; !s.Finished(); slot = (slot + 1) % 5 {
+		s.Next(p)
+		// the generators replacing the one of a slot keep its slot
+		id := p.TagValues()[0]
+		if id.(int)%5 != slot {
+			t.Errorf("generator %v in slot %d", id, slot)
+		}
+		ids[id] = true
+		p.Reset()
+	}
+	if len(ids) <= 5 {
+		t.Errorf("no generator replaced: got %d ids", len(ids))
+	}
+
+	for workers := 2; workers <= 5; workers++ {
+		s := conf.NewSimulator(time.Second, 0).(Partitioner)
+		if got := points(NewParallelSimulator(s, workers)); !reflect.DeepEqual(got, want) {
+			t.Errorf("%d workers: incorrect points with churn", workers)
+		}
+	}
+}
+
+func TestPartitionStepsStop(t *testing.T) {
+	conf := &BaseSimulatorConfig{
+		Start:                testTime,
+		End:                  testTime.Add(time.Hour),
+		InitGeneratorScale:   4,
+		GeneratorScale:       4,
+		GeneratorConstructor: randomGeneratorConstructor,
+		Seed:                 123,
+	}
+	s := NewParallelSimulator(conf.NewSimulator(time.Second, 0).(Partitioner), 4)
+	s.Next(data.NewPoint())
+	s.Stop()
+	// the partitions are far from finished, but close their channels
+	for i, steps := range s.steps.steps {
+		timeout := time.After(5 * time.Second)
+	drain:
+		for {
+			select {
+			case _, ok := <-steps:
+				if !ok {
+					break drain
+				}
+			case <-timeout:
+				t.Fatalf("partition %d not stopped", i)
+			}
+		}
+	}
+	// stopping twice is harmless
+	s.Stop()
+}
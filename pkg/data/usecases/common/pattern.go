@@ -0,0 +1,412 @@
+package common
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kinds of the anomalies injected by an AnomalyDistribution.
+const (
+	AnomalySpike      = "spike"
+	AnomalyLevelShift = "level-shift"
+	AnomalyFlatLine   = "flat-line"
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+
+	// DailyPeak is the time of day (UTC) at which the daily cycle of
+	// Patterns peaks.
+	DailyPeak = 14 * time.Hour
+	// WeeklyPeak is the offset from the Unix epoch, a Thursday, at which the
+	// weekly cycle of Patterns peaks: Wednesday noon.
+	WeeklyPeak = 6*day + 12*time.Hour
+)
+
+// SeasonalDistribution adds a sinusoidal cycle to an underlying distribution.
+// The cycle follows the simulated time, i.e. the timestamp of the measurement
+// the distribution belongs to.
+type SeasonalDistribution struct {
+	Dist      Distribution
+	Amplitude float64
+	Period    time.Duration
+	// Peak is the offset from the Unix epoch, modulo Period, at which the
+	// cycle peaks
+	Peak time.Duration
+
+	now *time.Time
+}
+
+// Seasonal returns a new SeasonalDistribution of dist, whose simulated time
+// is read from now.
+func Seasonal(dist Distribution, amplitude float64, period, peak time.Duration, now *time.Time) *SeasonalDistribution {
+	return &SeasonalDistribution{
+		Dist:      dist,
+		Amplitude: amplitude,
+		Period:    period,
+		Peak:      peak,
+		now:       now,
+	}
+}
+
+// Advance advances the underlying distribution.
+func (d *SeasonalDistribution) Advance(r *rand.Rand) {
+	d.Dist.Advance(r)
+}
+
+// Get returns the value of the underlying distribution plus the cycle at the
+// simulated time.
+func (d *SeasonalDistribution) Get() float64 {
+	phase := float64((d.now.UnixNano()-int64(d.Peak))%int64(d.Period)) / float64(d.Period)
+	return d.Dist.Get() + d.Amplitude*math.Cos(2*math.Pi*phase)
+}
+
+// TrendDistribution adds a trend to an underlying distribution, which
+// changes by Rate every Every since the start of the simulation, either
+// continuously or in steps.
+type TrendDistribution struct {
+	Dist  Distribution
+	Rate  float64
+	Every time.Duration
+	// Steps makes the trend change by Rate once every Every instead of
+	// continuously
+	Steps bool
+
+	start time.Time
+	now   *time.Time
+}
+
+// LinearTrend returns a new TrendDistribution of dist that changes linearly
+// by rate every per. The simulated time is read from now, whose value is the
+// start of the trend.
+func LinearTrend(dist Distribution, rate float64, per time.Duration, now *time.Time) *TrendDistribution {
+	return &TrendDistribution{
+		Dist:  dist,
+		Rate:  rate,
+		Every: per,
+		start: *now,
+		now:   now,
+	}
+}
+
+// StepTrend returns a new TrendDistribution of dist that changes by rate once
+// every every. The simulated time is read from now, whose value is the start
+// of the trend.
+func StepTrend(dist Distribution, rate float64, every time.Duration, now *time.Time) *TrendDistribution {
+	d := LinearTrend(dist, rate, every, now)
+	d.Steps = true
+	return d
+}
+
+// Advance advances the underlying distribution.
+func (d *TrendDistribution) Advance(r *rand.Rand) {
+	d.Dist.Advance(r)
+}
+
+// Get returns the value of the underlying distribution plus the trend at the
+// simulated time.
+func (d *TrendDistribution) Get() float64 {
+	n := float64(d.now.Sub(d.start)) / float64(d.Every)
+	if d.Steps {
+		n = math.Floor(n)
+	}
+	return d.Dist.Get() + d.Rate*n
+}
+
+// ClampedDistribution bounds the values of an underlying distribution.
+type ClampedDistribution struct {
+	Dist Distribution
+	Min  float64
+	Max  float64
+}
+
+// Clamp returns a new ClampedDistribution of dist.
+func Clamp(dist Distribution, min, max float64) *ClampedDistribution {
+	return &ClampedDistribution{Dist: dist, Min: min, Max: max}
+}
+
+// Advance advances the underlying distribution.
+func (d *ClampedDistribution) Advance(r *rand.Rand) {
+	d.Dist.Advance(r)
+}
+
+// Get returns the value of the underlying distribution within [Min, Max].
+func (d *ClampedDistribution) Get() float64 {
+	return math.Max(d.Min, math.Min(d.Max, d.Dist.Get()))
+}
+
+// AnomalyConfig are the anomalies injected by an AnomalyDistribution.
+type AnomalyConfig struct {
+	// SpikeRate, LevelShiftRate and FlatLineRate are the probabilities that
+	// an anomaly of the kind starts at a step without anomaly
+	SpikeRate      float64
+	LevelShiftRate float64
+	FlatLineRate   float64
+	// Magnitude is the size of a spike, in either direction; level shifts
+	// are half of it
+	Magnitude float64
+	// Duration is the number of steps of a level shift or flat-line; a spike
+	// lasts one step
+	Duration int
+}
+
+// AnomalySeries identifies the series of an AnomalyDistribution.
+type AnomalySeries struct {
+	Measurement string
+	// Entity is the tag identifying the host, truck... e.g. hostname=host_0
+	Entity string
+	Field  string
+}
+
+// Anomaly is an anomaly injected by an AnomalyDistribution, from Start to End
+// included.
+type Anomaly struct {
+	AnomalySeries
+	Kind  string
+	Start time.Time
+	End   time.Time
+}
+
+// AnomalyLog collects the anomalies injected by AnomalyDistributions, which
+// may be advanced in separate goroutines.
+type AnomalyLog struct {
+	mu        sync.Mutex
+	anomalies []*Anomaly
+}
+
+func (l *AnomalyLog) add(a *Anomaly) {
+	l.mu.Lock()
+	l.anomalies = append(l.anomalies, a)
+	l.mu.Unlock()
+}
+
+// Anomalies returns the logged anomalies sorted by start, measurement,
+// entity and field, which does not depend on the order they were logged in.
+func (l *AnomalyLog) Anomalies() []Anomaly {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	anomalies := make([]Anomaly, len(l.anomalies))
+	for i, a := range l.anomalies {
+		anomalies[i] = *a
+	}
+	sort.Slice(anomalies, func(i, j int) bool {
+		a, b := anomalies[i], anomalies[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		if a.Measurement != b.Measurement {
+			return a.Measurement < b.Measurement
+		}
+		if a.Entity != b.Entity {
+			return a.Entity < b.Entity
+		}
+		return a.Field < b.Field
+	})
+	return anomalies
+}
+
+// WriteCSV writes the logged anomalies to w as CSV, with a header.
+func (l *AnomalyLog) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"start", "end", "measurement", "entity", "field", "kind"}); err != nil {
+		return err
+	}
+	for _, a := range l.Anomalies() {
+		err := cw.Write([]string{
+			a.Start.UTC().Format(time.RFC3339Nano),
+			a.End.UTC().Format(time.RFC3339Nano),
+			a.Measurement,
+			a.Entity,
+			a.Field,
+			a.Kind,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// AnomalyDistribution injects spikes, level shifts and flat-lines into an
+// underlying distribution at random, and logs them with the simulated time.
+type AnomalyDistribution struct {
+	Dist Distribution
+	AnomalyConfig
+
+	series AnomalySeries
+	log    *AnomalyLog
+	now    *time.Time
+
+	current   *Anomaly
+	remaining int
+	offset    float64
+	flat      float64
+}
+
+// Anomalies returns a new AnomalyDistribution of dist, whose simulated time
+// is read from now. The anomalies are logged to log, if not nil.
+func Anomalies(dist Distribution, c AnomalyConfig, series AnomalySeries, log *AnomalyLog, now *time.Time) *AnomalyDistribution {
+	return &AnomalyDistribution{
+		Dist:          dist,
+		AnomalyConfig: c,
+		series:        series,
+		log:           log,
+		now:           now,
+	}
+}
+
+// Advance advances the underlying distribution and the current anomaly, or
+// starts a new one.
+func (d *AnomalyDistribution) Advance(r *rand.Rand) {
+	last := d.Get()
+	d.Dist.Advance(r)
+
+	if d.current != nil {
+		d.remaining--
+		if d.remaining > 0 {
+			d.current.End = *d.now
+			return
+		}
+		d.current = nil
+	}
+
+	x := r.Float64()
+	var kind string
+	switch {
+	case x < d.SpikeRate:
+		kind = AnomalySpike
+	case x < d.SpikeRate+d.LevelShiftRate:
+		kind = AnomalyLevelShift
+	case x < d.SpikeRate+d.LevelShiftRate+d.FlatLineRate:
+		kind = AnomalyFlatLine
+	default:
+		return
+	}
+
+	d.current = &Anomaly{AnomalySeries: d.series, Kind: kind, Start: *d.now, End: *d.now}
+	if d.log != nil {
+		d.log.add(d.current)
+	}
+	d.remaining = d.Duration
+	d.offset = d.Magnitude
+	if r.Intn(2) == 0 {
+		d.offset = -d.offset
+	}
+	switch kind {
+	case AnomalySpike:
+		d.remaining = 1
+	case AnomalyLevelShift:
+		d.offset /= 2
+	case AnomalyFlatLine:
+		d.flat = last
+	}
+}
+
+// Get returns the value of the underlying distribution changed by the
+// current anomaly, if any.
+func (d *AnomalyDistribution) Get() float64 {
+	if d.current == nil {
+		return d.Dist.Get()
+	}
+	if d.current.Kind == AnomalyFlatLine {
+		return d.flat
+	}
+	return d.Dist.Get() + d.offset
+}
+
+// Anomaly returns the kind of the current anomaly, or an empty string if
+// there is none.
+func (d *AnomalyDistribution) Anomaly() string {
+	if d.current == nil {
+		return ""
+	}
+	return d.current.Kind
+}
+
+// Patterns are the seasonality, trend and anomalies added to the fields of
+// the measurements that support them. Amplitudes, trends and magnitudes are
+// fractions of the range of each field.
+type Patterns struct {
+	DailyAmplitude  float64
+	WeeklyAmplitude float64
+	// Trend is the change per day, or per TrendStep if set
+	Trend     float64
+	TrendStep time.Duration
+	Anomalies AnomalyConfig
+	// Log collects the injected anomalies, if not nil
+	Log *AnomalyLog
+}
+
+// Patterned is implemented by the measurements whose fields can follow
+// Patterns.
+type Patterned interface {
+	// ApplyPatterns makes the fields of the measurement of the entity
+	// follow p.
+	ApplyPatterns(p *Patterns, entity Tag)
+}
+
+// Apply makes the measurements in ms of the entity follow p, if p is not
+// nil. The measurements that are not Patterned are left as they are.
+func (p *Patterns) Apply(ms []SimulatedMeasurement, entity Tag) {
+	if p == nil {
+		return
+	}
+	for _, m := range ms {
+		if pm, ok := m.(Patterned); ok {
+			pm.ApplyPatterns(p, entity)
+		}
+	}
+}
+
+// Wrap makes the distribution i of m, a field of the measurement of the
+// entity, follow p. Only clamped random walks, possibly within a
+// FloatPrecision, are wrapped, the range of the walk being the range of the
+// field.
+func (p *Patterns) Wrap(m *SubsystemMeasurement, i int, measurement, field []byte, entity Tag) {
+	series := AnomalySeries{
+		Measurement: string(measurement),
+		Entity:      fmt.Sprintf("%s=%v", entity.Key, entity.Value),
+		Field:       string(field),
+	}
+	m.Distributions[i] = p.wrap(m.Distributions[i], series, &m.Timestamp)
+}
+
+func (p *Patterns) wrap(d Distribution, series AnomalySeries, now *time.Time) Distribution {
+	if fp, ok := d.(*FloatPrecision); ok {
+		return &FloatPrecision{step: p.wrap(fp.step, series, now), precision: fp.precision}
+	}
+	cwd, ok := d.(*ClampedRandomWalkDistribution)
+	if !ok {
+		return d
+	}
+
+	span := cwd.Max - cwd.Min
+	d = cwd
+	if p.Trend != 0 {
+		if p.TrendStep > 0 {
+			d = StepTrend(d, p.Trend*span, p.TrendStep, now)
+		} else {
+			d = LinearTrend(d, p.Trend*span, day, now)
+		}
+	}
+	if p.DailyAmplitude > 0 {
+		d = Seasonal(d, p.DailyAmplitude*span, day, DailyPeak, now)
+	}
+	if p.WeeklyAmplitude > 0 {
+		d = Seasonal(d, p.WeeklyAmplitude*span, week, WeeklyPeak, now)
+	}
+	a := p.Anomalies
+	if a.SpikeRate > 0 || a.LevelShiftRate > 0 || a.FlatLineRate > 0 {
+		a.Magnitude *= span
+		d = Anomalies(d, a, series, p.Log, now)
+	}
+	return Clamp(d, cwd.Min, cwd.Max)
+}
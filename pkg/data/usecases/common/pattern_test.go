@@ -0,0 +1,251 @@
+package common
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeasonalDistribution(t *testing.T) {
+	now := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := Seasonal(&ConstantDistribution{State: 50}, 10, day, DailyPeak, &now)
+
+	cases := []struct {
+		hour int
+		want float64
+	}{
+		{hour: 14, want: 60},
+		{hour: 2, want: 40},
+		{hour: 8, want: 50},
+		{hour: 20, want: 50},
+		{hour: 38, want: 60},
+	}
+	for _, c := range cases {
+		now = time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(c.hour) * time.Hour)
+		if got := d.Get(); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("incorrect value at hour %d: got %f want %f", c.hour, got, c.want)
+		}
+	}
+
+	// a Wednesday noon
+	now = time.Date(2016, 1, 6, 12, 0, 0, 0, time.UTC)
+	w := Seasonal(&ConstantDistribution{}, 1, week, WeeklyPeak, &now)
+	if got := w.Get(); math.Abs(got-1) > 1e-9 {
+		t.Errorf("weekly cycle does not peak on Wednesday noon: got %f", got)
+	}
+
+	m := &mockDistribution{}
+	Seasonal(m, 1, day, 0, &now).Advance(nil)
+	if !m.AdvanceCalled {
+		t.Errorf("Seasonal Advance did not call underlying distribution Advance method")
+	}
+}
+
+func TestTrendDistribution(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	linear := LinearTrend(&ConstantDistribution{State: 1}, 2, day, &now)
+	steps := StepTrend(&ConstantDistribution{State: 1}, 2, day, &now)
+
+	for _, c := range []struct {
+		elapsed    time.Duration
+		wantLinear float64
+		wantSteps  float64
+	}{
+		{elapsed: 0, wantLinear: 1, wantSteps: 1},
+		{elapsed: 12 * time.Hour, wantLinear: 2, wantSteps: 1},
+		{elapsed: day, wantLinear: 3, wantSteps: 3},
+		{elapsed: 36 * time.Hour, wantLinear: 4, wantSteps: 3},
+	} {
+		now = start.Add(c.elapsed)
+		if got := linear.Get(); got != c.wantLinear {
+			t.Errorf("incorrect linear trend after %v: got %f want %f", c.elapsed, got, c.wantLinear)
+		}
+		if got := steps.Get(); got != c.wantSteps {
+			t.Errorf("incorrect step trend after %v: got %f want %f", c.elapsed, got, c.wantSteps)
+		}
+	}
+}
+
+func TestClampedDistribution(t *testing.T) {
+	c := &ConstantDistribution{State: 120}
+	d := Clamp(c, 0, 100)
+	if got := d.Get(); got != 100 {
+		t.Errorf("value not clamped to max: got %f", got)
+	}
+	c.State = -5
+	if got := d.Get(); got != 0 {
+		t.Errorf("value not clamped to min: got %f", got)
+	}
+	c.State = 42
+	if got := d.Get(); got != 42 {
+		t.Errorf("value in range changed: got %f", got)
+	}
+}
+
+func TestAnomalyDistribution(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	series := AnomalySeries{Measurement: "cpu", Entity: "hostname=host_0", Field: "usage_user"}
+
+	cases := []struct {
+		kind string
+		c    AnomalyConfig
+	}{
+		{kind: AnomalySpike, c: AnomalyConfig{SpikeRate: 1, Magnitude: 10, Duration: 3}},
+		{kind: AnomalyLevelShift, c: AnomalyConfig{LevelShiftRate: 1, Magnitude: 10, Duration: 3}},
+		{kind: AnomalyFlatLine, c: AnomalyConfig{FlatLineRate: 1, Magnitude: 10, Duration: 3}},
+	}
+	for _, c := range cases {
+		now := start
+		log := &AnomalyLog{}
+		walk := WD(&ConstantDistribution{State: 1}, 0)
+		d := Anomalies(walk, c.c, series, log, &now)
+		if got := d.Anomaly(); got != "" {
+			t.Errorf("%s: anomaly before the first step: got %s", c.kind, got)
+		}
+
+		r := rand.New(rand.NewSource(123))
+		now = now.Add(time.Minute)
+		d.Advance(r)
+		if got := d.Anomaly(); got != c.kind {
+			t.Fatalf("%s: incorrect anomaly: got %s", c.kind, got)
+		}
+		switch c.kind {
+		case AnomalySpike:
+			if got := math.Abs(d.Get() - walk.Get()); got != 10 {
+				t.Errorf("%s: incorrect offset: got %f want 10", c.kind, got)
+			}
+		case AnomalyLevelShift:
+			if got := math.Abs(d.Get() - walk.Get()); got != 5 {
+				t.Errorf("%s: incorrect offset: got %f want 5", c.kind, got)
+			}
+		case AnomalyFlatLine:
+			for i := 0; i < 2; i++ {
+				if got := d.Get(); got != 0 {
+					t.Errorf("%s: value not flat: got %f want 0", c.kind, got)
+				}
+				now = now.Add(time.Minute)
+				d.Advance(r)
+			}
+		}
+
+		anomalies := log.Anomalies()
+		if len(anomalies) == 0 {
+			t.Fatalf("%s: anomaly not logged", c.kind)
+		}
+		a := anomalies[0]
+		if a.AnomalySeries != series || a.Kind != c.kind || !a.Start.Equal(start.Add(time.Minute)) {
+			t.Errorf("%s: incorrect logged anomaly: got %+v", c.kind, a)
+		}
+		want := start.Add(time.Minute)
+		if c.kind == AnomalyFlatLine {
+			want = start.Add(3 * time.Minute)
+		}
+		if !a.End.Equal(want) {
+			t.Errorf("%s: incorrect end: got %v want %v", c.kind, a.End, want)
+		}
+	}
+
+	// no anomaly with zero rates
+	now := start
+	d := Anomalies(&ConstantDistribution{State: 1}, AnomalyConfig{Magnitude: 10, Duration: 3}, series, nil, &now)
+	r := rand.New(rand.NewSource(123))
+	for i := 0; i < 100; i++ {
+		d.Advance(r)
+		if d.Anomaly() != "" || d.Get() != 1 {
+			t.Fatalf("unexpected anomaly with zero rates: got %s", d.Anomaly())
+		}
+	}
+}
+
+func TestAnomalyLogWriteCSV(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	log := &AnomalyLog{}
+	log.add(&Anomaly{AnomalySeries{"cpu", "hostname=host_1", "usage_user"}, AnomalySpike, start.Add(time.Minute), start.Add(time.Minute)})
+	log.add(&Anomaly{AnomalySeries{"cpu", "hostname=host_1", "usage_idle"}, AnomalyFlatLine, start, start.Add(time.Hour)})
+	log.add(&Anomaly{AnomalySeries{"cpu", "hostname=host_0", "usage_user"}, AnomalyLevelShift, start, start.Add(time.Hour)})
+
+	var buf bytes.Buffer
+	if err := log.WriteCSV(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := strings.Join([]string{
+		"start,end,measurement,entity,field,kind",
+		"2016-01-01T00:00:00Z,2016-01-01T01:00:00Z,cpu,hostname=host_0,usage_user,level-shift",
+		"2016-01-01T00:00:00Z,2016-01-01T01:00:00Z,cpu,hostname=host_1,usage_idle,flat-line",
+		"2016-01-01T00:01:00Z,2016-01-01T00:01:00Z,cpu,hostname=host_1,usage_user,spike",
+	}, "\n") + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect CSV:\ngot\n%swant\n%s", got, want)
+	}
+}
+
+func TestPatternsWrap(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := NewSubsystemMeasurement(start, 3)
+	m.Distributions[0] = CWD(ND(0, 1), 0, 100, 50)
+	m.Distributions[1] = FP(CWD(ND(0, 1), 0, 10, 5), 1)
+	m.Distributions[2] = MWD(ND(0, 1), 0)
+
+	p := &Patterns{
+		DailyAmplitude: 0.2,
+		Trend:          0.1,
+		Anomalies:      AnomalyConfig{SpikeRate: 0.5, Magnitude: 0.5, Duration: 2},
+		Log:            &AnomalyLog{},
+	}
+	host := Tag{Key: []byte("hostname"), Value: "host_0"}
+	for i := range m.Distributions {
+		p.Wrap(m, i, []byte("cpu"), []byte("f"), host)
+	}
+
+	if _, ok := m.Distributions[0].(*ClampedDistribution); !ok {
+		t.Errorf("clamped random walk not wrapped: got %T", m.Distributions[0])
+	}
+	fp, ok := m.Distributions[1].(*FloatPrecision)
+	if !ok {
+		t.Fatalf("float precision not kept: got %T", m.Distributions[1])
+	}
+	if _, ok := fp.step.(*ClampedDistribution); !ok {
+		t.Errorf("random walk within float precision not wrapped: got %T", fp.step)
+	}
+	if _, ok := m.Distributions[2].(*MonotonicRandomWalkDistribution); !ok {
+		t.Errorf("monotonic random walk wrapped: got %T", m.Distributions[2])
+	}
+
+	// at 14:00 the daily cycle peaks
+	m.Timestamp = start.Add(DailyPeak)
+	if got, want := m.Distributions[0].Get(), 50+10*14/24.0+20; math.Abs(got-want) > 1e-9 {
+		t.Errorf("incorrect value at the daily peak: got %f", got)
+	}
+
+	r := rand.New(rand.NewSource(123))
+	for i := 0; i < 100; i++ {
+		m.Tick(time.Hour, r)
+		v := m.Distributions[0].Get()
+		if v < 0 || v > 100 {
+			t.Fatalf("value out of range: got %f", v)
+		}
+		if v := m.Distributions[1].Get(); v*10 != math.Trunc(v*10) {
+			t.Fatalf("value does not have the float precision: got %f", v)
+		}
+	}
+	anomalies := p.Log.Anomalies()
+	if len(anomalies) == 0 {
+		t.Fatalf("no anomaly logged")
+	}
+	if got := anomalies[0].Entity; got != "hostname=host_0" {
+		t.Errorf("incorrect entity: got %s", got)
+	}
+
+	// nil patterns do nothing
+	var nilPatterns *Patterns
+	nilPatterns.Apply([]SimulatedMeasurement{}, host)
+}
+
+func TestPatternConfigYAMLNames(t *testing.T) {
+	checkYAMLMatchesMapstructure(t, PatternConfig{})
+	checkInlinedInYAML(t, "PatternConfig")
+}
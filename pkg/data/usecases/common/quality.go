@@ -0,0 +1,192 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+)
+
+// Distributions of the delays of a Delay.
+const (
+	DelayConstant    = "constant"
+	DelayUniform     = "uniform"
+	DelayExponential = "exponential"
+)
+
+// DelayDistributionChoices are the distributions a Delay can follow.
+var DelayDistributionChoices = []string{DelayConstant, DelayUniform, DelayExponential}
+
+// Delay is a distribution of delays with a mean.
+type Delay struct {
+	// Distribution is one of DelayDistributionChoices, exponential if empty
+	Distribution string
+	Mean         time.Duration
+}
+
+// Draw returns a delay drawn from r: Mean itself if the distribution is
+// constant, uniform in [0, 2*Mean) if uniform, exponential with mean Mean
+// otherwise.
+func (d Delay) Draw(r *rand.Rand) time.Duration {
+	switch d.Distribution {
+	case DelayConstant:
+		return d.Mean
+	case DelayUniform:
+		return time.Duration(r.Float64() * 2 * float64(d.Mean))
+	default:
+		return time.Duration(r.ExpFloat64() * float64(d.Mean))
+	}
+}
+
+// Quality is the profile of the disorder injected in the IoT data: missing,
+// out-of-order and zero values in batches of entries, and on top of them,
+// late arrivals, duplicates and outages of whole fleets.
+//
+// The arrival time of an entry is the latest timestamp of the entries
+// simulated so far, i.e. the simulated wall time at which the entry is sent.
+type Quality struct {
+	// BatchSize is the number of entries of a batch, 0 for no batches, and
+	// so none of the disorder of the batches
+	BatchSize uint
+
+	// Chances that a batch is missing, is held back to be inserted later
+	// out of order, or is replaced by a batch held back before.
+	MissingBatchChance        float64
+	OutOfOrderBatchChance     float64
+	InsertPreviousBatchChance float64
+
+	// Chances that an entry of a batch is missing, is held back to be
+	// inserted later out of order, or is preceded by an entry held back
+	// before.
+	MissingEntryChance        float64
+	OutOfOrderEntryChance     float64
+	InsertPreviousEntryChance float64
+
+	// Chances that a tag or a field of an entry has no value.
+	ZeroTagChance   float64
+	ZeroFieldChance float64
+
+	// LateChance is the chance that an entry arrives LateDelay after its
+	// arrival time
+	LateChance float64
+	LateDelay  Delay
+	// DuplicateChance is the chance that an entry is sent again, LateDelay
+	// after it if LateDelay has a mean, right after it otherwise
+	DuplicateChance float64
+
+	// OutageInterval is the mean time between the outages of a fleet, during
+	// which its trucks send nothing, or 0 for no outages. The outages last
+	// OutageDuration.
+	OutageInterval time.Duration
+	OutageDuration time.Duration
+	// OutageBackfill tells whether the entries of a fleet are sent at the end
+	// of its outages, or are lost
+	OutageBackfill bool
+}
+
+// DefaultQuality is the Quality of the IoT use case unless told otherwise.
+var DefaultQuality = Quality{
+	BatchSize: 10,
+
+	MissingBatchChance:        0.01,
+	OutOfOrderBatchChance:     0.05,
+	InsertPreviousBatchChance: 0.5,
+
+	MissingEntryChance:        0.1,
+	OutOfOrderEntryChance:     0.3,
+	InsertPreviousEntryChance: 0.5,
+
+	ZeroTagChance:   0.01,
+	ZeroFieldChance: 0.1,
+
+	LateDelay:      Delay{Distribution: DelayExponential},
+	OutageBackfill: true,
+}
+
+// Disordered tells whether q injects late arrivals, duplicates or outages.
+func (q *Quality) Disordered() bool {
+	return q.LateChance > 0 || q.DuplicateChance > 0 || q.OutageInterval > 0
+}
+
+// Outage is a time range during which the trucks of a fleet send nothing.
+type Outage struct {
+	Fleet      string
+	Start, End time.Time
+	// Entries is the number of entries held back, or lost, by the outage
+	Entries uint64
+}
+
+// QualityReport counts the disorder injected by a Quality.
+type QualityReport struct {
+	// Entries is the number of entries sent, duplicates included
+	Entries uint64
+
+	MissingBatches       uint64
+	MissingBatchEntries  uint64
+	OutOfOrderBatches    uint64
+	MissingEntries       uint64
+	OutOfOrderEntries    uint64
+	ZeroTags, ZeroFields uint64
+
+	LateEntries   uint64
+	TotalLateness time.Duration
+	MaxLateness   time.Duration
+	Duplicates    uint64
+	// Outages are the outages that held back, or lost, entries, in the
+	// order they held back their first entry
+	Outages []*Outage
+	// Backfilled tells whether the entries of the outages were sent at
+	// their end
+	Backfilled bool
+}
+
+// QualityReporter is implemented by simulators that inject disorder in the
+// data.
+type QualityReporter interface {
+	QualityReport() *QualityReport
+}
+
+// Write writes a summary of the injected disorder to w.
+func (r *QualityReport) Write(w io.Writer) error {
+	meanLateness := time.Duration(0)
+	if r.LateEntries > 0 {
+		meanLateness = r.TotalLateness / time.Duration(r.LateEntries)
+	}
+	outageEntries := uint64(0)
+	for _, o := range r.Outages {
+		outageEntries += o.Entries
+	}
+	outageFate := "lost"
+	if r.Backfilled {
+		outageFate = "backfilled"
+	}
+
+	lines := []struct {
+		label string
+		value interface{}
+	}{
+		{"entries sent", r.Entries},
+		{"missing batches", fmt.Sprintf("%d (%d entries)", r.MissingBatches, r.MissingBatchEntries)},
+		{"out-of-order batches", r.OutOfOrderBatches},
+		{"missing entries", r.MissingEntries},
+		{"out-of-order entries", r.OutOfOrderEntries},
+		{"zero tags", r.ZeroTags},
+		{"zero fields", r.ZeroFields},
+		{"late entries", fmt.Sprintf("%d (mean delay %v, max delay %v)", r.LateEntries, meanLateness, r.MaxLateness)},
+		{"duplicates", r.Duplicates},
+		{"outages", fmt.Sprintf("%d (%d entries %s)", len(r.Outages), outageEntries, outageFate)},
+	}
+	for _, l := range lines {
+		if _, err := fmt.Fprintf(w, "%-22s%v\n", l.label+":", l.value); err != nil {
+			return err
+		}
+	}
+	for _, o := range r.Outages {
+		_, err := fmt.Fprintf(w, "  fleet=%s %s - %s: %d entries\n", o.Fleet,
+			o.Start.UTC().Format(time.RFC3339), o.End.UTC().Format(time.RFC3339), o.Entries)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package common
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// checkYAMLMatchesMapstructure checks that the fields of the struct type of
+// v have the same names in YAML as in the mapstructure keys read from flags.
+func checkYAMLMatchesMapstructure(t *testing.T, v interface{}) {
+	typ := reflect.TypeOf(v)
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		yamlTag, msTag := f.Tag.Get("yaml"), f.Tag.Get("mapstructure")
+		if msTag == ",squash" {
+			if yamlTag != ",inline" {
+				t.Errorf("%s.%s: squashed field is not inlined in YAML: got yaml:%q", typ.Name(), f.Name, yamlTag)
+			}
+			continue
+		}
+		if yamlTag != msTag {
+			t.Errorf("%s.%s: incorrect YAML name: got %q want %q", typ.Name(), f.Name, yamlTag, msTag)
+		}
+	}
+}
+
+// checkInlinedInYAML checks that the field name of DataGeneratorConfig, which
+// is squashed in the mapstructure keys, is inlined in YAML.
+func checkInlinedInYAML(t *testing.T, name string) {
+	f, _ := reflect.TypeOf(DataGeneratorConfig{}).FieldByName(name)
+	if got := f.Tag.Get("yaml"); got != ",inline" {
+		t.Errorf("%s is not inlined in YAML: got yaml:%q", name, got)
+	}
+}
+
+func TestQualityConfigYAMLNames(t *testing.T) {
+	checkYAMLMatchesMapstructure(t, QualityConfig{})
+	checkInlinedInYAML(t, "QualityConfig")
+}
+
+func TestDelayDraw(t *testing.T) {
+	cases := []struct {
+		distribution string
+		max          time.Duration
+	}{
+		{distribution: DelayConstant, max: time.Minute},
+		{distribution: DelayUniform, max: 2 * time.Minute},
+		{distribution: DelayExponential, max: time.Duration(math.MaxInt64)},
+		{distribution: "", max: time.Duration(math.MaxInt64)},
+	}
+	for _, c := range cases {
+		r := NewRand(123)
+		d := Delay{Distribution: c.distribution, Mean: time.Minute}
+		const draws = 10000
+		total := time.Duration(0)
+		for i := 0; i < draws; i++ {
+			delay := d.Draw(r)
+			if delay < 0 || delay > c.max {
+				t.Fatalf("%s: delay out of range: got %v", c.distribution, delay)
+			}
+			total += delay
+		}
+		if mean := total / draws; mean < 55*time.Second || mean > 65*time.Second {
+			t.Errorf("%s: incorrect mean delay: got %v want %v", c.distribution, mean, time.Minute)
+		}
+	}
+}
+
+func TestQualityConfigNewQuality(t *testing.T) {
+	c := &QualityConfig{}
+	if got := c.NewQuality(); *got != DefaultQuality {
+		t.Errorf("empty config is not the default quality: got %+v", got)
+	}
+	c = &QualityConfig{LateChance: 0.5, LateDelay: time.Minute, OutageInterval: time.Hour, OutageDuration: time.Minute}
+	q := c.NewQuality()
+	if q.BatchSize != 0 || q.LateChance != 0.5 || q.LateDelay.Mean != time.Minute || !q.Disordered() {
+		t.Errorf("incorrect quality: got %+v", q)
+	}
+}
+
+func TestQualityReportWrite(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := &QualityReport{
+		Entries:       100,
+		LateEntries:   2,
+		TotalLateness: 3 * time.Minute,
+		MaxLateness:   2 * time.Minute,
+		Outages: []*Outage{
+			{Fleet: "East", Start: start, End: start.Add(time.Hour), Entries: 7},
+			{Fleet: "West", Start: start.Add(time.Hour), End: start.Add(2 * time.Hour), Entries: 3},
+		},
+		Backfilled: true,
+	}
+	buf := &bytes.Buffer{}
+	if err := r.Write(buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"entries sent:         100\n",
+		"late entries:         2 (mean delay 1m30s, max delay 2m0s)\n",
+		"outages:              2 (10 entries backfilled)\n",
+		"  fleet=East 2016-01-01T00:00:00Z - 2016-01-01T01:00:00Z: 7 entries\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report does not contain %q: got\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package common
+
+import "math/rand"
+
+// splitMix64 is a rand.Source64 with a single word of state, so every
+// simulated entity can afford its own source. The rand.NewSource source keeps
+// several kilobytes of state.
+type splitMix64 struct {
+	state uint64
+}
+
+func (s *splitMix64) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+func (s *splitMix64) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (s *splitMix64) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// NewRand returns a PRNG seeded with seed.
+func NewRand(seed int64) *rand.Rand {
+	return rand.New(&splitMix64{state: uint64(seed)})
+}
+
+// EntitySeed derives the seed of the simulated entity (host, truck...) with
+// the given id from the seed of the simulation, so that the values of an
+// entity do not depend on which other entities are simulated, or in which
+// order.
+func EntitySeed(seed int64, id int) int64 {
+	s := splitMix64{state: uint64(seed)}
+	s.state ^= s.Uint64() + uint64(id)
+	return int64(s.Uint64())
+}
@@ -0,0 +1,32 @@
+package common
+
+import "testing"
+
+func TestNewRand(t *testing.T) {
+	a, b := NewRand(42), NewRand(42)
+	for i := 0; i < 100; i++ {
+		if x, y := a.Float64(), b.Float64(); x != y {
+			t.Fatalf("PRNGs with the same seed differ at %d: %f and %f", i, x, y)
+		}
+	}
+	if NewRand(42).Int63() == NewRand(43).Int63() {
+		t.Errorf("PRNGs with different seeds are the same")
+	}
+}
+
+func TestEntitySeed(t *testing.T) {
+	seeds := map[int64]int{}
+	for id := 0; id < 1000; id++ {
+		s := EntitySeed(7, id)
+		if s != EntitySeed(7, id) {
+			t.Errorf("seed of entity %d not deterministic", id)
+		}
+		if other, ok := seeds[s]; ok {
+			t.Errorf("entities %d and %d have the same seed", other, id)
+		}
+		seeds[s] = id
+	}
+	if EntitySeed(7, 0) == EntitySeed(8, 0) {
+		t.Errorf("seed of entity does not depend on the seed of the simulation")
+	}
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"github.com/timescale/tsbs/pkg/data"
+	"math/rand"
 	"reflect"
 	"time"
 )
@@ -21,8 +22,23 @@ type BaseSimulatorConfig struct {
 	InitGeneratorScale uint64
 	// GeneratorScale is the total number of Generators to have in the last reporting period
 	GeneratorScale uint64
-	// GeneratorConstructor is the function used to create a new Generator given an id number and start time
-	GeneratorConstructor func(i int, start time.Time) Generator
+	// GeneratorConstructor is the function used to create a new Generator given an id number, start time
+	// and the PRNG of the Generator
+	GeneratorConstructor func(i int, start time.Time, r *rand.Rand) Generator
+	// Seed is the seed of the simulation, from which the PRNG of each
+	// Generator is seeded
+	Seed int64
+	// Workers is the number of goroutines simulating the Generators, for
+	// simulators that support it
+	Workers int
+	// Patterns are the seasonality, trend and anomalies of the Patterned
+	// measurements, if not nil
+	Patterns *Patterns
+	// Churn are the changes of the Generators over time, if not nil
+	Churn *Churn
+	// Quality is the disorder injected in the data, for simulators that
+	// support it, DefaultQuality if nil
+	Quality *Quality
 }
 
 func calculateEpochs(duration time.Duration, interval time.Duration) uint64 {
@@ -31,13 +47,25 @@ func calculateEpochs(duration time.Duration, interval time.Duration) uint64 {
 
 // NewSimulator produces a Simulator that conforms to the given config over the specified interval.
 func (sc *BaseSimulatorConfig) NewSimulator(interval time.Duration, limit uint64) Simulator {
+	newGenerator := func(i int, start time.Time, r *rand.Rand) Generator {
+		g := sc.GeneratorConstructor(i, start, r)
+		sc.Patterns.Apply(g.Measurements(), g.Tags()[0])
+		return g
+	}
 	generators := make([]Generator, sc.GeneratorScale)
+	rands := make([]*rand.Rand, sc.GeneratorScale)
 	for i := 0; i < len(generators); i++ {
-		generators[i] = sc.GeneratorConstructor(i, sc.Start)
+		rands[i] = NewRand(EntitySeed(sc.Seed, i))
+		generators[i] = newGenerator(i, sc.Start, rands[i])
+	}
+	var churn *EntityChurn
+	if sc.Churn != nil {
+		churn = NewEntityChurn(sc.Churn, sc.Seed, rands)
 	}
 
+	measurements := len(generators[0].Measurements())
 	epochs := calculateEpochs(sc.End.Sub(sc.Start), interval)
-	maxPoints := epochs * sc.GeneratorScale * uint64(len(generators[0].Measurements()))
+	maxPoints := epochs * sc.GeneratorScale * uint64(measurements)
 	if limit > 0 && limit < maxPoints {
 		// Set specified points number limit
 		maxPoints = limit
@@ -48,6 +76,10 @@ func (sc *BaseSimulatorConfig) NewSimulator(interval time.Duration, limit uint64
 
 		generatorIndex: 0,
 		generators:     generators,
+		rands:          rands,
+		hi:             uint64(len(generators)),
+		newGenerator:   newGenerator,
+		churn:          churn,
 
 		epoch:           0,
 		epochs:          epochs,
@@ -58,6 +90,7 @@ func (sc *BaseSimulatorConfig) NewSimulator(interval time.Duration, limit uint64
 		interval:        interval,
 
 		simulatedMeasurementIndex: 0,
+		measurements:              measurements,
 	}
 
 	return sim
@@ -86,6 +119,12 @@ type BaseSimulator struct {
 
 	generatorIndex uint64
 	generators     []Generator
+	rands          []*rand.Rand
+	// the simulated generators are [lo, hi)
+	lo, hi uint64
+	// newGenerator creates the Generators replacing the churned ones
+	newGenerator func(i int, start time.Time, r *rand.Rand) Generator
+	churn        *EntityChurn
 
 	epoch           uint64
 	epochs          uint64
@@ -97,6 +136,10 @@ type BaseSimulator struct {
 	interval       time.Duration
 
 	simulatedMeasurementIndex int
+	// measurements is the number of measurements of every generator, which
+	// is not read from the generators, since churned generators are
+	// replaced while other partitions simulate theirs
+	measurements int
 }
 
 // Finished tells whether we have simulated all the necessary points.
@@ -106,16 +149,17 @@ func (s *BaseSimulator) Finished() bool {
 
 // Next advances a Point to the next state in the distributionGenerator.
 func (s *BaseSimulator) Next(p *data.Point) bool {
-	if s.generatorIndex == uint64(len(s.generators)) {
-		s.generatorIndex = 0
+	if s.generatorIndex == s.hi {
+		s.generatorIndex = s.lo
 		s.simulatedMeasurementIndex++
 	}
 
-	if s.simulatedMeasurementIndex == len(s.generators[0].Measurements()) {
+	if s.simulatedMeasurementIndex == s.measurements {
 		s.simulatedMeasurementIndex = 0
 
-		for i := 0; i < len(s.generators); i++ {
-			s.generators[i].TickAll(s.interval)
+		for i := s.lo; i < s.hi; i++ {
+			s.generators[i].TickAll(s.interval, s.rands[i])
+			s.churnGenerator(i)
 		}
 
 		s.adjustNumHostsForEpoch()
@@ -127,6 +171,9 @@ func (s *BaseSimulator) Next(p *data.Point) bool {
 	for _, tag := range generator.Tags() {
 		p.AppendTag(tag.Key, tag.Value)
 	}
+	if tag, ok := s.churn.Tag(int(s.generatorIndex)); ok {
+		p.AppendTag(tag.Key, tag.Value)
+	}
 
 	// Populate measurement-specific tags and fields:
 	generator.Measurements()[s.simulatedMeasurementIndex].ToPoint(p)
@@ -134,9 +181,44 @@ func (s *BaseSimulator) Next(p *data.Point) bool {
 	ret := s.generatorIndex < s.epochGenerators
 	s.madePoints++
 	s.generatorIndex++
+	if s.generatorIndex == s.hi {
+		// skip the points of the generators of other partitions
+		s.madePoints += uint64(len(s.generators)) - s.hi + s.lo
+	}
 	return ret
 }
 
+// churnGenerator replaces the generator i, or changes its tags, according to
+// the churn of s, after it was ticked.
+func (s *BaseSimulator) churnGenerator(i uint64) {
+	if s.churn == nil {
+		return
+	}
+	tags, _ := s.generators[i].(TagChanger)
+	if !s.churn.Tick(int(i), s.interval, s.rands[i], tags) {
+		return
+	}
+	// the new generator starts at the reading the others were ticked to
+	start := s.timestampStart.Add(time.Duration(s.epoch+1) * s.interval)
+	s.rands[i] = s.churn.Replace(int(i), func(id int, r *rand.Rand) {
+		s.generators[i] = s.newGenerator(id, start, r)
+	})
+}
+
+// Generators returns the number of generators of the simulator.
+func (s *BaseSimulator) Generators() int {
+	return len(s.generators)
+}
+
+// Partition returns a simulator of the generators [lo, hi) of s.
+func (s *BaseSimulator) Partition(lo, hi int) Simulator {
+	p := *s
+	p.lo, p.hi = uint64(lo), uint64(hi)
+	p.generatorIndex = p.lo
+	p.madePoints = s.madePoints + p.lo
+	return &p
+}
+
 // Fields returns all the simulated measurements for the device.
 func (s *BaseSimulator) Fields() map[string][]string {
 	if len(s.generators) <= 0 {
@@ -169,6 +251,9 @@ func (s *BaseSimulator) TagKeys() []string {
 	for i, tag := range tags {
 		tagKeys[i] = string(tag.Key)
 	}
+	if tag, ok := s.churn.Tag(0); ok {
+		tagKeys = append(tagKeys, string(tag.Key))
+	}
 
 	return tagKeys
 }
@@ -184,6 +269,9 @@ func (s *BaseSimulator) TagTypes() []string {
 	for i, tag := range tags {
 		types[i] = reflect.TypeOf(tag.Value).String()
 	}
+	if tag, ok := s.churn.Tag(0); ok {
+		types = append(types, reflect.TypeOf(tag.Value).String())
+	}
 
 	return types
 }
@@ -213,6 +301,6 @@ func (s *BaseSimulator) adjustNumHostsForEpoch() {
 
 // SimulatedMeasurement simulates one measurement (e.g. Redis for DevOps).
 type SimulatedMeasurement interface {
-	Tick(time.Duration)
+	Tick(time.Duration, *rand.Rand)
 	ToPoint(*data.Point)
 }
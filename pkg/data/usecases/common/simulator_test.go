@@ -3,6 +3,7 @@ package common
 import (
 	"fmt"
 	"github.com/timescale/tsbs/pkg/data"
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -56,10 +57,10 @@ func (d dummyGenerator) Tags() []Tag {
 	return tags
 }
 
-func (d dummyGenerator) TickAll(duration time.Duration) {
+func (d dummyGenerator) TickAll(duration time.Duration, r *rand.Rand) {
 }
 
-func dummyGeneratorConstructor(i int, start time.Time) Generator {
+func dummyGeneratorConstructor(i int, start time.Time, r *rand.Rand) Generator {
 	return &dummyGenerator{}
 }
 
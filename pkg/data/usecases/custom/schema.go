@@ -0,0 +1,350 @@
+package custom
+
+import (
+	"fmt"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"math/rand"
+	"time"
+)
+
+// Distribution types of a DistributionSpec, named after the common.Distribution
+// constructors.
+const (
+	DistributionND       = "nd"
+	DistributionUD       = "ud"
+	DistributionWD       = "wd"
+	DistributionCWD      = "cwd"
+	DistributionMWD      = "mwd"
+	DistributionLD       = "ld"
+	DistributionFP       = "fp"
+	DistributionConstant = "constant"
+
+	defaultEntityTag = "name"
+	entityNameFmt    = "%s_%d"
+	// paths of the distributions in validation errors
+	fieldPathFmt   = "measurement '%s': field '%s'"
+	wrappedPathFmt = "%s.%s"
+)
+
+const (
+	errCannotReadSchemaFmt  = "cannot read custom use case schema '%s': %v"
+	errCannotParseSchemaFmt = "cannot parse custom use case schema '%s': %v"
+	errNoEntityName         = "entity name cannot be empty"
+	errNegativeInterval     = "interval cannot be negative"
+	errNoMeasurements       = "schema has no measurements"
+	errEmptyTagKeyFmt       = "tag %d: key cannot be empty"
+	errDuplicateTagKeyFmt   = "tag '%s': duplicate key"
+	errNoTagValuesFmt       = "tag '%s': no values"
+	errTagWeightCountFmt    = "tag '%s': %d weights for %d values"
+	errBadTagWeightsFmt     = "tag '%s': weights must be non-negative with a positive sum"
+	errEmptyMeasurementFmt  = "measurement %d: name cannot be empty"
+	errDuplicateMeasureFmt  = "measurement '%s': duplicate name"
+	errNoFieldsFmt          = "measurement '%s': no fields"
+	errEmptyFieldFmt        = "measurement '%s': field %d: name cannot be empty"
+	errDuplicateFieldFmt    = "measurement '%s': field '%s': duplicate name"
+	errUnknownDistFmt       = "%s: unknown distribution type '%s'"
+	errMissingDistFmt       = "%s: missing %s distribution"
+	errNegativeStdDevFmt    = "%s: stddev cannot be negative"
+	errBadUniformBoundsFmt  = "%s: low cannot be greater than high"
+	errBadClampBoundsFmt    = "%s: min cannot be greater than max"
+	errStateOutOfBoundsFmt  = "%s: state must be between min and max"
+	errBadPrecisionFmt      = "%s: precision must be between 0 and 5"
+)
+
+// Schema describes a custom use case: the entities to simulate, their tags
+// and the measurements they report.
+type Schema struct {
+	Entity       EntitySpec        `yaml:"entity"`
+	Tags         []TagSpec         `yaml:"tags"`
+	Measurements []MeasurementSpec `yaml:"measurements"`
+	// Interval is the time between two readings of an entity, which
+	// overrides the log interval of the generator if set
+	Interval time.Duration `yaml:"interval"`
+}
+
+// EntitySpec describes the simulated entities, e.g. sensors.
+type EntitySpec struct {
+	// Name is the prefix of the name of each entity, which is followed by
+	// the id of the entity
+	Name string `yaml:"name"`
+	// Tag is the key of the tag holding the name of the entity, "name" by default
+	Tag string `yaml:"tag"`
+	// Count is the number of entities, which overrides the scale of the
+	// generator if set
+	Count uint64 `yaml:"count"`
+}
+
+// TagSpec describes a tag of the entities, whose value is chosen at random
+// for each entity.
+type TagSpec struct {
+	Key    string   `yaml:"key"`
+	Values []string `yaml:"values"`
+	// Weights are the relative probabilities of the values, which are
+	// equally likely if there are no weights
+	Weights []float64 `yaml:"weights"`
+}
+
+// MeasurementSpec describes a measurement reported by every entity at every
+// interval.
+type MeasurementSpec struct {
+	Name   string      `yaml:"name"`
+	Fields []FieldSpec `yaml:"fields"`
+}
+
+// FieldSpec describes a field of a measurement, whose value is simulated by
+// its distribution.
+type FieldSpec struct {
+	Name string `yaml:"name"`
+	// Integer reports the value as an integer instead of a float
+	Integer      bool             `yaml:"integer"`
+	Distribution DistributionSpec `yaml:"distribution"`
+}
+
+// DistributionSpec describes a common.Distribution and its parameters. Which
+// parameters are used depends on the Type:
+//
+//	nd:       mean, stddev
+//	ud:       low, high
+//	wd:       step, state
+//	cwd:      step, min, max, state (random between min and max if not set)
+//	mwd:      step, state
+//	ld:       motive, step, threshold
+//	fp:       dist, precision
+//	constant: state
+type DistributionSpec struct {
+	Type string `yaml:"type"`
+
+	Mean   float64 `yaml:"mean"`
+	StdDev float64 `yaml:"stddev"`
+
+	Low  float64 `yaml:"low"`
+	High float64 `yaml:"high"`
+
+	Step  *DistributionSpec `yaml:"step"`
+	Min   float64           `yaml:"min"`
+	Max   float64           `yaml:"max"`
+	State *float64          `yaml:"state"`
+
+	Motive    *DistributionSpec `yaml:"motive"`
+	Threshold float64           `yaml:"threshold"`
+
+	Dist      *DistributionSpec `yaml:"dist"`
+	Precision int               `yaml:"precision"`
+}
+
+// LoadSchema reads and validates the schema in the YAML file at path.
+func LoadSchema(path string) (*Schema, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(errCannotReadSchemaFmt, path, err)
+	}
+	s, err := ParseSchema(b)
+	if err != nil {
+		return nil, fmt.Errorf(errCannotParseSchemaFmt, path, err)
+	}
+	return s, nil
+}
+
+// ParseSchema parses and validates a schema from YAML.
+func ParseSchema(b []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := yaml.UnmarshalStrict(b, s); err != nil {
+		return nil, err
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate checks that the schema describes a valid use case, and sets the
+// defaults of unset values.
+func (s *Schema) Validate() error {
+	if s.Entity.Name == "" {
+		return fmt.Errorf(errNoEntityName)
+	}
+	if s.Entity.Tag == "" {
+		s.Entity.Tag = defaultEntityTag
+	}
+	if s.Interval < 0 {
+		return fmt.Errorf(errNegativeInterval)
+	}
+
+	keys := map[string]bool{s.Entity.Tag: true}
+	for i := range s.Tags {
+		if err := s.Tags[i].validate(i, keys); err != nil {
+			return err
+		}
+	}
+
+	if len(s.Measurements) == 0 {
+		return fmt.Errorf(errNoMeasurements)
+	}
+	names := map[string]bool{}
+	for i := range s.Measurements {
+		if err := s.Measurements[i].validate(i, names); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *TagSpec) validate(i int, keys map[string]bool) error {
+	if t.Key == "" {
+		return fmt.Errorf(errEmptyTagKeyFmt, i)
+	}
+	if keys[t.Key] {
+		return fmt.Errorf(errDuplicateTagKeyFmt, t.Key)
+	}
+	keys[t.Key] = true
+	if len(t.Values) == 0 {
+		return fmt.Errorf(errNoTagValuesFmt, t.Key)
+	}
+	if len(t.Weights) == 0 {
+		return nil
+	}
+	if len(t.Weights) != len(t.Values) {
+		return fmt.Errorf(errTagWeightCountFmt, t.Key, len(t.Weights), len(t.Values))
+	}
+	sum := 0.0
+	for _, w := range t.Weights {
+		if w < 0 {
+			return fmt.Errorf(errBadTagWeightsFmt, t.Key)
+		}
+		sum += w
+	}
+	if sum <= 0 {
+		return fmt.Errorf(errBadTagWeightsFmt, t.Key)
+	}
+	return nil
+}
+
+// choose returns a value of the tag drawn from r according to the weights.
+func (t *TagSpec) choose(r *rand.Rand) string {
+	if len(t.Weights) == 0 {
+		return common.RandomStringSliceChoice(t.Values, r)
+	}
+	sum := 0.0
+	for _, w := range t.Weights {
+		sum += w
+	}
+	x := r.Float64() * sum
+	for i, w := range t.Weights {
+		if x < w {
+			return t.Values[i]
+		}
+		x -= w
+	}
+	// rounding errors may leave x at the sum, past the last value
+	for i := len(t.Weights) - 1; ; i-- {
+		if t.Weights[i] > 0 {
+			return t.Values[i]
+		}
+	}
+}
+
+func (m *MeasurementSpec) validate(i int, names map[string]bool) error {
+	if m.Name == "" {
+		return fmt.Errorf(errEmptyMeasurementFmt, i)
+	}
+	if names[m.Name] {
+		return fmt.Errorf(errDuplicateMeasureFmt, m.Name)
+	}
+	names[m.Name] = true
+	if len(m.Fields) == 0 {
+		return fmt.Errorf(errNoFieldsFmt, m.Name)
+	}
+	fields := map[string]bool{}
+	for j, f := range m.Fields {
+		if f.Name == "" {
+			return fmt.Errorf(errEmptyFieldFmt, m.Name, j)
+		}
+		if fields[f.Name] {
+			return fmt.Errorf(errDuplicateFieldFmt, m.Name, f.Name)
+		}
+		fields[f.Name] = true
+		if err := f.Distribution.validate(fmt.Sprintf(fieldPathFmt, m.Name, f.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks the parameters of the distribution, whose position in the
+// schema is described by path.
+func (d *DistributionSpec) validate(path string) error {
+	switch d.Type {
+	case DistributionND:
+		if d.StdDev < 0 {
+			return fmt.Errorf(errNegativeStdDevFmt, path)
+		}
+	case DistributionUD:
+		if d.Low > d.High {
+			return fmt.Errorf(errBadUniformBoundsFmt, path)
+		}
+	case DistributionWD, DistributionMWD:
+		return validateWrapped(path, "step", d.Step)
+	case DistributionCWD:
+		if d.Min > d.Max {
+			return fmt.Errorf(errBadClampBoundsFmt, path)
+		}
+		if d.State != nil && (*d.State < d.Min || *d.State > d.Max) {
+			return fmt.Errorf(errStateOutOfBoundsFmt, path)
+		}
+		return validateWrapped(path, "step", d.Step)
+	case DistributionLD:
+		if err := validateWrapped(path, "motive", d.Motive); err != nil {
+			return err
+		}
+		return validateWrapped(path, "step", d.Step)
+	case DistributionFP:
+		if d.Precision < 0 || d.Precision > 5 {
+			return fmt.Errorf(errBadPrecisionFmt, path)
+		}
+		return validateWrapped(path, "dist", d.Dist)
+	case DistributionConstant:
+	default:
+		return fmt.Errorf(errUnknownDistFmt, path, d.Type)
+	}
+	return nil
+}
+
+func validateWrapped(path, name string, d *DistributionSpec) error {
+	if d == nil {
+		return fmt.Errorf(errMissingDistFmt, path, name)
+	}
+	return d.validate(fmt.Sprintf(wrappedPathFmt, path, name))
+}
+
+// state returns the initial state of the distribution, or def if not set.
+func (d *DistributionSpec) state(def float64) float64 {
+	if d.State == nil {
+		return def
+	}
+	return *d.State
+}
+
+// New returns a new distribution of the spec, whose initial state is drawn
+// from r if it is not set. The spec must be valid.
+func (d *DistributionSpec) New(r *rand.Rand) common.Distribution {
+	switch d.Type {
+	case DistributionND:
+		return common.ND(d.Mean, d.StdDev)
+	case DistributionUD:
+		return common.UD(d.Low, d.High)
+	case DistributionWD:
+		return common.WD(d.Step.New(r), d.state(0))
+	case DistributionCWD:
+		return common.CWD(d.Step.New(r), d.Min, d.Max, d.state(d.Min+r.Float64()*(d.Max-d.Min)))
+	case DistributionMWD:
+		return common.MWD(d.Step.New(r), d.state(0))
+	case DistributionLD:
+		return common.LD(d.Motive.New(r), d.Step.New(r), d.Threshold)
+	case DistributionFP:
+		return common.FP(d.Dist.New(r), d.Precision)
+	default:
+		return &common.ConstantDistribution{State: d.state(0)}
+	}
+}
@@ -0,0 +1,270 @@
+package custom
+
+import (
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSchema = `
+entity:
+  name: sensor
+  count: 4
+interval: 30s
+tags:
+- key: site
+  values: [north, south]
+  weights: [3, 1]
+- key: kind
+  values: [a, b, c]
+measurements:
+- name: env
+  fields:
+  - name: temperature
+    distribution:
+      type: fp
+      precision: 1
+      dist:
+        type: cwd
+        min: -10
+        max: 40
+        step: {type: nd, mean: 0, stddev: 0.5}
+  - name: humidity
+    integer: true
+    distribution:
+      type: cwd
+      min: 0
+      max: 100
+      state: 50
+      step: {type: ud, low: -1, high: 1}
+- name: power
+  fields:
+  - name: load
+    distribution:
+      type: ld
+      threshold: 0.5
+      motive: {type: ud, low: 0, high: 1}
+      step: {type: wd, step: {type: nd, stddev: 1}}
+  - name: total
+    distribution:
+      type: mwd
+      step: {type: ud, low: 0, high: 5}
+  - name: version
+    distribution: {type: constant, state: 2}
+`
+
+func TestParseSchema(t *testing.T) {
+	s, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Entity.Name != "sensor" || s.Entity.Count != 4 {
+		t.Errorf("incorrect entity: got %+v", s.Entity)
+	}
+	if s.Entity.Tag != defaultEntityTag {
+		t.Errorf("incorrect default entity tag: got %s want %s", s.Entity.Tag, defaultEntityTag)
+	}
+	if s.Interval != 30*time.Second {
+		t.Errorf("incorrect interval: got %v", s.Interval)
+	}
+	if got := len(s.Tags); got != 2 {
+		t.Errorf("incorrect tag count: got %d want 2", got)
+	}
+	if got := len(s.Measurements); got != 2 {
+		t.Fatalf("incorrect measurement count: got %d want 2", got)
+	}
+	temp := s.Measurements[0].Fields[0].Distribution
+	if temp.Type != DistributionFP || temp.Dist == nil || temp.Dist.Step.StdDev != 0.5 {
+		t.Errorf("incorrect nested distribution: got %+v", temp)
+	}
+
+	r := rand.New(rand.NewSource(123))
+	for _, m := range s.Measurements {
+		for _, f := range m.Fields {
+			if d := f.Distribution.New(r); d == nil {
+				t.Errorf("no distribution for field %s", f.Name)
+			}
+		}
+	}
+	if _, ok := s.Measurements[1].Fields[2].Distribution.New(r).(*common.ConstantDistribution); !ok {
+		t.Errorf("constant field is not a constant distribution")
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	field := func(d string) string {
+		return "entity: {name: e}\nmeasurements:\n- name: m\n  fields:\n  - {name: f, distribution: " + d + "}\n"
+	}
+	cases := []struct {
+		desc   string
+		schema string
+		errMsg string
+	}{
+		{
+			desc:   "unknown key",
+			schema: "entity: {name: e, bogus: 1}\n",
+			errMsg: "not found in type",
+		},
+		{
+			desc:   "no entity name",
+			schema: "measurements: [{name: m, fields: [{name: f, distribution: {type: nd}}]}]\n",
+			errMsg: errNoEntityName,
+		},
+		{
+			desc:   "negative interval",
+			schema: "entity: {name: e}\ninterval: -1s\n",
+			errMsg: errNegativeInterval,
+		},
+		{
+			desc:   "no measurements",
+			schema: "entity: {name: e}\n",
+			errMsg: errNoMeasurements,
+		},
+		{
+			desc:   "tag with entity key",
+			schema: "entity: {name: e}\ntags: [{key: name, values: [a]}]\n",
+			errMsg: "tag 'name': duplicate key",
+		},
+		{
+			desc:   "tag without values",
+			schema: "entity: {name: e}\ntags: [{key: k}]\n",
+			errMsg: "tag 'k': no values",
+		},
+		{
+			desc:   "tag weight count",
+			schema: "entity: {name: e}\ntags: [{key: k, values: [a, b], weights: [1]}]\n",
+			errMsg: "tag 'k': 1 weights for 2 values",
+		},
+		{
+			desc:   "negative tag weight",
+			schema: "entity: {name: e}\ntags: [{key: k, values: [a, b], weights: [2, -1]}]\n",
+			errMsg: "tag 'k': weights must be",
+		},
+		{
+			desc:   "zero tag weights",
+			schema: "entity: {name: e}\ntags: [{key: k, values: [a], weights: [0]}]\n",
+			errMsg: "tag 'k': weights must be",
+		},
+		{
+			desc:   "duplicate measurement",
+			schema: "entity: {name: e}\nmeasurements: [{name: m, fields: [{name: f, distribution: {type: nd}}]}, {name: m}]\n",
+			errMsg: "measurement 'm': duplicate name",
+		},
+		{
+			desc:   "no fields",
+			schema: "entity: {name: e}\nmeasurements: [{name: m}]\n",
+			errMsg: "measurement 'm': no fields",
+		},
+		{
+			desc:   "duplicate field",
+			schema: "entity: {name: e}\nmeasurements: [{name: m, fields: [{name: f, distribution: {type: nd}}, {name: f, distribution: {type: nd}}]}]\n",
+			errMsg: "field 'f': duplicate name",
+		},
+		{
+			desc:   "unknown distribution",
+			schema: field("{type: zipf}"),
+			errMsg: "unknown distribution type 'zipf'",
+		},
+		{
+			desc:   "negative stddev",
+			schema: field("{type: nd, stddev: -1}"),
+			errMsg: "stddev cannot be negative",
+		},
+		{
+			desc:   "uniform bounds",
+			schema: field("{type: ud, low: 2, high: 1}"),
+			errMsg: "low cannot be greater than high",
+		},
+		{
+			desc:   "missing step",
+			schema: field("{type: wd}"),
+			errMsg: "missing step distribution",
+		},
+		{
+			desc:   "clamp bounds",
+			schema: field("{type: cwd, min: 2, max: 1, step: {type: nd}}"),
+			errMsg: "min cannot be greater than max",
+		},
+		{
+			desc:   "state out of bounds",
+			schema: field("{type: cwd, min: 0, max: 1, state: 2, step: {type: nd}}"),
+			errMsg: "state must be between min and max",
+		},
+		{
+			desc:   "missing motive",
+			schema: field("{type: ld, step: {type: nd}}"),
+			errMsg: "missing motive distribution",
+		},
+		{
+			desc:   "invalid nested distribution",
+			schema: field("{type: fp, dist: {type: mwd, step: {type: ud, low: 1}}}"),
+			errMsg: "field 'f'.dist.step: low cannot be greater than high",
+		},
+		{
+			desc:   "precision",
+			schema: field("{type: fp, precision: 6, dist: {type: nd}}"),
+			errMsg: "precision must be between 0 and 5",
+		},
+	}
+	for _, c := range cases {
+		_, err := ParseSchema([]byte(c.schema))
+		if err == nil {
+			t.Errorf("%s: unexpected lack of error", c.desc)
+		} else if !strings.Contains(err.Error(), c.errMsg) {
+			t.Errorf("%s: incorrect error: got %v want %s", c.desc, err, c.errMsg)
+		}
+	}
+}
+
+func TestLoadSchema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-custom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "schema.yaml")
+
+	if _, err := LoadSchema(path); err == nil {
+		t.Errorf("unexpected lack of error for missing file")
+	}
+	if err := ioutil.WriteFile(path, []byte("entity: {name: e}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSchema(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("incorrect error for invalid schema: got %v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(testSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSchema(path); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTagSpecChoose(t *testing.T) {
+	tag := &TagSpec{Key: "k", Values: []string{"a", "b", "c"}, Weights: []float64{3, 0, 1}}
+	r := rand.New(rand.NewSource(123))
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		counts[tag.choose(r)]++
+	}
+	if counts["b"] != 0 {
+		t.Errorf("value of zero weight chosen %d times", counts["b"])
+	}
+	if counts["a"] < 2800 || counts["a"] > 3200 {
+		t.Errorf("incorrect frequency of weighted value: got %d of 4000 want ~3000", counts["a"])
+	}
+
+	tag.Weights = nil
+	for i := 0; i < 100; i++ {
+		counts[tag.choose(r)]++
+	}
+	if counts["b"] == 0 {
+		t.Errorf("value never chosen without weights")
+	}
+}
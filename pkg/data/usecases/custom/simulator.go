@@ -0,0 +1,136 @@
+package custom
+
+import (
+	"fmt"
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"math/rand"
+	"time"
+)
+
+// SimulatorConfig is used to create a Simulator of the entities described by
+// a Schema. It fulfills the common.SimulatorConfig interface.
+type SimulatorConfig struct {
+	Start time.Time
+	End   time.Time
+
+	InitGeneratorScale uint64
+	GeneratorScale     uint64
+	Seed               int64
+	Workers            int
+
+	Schema *Schema
+}
+
+// NewSimulator produces a Simulator of the schema over the specified
+// interval, or the interval of the schema if set, and points limit.
+func (sc *SimulatorConfig) NewSimulator(interval time.Duration, limit uint64) common.Simulator {
+	if sc.Schema.Interval > 0 {
+		interval = sc.Schema.Interval
+	}
+	base := &common.BaseSimulatorConfig{
+		Start:              sc.Start,
+		End:                sc.End,
+		InitGeneratorScale: sc.InitGeneratorScale,
+		GeneratorScale:     sc.GeneratorScale,
+		Seed:               sc.Seed,
+		Workers:            sc.Workers,
+		GeneratorConstructor: func(i int, start time.Time, r *rand.Rand) common.Generator {
+			return NewEntity(sc.Schema, i, start, r)
+		},
+	}
+	s := base.NewSimulator(interval, limit)
+	if sc.Workers > 1 {
+		s = common.NewParallelSimulator(s.(common.Partitioner), sc.Workers)
+	}
+	return s
+}
+
+// Entity is a simulated entity of a Schema, which reports all the
+// measurements of the schema.
+type Entity struct {
+	simulatedMeasurements []common.SimulatedMeasurement
+	tags                  []common.Tag
+}
+
+// NewEntity creates the entity with the given id, drawing its tags and the
+// initial state of its distributions from r.
+func NewEntity(s *Schema, i int, start time.Time, r *rand.Rand) *Entity {
+	tags := make([]common.Tag, 0, len(s.Tags)+1)
+	tags = append(tags, common.Tag{
+		Key:   []byte(s.Entity.Tag),
+		Value: fmt.Sprintf(entityNameFmt, s.Entity.Name, i),
+	})
+	for j := range s.Tags {
+		tags = append(tags, common.Tag{
+			Key:   []byte(s.Tags[j].Key),
+			Value: s.Tags[j].choose(r),
+		})
+	}
+
+	sm := make([]common.SimulatedMeasurement, len(s.Measurements))
+	for j := range s.Measurements {
+		sm[j] = newMeasurement(&s.Measurements[j], start, r)
+	}
+	return &Entity{
+		simulatedMeasurements: sm,
+		tags:                  tags,
+	}
+}
+
+// TickAll advances all Distributions of an Entity, drawing from the PRNG r of
+// the entity.
+func (e *Entity) TickAll(d time.Duration, r *rand.Rand) {
+	for i := range e.simulatedMeasurements {
+		e.simulatedMeasurements[i].Tick(d, r)
+	}
+}
+
+// Measurements returns the entity measurements.
+func (e Entity) Measurements() []common.SimulatedMeasurement {
+	return e.simulatedMeasurements
+}
+
+// Tags returns the entity tags.
+func (e Entity) Tags() []common.Tag {
+	return e.tags
+}
+
+// measurement is a measurement of a Schema, whose distributions are the ones
+// of the fields of its MeasurementSpec, in order.
+type measurement struct {
+	*common.SubsystemMeasurement
+	spec   *MeasurementSpec
+	name   []byte
+	labels [][]byte
+}
+
+func newMeasurement(spec *MeasurementSpec, start time.Time, r *rand.Rand) *measurement {
+	sub := common.NewSubsystemMeasurement(start, len(spec.Fields))
+	labels := make([][]byte, len(spec.Fields))
+	for i := range spec.Fields {
+		sub.Distributions[i] = spec.Fields[i].Distribution.New(r)
+		labels[i] = []byte(spec.Fields[i].Name)
+	}
+	return &measurement{
+		SubsystemMeasurement: sub,
+		spec:                 spec,
+		name:                 []byte(spec.Name),
+		labels:               labels,
+	}
+}
+
+// ToPoint serializes the measurement to data.Point, reporting integer fields
+// as int64.
+func (m *measurement) ToPoint(p *data.Point) {
+	p.SetMeasurementName(m.name)
+	p.SetTimestamp(&m.Timestamp)
+
+	for i, d := range m.Distributions {
+		if m.spec.Fields[i].Integer {
+			p.AppendField(m.labels[i], int64(d.Get()))
+		} else {
+			p.AppendField(m.labels[i], d.Get())
+		}
+	}
+}
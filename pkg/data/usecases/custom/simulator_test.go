@@ -0,0 +1,109 @@
+package custom
+
+import (
+	"fmt"
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testSimulatorConfig(t *testing.T, workers int) *SimulatorConfig {
+	s, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &SimulatorConfig{
+		Start:              start,
+		End:                start.Add(10 * time.Minute),
+		InitGeneratorScale: s.Entity.Count,
+		GeneratorScale:     s.Entity.Count,
+		Seed:               123,
+		Workers:            workers,
+		Schema:             s,
+	}
+}
+
+func TestNewEntity(t *testing.T) {
+	sc := testSimulatorConfig(t, 1)
+	e := NewEntity(sc.Schema, 2, sc.Start, rand.New(rand.NewSource(123)))
+
+	tags := e.Tags()
+	if got := len(tags); got != 3 {
+		t.Fatalf("incorrect tag count: got %d want 3", got)
+	}
+	if string(tags[0].Key) != "name" || tags[0].Value != "sensor_2" {
+		t.Errorf("incorrect entity tag: got %s=%v", tags[0].Key, tags[0].Value)
+	}
+	if v := tags[1].Value.(string); v != "north" && v != "south" {
+		t.Errorf("incorrect site tag: got %s", v)
+	}
+
+	if got := len(e.Measurements()); got != 2 {
+		t.Fatalf("incorrect measurement count: got %d want 2", got)
+	}
+	p := data.NewPoint()
+	e.Measurements()[0].ToPoint(p)
+	if got := string(p.MeasurementName()); got != "env" {
+		t.Errorf("incorrect measurement name: got %s", got)
+	}
+	if got := *p.Timestamp(); got != sc.Start {
+		t.Errorf("incorrect timestamp: got %v want %v", got, sc.Start)
+	}
+	values := p.FieldValues()
+	if _, ok := values[0].(float64); !ok {
+		t.Errorf("float field is not a float64: got %T", values[0])
+	}
+	if got, ok := values[1].(int64); !ok || got != 50 {
+		t.Errorf("incorrect integer field: got %v (%T) want 50", values[1], values[1])
+	}
+
+	e.TickAll(time.Minute, rand.New(rand.NewSource(123)))
+	p.Reset()
+	e.Measurements()[0].ToPoint(p)
+	if got := *p.Timestamp(); got != sc.Start.Add(time.Minute) {
+		t.Errorf("incorrect timestamp after tick: got %v", got)
+	}
+}
+
+func simulate(s common.Simulator) []string {
+	var points []string
+	for !s.Finished() {
+		p := data.NewPoint()
+		if s.Next(p) {
+			points = append(points, fmt.Sprintf("%s %v %v %v", p.MeasurementName(), p.TagValues(), p.FieldValues(), *p.Timestamp()))
+		}
+	}
+	return points
+}
+
+func TestSimulator(t *testing.T) {
+	sc := testSimulatorConfig(t, 1)
+	points := simulate(sc.NewSimulator(10*time.Second, 0))
+	// the interval of the schema overrides the one of the generator
+	want := 20 * 4 * 2
+	if got := len(points); got != want {
+		t.Errorf("incorrect point count: got %d want %d", got, want)
+	}
+
+	if again := simulate(sc.NewSimulator(10*time.Second, 0)); !reflect.DeepEqual(points, again) {
+		t.Errorf("simulation is not deterministic")
+	}
+
+	sc.Schema.Interval = 0
+	if got := len(simulate(sc.NewSimulator(time.Minute, 0))); got != 10*4*2 {
+		t.Errorf("incorrect point count with generator interval: got %d want %d", got, 10*4*2)
+	}
+}
+
+func TestSimulatorWorkers(t *testing.T) {
+	want := simulate(testSimulatorConfig(t, 1).NewSimulator(0, 0))
+	for _, workers := range []int{2, 3, 8} {
+		if got := simulate(testSimulatorConfig(t, workers).NewSimulator(0, 0)); !reflect.DeepEqual(got, want) {
+			t.Errorf("output of %d workers differs from the one of a single worker", workers)
+		}
+	}
+}
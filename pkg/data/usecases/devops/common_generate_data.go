@@ -3,6 +3,7 @@ package devops
 import (
 	"github.com/timescale/tsbs/pkg/data"
 	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"math/rand"
 	"time"
 )
 
@@ -10,6 +11,8 @@ import (
 type HostContext struct {
 	id    int
 	start time.Time
+	// rand is the PRNG of the host, used both to create and to advance it
+	rand *rand.Rand
 	// used for devops-generic use-case
 	metricCount  uint64 // number of metrics to generate
 	epochsToLive uint64 // number of epochs to live
@@ -24,18 +27,45 @@ type commonDevopsSimulatorConfig struct {
 	InitHostCount uint64
 	// HostCount is the total number of hosts to have in the last reporting period
 	HostCount uint64
-	// HostConstructor is the function used to create a new Host given an id number and start time
+	// HostConstructor is the function used to create a new Host given an id number, start time and PRNG
 	HostConstructor func(ctx *HostContext) Host
 	// MaxMetricCount is the max number of metrics per host to create when using generic-devops use-case
 	MaxMetricCount uint64
+	// Seed is the seed of the simulation, from which the PRNG of each host
+	// is seeded
+	Seed int64
+	// Patterns are the seasonality, trend and anomalies of the CPU
+	// measurement, if not nil
+	Patterns *common.Patterns
+	// Churn are the changes of the hosts over time, if not nil
+	Churn *common.Churn
 }
 
-func NewHostCtx(id int, start time.Time) *HostContext {
-	return &HostContext{id, start, 0, 0}
+func NewHostCtx(id int, start time.Time, r *rand.Rand) *HostContext {
+	return &HostContext{id, start, r, 0, 0}
 }
 
-func NewHostCtxTime(start time.Time) *HostContext {
-	return &HostContext{0, start, 0, 0}
+func NewHostCtxTime(start time.Time, r *rand.Rand) *HostContext {
+	return &HostContext{0, start, r, 0, 0}
+}
+
+// newHostRands returns the PRNGs of the hosts of c, each seeded from the seed
+// of c and the id of the host.
+func newHostRands(c commonDevopsSimulatorConfig) []*rand.Rand {
+	rands := make([]*rand.Rand, c.HostCount)
+	for i := range rands {
+		rands[i] = common.NewRand(common.EntitySeed(c.Seed, i))
+	}
+	return rands
+}
+
+// newHost creates the host with the given id, start time and PRNG with the
+// HostConstructor of c, and makes its measurements follow the patterns of c,
+// if any.
+func (c commonDevopsSimulatorConfig) newHost(id int, start time.Time, r *rand.Rand) Host {
+	h := c.HostConstructor(NewHostCtx(id, start, r))
+	c.Patterns.Apply(h.SimulatedMeasurements, common.Tag{Key: MachineTagKeys[0], Value: h.Name})
+	return h
 }
 
 func calculateEpochs(c commonDevopsSimulatorConfig, interval time.Duration) uint64 {
@@ -48,6 +78,12 @@ type commonDevopsSimulator struct {
 
 	hostIndex uint64
 	hosts     []Host
+	rands     []*rand.Rand
+	// the simulated hosts are [lo, hi)
+	lo, hi uint64
+	// newHost creates the hosts replacing the churned ones
+	newHost func(id int, start time.Time, r *rand.Rand) Host
+	churn   *common.EntityChurn
 
 	epoch      uint64
 	epochs     uint64
@@ -59,6 +95,34 @@ type commonDevopsSimulator struct {
 	interval       time.Duration
 }
 
+// newCommonDevopsSimulator returns a commonDevopsSimulator of hosts, which
+// advances each host with its PRNG in rands.
+func newCommonDevopsSimulator(c commonDevopsSimulatorConfig, hosts []Host, rands []*rand.Rand, interval time.Duration, maxPoints uint64) *commonDevopsSimulator {
+	var churn *common.EntityChurn
+	if c.Churn != nil {
+		churn = common.NewEntityChurn(c.Churn, c.Seed, rands)
+	}
+	return &commonDevopsSimulator{
+		madePoints: 0,
+		maxPoints:  maxPoints,
+
+		hostIndex: 0,
+		hosts:     hosts,
+		rands:     rands,
+		hi:        uint64(len(hosts)),
+		newHost:   c.newHost,
+		churn:     churn,
+
+		epoch:          0,
+		epochs:         calculateEpochs(c, interval),
+		epochHosts:     c.InitHostCount,
+		initHosts:      c.InitHostCount,
+		timestampStart: c.Start,
+		timestampEnd:   c.End,
+		interval:       interval,
+	}
+}
+
 // Finished tells whether we have simulated all the necessary points
 func (s *commonDevopsSimulator) Finished() bool {
 	return s.madePoints >= s.maxPoints
@@ -76,6 +140,9 @@ func (s *commonDevopsSimulator) TagKeys() []string {
 	for i, t := range MachineTagKeys {
 		tagKeysAsStr[i] = string(t)
 	}
+	if tag, ok := s.churn.Tag(0); ok {
+		tagKeysAsStr = append(tagKeysAsStr, string(tag.Key))
+	}
 	return tagKeysAsStr
 }
 
@@ -84,6 +151,9 @@ func (s *commonDevopsSimulator) TagTypes() []string {
 	for i := 0; i < len(MachineTagKeys); i++ {
 		types[i] = machineTagType.String()
 	}
+	if _, ok := s.churn.Tag(0); ok {
+		types = append(types, machineTagType.String())
+	}
 	return types
 }
 
@@ -124,14 +194,61 @@ func (s *commonDevopsSimulator) populatePoint(p *data.Point, measureIdx int) boo
 	p.AppendTag(MachineTagKeys[7], host.Service)
 	p.AppendTag(MachineTagKeys[8], host.ServiceVersion)
 	p.AppendTag(MachineTagKeys[9], host.ServiceEnvironment)
+	if tag, ok := s.churn.Tag(int(s.hostIndex)); ok {
+		p.AppendTag(tag.Key, tag.Value)
+	}
 
 	// Populate measurement-specific tags and fields:
 	host.SimulatedMeasurements[measureIdx].ToPoint(p)
 
 	ret := s.hostIndex < s.epochHosts
+	s.nextHost()
+	return ret
+}
+
+// nextHost moves to the next host of the partition.
+func (s *commonDevopsSimulator) nextHost() {
 	s.madePoints++
 	s.hostIndex++
-	return ret
+	if s.hostIndex == s.hi {
+		// skip the points of the hosts of other partitions
+		s.madePoints += uint64(len(s.hosts)) - s.hi + s.lo
+	}
+}
+
+// tickHosts advances the hosts of the partition by the interval.
+func (s *commonDevopsSimulator) tickHosts() {
+	for i := s.lo; i < s.hi; i++ {
+		s.hosts[i].TickAll(s.interval, s.rands[i])
+		s.churnHost(i)
+	}
+}
+
+// churnHost replaces the host i, or changes its tags, according to the churn
+// of s, after it was ticked.
+func (s *commonDevopsSimulator) churnHost(i uint64) {
+	if s.churn == nil || !s.churn.Tick(int(i), s.interval, s.rands[i], &s.hosts[i]) {
+		return
+	}
+	// the new host starts at the reading the others were ticked to
+	start := s.timestampStart.Add(time.Duration(s.epoch+1) * s.interval)
+	s.rands[i] = s.churn.Replace(int(i), func(id int, r *rand.Rand) {
+		s.hosts[i] = s.newHost(id, start, r)
+	})
+}
+
+// Generators returns the number of hosts of the simulator.
+func (s *commonDevopsSimulator) Generators() int {
+	return len(s.hosts)
+}
+
+// partition returns a copy of s that simulates the hosts [lo, hi) only.
+func (s *commonDevopsSimulator) partition(lo, hi int) *commonDevopsSimulator {
+	p := *s
+	p.lo, p.hi = uint64(lo), uint64(hi)
+	p.hostIndex = p.lo
+	p.madePoints = s.madePoints + p.lo
+	return &p
 }
 
 // TODO(rrk) - Can probably turn this logic into a separate interface and implement other
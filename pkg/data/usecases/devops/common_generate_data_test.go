@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/timescale/tsbs/pkg/data"
 	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -124,9 +125,10 @@ func TestCommonDevopsSimulatorFinished(t *testing.T) {
 }
 
 func TestCommonDevopsSimulatorFields(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
 	s := &commonDevopsSimulator{}
 	host := Host{}
-	host.SimulatedMeasurements = []common.SimulatedMeasurement{NewCPUMeasurement(time.Now())}
+	host.SimulatedMeasurements = []common.SimulatedMeasurement{NewCPUMeasurement(time.Now(), r)}
 	s.hosts = append(s.hosts, host)
 	fields := s.Fields()
 	if got := len(fields); got != 1 {
@@ -144,7 +146,7 @@ func TestCommonDevopsSimulatorFields(t *testing.T) {
 	// because we assume each Host has the same set of simulated measurements.
 	// TODO - Examine whether this assumption should be refined.
 	host = Host{}
-	host.SimulatedMeasurements = []common.SimulatedMeasurement{NewMemMeasurement(time.Now())}
+	host.SimulatedMeasurements = []common.SimulatedMeasurement{NewMemMeasurement(time.Now(), r)}
 	s.hosts = append(s.hosts, host)
 	fields = s.Fields()
 	if got := len(fields); got != 1 {
@@ -153,7 +155,7 @@ func TestCommonDevopsSimulatorFields(t *testing.T) {
 
 	// Add new measurement, this should change the result.
 	host = s.hosts[0]
-	host.SimulatedMeasurements = append(host.SimulatedMeasurements, NewMemMeasurement(time.Now()))
+	host.SimulatedMeasurements = append(host.SimulatedMeasurements, NewMemMeasurement(time.Now(), r))
 	s.hosts[0] = host
 	fields = s.Fields()
 	if got := len(fields); got != 2 {
@@ -179,6 +181,7 @@ func sprintf(format string, args ...interface{}) string {
 var prefix = []string{"host", "region", "datacenter", "rack", "os", "arch", "team", "service", "service_version", "service_env"}
 
 func TestCommonDevopsSimulatorPopulatePoint(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
 	s := &commonDevopsSimulator{}
 	numHosts := uint64(2)
 	for i := uint64(0); i < numHosts; i++ {
@@ -194,7 +197,7 @@ func TestCommonDevopsSimulatorPopulatePoint(t *testing.T) {
 			ServiceVersion:     sprintf("%s%d", prefix[8], i),
 			ServiceEnvironment: sprintf("%s%d", prefix[9], i),
 		}
-		host.SimulatedMeasurements = []common.SimulatedMeasurement{NewCPUMeasurement(time.Now())}
+		host.SimulatedMeasurements = []common.SimulatedMeasurement{NewCPUMeasurement(time.Now(), r)}
 		s.hosts = append(s.hosts, host)
 	}
 	s.hostIndex = 0
@@ -304,3 +307,135 @@ func TestCommonDevopsSimulatorTagTypes(t *testing.T) {
 		}
 	}
 }
+
+// simulatedPoints returns the points of s, with whether they should be
+// written, as strings.
+func simulatedPoints(s common.Simulator) []string {
+	points := make([]string, 0)
+	p := data.NewPoint()
+	for !s.Finished() {
+		write := s.Next(p)
+		points = append(points, fmt.Sprintf("%t %s %s %v %v %v", write, p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldValues(), p.Timestamp()))
+		p.Reset()
+	}
+	return points
+}
+
+func TestPartitionedSimulators(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Minute)
+	churn := &common.Churn{Lifetime: 30 * time.Second, TagChangeRate: 0.1, CardinalityTag: []byte("pod"), CardinalityValues: 1000}
+	cases := []struct {
+		desc string
+		conf common.SimulatorConfig
+	}{
+		{
+			desc: "cpu-only",
+			conf: &CPUOnlySimulatorConfig{Start: start, End: end, InitHostCount: 3, HostCount: 7, HostConstructor: NewHostCPUOnly, Seed: 1},
+		},
+		{
+			desc: "devops",
+			conf: &DevopsSimulatorConfig{Start: start, End: end, InitHostCount: 3, HostCount: 7, HostConstructor: NewHost, Seed: 1},
+		},
+		{
+			desc: "generic",
+			conf: &GenericMetricsSimulatorConfig{DevopsSimulatorConfig: &DevopsSimulatorConfig{Start: start, End: end, InitHostCount: 3, HostCount: 7, HostConstructor: NewHostGenericMetrics, MaxMetricCount: 5, Seed: 1}},
+		},
+		{
+			desc: "devops churn",
+			conf: &DevopsSimulatorConfig{Start: start, End: end, InitHostCount: 3, HostCount: 7, HostConstructor: NewHost, Seed: 1, Churn: churn},
+		},
+		{
+			desc: "cpu-only churn",
+			conf: &CPUOnlySimulatorConfig{Start: start, End: end, InitHostCount: 3, HostCount: 7, HostConstructor: NewHostCPUOnly, Seed: 1, Churn: churn},
+		},
+	}
+	for _, c := range cases {
+		for _, limit := range []uint64{0, 10, 123} {
+			resetGenericMetricFields()
+			want := simulatedPoints(c.conf.NewSimulator(10*time.Second, limit))
+			for workers := 1; workers <= 8; workers++ {
+				resetGenericMetricFields()
+				s := c.conf.NewSimulator(10*time.Second, limit).(common.Partitioner)
+				got := simulatedPoints(common.NewParallelSimulator(s, workers))
+				if len(got) != len(want) {
+					t.Fatalf("%s: limit %d, %d workers: incorrect number of points: got %d want %d", c.desc, limit, workers, len(got), len(want))
+				}
+				for i := range want {
+					if got[i] != want[i] {
+						t.Errorf("%s: limit %d, %d workers: incorrect point %d: got\n%s\nwant\n%s", c.desc, limit, workers, i, got[i], want[i])
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestHostSubsetRegeneration(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	hostPoints := func(hostCount uint64) map[string][]string {
+		c := &DevopsSimulatorConfig{Start: start, End: start.Add(time.Minute), InitHostCount: hostCount, HostCount: hostCount, HostConstructor: NewHost, Seed: 42}
+		s := c.NewSimulator(10*time.Second, 0)
+		ret := map[string][]string{}
+		p := data.NewPoint()
+		for !s.Finished() {
+			s.Next(p)
+			hostname := p.GetTagValue(MachineTagKeys[0]).(string)
+			ret[hostname] = append(ret[hostname], fmt.Sprintf("%s %v %v %v", p.MeasurementName(), p.TagValues(), p.FieldValues(), p.Timestamp()))
+			p.Reset()
+		}
+		return ret
+	}
+
+	all := hostPoints(10)
+	subset := hostPoints(4)
+	if len(subset) != 4 {
+		t.Fatalf("incorrect number of hosts: got %d want 4", len(subset))
+	}
+	for hostname, want := range subset {
+		got := all[hostname]
+		if len(got) != len(want) {
+			t.Fatalf("%s: incorrect number of points: got %d want %d", hostname, len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: point %d differs with more hosts: got\n%s\nwant\n%s", hostname, i, got[i], want[i])
+				break
+			}
+		}
+	}
+}
+
+func TestHostChurn(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &CPUOnlySimulatorConfig{
+		Start: start, End: start.Add(time.Hour), InitHostCount: 3, HostCount: 3, HostConstructor: NewHostCPUOnly, Seed: 1,
+		Churn: &common.Churn{Lifetime: 10 * time.Minute, CardinalityTag: []byte("pod"), CardinalityValues: 1000},
+	}
+	s := c.NewSimulator(10*time.Second, 0)
+	if got := s.TagKeys(); got[len(got)-1] != "pod" {
+		t.Errorf("extra tag missing from tag keys: got %v", got)
+	}
+	if got, want := len(s.TagTypes()), len(s.TagKeys()); got != want {
+		t.Errorf("incorrect number of tag types: got %d want %d", got, want)
+	}
+
+	hostnames := map[string]bool{}
+	p := data.NewPoint()
+	for !s.Finished() {
+		s.Next(p)
+		hostnames[p.GetTagValue(MachineTagKeys[0]).(string)] = true
+		if p.GetTagValue([]byte("pod")) == nil {
+			t.Fatalf("point without extra tag: %v", p.TagValues())
+		}
+		p.Reset()
+	}
+	// about 3 hosts replaced every 10 minutes
+	if len(hostnames) <= 3 {
+		t.Errorf("no host replaced: got %d hosts", len(hostnames))
+	}
+	if !hostnames["host_3"] {
+		t.Errorf("replacing hosts do not take the next ids: got %v", hostnames)
+	}
+}
@@ -10,16 +10,16 @@ import (
 var (
 	labelCPU  = []byte("cpu") // heap optimization
 	cpuFields = []common.LabeledDistributionMaker{
-		{Label: []byte("usage_user"), DistributionMaker: func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{Label: []byte("usage_system"), DistributionMaker: func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{Label: []byte("usage_idle"), DistributionMaker: func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{Label: []byte("usage_nice"), DistributionMaker: func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{Label: []byte("usage_iowait"), DistributionMaker: func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{Label: []byte("usage_irq"), DistributionMaker: func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{Label: []byte("usage_softirq"), DistributionMaker: func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{Label: []byte("usage_steal"), DistributionMaker: func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{Label: []byte("usage_guest"), DistributionMaker: func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
-		{Label: []byte("usage_guest_nice"), DistributionMaker: func() common.Distribution { return common.CWD(cpuND, 0.0, 100.0, rand.Float64()*100.0) }},
+		{Label: []byte("usage_user"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.CWD(cpuND, 0.0, 100.0, r.Float64()*100.0) }},
+		{Label: []byte("usage_system"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.CWD(cpuND, 0.0, 100.0, r.Float64()*100.0) }},
+		{Label: []byte("usage_idle"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.CWD(cpuND, 0.0, 100.0, r.Float64()*100.0) }},
+		{Label: []byte("usage_nice"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.CWD(cpuND, 0.0, 100.0, r.Float64()*100.0) }},
+		{Label: []byte("usage_iowait"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.CWD(cpuND, 0.0, 100.0, r.Float64()*100.0) }},
+		{Label: []byte("usage_irq"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.CWD(cpuND, 0.0, 100.0, r.Float64()*100.0) }},
+		{Label: []byte("usage_softirq"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.CWD(cpuND, 0.0, 100.0, r.Float64()*100.0) }},
+		{Label: []byte("usage_steal"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.CWD(cpuND, 0.0, 100.0, r.Float64()*100.0) }},
+		{Label: []byte("usage_guest"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.CWD(cpuND, 0.0, 100.0, r.Float64()*100.0) }},
+		{Label: []byte("usage_guest_nice"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.CWD(cpuND, 0.0, 100.0, r.Float64()*100.0) }},
 	}
 )
 
@@ -32,19 +32,26 @@ type CPUMeasurement struct {
 	*common.SubsystemMeasurement
 }
 
-func NewCPUMeasurement(start time.Time) *CPUMeasurement {
-	return newCPUMeasurementNumDistributions(start, len(cpuFields))
+func NewCPUMeasurement(start time.Time, r *rand.Rand) *CPUMeasurement {
+	return newCPUMeasurementNumDistributions(start, len(cpuFields), r)
 }
 
-func newSingleCPUMeasurement(start time.Time) *CPUMeasurement {
-	return newCPUMeasurementNumDistributions(start, 1)
+func newSingleCPUMeasurement(start time.Time, r *rand.Rand) *CPUMeasurement {
+	return newCPUMeasurementNumDistributions(start, 1, r)
 }
 
-func newCPUMeasurementNumDistributions(start time.Time, numDistributions int) *CPUMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, cpuFields[:numDistributions])
+func newCPUMeasurementNumDistributions(start time.Time, numDistributions int, r *rand.Rand) *CPUMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, cpuFields[:numDistributions], r)
 	return &CPUMeasurement{sub}
 }
 
 func (m *CPUMeasurement) ToPoint(p *data.Point) {
 	m.ToPointAllInt64(p, labelCPU, cpuFields)
 }
+
+// ApplyPatterns makes all the CPU fields follow p.
+func (m *CPUMeasurement) ApplyPatterns(p *common.Patterns, host common.Tag) {
+	for i := range m.Distributions {
+		p.Wrap(m.SubsystemMeasurement, i, labelCPU, cpuFields[i].Label, host)
+	}
+}
@@ -28,27 +28,29 @@ func (d *CPUOnlySimulator) Headers() *common.GeneratedDataHeaders {
 // Next advances a Point to the next state in the distributionGenerator.
 func (d *CPUOnlySimulator) Next(p *data.Point) bool {
 	// Switch to the next metric if needed
-	if d.hostIndex == uint64(len(d.hosts)) {
-		d.hostIndex = 0
-
-		for i := 0; i < len(d.hosts); i++ {
-			d.hosts[i].TickAll(d.interval)
-		}
-
+	if d.hostIndex == d.hi {
+		d.hostIndex = d.lo
+		d.tickHosts()
 		d.adjustNumHostsForEpoch()
 	}
 
 	return d.populatePoint(p, 0)
 }
 
+// Partition returns a simulator of the hosts [lo, hi) of d.
+func (d *CPUOnlySimulator) Partition(lo, hi int) common.Simulator {
+	return &CPUOnlySimulator{d.partition(lo, hi)}
+}
+
 // CPUOnlySimulatorConfig is used to create a CPUOnlySimulator.
 type CPUOnlySimulatorConfig commonDevopsSimulatorConfig
 
 // NewSimulator produces a Simulator that conforms to the given SimulatorConfig over the specified interval
 func (c *CPUOnlySimulatorConfig) NewSimulator(interval time.Duration, limit uint64) common.Simulator {
 	hostInfos := make([]Host, c.HostCount)
+	rands := newHostRands(commonDevopsSimulatorConfig(*c))
 	for i := 0; i < len(hostInfos); i++ {
-		hostInfos[i] = c.HostConstructor(NewHostCtx(i, c.Start))
+		hostInfos[i] = commonDevopsSimulatorConfig(*c).newHost(i, c.Start, rands[i])
 	}
 
 	epochs := calculateEpochs(commonDevopsSimulatorConfig(*c), interval)
@@ -57,21 +59,7 @@ func (c *CPUOnlySimulatorConfig) NewSimulator(interval time.Duration, limit uint
 		// Set specified points number limit
 		maxPoints = limit
 	}
-	sim := &CPUOnlySimulator{&commonDevopsSimulator{
-		madePoints: 0,
-		maxPoints:  maxPoints,
-
-		hostIndex: 0,
-		hosts:     hostInfos,
-
-		epoch:          0,
-		epochs:         epochs,
-		epochHosts:     c.InitHostCount,
-		initHosts:      c.InitHostCount,
-		timestampStart: c.Start,
-		timestampEnd:   c.End,
-		interval:       interval,
-	}}
+	sim := &CPUOnlySimulator{newCommonDevopsSimulator(commonDevopsSimulatorConfig(*c), hostInfos, rands, interval, maxPoints)}
 
 	return sim
 }
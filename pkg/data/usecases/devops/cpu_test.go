@@ -33,7 +33,8 @@ func testDistributionsAreDifferent(oldVals map[string]float64, m *common.Subsyst
 
 func TestCPUMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewCPUMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewCPUMeasurement(now, r)
 	duration := time.Second
 	oldVals := map[string]float64{}
 	fields := ldmToFieldLabels(cpuFields)
@@ -41,13 +42,12 @@ func TestCPUMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.Label)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
 	}
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err = testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
@@ -56,9 +56,10 @@ func TestCPUMeasurementTick(t *testing.T) {
 
 func TestCPUMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewCPUMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewCPUMeasurement(now, r)
 	duration := time.Second
-	m.Tick(duration)
+	m.Tick(duration, r)
 
 	p := data.NewPoint()
 	m.ToPoint(p)
@@ -75,7 +76,8 @@ func TestCPUMeasurementToPoint(t *testing.T) {
 
 func TestSingleCPUMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := newSingleCPUMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := newSingleCPUMeasurement(now, r)
 	duration := time.Second
 	oldVals := map[string]float64{}
 	fields := ldmToFieldLabels(cpuFields[:1]) // only the first field in this use case
@@ -86,13 +88,12 @@ func TestSingleCPUMeasurementTick(t *testing.T) {
 		oldVals[string(f)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
 	}
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err = testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
@@ -101,10 +102,11 @@ func TestSingleCPUMeasurementTick(t *testing.T) {
 
 func TestSingleCPUMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := newSingleCPUMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := newSingleCPUMeasurement(now, r)
 	duration := time.Second
 	fields := cpuFields[:1] // only the first field in this use case
-	m.Tick(duration)
+	m.Tick(duration, r)
 
 	p := data.NewPoint()
 	m.ToPoint(p)
@@ -122,3 +124,27 @@ func TestSingleCPUMeasurementToPoint(t *testing.T) {
 		}
 	}
 }
+
+func TestCPUMeasurementApplyPatterns(t *testing.T) {
+	now := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := rand.New(rand.NewSource(123))
+	m := NewCPUMeasurement(now, r)
+	p := &common.Patterns{DailyAmplitude: 0.2, Anomalies: common.AnomalyConfig{SpikeRate: 0.1, Magnitude: 0.5, Duration: 1}, Log: &common.AnomalyLog{}}
+	p.Apply([]common.SimulatedMeasurement{m}, common.Tag{Key: MachineTagKeys[0], Value: "host_0"})
+
+	for i, d := range m.Distributions {
+		if _, ok := d.(*common.ClampedDistribution); !ok {
+			t.Errorf("field %s does not follow the patterns: got %T", cpuFields[i].Label, d)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		m.Tick(time.Hour, r)
+	}
+	anomalies := p.Log.Anomalies()
+	if len(anomalies) == 0 {
+		t.Fatalf("no anomaly logged")
+	}
+	if a := anomalies[0]; a.Measurement != "cpu" || a.Entity != "hostname=host_0" {
+		t.Errorf("incorrect series of anomaly: got %+v", a.AnomalySeries)
+	}
+}
@@ -28,7 +28,8 @@ func testIfInStringSlice(t *testing.T, arr []string, choice string) {
 
 func TestDiskMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewDiskMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewDiskMeasurement(now, r)
 	origPath := string(m.path)
 	origFS := string(m.fsType)
 	duration := time.Second
@@ -38,8 +39,7 @@ func TestDiskMeasurementTick(t *testing.T) {
 		oldVals[string(f)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
@@ -51,7 +51,7 @@ func TestDiskMeasurementTick(t *testing.T) {
 		t.Errorf("disk FS type is incorrect: got %s want %s", got, origFS)
 	}
 
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err = testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
@@ -66,12 +66,13 @@ func TestDiskMeasurementTick(t *testing.T) {
 
 func TestDiskMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewDiskMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewDiskMeasurement(now, r)
 	origPath := m.path
 	origFS := m.fsType
 	testIfInStringSlice(t, diskFSTypeChoices, m.fsType)
 	duration := time.Second
-	m.Tick(duration)
+	m.Tick(duration, r)
 
 	p := data.NewPoint()
 	m.ToPoint(p)
@@ -24,13 +24,13 @@ var (
 	timeND  = common.ND(5, 1)
 
 	diskIOFields = []common.LabeledDistributionMaker{
-		{Label: []byte("reads"), DistributionMaker: func() common.Distribution { return common.MWD(opsND, 0) }},
-		{Label: []byte("writes"), DistributionMaker: func() common.Distribution { return common.MWD(opsND, 0) }},
-		{Label: []byte("read_bytes"), DistributionMaker: func() common.Distribution { return common.MWD(bytesND, 0) }},
-		{Label: []byte("write_bytes"), DistributionMaker: func() common.Distribution { return common.MWD(bytesND, 0) }},
-		{Label: []byte("read_time"), DistributionMaker: func() common.Distribution { return common.MWD(timeND, 0) }},
-		{Label: []byte("write_time"), DistributionMaker: func() common.Distribution { return common.MWD(timeND, 0) }},
-		{Label: []byte("io_time"), DistributionMaker: func() common.Distribution { return common.MWD(timeND, 0) }},
+		{Label: []byte("reads"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(opsND, 0) }},
+		{Label: []byte("writes"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(opsND, 0) }},
+		{Label: []byte("read_bytes"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(bytesND, 0) }},
+		{Label: []byte("write_bytes"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(bytesND, 0) }},
+		{Label: []byte("read_time"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(timeND, 0) }},
+		{Label: []byte("write_time"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(timeND, 0) }},
+		{Label: []byte("io_time"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(timeND, 0) }},
 	}
 )
 
@@ -39,9 +39,9 @@ type DiskIOMeasurement struct {
 	serial string
 }
 
-func NewDiskIOMeasurement(start time.Time) *DiskIOMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, diskIOFields)
-	serial := fmt.Sprintf(diskSerialFmt, rand.Intn(1000), rand.Intn(1000), rand.Intn(1000))
+func NewDiskIOMeasurement(start time.Time, r *rand.Rand) *DiskIOMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, diskIOFields, r)
+	serial := fmt.Sprintf(diskSerialFmt, r.Intn(1000), r.Intn(1000), r.Intn(1000))
 	return &DiskIOMeasurement{
 		SubsystemMeasurement: sub,
 		serial:               serial,
@@ -9,7 +9,8 @@ import (
 
 func TestDiskIOMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewDiskIOMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewDiskIOMeasurement(now, r)
 	origSerial := string(m.serial)
 	duration := time.Second
 	oldVals := map[string]float64{}
@@ -18,8 +19,7 @@ func TestDiskIOMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.Label)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
@@ -27,7 +27,7 @@ func TestDiskIOMeasurementTick(t *testing.T) {
 	if got := string(m.serial); got != origSerial {
 		t.Errorf("server name updated unexpectedly: got %s want %s", got, origSerial)
 	}
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err = testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
@@ -39,10 +39,11 @@ func TestDiskIOMeasurementTick(t *testing.T) {
 
 func TestDiskIOMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewDiskIOMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewDiskIOMeasurement(now, r)
 	origSerial := string(m.serial)
 	duration := time.Second
-	m.Tick(duration)
+	m.Tick(duration, r)
 
 	p := data.NewPoint()
 	m.ToPoint(p)
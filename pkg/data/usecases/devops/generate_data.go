@@ -11,29 +11,38 @@ import (
 type DevopsSimulator struct {
 	*commonDevopsSimulator
 	simulatedMeasurementIndex int
+	// measurements is the number of measurements of every host, which is
+	// not read from the hosts, since churned hosts are replaced while other
+	// partitions simulate theirs
+	measurements int
 }
 
 // Next advances a Point to the next state in the distributionGenerator.
 func (d *DevopsSimulator) Next(p *data.Point) bool {
 	// switch to the next metric if needed
-	if d.hostIndex == uint64(len(d.hosts)) {
-		d.hostIndex = 0
+	if d.hostIndex == d.hi {
+		d.hostIndex = d.lo
 		d.simulatedMeasurementIndex++
 	}
 
-	if d.simulatedMeasurementIndex == len(d.hosts[0].SimulatedMeasurements) {
+	if d.simulatedMeasurementIndex == d.measurements {
 		d.simulatedMeasurementIndex = 0
-
-		for i := 0; i < len(d.hosts); i++ {
-			d.hosts[i].TickAll(d.interval)
-		}
-
+		d.tickHosts()
 		d.adjustNumHostsForEpoch()
 	}
 
 	return d.populatePoint(p, d.simulatedMeasurementIndex)
 }
 
+// Partition returns a simulator of the hosts [lo, hi) of d.
+func (d *DevopsSimulator) Partition(lo, hi int) common.Simulator {
+	return &DevopsSimulator{
+		commonDevopsSimulator:     d.partition(lo, hi),
+		simulatedMeasurementIndex: d.simulatedMeasurementIndex,
+		measurements:              d.measurements,
+	}
+}
+
 func (s *DevopsSimulator) TagKeys() []string {
 	tagKeysAsStr := make([]string, len(MachineTagKeys))
 	for i, t := range MachineTagKeys {
@@ -64,33 +73,22 @@ type DevopsSimulatorConfig commonDevopsSimulatorConfig
 // NewSimulator produces a Simulator that conforms to the given SimulatorConfig over the specified interval
 func (d *DevopsSimulatorConfig) NewSimulator(interval time.Duration, limit uint64) common.Simulator {
 	hostInfos := make([]Host, d.HostCount)
+	rands := newHostRands(commonDevopsSimulatorConfig(*d))
 	for i := 0; i < len(hostInfos); i++ {
-		hostInfos[i] = d.HostConstructor(NewHostCtx(i, d.Start))
+		hostInfos[i] = commonDevopsSimulatorConfig(*d).newHost(i, d.Start, rands[i])
 	}
 
+	measurements := len(hostInfos[0].SimulatedMeasurements)
 	epochs := calculateEpochs(commonDevopsSimulatorConfig(*d), interval)
-	maxPoints := epochs * d.HostCount * uint64(len(hostInfos[0].SimulatedMeasurements))
+	maxPoints := epochs * d.HostCount * uint64(measurements)
 	if limit > 0 && limit < maxPoints {
 		// Set specified points number limit
 		maxPoints = limit
 	}
 	dg := &DevopsSimulator{
-		commonDevopsSimulator: &commonDevopsSimulator{
-			madePoints: 0,
-			maxPoints:  maxPoints,
-
-			hostIndex: 0,
-			hosts:     hostInfos,
-
-			epoch:          0,
-			epochs:         epochs,
-			epochHosts:     d.InitHostCount,
-			initHosts:      d.InitHostCount,
-			timestampStart: d.Start,
-			timestampEnd:   d.End,
-			interval:       interval,
-		},
+		commonDevopsSimulator:     newCommonDevopsSimulator(commonDevopsSimulatorConfig(*d), hostInfos, rands, interval, maxPoints),
 		simulatedMeasurementIndex: 0,
+		measurements:              measurements,
 	}
 
 	return dg
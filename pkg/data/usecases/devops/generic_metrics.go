@@ -25,13 +25,13 @@ func initGenericMetricFields(size uint64) {
 	if genericMetricFields == nil {
 		genericMetricFields = make([]common.LabeledDistributionMaker, size)
 		for i := range genericMetricFields {
-			genericMetricFields[i] = common.LabeledDistributionMaker{Label: []byte(fmt.Sprintf("metric_%d", i)), DistributionMaker: func() common.Distribution { return common.CWD(metricND, 0.0, 1000, rand.Float64()*1000) }}
+			genericMetricFields[i] = common.LabeledDistributionMaker{Label: []byte(fmt.Sprintf("metric_%d", i)), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.CWD(metricND, 0.0, 1000, r.Float64()*1000) }}
 		}
 	}
 }
 
-func NewGenericMeasurements(start time.Time, count uint64) *GenericMeasurements {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, genericMetricFields[:count])
+func NewGenericMeasurements(start time.Time, count uint64, r *rand.Rand) *GenericMeasurements {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, genericMetricFields[:count], r)
 	return &GenericMeasurements{sub}
 }
 
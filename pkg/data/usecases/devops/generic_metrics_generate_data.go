@@ -22,13 +22,14 @@ type GenericMetricsSimulator struct {
 // 50% of hosts is long lived and 50% has a liftspan that follows zipf distribution.
 func (c *GenericMetricsSimulatorConfig) NewSimulator(interval time.Duration, limit uint64) common.Simulator {
 	hostInfos := make([]Host, c.HostCount)
+	rands := newHostRands(commonDevopsSimulatorConfig(*c.DevopsSimulatorConfig))
 	// initialize all generic metric fields at once so they can be reused for different hosts
 	initGenericMetricFields(c.MaxMetricCount)
 	hostMetricCount := generateHostMetricCount(c.HostCount, c.MaxMetricCount)
 	epochs := calculateEpochs(commonDevopsSimulatorConfig(*c.DevopsSimulatorConfig), interval)
 	epochsToLive := generateHostEpochsToLive(c.HostCount, epochs)
 	for i := 0; i < len(hostInfos); i++ {
-		hostInfos[i] = c.HostConstructor(&HostContext{i, c.Start, hostMetricCount[i], epochsToLive[i]})
+		hostInfos[i] = c.HostConstructor(&HostContext{i, c.Start, rands[i], hostMetricCount[i], epochsToLive[i]})
 	}
 
 	// This is not an optimal upper limit as it doesn't take into account host liveness but should be good enough
@@ -37,21 +38,7 @@ func (c *GenericMetricsSimulatorConfig) NewSimulator(interval time.Duration, lim
 		maxPoints = limit
 	}
 	dg := &GenericMetricsSimulator{
-		commonDevopsSimulator: &commonDevopsSimulator{
-			madePoints: 0,
-			maxPoints:  maxPoints,
-
-			hostIndex: 0,
-			hosts:     hostInfos,
-
-			epoch:          0,
-			epochs:         epochs,
-			epochHosts:     c.InitHostCount,
-			initHosts:      c.InitHostCount,
-			timestampStart: c.Start,
-			timestampEnd:   c.End,
-			interval:       interval,
-		},
+		commonDevopsSimulator: newCommonDevopsSimulator(commonDevopsSimulatorConfig(*c.DevopsSimulatorConfig), hostInfos, rands, interval, maxPoints),
 	}
 
 	return dg
@@ -80,15 +67,13 @@ func (gms *GenericMetricsSimulator) Headers() *common.GeneratedDataHeaders {
 
 // Next advances a Point to the next state in the distributionGenerator.
 func (gms *GenericMetricsSimulator) Next(p *data.Point) bool {
-	if gms.hostIndex >= uint64(len(gms.hosts)) {
+	if gms.hostIndex >= gms.hi {
 		// we ended here b/c we reach the host limit
 		// let's restart from the 1st host
-		gms.hostIndex = 0
+		gms.hostIndex = gms.lo
 		// advance time & measurements for all the hosts. Note that this will advance
 		// measurements for non started hosts as well - not an optimal but should be good enought
-		for _, h := range gms.hosts {
-			h.TickAll(gms.interval)
-		}
+		gms.tickHosts()
 		// increment epoch and adjust epoch hosts
 		gms.adjustNumHostsForEpoch()
 	}
@@ -110,7 +95,11 @@ func (gms *GenericMetricsSimulator) Next(p *data.Point) bool {
 	}
 
 	// otherwise just move to the next host
-	gms.hostIndex++
-	gms.madePoints++
+	gms.nextHost()
 	return false
 }
+
+// Partition returns a simulator of the hosts [lo, hi) of gms.
+func (gms *GenericMetricsSimulator) Partition(lo, hi int) common.Simulator {
+	return &GenericMetricsSimulator{gms.partition(lo, hi)}
+}
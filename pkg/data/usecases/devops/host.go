@@ -161,32 +161,32 @@ type generator func(ctx *HostContext) []common.SimulatedMeasurement
 
 func newHostMeasurements(ctx *HostContext) []common.SimulatedMeasurement {
 	return []common.SimulatedMeasurement{
-		NewCPUMeasurement(ctx.start),
-		NewDiskIOMeasurement(ctx.start),
-		NewDiskMeasurement(ctx.start),
-		NewKernelMeasurement(ctx.start),
-		NewMemMeasurement(ctx.start),
-		NewNetMeasurement(ctx.start),
-		NewNginxMeasurement(ctx.start),
-		NewPostgresqlMeasurement(ctx.start),
-		NewRedisMeasurement(ctx.start),
+		NewCPUMeasurement(ctx.start, ctx.rand),
+		NewDiskIOMeasurement(ctx.start, ctx.rand),
+		NewDiskMeasurement(ctx.start, ctx.rand),
+		NewKernelMeasurement(ctx.start, ctx.rand),
+		NewMemMeasurement(ctx.start, ctx.rand),
+		NewNetMeasurement(ctx.start, ctx.rand),
+		NewNginxMeasurement(ctx.start, ctx.rand),
+		NewPostgresqlMeasurement(ctx.start, ctx.rand),
+		NewRedisMeasurement(ctx.start, ctx.rand),
 	}
 }
 
 func newCPUOnlyHostMeasurements(ctx *HostContext) []common.SimulatedMeasurement {
 	return []common.SimulatedMeasurement{
-		NewCPUMeasurement(ctx.start),
+		NewCPUMeasurement(ctx.start, ctx.rand),
 	}
 }
 
 func newCPUSingleHostMeasurements(ctx *HostContext) []common.SimulatedMeasurement {
 	return []common.SimulatedMeasurement{
-		newSingleCPUMeasurement(ctx.start),
+		newSingleCPUMeasurement(ctx.start, ctx.rand),
 	}
 }
 
 func newGenericHostMeasurements(ctx *HostContext) []common.SimulatedMeasurement {
-	return []common.SimulatedMeasurement{NewGenericMeasurements(ctx.start, ctx.metricCount)}
+	return []common.SimulatedMeasurement{NewGenericMeasurements(ctx.start, ctx.metricCount, ctx.rand)}
 }
 
 // NewHost creates a new host in a simulated devops use case
@@ -215,20 +215,20 @@ func NewHostGenericMetrics(ctx *HostContext) Host {
 func newHostWithMeasurementGenerator(gen generator, ctx *HostContext) Host {
 	sm := gen(ctx)
 
-	region := randomRegionSliceChoice(regions)
+	region := randomRegionSliceChoice(regions, ctx.rand)
 
 	h := Host{
 		// Tag Values that are static throughout the life of a Host:
 		Name:               fmt.Sprintf(hostFmt, ctx.id),
 		Region:             region.Name,
-		Datacenter:         common.RandomStringSliceChoice(region.Datacenters),
-		Rack:               getStringRandomInt(machineRackChoicesPerDatacenter),
-		Arch:               common.RandomStringSliceChoice(MachineArchChoices),
-		OS:                 common.RandomStringSliceChoice(MachineOSChoices),
-		Service:            getStringRandomInt(machineServiceChoices),
-		ServiceVersion:     getStringRandomInt(machineServiceVersionChoices),
-		ServiceEnvironment: common.RandomStringSliceChoice(MachineServiceEnvironmentChoices),
-		Team:               common.RandomStringSliceChoice(MachineTeamChoices),
+		Datacenter:         common.RandomStringSliceChoice(region.Datacenters, ctx.rand),
+		Rack:               getStringRandomInt(machineRackChoicesPerDatacenter, ctx.rand),
+		Arch:               common.RandomStringSliceChoice(MachineArchChoices, ctx.rand),
+		OS:                 common.RandomStringSliceChoice(MachineOSChoices, ctx.rand),
+		Service:            getStringRandomInt(machineServiceChoices, ctx.rand),
+		ServiceVersion:     getStringRandomInt(machineServiceVersionChoices, ctx.rand),
+		ServiceEnvironment: common.RandomStringSliceChoice(MachineServiceEnvironmentChoices, ctx.rand),
+		Team:               common.RandomStringSliceChoice(MachineTeamChoices, ctx.rand),
 
 		SimulatedMeasurements: sm,
 		GenericMetricCount:    ctx.metricCount,
@@ -239,17 +239,24 @@ func newHostWithMeasurementGenerator(gen generator, ctx *HostContext) Host {
 	return h
 }
 
-// TickAll advances all Distributions of a Host.
-func (h *Host) TickAll(d time.Duration) {
+// TickAll advances all Distributions of a Host, drawing from the PRNG r of
+// the host.
+func (h *Host) TickAll(d time.Duration, r *rand.Rand) {
 	for i := range h.SimulatedMeasurements {
-		h.SimulatedMeasurements[i].Tick(d)
+		h.SimulatedMeasurements[i].Tick(d, r)
 	}
 }
 
-func getStringRandomInt(limit int64) string {
-	return strconv.FormatInt(rand.Int63n(limit), 10)
+// ChangeTags deploys another version of the service of the host, drawn
+// from r.
+func (h *Host) ChangeTags(r *rand.Rand) {
+	h.ServiceVersion = getStringRandomInt(machineServiceVersionChoices, r)
 }
 
-func randomRegionSliceChoice(s []region) *region {
-	return &s[rand.Intn(len(s))]
+func getStringRandomInt(limit int64, r *rand.Rand) string {
+	return strconv.FormatInt(r.Int63n(limit), 10)
+}
+
+func randomRegionSliceChoice(s []region, r *rand.Rand) *region {
+	return &s[r.Intn(len(s))]
 }
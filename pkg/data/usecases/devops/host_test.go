@@ -4,14 +4,16 @@ import (
 	"fmt"
 	"github.com/timescale/tsbs/pkg/data"
 	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"math/rand"
 	"strconv"
 	"testing"
 	"time"
 )
 
 func TestNewHostMeasurements(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
 	start := time.Now()
-	measurements := newHostMeasurements(NewHostCtxTime(start))
+	measurements := newHostMeasurements(NewHostCtxTime(start, r))
 	if got := len(measurements); got != 9 {
 		t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 	}
@@ -58,8 +60,9 @@ func TestNewHostMeasurements(t *testing.T) {
 }
 
 func TestNewCPUOnlyHostMeasurements(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
 	start := time.Now()
-	measurements := newCPUOnlyHostMeasurements(NewHostCtxTime(start))
+	measurements := newCPUOnlyHostMeasurements(NewHostCtxTime(start, r))
 	if got := len(measurements); got != 1 {
 		t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 	}
@@ -74,8 +77,9 @@ func TestNewCPUOnlyHostMeasurements(t *testing.T) {
 }
 
 func TestNewCPUSingleHostMeasurements(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
 	start := time.Now()
-	measurements := newCPUSingleHostMeasurements(NewHostCtxTime(start))
+	measurements := newCPUSingleHostMeasurements(NewHostCtxTime(start, r))
 	if got := len(measurements); got != 1 {
 		t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 	}
@@ -90,10 +94,11 @@ func TestNewCPUSingleHostMeasurements(t *testing.T) {
 }
 
 func TestNewHost(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
 	now := time.Now()
 	// test 1000 times to get diversity of results
 	for i := 0; i < 1000; i++ {
-		h := NewHost(NewHostCtx(i, now))
+		h := NewHost(NewHostCtx(i, now, r))
 		if got := len(h.SimulatedMeasurements); got != 9 {
 			t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 		}
@@ -113,10 +118,11 @@ func TestNewHost(t *testing.T) {
 }
 
 func TestNewHostCPUOnly(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
 	now := time.Now()
 	// test 1000 times to get diversity of results
 	for i := 0; i < 1000; i++ {
-		h := NewHostCPUOnly(NewHostCtx(i, now))
+		h := NewHostCPUOnly(NewHostCtx(i, now, r))
 		if got := len(h.SimulatedMeasurements); got != 1 {
 			t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 		}
@@ -136,10 +142,11 @@ func TestNewHostCPUOnly(t *testing.T) {
 }
 
 func TestNewHostCPUSingle(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
 	now := time.Now()
 	// test 1000 times to get diversity of results
 	for i := 0; i < 1000; i++ {
-		h := NewHostCPUSingle(NewHostCtx(i, now))
+		h := NewHostCPUSingle(NewHostCtx(i, now, r))
 		if got := len(h.SimulatedMeasurements); got != 1 {
 			t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 		}
@@ -159,13 +166,14 @@ func TestNewHostCPUSingle(t *testing.T) {
 }
 
 func TestNewHostGenericMeasurments(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
 	now := time.Now()
 	metricCount := uint64(100)
 	resetGenericMetricFields()
 	initGenericMetricFields(metricCount)
 	// test 1000 times to get diversity of results
 	for i := 0; i < 1000; i++ {
-		h := NewHostGenericMetrics(&HostContext{i, now, metricCount, 0})
+		h := NewHostGenericMetrics(&HostContext{i, now, r, metricCount, 0})
 		if got := len(h.SimulatedMeasurements); got != 1 {
 			t.Errorf("incorrect number of measurements: got %d want %d", got, 1)
 		}
@@ -210,10 +218,11 @@ func testStringNumberIsValid(t *testing.T, limit int64, s string) {
 }
 
 func TestNewHostWithMeasurementGenerator(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
 	now := time.Now()
 	// test 1000 times to get diversity of results
 	for i := 0; i < 1000; i++ {
-		h := newHostWithMeasurementGenerator(testGenerator, NewHostCtx(i, now))
+		h := newHostWithMeasurementGenerator(testGenerator, NewHostCtx(i, now, r))
 		wantName := fmt.Sprintf(hostFmt, i)
 		if got := string(h.Name); got != wantName {
 			t.Errorf("incorrect host name format: got %s want %s", got, wantName)
@@ -238,21 +247,22 @@ type testMeasurement struct {
 	ticks int
 }
 
-func (m *testMeasurement) Tick(_ time.Duration)  { m.ticks++ }
-func (m *testMeasurement) ToPoint(_ *data.Point) {}
+func (m *testMeasurement) Tick(_ time.Duration, _ *rand.Rand) { m.ticks++ }
+func (m *testMeasurement) ToPoint(_ *data.Point)              {}
 
 func TestHostTickAll(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
 	now := time.Now()
-	h := newHostWithMeasurementGenerator(testGenerator, NewHostCtxTime(now))
+	h := newHostWithMeasurementGenerator(testGenerator, NewHostCtxTime(now, r))
 	if got := h.SimulatedMeasurements[0].(*testMeasurement).ticks; got != 0 {
 		t.Errorf("ticks not equal to 0 to start: got %d", got)
 	}
-	h.TickAll(time.Second)
+	h.TickAll(time.Second, nil)
 	if got := h.SimulatedMeasurements[0].(*testMeasurement).ticks; got != 1 {
 		t.Errorf("ticks incorrect: got %d want %d", got, 1)
 	}
 	h.SimulatedMeasurements = append(h.SimulatedMeasurements, &testMeasurement{})
-	h.TickAll(time.Second)
+	h.TickAll(time.Second, nil)
 	if got := h.SimulatedMeasurements[0].(*testMeasurement).ticks; got != 2 {
 		t.Errorf("ticks incorrect after 2nd tick: got %d want %d", got, 2)
 	}
@@ -261,10 +271,25 @@ func TestHostTickAll(t *testing.T) {
 	}
 }
 
+func TestHostChangeTags(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
+	h := NewHost(NewHostCtxTime(time.Now(), r))
+	versions := map[string]bool{h.ServiceVersion: true}
+	for i := 0; i < 100; i++ {
+		h.ChangeTags(r)
+		testStringNumberIsValid(t, machineServiceVersionChoices, h.ServiceVersion)
+		versions[h.ServiceVersion] = true
+	}
+	if len(versions) < 2 {
+		t.Errorf("service version never changed")
+	}
+}
+
 func TestGetStringRandomInt(t *testing.T) {
 	limit := int64(100)
+	r := rand.New(rand.NewSource(123))
 	for i := 0; i < 1000000; i++ {
-		s := getStringRandomInt(limit)
+		s := getStringRandomInt(limit, r)
 		testStringNumberIsValid(t, limit, s)
 	}
 }
@@ -279,8 +304,9 @@ func testIfInRegionSlice(t *testing.T, arr []region, choice *region) {
 }
 
 func TestRandomRegionSliceChoice(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
 	for i := 0; i < 1000000; i++ {
-		r := randomRegionSliceChoice(regions)
-		testIfInRegionSlice(t, regions, r)
+		choice := randomRegionSliceChoice(regions, r)
+		testIfInRegionSlice(t, regions, choice)
 	}
 }
@@ -17,11 +17,11 @@ var (
 	kernelND = common.ND(5, 1)
 
 	kernelFields = []common.LabeledDistributionMaker{
-		{Label: []byte("interrupts"), DistributionMaker: func() common.Distribution { return common.MWD(kernelND, 0) }},
-		{Label: []byte("context_switches"), DistributionMaker: func() common.Distribution { return common.MWD(kernelND, 0) }},
-		{Label: []byte("processes_forked"), DistributionMaker: func() common.Distribution { return common.MWD(kernelND, 0) }},
-		{Label: []byte("disk_pages_in"), DistributionMaker: func() common.Distribution { return common.MWD(kernelND, 0) }},
-		{Label: []byte("disk_pages_out"), DistributionMaker: func() common.Distribution { return common.MWD(kernelND, 0) }},
+		{Label: []byte("interrupts"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(kernelND, 0) }},
+		{Label: []byte("context_switches"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(kernelND, 0) }},
+		{Label: []byte("processes_forked"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(kernelND, 0) }},
+		{Label: []byte("disk_pages_in"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(kernelND, 0) }},
+		{Label: []byte("disk_pages_out"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(kernelND, 0) }},
 	}
 )
 
@@ -30,9 +30,9 @@ type KernelMeasurement struct {
 	bootTime int64
 }
 
-func NewKernelMeasurement(start time.Time) *KernelMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, kernelFields)
-	bootTime := rand.Int63n(240)
+func NewKernelMeasurement(start time.Time, r *rand.Rand) *KernelMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, kernelFields, r)
+	bootTime := r.Int63n(240)
 	return &KernelMeasurement{
 		SubsystemMeasurement: sub,
 		bootTime:             bootTime,
@@ -9,7 +9,8 @@ import (
 
 func TestKernelMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewKernelMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewKernelMeasurement(now, r)
 	duration := time.Second
 	bootTime := m.bootTime
 	oldVals := map[string]float64{}
@@ -18,8 +19,7 @@ func TestKernelMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.Label)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
@@ -27,7 +27,7 @@ func TestKernelMeasurementTick(t *testing.T) {
 	if got := m.bootTime; got != bootTime {
 		t.Errorf("boot time changed unexpectedly: got %d", got)
 	}
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err = testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
@@ -39,10 +39,11 @@ func TestKernelMeasurementTick(t *testing.T) {
 
 func TestKernelMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewKernelMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewKernelMeasurement(now, r)
 	duration := time.Second
 	bootTime := m.bootTime
-	m.Tick(duration)
+	m.Tick(duration, r)
 
 	p := data.NewPoint()
 	m.ToPoint(p)
@@ -18,7 +18,8 @@ func testIfInInt64Slice(t *testing.T, arr []int64, choice int64) {
 
 func TestMemMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewMemMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewMemMeasurement(now, r)
 	duration := time.Second
 	oldVals := map[string]float64{}
 	oldTotal := m.bytesTotal
@@ -28,8 +29,7 @@ func TestMemMeasurementTick(t *testing.T) {
 		oldVals[string(f)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
@@ -37,7 +37,7 @@ func TestMemMeasurementTick(t *testing.T) {
 	if got := m.bytesTotal; got != oldTotal {
 		t.Errorf("total bytes unexpectedly changed: got %d want %d", got, oldTotal)
 	}
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err = testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
@@ -49,9 +49,10 @@ func TestMemMeasurementTick(t *testing.T) {
 
 func TestMemMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewMemMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewMemMeasurement(now, r)
 	duration := time.Second
-	m.Tick(duration)
+	m.Tick(duration, r)
 
 	p := data.NewPoint()
 	m.ToPoint(p)
@@ -19,14 +19,14 @@ var (
 	lowND  = common.ND(5, 1)
 
 	netFields = []common.LabeledDistributionMaker{
-		{Label: []byte("bytes_sent"), DistributionMaker: func() common.Distribution { return common.MWD(highND, 0) }},
-		{Label: []byte("bytes_recv"), DistributionMaker: func() common.Distribution { return common.MWD(highND, 0) }},
-		{Label: []byte("packets_sent"), DistributionMaker: func() common.Distribution { return common.MWD(highND, 0) }},
-		{Label: []byte("packets_recv"), DistributionMaker: func() common.Distribution { return common.MWD(highND, 0) }},
-		{Label: []byte("err_in"), DistributionMaker: func() common.Distribution { return common.MWD(lowND, 0) }},
-		{Label: []byte("err_out"), DistributionMaker: func() common.Distribution { return common.MWD(lowND, 0) }},
-		{Label: []byte("drop_in"), DistributionMaker: func() common.Distribution { return common.MWD(lowND, 0) }},
-		{Label: []byte("drop_out"), DistributionMaker: func() common.Distribution { return common.MWD(lowND, 0) }},
+		{Label: []byte("bytes_sent"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(highND, 0) }},
+		{Label: []byte("bytes_recv"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(highND, 0) }},
+		{Label: []byte("packets_sent"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(highND, 0) }},
+		{Label: []byte("packets_recv"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(highND, 0) }},
+		{Label: []byte("err_in"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(lowND, 0) }},
+		{Label: []byte("err_out"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(lowND, 0) }},
+		{Label: []byte("drop_in"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(lowND, 0) }},
+		{Label: []byte("drop_out"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(lowND, 0) }},
 	}
 )
 
@@ -35,9 +35,9 @@ type NetMeasurement struct {
 	interfaceName string
 }
 
-func NewNetMeasurement(start time.Time) *NetMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, netFields)
-	interfaceName := fmt.Sprintf("eth%d", rand.Intn(4))
+func NewNetMeasurement(start time.Time, r *rand.Rand) *NetMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, netFields, r)
+	interfaceName := fmt.Sprintf("eth%d", r.Intn(4))
 	return &NetMeasurement{
 		SubsystemMeasurement: sub,
 		interfaceName:        interfaceName,
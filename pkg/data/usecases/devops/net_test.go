@@ -9,7 +9,8 @@ import (
 
 func TestNetMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewNetMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewNetMeasurement(now, r)
 	origName := string(m.interfaceName)
 	duration := time.Second
 	oldVals := map[string]float64{}
@@ -18,8 +19,7 @@ func TestNetMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.Label)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
@@ -27,7 +27,7 @@ func TestNetMeasurementTick(t *testing.T) {
 	if got := string(m.interfaceName); got != origName {
 		t.Errorf("server name updated unexpectedly: got %s want %s", got, origName)
 	}
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err = testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
@@ -39,10 +39,11 @@ func TestNetMeasurementTick(t *testing.T) {
 
 func TestNetMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewNetMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewNetMeasurement(now, r)
 	origName := m.interfaceName
 	duration := time.Second
-	m.Tick(duration)
+	m.Tick(duration, r)
 
 	p := data.NewPoint()
 	m.ToPoint(p)
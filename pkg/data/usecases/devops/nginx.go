@@ -20,13 +20,13 @@ var (
 	nginxND = common.ND(5, 1)
 
 	nginxFields = []common.LabeledDistributionMaker{
-		{Label: []byte("accepts"), DistributionMaker: func() common.Distribution { return common.MWD(nginxND, 0) }},
-		{Label: []byte("active"), DistributionMaker: func() common.Distribution { return common.CWD(nginxND, 0, 100, 0) }},
-		{Label: []byte("handled"), DistributionMaker: func() common.Distribution { return common.MWD(nginxND, 0) }},
-		{Label: []byte("reading"), DistributionMaker: func() common.Distribution { return common.CWD(nginxND, 0, 100, 0) }},
-		{Label: []byte("requests"), DistributionMaker: func() common.Distribution { return common.MWD(nginxND, 0) }},
-		{Label: []byte("waiting"), DistributionMaker: func() common.Distribution { return common.CWD(nginxND, 0, 100, 0) }},
-		{Label: []byte("writing"), DistributionMaker: func() common.Distribution { return common.CWD(nginxND, 0, 100, 0) }},
+		{Label: []byte("accepts"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(nginxND, 0) }},
+		{Label: []byte("active"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.CWD(nginxND, 0, 100, 0) }},
+		{Label: []byte("handled"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(nginxND, 0) }},
+		{Label: []byte("reading"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.CWD(nginxND, 0, 100, 0) }},
+		{Label: []byte("requests"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.MWD(nginxND, 0) }},
+		{Label: []byte("waiting"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.CWD(nginxND, 0, 100, 0) }},
+		{Label: []byte("writing"), DistributionMaker: func(r *rand.Rand) common.Distribution { return common.CWD(nginxND, 0, 100, 0) }},
 	}
 )
 
@@ -35,10 +35,10 @@ type NginxMeasurement struct {
 	port, serverName string
 }
 
-func NewNginxMeasurement(start time.Time) *NginxMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, nginxFields)
-	serverName := fmt.Sprintf("nginx_%d", rand.Intn(100000))
-	port := strconv.FormatInt(rand.Int63n(20000)+1024, 10)
+func NewNginxMeasurement(start time.Time, r *rand.Rand) *NginxMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, nginxFields, r)
+	serverName := fmt.Sprintf("nginx_%d", r.Intn(100000))
+	port := strconv.FormatInt(r.Int63n(20000)+1024, 10)
 	return &NginxMeasurement{
 		SubsystemMeasurement: sub,
 		port:                 port,
@@ -9,7 +9,8 @@ import (
 
 func TestNginxMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewNginxMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewNginxMeasurement(now, r)
 	origName := string(m.serverName)
 	origPort := string(m.port)
 	duration := time.Second
@@ -19,8 +20,7 @@ func TestNginxMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.Label)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
@@ -31,7 +31,7 @@ func TestNginxMeasurementTick(t *testing.T) {
 	if got := string(m.port); got != origPort {
 		t.Errorf("port updated unexpectedly: got %s want %s", got, origPort)
 	}
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err = testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
@@ -46,11 +46,12 @@ func TestNginxMeasurementTick(t *testing.T) {
 
 func TestNginxMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewNginxMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewNginxMeasurement(now, r)
 	origName := m.serverName
 	origPort := m.port
 	duration := time.Second
-	m.Tick(duration)
+	m.Tick(duration, r)
 
 	p := data.NewPoint()
 	m.ToPoint(p)
@@ -9,7 +9,8 @@ import (
 
 func TestPostgresqlMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewPostgresqlMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewPostgresqlMeasurement(now, r)
 	duration := time.Second
 	oldVals := map[string]float64{}
 	fields := ldmToFieldLabels(postgresqlFields)
@@ -17,13 +18,12 @@ func TestPostgresqlMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.Label)] = m.Distributions[i].Get()
 	}
 
-	rand.Seed(123)
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err := testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
 	}
-	m.Tick(duration)
+	m.Tick(duration, r)
 	err = testDistributionsAreDifferent(oldVals, m.SubsystemMeasurement, fields)
 	if err != nil {
 		t.Errorf(err.Error())
@@ -32,9 +32,10 @@ func TestPostgresqlMeasurementTick(t *testing.T) {
 
 func TestPostgresqlMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewPostgresqlMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewPostgresqlMeasurement(now, r)
 	duration := time.Second
-	m.Tick(duration)
+	m.Tick(duration, r)
 
 	p := data.NewPoint()
 	m.ToPoint(p)
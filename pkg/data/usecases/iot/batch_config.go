@@ -1,21 +1,8 @@
 package iot
 
-import "math/rand"
-
-var (
-	// Batch chances.
-	bMissingChance        = 0.01
-	bOutOfOrderChance     = 0.05
-	bInsertPreviousChance = 0.5
-
-	// Entry chances.
-	eMissingChance        = 0.1
-	eOutOfOrderChance     = 0.3
-	eInsertPreviousChance = 0.5
-
-	// Zero values.
-	zeroTagChance   = 0.01
-	zeroFieldChance = 0.1
+import (
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"math/rand"
 )
 
 type batchConfig struct {
@@ -32,9 +19,11 @@ type batchConfig struct {
 	OutOfOrderEntries   map[int]bool
 }
 
-func newBatchConfig(outOfOrderBatchCount, outOfOrderEntryCount, fieldCount, tagCount int) *batchConfig {
+// newBatchConfig draws the configuration of the next batch from r, with the
+// chances of q.
+func newBatchConfig(q *common.Quality, outOfOrderBatchCount, outOfOrderEntryCount, fieldCount, tagCount int, r *rand.Rand) *batchConfig {
 
-	batchMissing := rand.Float64() < bMissingChance
+	batchMissing := r.Float64() < q.MissingBatchChance
 
 	if batchMissing {
 		return &batchConfig{
@@ -42,11 +31,11 @@ func newBatchConfig(outOfOrderBatchCount, outOfOrderEntryCount, fieldCount, tagC
 		}
 	}
 
-	batchOutOfOrder := rand.Float64() < bOutOfOrderChance
+	batchOutOfOrder := r.Float64() < q.OutOfOrderBatchChance
 
 	batchInsertPrevious := false
 	if outOfOrderBatchCount > 0 {
-		batchInsertPrevious = rand.Float64() < bInsertPreviousChance
+		batchInsertPrevious = r.Float64() < q.InsertPreviousBatchChance
 	}
 
 	zeroFields := make(map[int]int)
@@ -55,27 +44,27 @@ func newBatchConfig(outOfOrderBatchCount, outOfOrderEntryCount, fieldCount, tagC
 	missingEntries := make(map[int]bool)
 	outOfOrderEntries := make(map[int]bool)
 
-	for i := 0; i < defaultBatchSize; i++ {
-		if outOfOrderEntryCount > 0 && rand.Float64() < eInsertPreviousChance {
+	for i := 0; i < int(q.BatchSize); i++ {
+		if outOfOrderEntryCount > 0 && r.Float64() < q.InsertPreviousEntryChance {
 			insertPreviousEntry[i] = true
 			outOfOrderEntryCount--
 		}
 
-		if rand.Float64() < eMissingChance {
+		if r.Float64() < q.MissingEntryChance {
 			missingEntries[i] = true
 			// Since the entry is missing, no point in setting zero values or making it out-of-order.
 			continue
 		}
 
-		if fieldCount > 0 && rand.Float64() < zeroFieldChance {
-			zeroFields[i] = rand.Intn(fieldCount)
+		if fieldCount > 0 && r.Float64() < q.ZeroFieldChance {
+			zeroFields[i] = r.Intn(fieldCount)
 		}
 
-		if tagCount > 0 && rand.Float64() < zeroTagChance {
-			zeroTags[i] = rand.Intn(tagCount)
+		if tagCount > 0 && r.Float64() < q.ZeroTagChance {
+			zeroTags[i] = r.Intn(tagCount)
 		}
 
-		if rand.Float64() < eOutOfOrderChance {
+		if r.Float64() < q.OutOfOrderEntryChance {
 			outOfOrderEntries[i] = true
 		}
 	}
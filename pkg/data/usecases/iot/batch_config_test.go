@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
 )
 
 var (
@@ -17,11 +18,11 @@ func TestNewBatchConfig(t *testing.T) {
 	batchRuns := make([][]*batchConfig, numberOfRuns)
 
 	for i := 0; i < numberOfRuns; i++ {
-		rand.Seed(123)
+		r := rand.New(rand.NewSource(123))
 		batchRuns[i] = make([]*batchConfig, numberOfBatches)
 
 		for j := 0; j < numberOfBatches; j++ {
-			batchRuns[i][j] = newBatchConfig(j, j, j+5, j+5)
+			batchRuns[i][j] = newBatchConfig(&common.DefaultQuality, j, j, j+5, j+5, r)
 		}
 	}
 
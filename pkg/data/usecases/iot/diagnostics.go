@@ -3,6 +3,7 @@ package iot
 import (
 	"github.com/timescale/tsbs/pkg/data"
 	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"math/rand"
 	"time"
 )
 
@@ -25,7 +26,7 @@ var (
 	diagnosticsFields = []common.LabeledDistributionMaker{
 		{
 			Label: labelFuelState,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
 					&customFuelDistribution{common.CWD(fuelUD, 0, maxFuel, maxFuel)},
 					1,
@@ -34,7 +35,7 @@ var (
 		},
 		{
 			Label: labelCurrentLoad,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
 					common.LD(loadSaddleUD, loadUD, 1-loadChangeChance),
 					0,
@@ -43,7 +44,7 @@ var (
 		},
 		{
 			Label: labelStatus,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
 					common.CWD(statusND, 0, 5, 0),
 					0,
@@ -59,8 +60,8 @@ type customFuelDistribution struct {
 
 // Advance computes the next value of this distribution and stores it.
 // Its custom behavior is to refuel the truck once it gets to the min value.
-func (d *customFuelDistribution) Advance() {
-	d.ClampedRandomWalkDistribution.Advance()
+func (d *customFuelDistribution) Advance(r *rand.Rand) {
+	d.ClampedRandomWalkDistribution.Advance(r)
 	if d.State == d.Min {
 		d.State = d.Max
 	}
@@ -82,9 +83,9 @@ func (m *DiagnosticsMeasurement) ToPoint(p *data.Point) {
 	p.AppendField(diagnosticsFields[2].Label, int64(m.Distributions[2].Get()))
 }
 
-// NewDiagnosticsMeasurement creates a DiagnosticsMeasurement with start time.
-func NewDiagnosticsMeasurement(start time.Time) *DiagnosticsMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, diagnosticsFields)
+// NewDiagnosticsMeasurement creates a DiagnosticsMeasurement with start time, drawing its initial state from r.
+func NewDiagnosticsMeasurement(start time.Time, r *rand.Rand) *DiagnosticsMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, diagnosticsFields, r)
 
 	return &DiagnosticsMeasurement{
 		SubsystemMeasurement: sub,
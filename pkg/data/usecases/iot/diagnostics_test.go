@@ -3,15 +3,17 @@ package iot
 import (
 	"github.com/timescale/tsbs/pkg/data"
 	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"math/rand"
 	"testing"
 	"time"
 )
 
 func TestDiagnosticsMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewDiagnosticsMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewDiagnosticsMeasurement(now, r)
 	duration := time.Second
-	m.Tick(duration)
+	m.Tick(duration, r)
 
 	p := data.NewPoint()
 	m.ToPoint(p)
@@ -38,8 +40,8 @@ func TestCustomFuelDistribution(t *testing.T) {
 
 	for i := 0; i < testCount; i++ {
 		for clampedDist.Get() > fuelMin {
-			clampedDist.Advance()
-			fuelDist.Advance()
+			clampedDist.Advance(nil)
+			fuelDist.Advance(nil)
 
 			if clampedDist.Get() != fuelDist.Get() {
 
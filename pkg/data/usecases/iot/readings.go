@@ -1,6 +1,7 @@
 package iot
 
 import (
+	"bytes"
 	"github.com/timescale/tsbs/pkg/data"
 	"github.com/timescale/tsbs/pkg/data/usecases/common"
 	"math/rand"
@@ -34,34 +35,34 @@ var (
 	readingsFields = []common.LabeledDistributionMaker{
 		{
 			Label: labelLatitude,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
-					common.CWD(geoStepUD, -90.0, 90.0, rand.Float64()*maxLatitude),
+					common.CWD(geoStepUD, -90.0, 90.0, r.Float64()*maxLatitude),
 					5,
 				)
 			},
 		},
 		{
 			Label: labelLongitude,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
-					common.CWD(geoStepUD, -180, 180, rand.Float64()*maxLongitude),
+					common.CWD(geoStepUD, -180, 180, r.Float64()*maxLongitude),
 					5,
 				)
 			},
 		},
 		{
 			Label: labelElevation,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
-					common.CWD(bigUD, 0, maxElevation, rand.Float64()*500),
+					common.CWD(bigUD, 0, maxElevation, r.Float64()*500),
 					0,
 				)
 			},
 		},
 		{
 			Label: labelVelocity,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
 					common.CWD(bigUD, 0, maxVelocity, 0),
 					0,
@@ -70,16 +71,16 @@ var (
 		},
 		{
 			Label: labelHeading,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
-					common.CWD(smallUD, 0, maxHeading, rand.Float64()*maxHeading),
+					common.CWD(smallUD, 0, maxHeading, r.Float64()*maxHeading),
 					0,
 				)
 			},
 		},
 		{
 			Label: labelGrade,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
 					common.CWD(smallUD, 0, maxGrade, 0),
 					0,
@@ -88,7 +89,7 @@ var (
 		},
 		{
 			Label: labelFuelConsumption,
-			DistributionMaker: func() common.Distribution {
+			DistributionMaker: func(r *rand.Rand) common.Distribution {
 				return common.FP(
 					common.CWD(smallUD, 0, maxFuelConsumption, maxFuelConsumption/2),
 					1,
@@ -114,9 +115,19 @@ func (m *ReadingsMeasurement) ToPoint(p *data.Point) {
 	}
 }
 
-// NewReadingsMeasurement creates a new ReadingsMeasurement with start time.
-func NewReadingsMeasurement(start time.Time) *ReadingsMeasurement {
-	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, readingsFields)
+// ApplyPatterns makes the velocity and fuel consumption follow p, the other
+// readings being positions that do not have cycles.
+func (m *ReadingsMeasurement) ApplyPatterns(p *common.Patterns, truck common.Tag) {
+	for i, f := range readingsFields {
+		if bytes.Equal(f.Label, labelVelocity) || bytes.Equal(f.Label, labelFuelConsumption) {
+			p.Wrap(m.SubsystemMeasurement, i, labelReadings, f.Label, truck)
+		}
+	}
+}
+
+// NewReadingsMeasurement creates a new ReadingsMeasurement with start time, drawing its initial state from r.
+func NewReadingsMeasurement(start time.Time, r *rand.Rand) *ReadingsMeasurement {
+	sub := common.NewSubsystemMeasurementWithDistributionMakers(start, readingsFields, r)
 
 	return &ReadingsMeasurement{
 		SubsystemMeasurement: sub,
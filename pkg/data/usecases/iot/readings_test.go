@@ -1,16 +1,20 @@
 package iot
 
 import (
+	"bytes"
 	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"math/rand"
 	"testing"
 	"time"
 )
 
 func TestReadingsMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewReadingsMeasurement(now)
+	r := rand.New(rand.NewSource(123))
+	m := NewReadingsMeasurement(now, r)
 	duration := time.Second
-	m.Tick(duration)
+	m.Tick(duration, r)
 
 	p := data.NewPoint()
 	m.ToPoint(p)
@@ -24,3 +28,26 @@ func TestReadingsMeasurementToPoint(t *testing.T) {
 		}
 	}
 }
+
+func TestReadingsMeasurementApplyPatterns(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
+	// at the peak of the daily cycle, so that the cycle changes the value of
+	// a wrapped field in 12 hours, even at the bounds of its range
+	m := NewReadingsMeasurement(time.Date(2016, 1, 1, 14, 0, 0, 0, time.UTC), r)
+	p := &common.Patterns{DailyAmplitude: 0.2}
+	m.ApplyPatterns(p, common.Tag{Key: []byte("name"), Value: "truck_0"})
+
+	for i, f := range readingsFields {
+		fp, ok := m.Distributions[i].(*common.FloatPrecision)
+		if !ok {
+			t.Fatalf("field %s lost its float precision: got %T", f.Label, m.Distributions[i])
+		}
+		want := bytes.Equal(f.Label, labelVelocity) || bytes.Equal(f.Label, labelFuelConsumption)
+		before := fp.Get()
+		m.Timestamp = m.Timestamp.Add(12 * time.Hour)
+		if got := fp.Get() != before; got != want {
+			t.Errorf("field %s follows the patterns: got %v want %v", f.Label, got, want)
+		}
+		m.Timestamp = m.Timestamp.Add(-12 * time.Hour)
+	}
+}
@@ -3,14 +3,11 @@ package iot
 import (
 	"github.com/timescale/tsbs/pkg/data"
 	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"math/rand"
+	"sort"
 	"time"
 )
 
-const (
-	// The default size of a batch of entries within a simulation.
-	defaultBatchSize = 10
-)
-
 // SimulatorConfig is used to create an IoT Simulator.
 // It fulfills the common.SimulatorConfig interface.
 type SimulatorConfig common.BaseSimulatorConfig
@@ -19,6 +16,16 @@ type SimulatorConfig common.BaseSimulatorConfig
 // config over the specified interval and points limit.
 func (sc *SimulatorConfig) NewSimulator(interval time.Duration, limit uint64) common.Simulator {
 	s := (*common.BaseSimulatorConfig)(sc).NewSimulator(interval, limit)
+	if sc.Workers > 1 {
+		// the entries of the trucks are simulated in parallel, the batches
+		// built from them in order
+		s = common.NewParallelSimulator(s.(common.Partitioner), sc.Workers)
+	}
+
+	q := sc.Quality
+	if q == nil {
+		q = &common.DefaultQuality
+	}
 
 	maxFieldCount := 0
 
@@ -29,13 +36,28 @@ func (sc *SimulatorConfig) NewSimulator(interval time.Duration, limit uint64) co
 	}
 
 	return &Simulator{
-		base:            s,
-		batchSize:       defaultBatchSize,
-		configGenerator: newBatchConfig,
-		maxFieldCount:   maxFieldCount,
+		base:      s,
+		batchSize: q.BatchSize,
+		configGenerator: func(outOfOrderBatchCount, outOfOrderEntryCount, fieldCount, tagCount int, r *rand.Rand) *batchConfig {
+			return newBatchConfig(q, outOfOrderBatchCount, outOfOrderEntryCount, fieldCount, tagCount, r)
+		},
+		maxFieldCount: maxFieldCount,
+		tagCount:      len(s.TagKeys()),
+		rand:          common.NewRand(sc.Seed),
+		quality:       q,
+		// -1 is not the id of a truck
+		disorderRand: common.NewRand(common.EntitySeed(sc.Seed, -1)),
+		outages:      make(map[string][]*common.Outage),
+		report:       common.QualityReport{Backfilled: q.OutageBackfill},
 	}
 }
 
+// delayedEntry is an entry sent after its arrival time.
+type delayedEntry struct {
+	due   time.Time
+	entry *data.Point
+}
+
 // Simulator is responsible for simulating entries for the IoT use case.
 // It will run on batches of entries and apply the generated batch configuration
 // which it gets from the config distributionGenerator. That way it can introduce things like
@@ -43,9 +65,21 @@ func (sc *SimulatorConfig) NewSimulator(interval time.Duration, limit uint64) co
 type Simulator struct {
 	base            common.Simulator
 	batchSize       uint
-	configGenerator func(outOfOrderBatchCount, outOfOrderEntryCount, fieldCount, tagCount int) *batchConfig
+	configGenerator func(outOfOrderBatchCount, outOfOrderEntryCount, fieldCount, tagCount int, r *rand.Rand) *batchConfig
 	// maxFieldCount is the maximum amount of fields an entry can have
 	maxFieldCount int
+	// tagCount is the number of tags of an entry, which is not read from the
+	// trucks, since their tags change while they are simulated in parallel
+	tagCount int
+	// rand is the PRNG of the batch configurations, which is independent
+	// of the ones of the trucks
+	rand *rand.Rand
+	// quality is the late arrivals, duplicates and outages injected after
+	// the batches, if not nil, drawn from disorderRand, and report counts
+	// all the injected disorder
+	quality      *common.Quality
+	disorderRand *rand.Rand
+	report       common.QualityReport
 
 	// Mutable state.
 	currBatch         []*data.Point
@@ -54,6 +88,14 @@ type Simulator struct {
 	// offset is used for dealing with batch generation and keeping the
 	// insert index consistent.
 	offset int
+	// arrival is the arrival time of the last entry, the latest timestamp
+	// of the entries so far.
+	arrival time.Time
+	// delayed are the entries to send after their arrival time, by due
+	// time.
+	delayed []delayedEntry
+	// outages are the outages of each fleet, by start.
+	outages map[string][]*common.Outage
 }
 
 // Fields returns the fields of an entry.
@@ -73,13 +115,46 @@ func (s Simulator) TagTypes() []string {
 
 // Finished checks if the simulator is done.
 func (s Simulator) Finished() bool {
+	return s.entriesFinished() && len(s.delayed) == 0
+}
+
+// Stop ends the goroutines simulating the trucks, if any.
+func (s *Simulator) Stop() {
+	if st, ok := s.base.(common.Stopper); ok {
+		st.Stop()
+	}
+}
+
+// entriesFinished tells whether all the entries were taken from the
+// batches.
+func (s Simulator) entriesFinished() bool {
 	return s.base.Finished() && len(s.currBatch) == 0 && !s.pendingOutOfOrderItems()
 }
 
-// Next populates the serialize.Point with the next entry from the batch.
+// QualityReport returns the disorder injected so far.
+func (s *Simulator) QualityReport() *common.QualityReport {
+	return &s.report
+}
+
+// Next populates the serialize.Point with the next entry to send, which
+// may be an entry held back by the late arrivals, duplicates and outages.
+func (s *Simulator) Next(p *data.Point) bool {
+	var ret bool
+	if s.quality != nil && s.quality.Disordered() {
+		ret = s.nextDelayed(p)
+	} else {
+		ret = s.nextEntry(p)
+	}
+	if ret {
+		s.report.Entries++
+	}
+	return ret
+}
+
+// nextEntry populates the serialize.Point with the next entry from the batch.
 // If the current pregenerated batch is empty, it tries to generate a new one
 // in order to populate the next entry.
-func (s *Simulator) Next(p *data.Point) bool {
+func (s *Simulator) nextEntry(p *data.Point) bool {
 	if s.batchSize == 0 {
 		return s.base.Next(p)
 	}
@@ -146,7 +221,7 @@ func (s *Simulator) simulateNextBatch() bool {
 		return false
 	}
 
-	bc := s.configGenerator(len(s.outOfOrderBatches), len(s.outOfOrderEntries), s.maxFieldCount, len(s.TagKeys()))
+	bc := s.configGenerator(len(s.outOfOrderBatches), len(s.outOfOrderEntries), s.maxFieldCount, s.tagCount, s.rand)
 
 	if bc.InsertPrevious {
 		if len(s.outOfOrderBatches) == 0 {
@@ -158,11 +233,13 @@ func (s *Simulator) simulateNextBatch() bool {
 	}
 
 	if bc.Missing {
+		s.report.MissingBatches++
 		s.flushBatch()
 		return s.simulateNextBatch()
 	}
 
 	if bc.OutOfOrder {
+		s.report.OutOfOrderBatches++
 		s.generateOutOfOrderBatch(bc)
 		return s.simulateNextBatch()
 	}
@@ -203,6 +280,7 @@ func (s *Simulator) generateBatch(bc *batchConfig) []*data.Point {
 				index = index % len(keys)
 			}
 			entry.ClearFieldValue(keys[index])
+			s.report.ZeroFields++
 		}
 
 		if index, ok := bc.ZeroTags[i]; ok {
@@ -211,6 +289,7 @@ func (s *Simulator) generateBatch(bc *batchConfig) []*data.Point {
 				panic("trying to zero a tag value with a non-existant index")
 			}
 			entry.ClearTagValue(keys[index])
+			s.report.ZeroTags++
 		}
 
 		batch[i] = entry
@@ -236,6 +315,13 @@ func (s *Simulator) getNextEntry(index int, bc *batchConfig) (*data.Point, bool)
 			entry = s.outOfOrderEntries[0]
 			s.outOfOrderEntries = s.outOfOrderEntries[1:]
 		} else {
+			// skipping missing or out of order entries must not simulate
+			// entries past the end of the base simulator
+			if s.base.Finished() {
+				valid = false
+				break
+			}
+
 			entry = data.NewPoint()
 
 			if valid = s.base.Next(entry); !valid {
@@ -244,11 +330,13 @@ func (s *Simulator) getNextEntry(index int, bc *batchConfig) (*data.Point, bool)
 		}
 
 		if bc.MissingEntries[index+s.offset] {
+			s.report.MissingEntries++
 			s.offset++
 			continue
 		}
 
 		if bc.OutOfOrderEntries[index+s.offset] {
+			s.report.OutOfOrderEntries++
 			s.outOfOrderEntries = append(s.outOfOrderEntries, entry)
 			s.offset++
 			continue
@@ -277,5 +365,114 @@ func (s *Simulator) flushBatch() {
 		if !valid {
 			break
 		}
+		s.report.MissingBatchEntries++
+	}
+}
+
+// nextDelayed populates the serialize.Point with the next entry due to be
+// sent, taking entries from the batches and holding them back until they are
+// due.
+func (s *Simulator) nextDelayed(p *data.Point) bool {
+	for {
+		if len(s.delayed) > 0 && (s.entriesFinished() || !s.delayed[0].due.After(s.arrival)) {
+			p.Copy(s.delayed[0].entry)
+			s.delayed = s.delayed[1:]
+			return true
+		}
+		if s.entriesFinished() {
+			return false
+		}
+
+		entry := data.NewPoint()
+		if !s.nextEntry(entry) {
+			return false
+		}
+		s.disorder(entry)
+	}
+}
+
+// disorder holds back entry until it is due, which is at its arrival time
+// unless it is late or in an outage of its fleet, and may duplicate it.
+func (s *Simulator) disorder(entry *data.Point) {
+	q := s.quality
+	if entry.Timestamp().After(s.arrival) {
+		s.arrival = *entry.Timestamp()
+	}
+
+	if q.OutageInterval > 0 {
+		if fleet, ok := entry.GetTagValue(labelFleet).(string); ok {
+			if o := s.outage(fleet, *entry.Timestamp()); o != nil {
+				if o.Entries == 0 {
+					s.report.Outages = append(s.report.Outages, o)
+				}
+				o.Entries++
+				if q.OutageBackfill {
+					s.delay(entry, o.End)
+				}
+				return
+			}
+		}
+	}
+
+	due := s.arrival
+	if q.LateChance > 0 && s.disorderRand.Float64() < q.LateChance {
+		late := q.LateDelay.Draw(s.disorderRand)
+		due = due.Add(late)
+		s.report.LateEntries++
+		s.report.TotalLateness += late
+		if late > s.report.MaxLateness {
+			s.report.MaxLateness = late
+		}
+	}
+	s.delay(entry, due)
+
+	if q.DuplicateChance > 0 && s.disorderRand.Float64() < q.DuplicateChance {
+		duplicate := data.NewPoint()
+		duplicate.Copy(entry)
+		due := s.arrival
+		if q.LateDelay.Mean > 0 {
+			due = due.Add(q.LateDelay.Draw(s.disorderRand))
+		}
+		s.delay(duplicate, due)
+		s.report.Duplicates++
+	}
+}
+
+// delay holds back entry until due, after the entries due before or at the
+// same time.
+func (s *Simulator) delay(entry *data.Point, due time.Time) {
+	i := sort.Search(len(s.delayed), func(i int) bool {
+		return s.delayed[i].due.After(due)
+	})
+	s.delayed = append(s.delayed, delayedEntry{})
+	copy(s.delayed[i+1:], s.delayed[i:])
+	s.delayed[i] = delayedEntry{due: due, entry: entry}
+}
+
+// outage returns the outage of fleet at t, if any, drawing the outages of
+// the fleet up to t.
+func (s *Simulator) outage(fleet string, t time.Time) *common.Outage {
+	outages := s.outages[fleet]
+	// the outages of a fleet start after the first entry of the fleet
+	end := t
+	if len(outages) > 0 {
+		end = outages[len(outages)-1].End
+	}
+	for !end.After(t) {
+		start := end.Add(time.Duration(s.disorderRand.ExpFloat64() * float64(s.quality.OutageInterval)))
+		end = start.Add(s.quality.OutageDuration)
+		outages = append(outages, &common.Outage{Fleet: fleet, Start: start, End: end})
+	}
+	s.outages[fleet] = outages
+
+	for i := len(outages) - 1; i >= 0; i-- {
+		if t.Before(outages[i].Start) {
+			continue
+		}
+		if t.Before(outages[i].End) {
+			return outages[i]
+		}
+		break
 	}
+	return nil
 }
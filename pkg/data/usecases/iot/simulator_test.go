@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/timescale/tsbs/pkg/data"
 	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"math/rand"
 	"reflect"
 	"testing"
 	"time"
@@ -184,15 +185,15 @@ func pointsEqual(one *data.Point, two *data.Point) bool {
 func TestSimulatorNext(t *testing.T) {
 	cases := []struct {
 		desc                string
-		config              func(batchSize int) func(int, int, int, int) *batchConfig
+		config              func(batchSize int) func(int, int, int, int, *rand.Rand) *batchConfig
 		resultsPerBatchSize map[int][]int
 		zeroFieldsResults   map[int][]int
 		zeroTagsResults     map[int][]int
 	}{
 		{
 			desc: "no config",
-			config: func(batchSize int) func(i, j, k, z int) *batchConfig {
-				return func(i, j, k, z int) *batchConfig {
+			config: func(batchSize int) func(i, j, k, z int, r *rand.Rand) *batchConfig {
+				return func(i, j, k, z int, r *rand.Rand) *batchConfig {
 					return &batchConfig{}
 				}
 			},
@@ -206,8 +207,8 @@ func TestSimulatorNext(t *testing.T) {
 		},
 		{
 			desc: "all batches missing",
-			config: func(batchSize int) func(i, j, k, z int) *batchConfig {
-				return func(i, j, k, z int) *batchConfig {
+			config: func(batchSize int) func(i, j, k, z int, r *rand.Rand) *batchConfig {
+				return func(i, j, k, z int, r *rand.Rand) *batchConfig {
 					return &batchConfig{
 						Missing: true,
 					}
@@ -225,8 +226,8 @@ func TestSimulatorNext(t *testing.T) {
 			// Since we append all out of order stuff at the end, should have
 			// same results as no config.
 			desc: "all batches out of order",
-			config: func(batchSize int) func(i, j, k, z int) *batchConfig {
-				return func(i, j, k, z int) *batchConfig {
+			config: func(batchSize int) func(i, j, k, z int, r *rand.Rand) *batchConfig {
+				return func(i, j, k, z int, r *rand.Rand) *batchConfig {
 					return &batchConfig{
 						OutOfOrder: true,
 					}
@@ -242,8 +243,8 @@ func TestSimulatorNext(t *testing.T) {
 		},
 		{
 			desc: "first entry of every batch missing",
-			config: func(batchSize int) func(i, j, k, z int) *batchConfig {
-				return func(i, j, k, z int) *batchConfig {
+			config: func(batchSize int) func(i, j, k, z int, r *rand.Rand) *batchConfig {
+				return func(i, j, k, z int, r *rand.Rand) *batchConfig {
 					return &batchConfig{
 						MissingEntries: map[int]bool{0: true},
 					}
@@ -259,8 +260,8 @@ func TestSimulatorNext(t *testing.T) {
 		},
 		{
 			desc: "last entry of every batch missing",
-			config: func(batchSize int) func(i, j, k, z int) *batchConfig {
-				return func(i, j, k, z int) *batchConfig {
+			config: func(batchSize int) func(i, j, k, z int, r *rand.Rand) *batchConfig {
+				return func(i, j, k, z int, r *rand.Rand) *batchConfig {
 					return &batchConfig{
 						MissingEntries: map[int]bool{batchSize - 1: true},
 					}
@@ -276,8 +277,8 @@ func TestSimulatorNext(t *testing.T) {
 		},
 		{
 			desc: "first entry of every batch out of order",
-			config: func(batchSize int) func(i, j, k, z int) *batchConfig {
-				return func(i, j, k, z int) *batchConfig {
+			config: func(batchSize int) func(i, j, k, z int, r *rand.Rand) *batchConfig {
+				return func(i, j, k, z int, r *rand.Rand) *batchConfig {
 					return &batchConfig{
 						OutOfOrderEntries: map[int]bool{0: true},
 					}
@@ -293,8 +294,8 @@ func TestSimulatorNext(t *testing.T) {
 		},
 		{
 			desc: "last entry of every batch out of order",
-			config: func(batchSize int) func(i, j, k, z int) *batchConfig {
-				return func(i, j, k, z int) *batchConfig {
+			config: func(batchSize int) func(i, j, k, z int, r *rand.Rand) *batchConfig {
+				return func(i, j, k, z int, r *rand.Rand) *batchConfig {
 					return &batchConfig{
 						OutOfOrderEntries: map[int]bool{batchSize - 1: true},
 					}
@@ -310,8 +311,8 @@ func TestSimulatorNext(t *testing.T) {
 		},
 		{
 			desc: "insert first batch at the end",
-			config: func(batchSize int) func(i, j, k, z int) *batchConfig {
-				return func(i, j, k, z int) *batchConfig {
+			config: func(batchSize int) func(i, j, k, z int, r *rand.Rand) *batchConfig {
+				return func(i, j, k, z int, r *rand.Rand) *batchConfig {
 					return &batchConfig{
 						OutOfOrder: i == 0,
 					}
@@ -327,8 +328,8 @@ func TestSimulatorNext(t *testing.T) {
 		},
 		{
 			desc: "make every batch out of order and insert right away",
-			config: func(batchSize int) func(i, j, k, z int) *batchConfig {
-				return func(i, j, k, z int) *batchConfig {
+			config: func(batchSize int) func(i, j, k, z int, r *rand.Rand) *batchConfig {
+				return func(i, j, k, z int, r *rand.Rand) *batchConfig {
 					return &batchConfig{
 						OutOfOrder:     true,
 						InsertPrevious: i > 0,
@@ -345,8 +346,8 @@ func TestSimulatorNext(t *testing.T) {
 		},
 		{
 			desc: "insert last entry of previous batch as last entry of next batch",
-			config: func(batchSize int) func(i, j, k, z int) *batchConfig {
-				return func(i, j, k, z int) *batchConfig {
+			config: func(batchSize int) func(i, j, k, z int, r *rand.Rand) *batchConfig {
+				return func(i, j, k, z int, r *rand.Rand) *batchConfig {
 					insertPreviousEntry := make(map[int]bool)
 					if j > 0 {
 						insertPreviousEntry[batchSize-1] = true
@@ -367,8 +368,8 @@ func TestSimulatorNext(t *testing.T) {
 		},
 		{
 			desc: "insert first entry of previous batch as last entry of next batch",
-			config: func(batchSize int) func(i, j, k, z int) *batchConfig {
-				return func(i, j, k, z int) *batchConfig {
+			config: func(batchSize int) func(i, j, k, z int, r *rand.Rand) *batchConfig {
+				return func(i, j, k, z int, r *rand.Rand) *batchConfig {
 					insertPreviousEntry := make(map[int]bool)
 					if j > 0 {
 						insertPreviousEntry[batchSize-1] = true
@@ -389,8 +390,8 @@ func TestSimulatorNext(t *testing.T) {
 		},
 		{
 			desc: "insert multiple out of order entries sequentially",
-			config: func(batchSize int) func(i, j, k, z int) *batchConfig {
-				return func(i, j, k, z int) *batchConfig {
+			config: func(batchSize int) func(i, j, k, z int, r *rand.Rand) *batchConfig {
+				return func(i, j, k, z int, r *rand.Rand) *batchConfig {
 					insertPreviousEntry := make(map[int]bool)
 					if j > 0 {
 						for index := 0; index < j; index++ {
@@ -413,8 +414,8 @@ func TestSimulatorNext(t *testing.T) {
 		},
 		{
 			desc: "zero first field of the first entry for all batches",
-			config: func(batchSize int) func(i, j, k, z int) *batchConfig {
-				return func(i, j, k, z int) *batchConfig {
+			config: func(batchSize int) func(i, j, k, z int, r *rand.Rand) *batchConfig {
+				return func(i, j, k, z int, r *rand.Rand) *batchConfig {
 					return &batchConfig{
 						ZeroFields: map[int]int{0: 0},
 					}
@@ -437,8 +438,8 @@ func TestSimulatorNext(t *testing.T) {
 		},
 		{
 			desc: "zero 3rd tag of the last entry for all batches",
-			config: func(batchSize int) func(i, j, k, z int) *batchConfig {
-				return func(i, j, k, z int) *batchConfig {
+			config: func(batchSize int) func(i, j, k, z int, r *rand.Rand) *batchConfig {
+				return func(i, j, k, z int, r *rand.Rand) *batchConfig {
 					return &batchConfig{
 						ZeroTags: map[int]int{batchSize - 1: 3},
 					}
@@ -461,8 +462,8 @@ func TestSimulatorNext(t *testing.T) {
 		},
 		{
 			desc: "combine both zero field and zero tag",
-			config: func(batchSize int) func(i, j, k, z int) *batchConfig {
-				return func(i, j, k, z int) *batchConfig {
+			config: func(batchSize int) func(i, j, k, z int, r *rand.Rand) *batchConfig {
+				return func(i, j, k, z int, r *rand.Rand) *batchConfig {
 					return &batchConfig{
 						ZeroFields: map[int]int{0: 0},
 						ZeroTags:   map[int]int{batchSize - 1: 3},
@@ -578,3 +579,204 @@ func TestSimulatorTagTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestSimulatorWorkers(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := func(workers int, churn *common.Churn) []string {
+		sc := &SimulatorConfig{
+			Start: start,
+			End:   start.Add(time.Minute),
+
+			InitGeneratorScale:   5,
+			GeneratorScale:       13,
+			GeneratorConstructor: NewTruck,
+			Seed:                 123,
+			Workers:              workers,
+			Churn:                churn,
+		}
+		s := sc.NewSimulator(time.Second, 0)
+		ret := make([]string, 0)
+		p := data.NewPoint()
+		for !s.Finished() {
+			write := s.Next(p)
+			ret = append(ret, fmt.Sprintf("%t %s %v %v %v", write, p.MeasurementName(), p.TagValues(), p.FieldValues(), p.Timestamp()))
+			p.Reset()
+		}
+		return ret
+	}
+
+	churn := &common.Churn{Lifetime: 10 * time.Second, TagChangeRate: 0.1, CardinalityTag: []byte("pod"), CardinalityValues: 10}
+	for _, c := range []*common.Churn{nil, churn} {
+		want := points(1, c)
+		for _, workers := range []int{2, 5, 13, 20} {
+			if got := points(workers, c); !reflect.DeepEqual(got, want) {
+				t.Errorf("%d workers, churn %v: points differ from the ones of a single worker", workers, c)
+			}
+		}
+	}
+}
+
+type disorderedEntry struct {
+	fleet     interface{}
+	timestamp time.Time
+	// arrival is the latest timestamp of the entries sent before it
+	arrival time.Time
+	line    string
+}
+
+func disorderedEntries(q *common.Quality) ([]disorderedEntry, *common.QualityReport) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	sc := &SimulatorConfig{
+		Start:                start,
+		End:                  start.Add(2 * time.Hour),
+		InitGeneratorScale:   4,
+		GeneratorScale:       4,
+		GeneratorConstructor: NewTruck,
+		Seed:                 123,
+		Quality:              q,
+	}
+	s := sc.NewSimulator(10*time.Second, 0)
+	var entries []disorderedEntry
+	var arrival time.Time
+	p := data.NewPoint()
+	for !s.Finished() {
+		if s.Next(p) {
+			entries = append(entries, disorderedEntry{
+				fleet:     p.GetTagValue(labelFleet),
+				timestamp: *p.Timestamp(),
+				arrival:   arrival,
+				line:      fmt.Sprintf("%s %v %v %v", p.MeasurementName(), p.TagValues(), p.FieldValues(), p.Timestamp()),
+			})
+			if p.Timestamp().After(arrival) {
+				arrival = *p.Timestamp()
+			}
+		}
+		p.Reset()
+	}
+	return entries, s.(common.QualityReporter).QualityReport()
+}
+
+func entryLines(entries []disorderedEntry) map[string]int {
+	lines := map[string]int{}
+	for _, e := range entries {
+		lines[e.line]++
+	}
+	return lines
+}
+
+func TestSimulatorLateEntries(t *testing.T) {
+	want, _ := disorderedEntries(&common.Quality{})
+	got, report := disorderedEntries(&common.Quality{
+		LateChance: 0.1,
+		LateDelay:  common.Delay{Distribution: common.DelayConstant, Mean: time.Minute},
+	})
+	if !reflect.DeepEqual(entryLines(got), entryLines(want)) {
+		t.Fatalf("late entries are not the entries without disorder")
+	}
+	if report.LateEntries == 0 || report.MaxLateness != time.Minute {
+		t.Errorf("incorrect late entries: got %d with max delay %v", report.LateEntries, report.MaxLateness)
+	}
+	if report.Entries != uint64(len(got)) {
+		t.Errorf("incorrect number of entries sent: got %d want %d", report.Entries, len(got))
+	}
+
+	late := uint64(0)
+	last := got[len(got)-1].timestamp
+	for _, e := range got {
+		// late entries are sent once an entry a minute after them is
+		// simulated, i.e. after the entries of the reading before it, unless
+		// they are due after the last one
+		lateness := e.arrival.Sub(e.timestamp)
+		if lateness > 0 && e.arrival.Before(last) {
+			if lateness < 50*time.Second || lateness > 70*time.Second {
+				t.Errorf("entry at %v sent %v late", e.timestamp, lateness)
+			}
+			late++
+		}
+	}
+	if late == 0 || late > report.LateEntries {
+		t.Errorf("incorrect number of late entries sent: got %d want at most %d", late, report.LateEntries)
+	}
+}
+
+func TestSimulatorDuplicates(t *testing.T) {
+	want, _ := disorderedEntries(&common.Quality{})
+	got, report := disorderedEntries(&common.Quality{DuplicateChance: 1})
+	if len(got) != 2*len(want) {
+		t.Fatalf("incorrect number of entries: got %d want %d", len(got), 2*len(want))
+	}
+	for i := range want {
+		if got[2*i].line != want[i].line || got[2*i+1].line != want[i].line {
+			t.Fatalf("entry %d not followed by its duplicate: got\n%s\n%s\nwant\n%s", i, got[2*i].line, got[2*i+1].line, want[i].line)
+		}
+	}
+	if report.Duplicates != uint64(len(want)) {
+		t.Errorf("incorrect number of duplicates: got %d want %d", report.Duplicates, len(want))
+	}
+}
+
+func TestSimulatorOutages(t *testing.T) {
+	want, _ := disorderedEntries(&common.Quality{})
+	q := &common.Quality{OutageInterval: 30 * time.Minute, OutageDuration: 10 * time.Minute, OutageBackfill: true}
+	inOutage := func(e disorderedEntry, outages []*common.Outage) *common.Outage {
+		for _, o := range outages {
+			if o.Fleet == e.fleet && !e.timestamp.Before(o.Start) && e.timestamp.Before(o.End) {
+				return o
+			}
+		}
+		return nil
+	}
+
+	got, report := disorderedEntries(q)
+	if len(report.Outages) == 0 {
+		t.Fatalf("no outages")
+	}
+	if !reflect.DeepEqual(entryLines(got), entryLines(want)) {
+		t.Fatalf("backfilled entries are not the entries without disorder")
+	}
+	// the entries of an outage are sent after the entries of the other
+	// fleets until its end
+	for _, o := range report.Outages {
+		last := -1
+		for i, e := range got {
+			if e.timestamp.Before(o.End) && inOutage(e, report.Outages) == nil {
+				last = i
+			}
+		}
+		for i, e := range got {
+			if inOutage(e, report.Outages) == o && i < last {
+				t.Errorf("entry of fleet %v at %v sent during its outage until %v", e.fleet, e.timestamp, o.End)
+				break
+			}
+		}
+	}
+
+	q.OutageBackfill = false
+	got, report = disorderedEntries(q)
+	lost := uint64(0)
+	for _, o := range report.Outages {
+		lost += o.Entries
+	}
+	if uint64(len(got))+lost != uint64(len(want)) {
+		t.Errorf("incorrect number of entries: got %d + %d lost want %d", len(got), lost, len(want))
+	}
+	for _, e := range got {
+		if inOutage(e, report.Outages) != nil {
+			t.Errorf("entry of fleet %v at %v sent despite the outage", e.fleet, e.timestamp)
+		}
+	}
+}
+
+func TestSimulatorQualityReport(t *testing.T) {
+	want, _ := disorderedEntries(&common.Quality{})
+	got, report := disorderedEntries(&common.DefaultQuality)
+	if report.Entries != uint64(len(got)) {
+		t.Errorf("incorrect number of entries sent: got %d want %d", report.Entries, len(got))
+	}
+	if lost := report.MissingEntries + report.MissingBatchEntries; report.Entries+lost != uint64(len(want)) {
+		t.Errorf("incorrect number of missing entries: got %d sent + %d missing want %d", report.Entries, lost, len(want))
+	}
+	if report.MissingBatches == 0 || report.OutOfOrderBatches == 0 || report.OutOfOrderEntries == 0 || report.ZeroFields == 0 {
+		t.Errorf("disorder of the batches not reported: got %+v", report)
+	}
+}
@@ -9,6 +9,8 @@ import (
 
 const (
 	truckNameFmt = "truck_%d"
+	// driverTagIndex is the index of the driver among the tags of a truck
+	driverTagIndex = 2
 )
 
 type model struct {
@@ -19,6 +21,8 @@ type model struct {
 }
 
 var (
+	labelFleet = []byte("fleet")
+
 	driverChoices = []string{
 		"Derek",
 		"Rodney",
@@ -71,13 +75,19 @@ type Truck struct {
 	tags                  []common.Tag
 }
 
-// TickAll advances all Distributions of a Truck.
-func (t *Truck) TickAll(d time.Duration) {
+// TickAll advances all Distributions of a Truck, drawing from the PRNG r of
+// the truck.
+func (t *Truck) TickAll(d time.Duration, r *rand.Rand) {
 	for i := range t.simulatedMeasurements {
-		t.simulatedMeasurements[i].Tick(d)
+		t.simulatedMeasurements[i].Tick(d, r)
 	}
 }
 
+// ChangeTags reassigns the truck to another driver, drawn from r.
+func (t *Truck) ChangeTags(r *rand.Rand) {
+	t.tags[driverTagIndex].Value = common.RandomStringSliceChoice(driverChoices, r)
+}
+
 // Measurements returns the trucks measurements.
 func (t Truck) Measurements() []common.SimulatedMeasurement {
 	return t.simulatedMeasurements
@@ -88,31 +98,32 @@ func (t Truck) Tags() []common.Tag {
 	return t.tags
 }
 
-func newTruckMeasurements(start time.Time) []common.SimulatedMeasurement {
+func newTruckMeasurements(start time.Time, r *rand.Rand) []common.SimulatedMeasurement {
 	return []common.SimulatedMeasurement{
-		NewReadingsMeasurement(start),
-		NewDiagnosticsMeasurement(start),
+		NewReadingsMeasurement(start, r),
+		NewDiagnosticsMeasurement(start, r),
 	}
 }
 
-// NewTruck creates a new truck in a simulated iot use case
-func NewTruck(i int, start time.Time) common.Generator {
-	truck := newTruckWithMeasurementGenerator(i, start, newTruckMeasurements)
+// NewTruck creates a new truck in a simulated iot use case, drawing its tags
+// and initial state from r
+func NewTruck(i int, start time.Time, r *rand.Rand) common.Generator {
+	truck := newTruckWithMeasurementGenerator(i, start, r, newTruckMeasurements)
 	return &truck
 }
 
-func newTruckWithMeasurementGenerator(i int, start time.Time, generator func(time.Time) []common.SimulatedMeasurement) Truck {
-	sm := generator(start)
+func newTruckWithMeasurementGenerator(i int, start time.Time, r *rand.Rand, generator func(time.Time, *rand.Rand) []common.SimulatedMeasurement) Truck {
+	sm := generator(start, r)
 
-	m := modelChoices[rand.Intn(len(modelChoices))]
+	m := modelChoices[r.Intn(len(modelChoices))]
 
 	h := Truck{
 		tags: []common.Tag{
 			{Key: []byte("name"), Value: fmt.Sprintf(truckNameFmt, i)},
-			{Key: []byte("fleet"), Value: common.RandomStringSliceChoice(FleetChoices)},
-			{Key: []byte("driver"), Value: common.RandomStringSliceChoice(driverChoices)},
+			{Key: labelFleet, Value: common.RandomStringSliceChoice(FleetChoices, r)},
+			{Key: []byte("driver"), Value: common.RandomStringSliceChoice(driverChoices, r)},
 			{Key: []byte("model"), Value: m.Name},
-			{Key: []byte("device_version"), Value: common.RandomStringSliceChoice(deviceVersionChoices)},
+			{Key: []byte("device_version"), Value: common.RandomStringSliceChoice(deviceVersionChoices, r)},
 			{Key: []byte("load_capacity"), Value: m.LoadCapacity},
 			{Key: []byte("fuel_capacity"), Value: m.FuelCapacity},
 			{Key: []byte("nominal_fuel_consumption"), Value: m.FuelConsumption},
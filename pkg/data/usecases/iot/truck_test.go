@@ -3,11 +3,13 @@ package iot
 import (
 	"github.com/timescale/tsbs/pkg/data"
 	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"math/rand"
+	"reflect"
 	"testing"
 	"time"
 )
 
-func testGenerator(s time.Time) []common.SimulatedMeasurement {
+func testGenerator(s time.Time, r *rand.Rand) []common.SimulatedMeasurement {
 	return []common.SimulatedMeasurement{
 		&testMeasurement{ticks: 0},
 	}
@@ -17,13 +19,14 @@ type testMeasurement struct {
 	ticks int
 }
 
-func (m *testMeasurement) Tick(_ time.Duration)  { m.ticks++ }
-func (m *testMeasurement) ToPoint(_ *data.Point) {}
+func (m *testMeasurement) Tick(_ time.Duration, _ *rand.Rand) { m.ticks++ }
+func (m *testMeasurement) ToPoint(_ *data.Point)              {}
 
 func TestNewTruckMeasurements(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
 	start := time.Now()
 
-	measurements := newTruckMeasurements(start)
+	measurements := newTruckMeasurements(start, r)
 
 	if got := len(measurements); got != 2 {
 		t.Errorf("incorrect number of measurements: got %d want %d", got, 2)
@@ -42,8 +45,9 @@ func TestNewTruckMeasurements(t *testing.T) {
 }
 
 func TestNewTruck(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
 	start := time.Now()
-	generator := NewTruck(1, start)
+	generator := NewTruck(1, start, r)
 
 	truck := generator.(*Truck)
 
@@ -57,17 +61,18 @@ func TestNewTruck(t *testing.T) {
 }
 
 func TestTruckTickAll(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
 	now := time.Now()
-	truck := newTruckWithMeasurementGenerator(0, now, testGenerator)
+	truck := newTruckWithMeasurementGenerator(0, now, r, testGenerator)
 	if got := truck.simulatedMeasurements[0].(*testMeasurement).ticks; got != 0 {
 		t.Errorf("ticks not equal to 0 to start: got %d", got)
 	}
-	truck.TickAll(time.Second)
+	truck.TickAll(time.Second, nil)
 	if got := truck.simulatedMeasurements[0].(*testMeasurement).ticks; got != 1 {
 		t.Errorf("ticks incorrect: got %d want %d", got, 1)
 	}
 	truck.simulatedMeasurements = append(truck.simulatedMeasurements, &testMeasurement{})
-	truck.TickAll(time.Second)
+	truck.TickAll(time.Second, nil)
 	if got := truck.simulatedMeasurements[0].(*testMeasurement).ticks; got != 2 {
 		t.Errorf("ticks incorrect after 2nd tick: got %d want %d", got, 2)
 	}
@@ -75,3 +80,35 @@ func TestTruckTickAll(t *testing.T) {
 		t.Errorf("ticks incorrect after 2nd tick: got %d want %d", got, 1)
 	}
 }
+
+func TestNewTruckDeterministic(t *testing.T) {
+	start := time.Now()
+	tags := func(seed int64) []common.Tag {
+		return NewTruck(3, start, common.NewRand(common.EntitySeed(seed, 3))).Tags()
+	}
+	want := tags(123)
+	// advancing the global source must not affect the truck
+	rand.Float64()
+	if got := tags(123); !reflect.DeepEqual(got, want) {
+		t.Errorf("trucks with the same seed differ: got %v want %v", got, want)
+	}
+}
+
+func TestTruckChangeTags(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
+	truck := NewTruck(0, time.Now(), r).(*Truck)
+	before := append([]common.Tag(nil), truck.Tags()...)
+	drivers := map[interface{}]bool{}
+	for i := 0; i < 100; i++ {
+		truck.ChangeTags(r)
+		drivers[truck.Tags()[driverTagIndex].Value] = true
+		for j, tag := range truck.Tags() {
+			if j != driverTagIndex && !reflect.DeepEqual(tag, before[j]) {
+				t.Fatalf("tag %s changed: got %v want %v", tag.Key, tag.Value, before[j].Value)
+			}
+		}
+	}
+	if len(drivers) < 2 {
+		t.Errorf("driver never reassigned")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/timescale/tsbs/internal/utils"
 	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"github.com/timescale/tsbs/pkg/data/usecases/custom"
 	"github.com/timescale/tsbs/pkg/data/usecases/devops"
 	"github.com/timescale/tsbs/pkg/data/usecases/iot"
 	"math"
@@ -12,6 +13,13 @@ import (
 const errCannotParseTimeFmt = "cannot parse time from string '%s': %v"
 
 func GetSimulatorConfig(dgc *common.DataGeneratorConfig) (common.SimulatorConfig, error) {
+	return GetSimulatorConfigWithPatterns(dgc, dgc.NewPatterns())
+}
+
+// GetSimulatorConfigWithPatterns returns the SimulatorConfig of dgc, whose
+// simulators follow patterns, if not nil, in the use cases that support them.
+func GetSimulatorConfigWithPatterns(dgc *common.DataGeneratorConfig, patterns *common.Patterns) (common.SimulatorConfig, error) {
+	churn := dgc.NewChurn()
 	var ret common.SimulatorConfig
 	var err error
 	tsStart, err := utils.ParseUTCTime(dgc.TimeStart)
@@ -32,6 +40,9 @@ func GetSimulatorConfig(dgc *common.DataGeneratorConfig) (common.SimulatorConfig
 			InitHostCount:   dgc.InitialScale,
 			HostCount:       dgc.Scale,
 			HostConstructor: devops.NewHost,
+			Seed:            dgc.Seed,
+			Patterns:        patterns,
+			Churn:           churn,
 		}
 	case common.UseCaseIoT:
 		ret = &iot.SimulatorConfig{
@@ -41,6 +52,11 @@ func GetSimulatorConfig(dgc *common.DataGeneratorConfig) (common.SimulatorConfig
 			InitGeneratorScale:   dgc.InitialScale,
 			GeneratorScale:       dgc.Scale,
 			GeneratorConstructor: iot.NewTruck,
+			Seed:                 dgc.Seed,
+			Workers:              int(dgc.Workers),
+			Patterns:             patterns,
+			Churn:                churn,
+			Quality:              dgc.NewQuality(),
 		}
 	case common.UseCaseCPUOnly:
 		ret = &devops.CPUOnlySimulatorConfig{
@@ -50,6 +66,9 @@ func GetSimulatorConfig(dgc *common.DataGeneratorConfig) (common.SimulatorConfig
 			InitHostCount:   dgc.InitialScale,
 			HostCount:       dgc.Scale,
 			HostConstructor: devops.NewHostCPUOnly,
+			Seed:            dgc.Seed,
+			Patterns:        patterns,
+			Churn:           churn,
 		}
 	case common.UseCaseCPUSingle:
 		ret = &devops.CPUOnlySimulatorConfig{
@@ -59,6 +78,9 @@ func GetSimulatorConfig(dgc *common.DataGeneratorConfig) (common.SimulatorConfig
 			InitHostCount:   dgc.InitialScale,
 			HostCount:       dgc.Scale,
 			HostConstructor: devops.NewHostCPUSingle,
+			Seed:            dgc.Seed,
+			Patterns:        patterns,
+			Churn:           churn,
 		}
 	case common.UseCaseDevopsGeneric:
 		if dgc.InitialScale == dgc.Scale {
@@ -74,8 +96,29 @@ func GetSimulatorConfig(dgc *common.DataGeneratorConfig) (common.SimulatorConfig
 				HostCount:       dgc.Scale,
 				HostConstructor: devops.NewHostGenericMetrics,
 				MaxMetricCount:  dgc.MaxMetricCountPerHost,
+				Seed:            dgc.Seed,
 			},
 		}
+	case common.UseCaseCustom:
+		schema, err := custom.LoadSchema(dgc.CustomSchema)
+		if err != nil {
+			return nil, err
+		}
+		if schema.Entity.Count > 0 {
+			// the schema fixes the number of entities
+			dgc.Scale = schema.Entity.Count
+			dgc.InitialScale = schema.Entity.Count
+		}
+		ret = &custom.SimulatorConfig{
+			Start: tsStart,
+			End:   tsEnd,
+
+			InitGeneratorScale: dgc.InitialScale,
+			GeneratorScale:     dgc.Scale,
+			Seed:               dgc.Seed,
+			Workers:            int(dgc.Workers),
+			Schema:             schema,
+		}
 	default:
 		err = fmt.Errorf("unknown use case: '%s'", dgc.Use)
 	}
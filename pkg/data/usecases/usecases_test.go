@@ -2,8 +2,12 @@ package usecases
 
 import (
 	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"github.com/timescale/tsbs/pkg/data/usecases/custom"
 	"github.com/timescale/tsbs/pkg/data/usecases/devops"
 	"github.com/timescale/tsbs/pkg/data/usecases/iot"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -39,8 +43,32 @@ func TestGetSimulatorConfig(t *testing.T) {
 	checkType(common.UseCaseCPUOnly, &devops.CPUOnlySimulatorConfig{})
 	checkType(common.UseCaseCPUSingle, &devops.CPUOnlySimulatorConfig{})
 
+	dir, err := ioutil.TempDir("", "tsbs-custom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	schema := filepath.Join(dir, "schema.yaml")
+	dgc.CustomSchema = schema
+	dgc.Use = common.UseCaseCustom
+	if _, err := GetSimulatorConfig(dgc); err == nil {
+		t.Errorf("unexpected lack of error for missing custom schema")
+	}
+	yaml := "entity: {name: sensor, count: 3}\n" +
+		"measurements:\n" +
+		"- name: env\n" +
+		"  fields:\n" +
+		"  - {name: temp, distribution: {type: nd, stddev: 1}}\n"
+	if err := ioutil.WriteFile(schema, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	checkType(common.UseCaseCustom, &custom.SimulatorConfig{})
+	if dgc.Scale != 3 || dgc.InitialScale != 3 {
+		t.Errorf("entity count of schema does not override scale: got %d and %d", dgc.Scale, dgc.InitialScale)
+	}
+
 	dgc.Use = "bogus use case"
-	_, err := GetSimulatorConfig(dgc)
+	_, err = GetSimulatorConfig(dgc)
 	if err == nil {
 		t.Errorf("unexpected lack of error for bogus use case")
 	}
@@ -0,0 +1,95 @@
+package inspect
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// maxListedSeries is the number of series of each kind of difference that a
+// Diff writes.
+const maxListedSeries = 10
+
+// Diff is the difference between the series of two Stats.
+type Diff struct {
+	// OnlyA and OnlyB are the series of only one of the Stats
+	OnlyA, OnlyB []string
+	// Different are the series whose points differ
+	Different []SeriesDiff
+	// Same is the number of identical series
+	Same int
+}
+
+// SeriesDiff is a series whose points differ.
+type SeriesDiff struct {
+	Key              string
+	PointsA, PointsB uint64
+}
+
+// Compare compares the series of a and b. Two series are identical if they
+// have the same points in the same order, the order of the points of
+// different series does not matter.
+func Compare(a, b *Stats) *Diff {
+	d := &Diff{}
+	for key, sa := range a.series {
+		sb, ok := b.series[key]
+		switch {
+		case !ok:
+			d.OnlyA = append(d.OnlyA, key)
+		case sa.points != sb.points || sa.hash.Sum64() != sb.hash.Sum64():
+			d.Different = append(d.Different, SeriesDiff{Key: key, PointsA: sa.points, PointsB: sb.points})
+		default:
+			d.Same++
+		}
+	}
+	for key := range b.series {
+		if _, ok := a.series[key]; !ok {
+			d.OnlyB = append(d.OnlyB, key)
+		}
+	}
+	sort.Strings(d.OnlyA)
+	sort.Strings(d.OnlyB)
+	sort.Slice(d.Different, func(i, j int) bool { return d.Different[i].Key < d.Different[j].Key })
+	return d
+}
+
+// Identical tells whether both Stats have the same series with the same
+// points.
+func (d *Diff) Identical() bool {
+	return len(d.OnlyA) == 0 && len(d.OnlyB) == 0 && len(d.Different) == 0
+}
+
+// Write writes a summary of d to w, listing the first differing series.
+func (d *Diff) Write(w io.Writer, nameA, nameB string) error {
+	if d.Identical() {
+		_, err := fmt.Fprintf(w, "identical: %d series\n", d.Same)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "different: %d identical series, %d differing, %d only in %s, %d only in %s\n",
+		d.Same, len(d.Different), len(d.OnlyA), nameA, len(d.OnlyB), nameB)
+	if err != nil {
+		return err
+	}
+	for i, s := range d.Different {
+		if i == maxListedSeries {
+			break
+		}
+		if _, err := fmt.Fprintf(w, "  differs: %s (%d points vs %d)\n", s.Key, s.PointsA, s.PointsB); err != nil {
+			return err
+		}
+	}
+	for _, only := range []struct {
+		keys []string
+		name string
+	}{{d.OnlyA, nameA}, {d.OnlyB, nameB}} {
+		for i, key := range only.keys {
+			if i == maxListedSeries {
+				break
+			}
+			if _, err := fmt.Fprintf(w, "  only in %s: %s\n", only.name, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package inspect
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func readTestStats(t *testing.T, data string) *Stats {
+	s := NewStats(0)
+	if err := s.Read(strings.NewReader(data)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return s
+}
+
+func TestCompare(t *testing.T) {
+	a := readTestStats(t, testData)
+
+	// the order of the points of different series does not matter
+	reordered := `mem,hostname=host_0 used=1i 0
+mem,hostname=host_0 used=3i 10000000000
+cpu,hostname=host_0 usage=10,label="a" 0
+cpu,hostname=host_0 usage=20,label="b" 10000000000
+cpu,hostname=host_0 usage=30,label="c" 40000000000
+cpu,hostname=host_0 usage=5,label="d" 20000000000
+cpu,hostname=host_0 usage=50,label="e" 40000000000
+cpu,hostname=host_0 usage=60,label="f" 50000000000
+`
+	if d := Compare(a, readTestStats(t, reordered)); !d.Identical() || d.Same != 2 {
+		t.Errorf("incorrect diff of reordered series: got %+v", d)
+	}
+
+	// the order of the points of a series does
+	swapped := strings.Replace(reordered, `usage=5,label="d" 20000000000
+cpu,hostname=host_0 usage=50,label="e" 40000000000`, `usage=50,label="e" 40000000000
+cpu,hostname=host_0 usage=5,label="d" 20000000000`, 1)
+	d := Compare(a, readTestStats(t, swapped))
+	if d.Identical() || len(d.Different) != 1 || d.Different[0].Key != "cpu,hostname=host_0" {
+		t.Errorf("incorrect diff of swapped points: got %+v", d)
+	}
+
+	other := testData + "disk,hostname=host_1 free=1 0\n"
+	d = Compare(readTestStats(t, strings.Replace(other, "mem,", "net,", -1)), a)
+	if len(d.OnlyA) != 2 || len(d.OnlyB) != 1 || d.OnlyB[0] != "mem,hostname=host_0" || d.Same != 1 {
+		t.Errorf("incorrect diff of other series: got %+v", d)
+	}
+}
+
+func TestDiffWrite(t *testing.T) {
+	a := readTestStats(t, testData)
+	var buf bytes.Buffer
+	if err := Compare(a, a).Write(&buf, "a", "b"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "identical: 2 series\n"; buf.String() != want {
+		t.Errorf("incorrect output: got\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	b := readTestStats(t, strings.Replace(testData, "usage=60", "usage=61", 1)+"disk,hostname=host_1 free=1 0\n")
+	if err := Compare(a, b).Write(&buf, "a", "b"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "different: 1 identical series, 1 differing, 0 only in a, 1 only in b\n" +
+		"  differs: cpu,hostname=host_0 (6 points vs 6)\n" +
+		"  only in b: disk,hostname=host_1\n"
+	if buf.String() != want {
+		t.Errorf("incorrect output: got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,302 @@
+// Package inspect computes statistics of generated data files in Influx line
+// protocol, and compares them series by series.
+package inspect
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/timescale/tsbs/InfluxDB-client/models"
+)
+
+const (
+	errParseFmt    = "line %d: %v"
+	errFieldsFmt   = "line %d: cannot read fields: %v"
+	maxLineSize    = 16 << 20
+	gapMultiplier  = 2
+	notNumericType = "non-numeric"
+)
+
+// Stats are the statistics of the points of a data file.
+type Stats struct {
+	// Interval is the expected time between the points of a series, or 0 to
+	// use the most common one of each measurement
+	Interval time.Duration
+
+	Points     uint64
+	Start, End time.Time
+
+	lines        uint64
+	measurements map[string]*measurementStats
+	series       map[string]*series
+}
+
+// series is the state of a series.
+type series struct {
+	points uint64
+	// latest is the latest timestamp of the series so far
+	latest int64
+	// hash is the hash of the points of the series, in order
+	hash hash.Hash64
+}
+
+// measurementStats are the statistics of the points of a measurement.
+type measurementStats struct {
+	name       string
+	points     uint64
+	series     uint64
+	outOfOrder uint64
+	duplicates uint64
+	// deltas counts the times between the consecutive points of the series
+	deltas map[int64]uint64
+	fields map[string]*fieldStats
+}
+
+// fieldStats are the statistics of the values of a field.
+type fieldStats struct {
+	count    uint64
+	numeric  uint64
+	min, max float64
+}
+
+// NewStats returns empty Stats, expecting the points of a series to be
+// interval apart, or the most common interval of their measurement if 0.
+func NewStats(interval time.Duration) *Stats {
+	return &Stats{
+		Interval:     interval,
+		measurements: make(map[string]*measurementStats),
+		series:       make(map[string]*series),
+	}
+}
+
+// Read adds the points of the line protocol read from r.
+func (s *Stats) Read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		s.lines++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		points, err := models.ParsePoints(line)
+		if err != nil {
+			return fmt.Errorf(errParseFmt, s.lines, err)
+		}
+		for _, p := range points {
+			if err := s.Add(p); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// Add adds p.
+func (s *Stats) Add(p models.Point) error {
+	fields, err := p.Fields()
+	if err != nil {
+		return fmt.Errorf(errFieldsFmt, s.lines, err)
+	}
+	ts := p.Time()
+	if s.Points == 0 || ts.Before(s.Start) {
+		s.Start = ts
+	}
+	if s.Points == 0 || ts.After(s.End) {
+		s.End = ts
+	}
+	s.Points++
+
+	m := s.measurement(string(p.Name()))
+	m.points++
+	for k, v := range fields {
+		m.field(k).add(v)
+	}
+
+	nanos := ts.UnixNano()
+	key := string(p.Key())
+	sr, ok := s.series[key]
+	if !ok {
+		sr = &series{latest: nanos, hash: fnv.New64a()}
+		s.series[key] = sr
+		m.series++
+	} else if nanos < sr.latest {
+		m.outOfOrder++
+	} else if nanos == sr.latest {
+		m.duplicates++
+	} else {
+		m.deltas[nanos-sr.latest]++
+		sr.latest = nanos
+	}
+	sr.points++
+	sr.hash.Write([]byte(p.String()))
+	sr.hash.Write([]byte{'\n'})
+	return nil
+}
+
+func (s *Stats) measurement(name string) *measurementStats {
+	m, ok := s.measurements[name]
+	if !ok {
+		m = &measurementStats{
+			name:   name,
+			deltas: make(map[int64]uint64),
+			fields: make(map[string]*fieldStats),
+		}
+		s.measurements[name] = m
+	}
+	return m
+}
+
+func (m *measurementStats) field(name string) *fieldStats {
+	f, ok := m.fields[name]
+	if !ok {
+		f = &fieldStats{min: math.Inf(1), max: math.Inf(-1)}
+		m.fields[name] = f
+	}
+	return f
+}
+
+func (f *fieldStats) add(v interface{}) {
+	f.count++
+	var x float64
+	switch v := v.(type) {
+	case float64:
+		x = v
+	case int64:
+		x = float64(v)
+	case uint64:
+		x = float64(v)
+	default:
+		return
+	}
+	f.numeric++
+	f.min = math.Min(f.min, x)
+	f.max = math.Max(f.max, x)
+}
+
+// Series returns the number of series.
+func (s *Stats) Series() int {
+	return len(s.series)
+}
+
+// interval returns the expected time between the points of the series of
+// m, 0 if unknown.
+func (s *Stats) interval(m *measurementStats) int64 {
+	if s.Interval > 0 {
+		return int64(s.Interval)
+	}
+	mode, count := int64(0), uint64(0)
+	for d, c := range m.deltas {
+		if c > count || c == count && d < mode {
+			mode, count = d, c
+		}
+	}
+	return mode
+}
+
+// gaps returns the number of times between consecutive points of m of at
+// least twice the interval, an estimate of the points missing in them, and
+// the longest one.
+func (s *Stats) gaps(m *measurementStats, interval int64) (gaps, missing uint64, longest time.Duration) {
+	if interval == 0 {
+		return 0, 0, 0
+	}
+	for d, c := range m.deltas {
+		if d < gapMultiplier*interval {
+			continue
+		}
+		gaps += c
+		missing += c * uint64(d/interval-1)
+		if time.Duration(d) > longest {
+			longest = time.Duration(d)
+		}
+	}
+	return gaps, missing, longest
+}
+
+// Write writes the statistics to w.
+func (s *Stats) Write(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "points: %d\nseries: %d\nmeasurements: %d\n", s.Points, len(s.series), len(s.measurements))
+	if err != nil {
+		return err
+	}
+	if s.Points > 0 {
+		_, err := fmt.Fprintf(w, "time range: %s - %s (%v)\n",
+			s.Start.UTC().Format(time.RFC3339Nano), s.End.UTC().Format(time.RFC3339Nano), s.End.Sub(s.Start))
+		if err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(s.measurements))
+	for name := range s.measurements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := s.writeMeasurement(w, s.measurements[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Stats) writeMeasurement(w io.Writer, m *measurementStats) error {
+	interval := s.interval(m)
+	gaps, missing, longest := s.gaps(m, interval)
+	lines := []struct {
+		label string
+		value interface{}
+	}{
+		{"points", m.points},
+		{"series", m.series},
+		{"interval", time.Duration(interval)},
+		{"gaps", fmt.Sprintf("%d (~%d points missing, longest %v)", gaps, missing, longest)},
+		{"out-of-order", fmt.Sprintf("%d (%.2f%%)", m.outOfOrder, percent(m.outOfOrder, m.points))},
+		{"duplicate timestamps", m.duplicates},
+	}
+	if _, err := fmt.Fprintf(w, "\nmeasurement %s:\n", m.name); err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if _, err := fmt.Fprintf(w, "  %-22s%v\n", l.label+":", l.value); err != nil {
+			return err
+		}
+	}
+
+	fields := make([]string, 0, len(m.fields))
+	for name := range m.fields {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	if _, err := fmt.Fprintf(w, "  fields:\n"); err != nil {
+		return err
+	}
+	for _, name := range fields {
+		f := m.fields[name]
+		var err error
+		if f.numeric == 0 {
+			_, err = fmt.Fprintf(w, "    %-24s %10d values, %s\n", name, f.count, notNumericType)
+		} else {
+			_, err = fmt.Fprintf(w, "    %-24s %10d values, min %g, max %g\n", name, f.count, f.min, f.max)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func percent(a, b uint64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return 100 * float64(a) / float64(b)
+}
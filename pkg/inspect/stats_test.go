@@ -0,0 +1,99 @@
+package inspect
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testData has a cpu series with a gap of two points, an out-of-order point
+// and a duplicate timestamp, and a mem series of another measurement.
+const testData = `# comment
+cpu,hostname=host_0 usage=10,label="a" 0
+mem,hostname=host_0 used=1i 0
+cpu,hostname=host_0 usage=20,label="b" 10000000000
+
+cpu,hostname=host_0 usage=30,label="c" 40000000000
+cpu,hostname=host_0 usage=5,label="d" 20000000000
+cpu,hostname=host_0 usage=50,label="e" 40000000000
+mem,hostname=host_0 used=3i 10000000000
+cpu,hostname=host_0 usage=60,label="f" 50000000000
+`
+
+func TestStatsRead(t *testing.T) {
+	s := NewStats(0)
+	if err := s.Read(strings.NewReader(testData)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Points != 8 {
+		t.Errorf("incorrect points: got %d want %d", s.Points, 8)
+	}
+	if s.Series() != 2 {
+		t.Errorf("incorrect series: got %d want %d", s.Series(), 2)
+	}
+	if !s.Start.Equal(time.Unix(0, 0)) || !s.End.Equal(time.Unix(50, 0)) {
+		t.Errorf("incorrect time range: got %v - %v", s.Start, s.End)
+	}
+
+	cpu := s.measurements["cpu"]
+	if cpu.points != 6 || cpu.series != 1 || cpu.outOfOrder != 1 || cpu.duplicates != 1 {
+		t.Errorf("incorrect cpu stats: got %+v", cpu)
+	}
+	interval := s.interval(cpu)
+	if interval != int64(10*time.Second) {
+		t.Errorf("incorrect interval: got %v want %v", time.Duration(interval), 10*time.Second)
+	}
+	gaps, missing, longest := s.gaps(cpu, interval)
+	if gaps != 1 || missing != 2 || longest != 30*time.Second {
+		t.Errorf("incorrect gaps: got %d gaps, %d missing, longest %v", gaps, missing, longest)
+	}
+	if f := cpu.fields["usage"]; f.count != 6 || f.min != 5 || f.max != 60 {
+		t.Errorf("incorrect usage stats: got %+v", f)
+	}
+	if f := cpu.fields["label"]; f.count != 6 || f.numeric != 0 {
+		t.Errorf("incorrect label stats: got %+v", f)
+	}
+	if f := s.measurements["mem"].fields["used"]; f.count != 2 || f.min != 1 || f.max != 3 {
+		t.Errorf("incorrect used stats: got %+v", f)
+	}
+
+	// an explicit interval changes the gaps
+	s.Interval = 30 * time.Second
+	if gaps, _, _ := s.gaps(cpu, s.interval(cpu)); gaps != 0 {
+		t.Errorf("incorrect gaps with interval %v: got %d want 0", s.Interval, gaps)
+	}
+}
+
+func TestStatsReadError(t *testing.T) {
+	s := NewStats(0)
+	err := s.Read(strings.NewReader("cpu,hostname=host_0 usage=1 0\ncpu,hostname=host_0\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("incorrect error for bad line: got %v", err)
+	}
+}
+
+func TestStatsWrite(t *testing.T) {
+	s := NewStats(0)
+	if err := s.Read(strings.NewReader(testData)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := s.Write(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"points: 8\nseries: 2\nmeasurements: 2\n",
+		"time range: 1970-01-01T00:00:00Z - 1970-01-01T00:00:50Z (50s)\n",
+		"\nmeasurement cpu:\n  points:               6\n",
+		"  gaps:                 1 (~2 points missing, longest 30s)\n",
+		"  out-of-order:         1 (16.67%)\n",
+		"    label                             6 values, non-numeric\n",
+		"    usage                             6 values, min 5, max 60\n",
+		"\nmeasurement mem:\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output does not contain %q: got\n%s", want, buf.String())
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package loadcmd
 
 import (
 	"time"
@@ -29,6 +29,14 @@ type RunnerConfig struct {
 	InsertIntervals string `yaml:"insert-intervals" mapstructure:"insert-intervals"`
 	FlowControl     bool   `yaml:"flow-control" mapstructure:"flow-control"`
 	ChannelCapacity uint   `yaml:"channel-capacity" mapstructure:"channel-capacity"`
+	MetricsAddr     string `yaml:"metrics-addr" mapstructure:"metrics-addr"`
+	BatchStatsCSV   string `yaml:"batch-stats-csv" mapstructure:"batch-stats-csv"`
+	RateLimit       string `yaml:"rate-limit" mapstructure:"rate-limit"`
+	RateUnit        string `yaml:"rate-unit" mapstructure:"rate-unit"`
+
+	CheckpointFile     string        `yaml:"checkpoint-file" mapstructure:"checkpoint-file"`
+	CheckpointInterval time.Duration `yaml:"checkpoint-interval" mapstructure:"checkpoint-interval"`
+	Resume             bool          `yaml:"resume" mapstructure:"resume"`
 }
 
 type DataSourceConfig struct {
@@ -1,4 +1,4 @@
-package main
+package loadcmd
 
 import (
 	"bytes"
@@ -9,7 +9,6 @@ import (
 	"github.com/timescale/tsbs/pkg/data/source"
 	"github.com/timescale/tsbs/pkg/targets"
 	"github.com/timescale/tsbs/pkg/targets/constants"
-	"github.com/timescale/tsbs/pkg/targets/initializers"
 	"gopkg.in/yaml.v2"
 	"strings"
 )
@@ -25,7 +24,9 @@ func initConfigCMD() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "config",
 		Short: "Generate example config yaml file and save it to" + writeConfigTo,
-		Run:   config,
+		RunE:  config,
+		// the error of an unknown target already lists the valid ones
+		SilenceUsage: true,
 	}
 
 	cmd.PersistentFlags().String(
@@ -35,24 +36,28 @@ func initConfigCMD() *cobra.Command {
 	)
 	cmd.PersistentFlags().String(
 		targetDbFlag,
-		constants.FormatPrometheus,
-		"specify target db, valid: "+strings.Join(constants.SupportedFormats(), ", "),
+		constants.FormatInflux,
+		"specify target db, valid: "+strings.Join(targets.RegisteredFormats(), ", "),
 	)
 	return cmd
 }
 
-func config(cmd *cobra.Command, _ []string) {
+func config(cmd *cobra.Command, _ []string) error {
 	dataSourceSelected := readFlag(cmd, dataSourceFlag)
 	targetSelected := readFlag(cmd, targetDbFlag)
 
 	exampleConfig := getEmptyConfigWithoutDbSpecifics(targetSelected, dataSourceSelected)
-	target := initializers.GetTarget(targetSelected)
+	target, err := targets.GetTarget(targetSelected)
+	if err != nil {
+		return err
+	}
 	v := setExampleConfigInViper(exampleConfig, target)
 
 	if err := v.WriteConfigAs(writeConfigTo); err != nil {
 		panic(fmt.Errorf("could not write sample config to file %s: %v", writeConfigTo, err))
 	}
 	fmt.Printf("Wrote example config to: %s\n", writeConfigTo)
+	return nil
 }
 
 func getEmptyConfigWithoutDbSpecifics(target, dataSource string) *LoadConfig {
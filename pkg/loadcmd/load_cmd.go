@@ -1,4 +1,4 @@
-package main
+package loadcmd
 
 import (
 	"fmt"
@@ -6,8 +6,6 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/timescale/tsbs/pkg/targets"
-	"github.com/timescale/tsbs/pkg/targets/constants"
-	"github.com/timescale/tsbs/pkg/targets/initializers"
 )
 
 type cmdRunner func(*cobra.Command, []string)
@@ -28,7 +26,10 @@ func initLoadCMD() (*cobra.Command, error) {
 		return nil, fmt.Errorf("could not bind flags to configuration: %v", err)
 	}
 
-	subCommands := initLoadSubCommands()
+	subCommands, err := initLoadSubCommands()
+	if err != nil {
+		return nil, err
+	}
 	cmd.AddCommand(subCommands...)
 	return cmd, nil
 }
@@ -40,11 +41,14 @@ func loadCmdFlags() *pflag.FlagSet {
 	return fs
 }
 
-func initLoadSubCommands() []*cobra.Command {
-	allFormats := constants.SupportedFormats()
+func initLoadSubCommands() ([]*cobra.Command, error) {
+	allFormats := targets.RegisteredFormats()
 	commands := make([]*cobra.Command, len(allFormats))
 	for i, format := range allFormats {
-		target := initializers.GetTarget(format)
+		target, err := targets.GetTarget(format)
+		if err != nil {
+			return nil, err
+		}
 		cmd := &cobra.Command{
 			Use:   format,
 			Short: "Load data into " + format + " as a target db",
@@ -55,7 +59,7 @@ func initLoadSubCommands() []*cobra.Command {
 		commands[i] = cmd
 	}
 
-	return commands
+	return commands, nil
 }
 
 func createRunLoad(target targets.ImplementedTarget) cmdRunner {
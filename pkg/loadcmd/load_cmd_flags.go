@@ -1,4 +1,4 @@
-package main
+package loadcmd
 
 import (
 	"fmt"
@@ -67,6 +67,37 @@ func addLoaderRunnerFlags(fs *pflag.FlagSet) {
 			"Default 0 means that:\n\tif hash-workers=false then capacity = 5 * number of workers\n\t"+
 			"if hash-workers=true, then capacity = 5 for each worker",
 	)
+	fs.String(
+		"loader.runner.metrics-addr",
+		"",
+		"Serve Prometheus metrics on /metrics of this address, e.g. ':9100' (empty to disable)",
+	)
+	fs.String(
+		"loader.runner.batch-stats-csv",
+		"",
+		"Write the batch latency percentiles and bytes sent of every reporting period to this CSV file",
+	)
+	fs.String(
+		"loader.runner.rate-limit",
+		"",
+		"Target insert rate per second of all workers together, in rate-unit: a constant, e.g. '500000', or a ramp of offset:rate points, e.g. '0s:100000,5m:500000' (empty = as fast as possible)",
+	)
+	fs.String(
+		"loader.runner.rate-unit",
+		"metrics",
+		"Unit of rate-limit: metrics, rows or bytes",
+	)
+	fs.String(
+		"loader.runner.checkpoint-file",
+		"",
+		"Periodically write the number of items of the input that were loaded to this file (requires flow-control)",
+	)
+	fs.Duration("loader.runner.checkpoint-interval", 10*time.Second, "Period to write the checkpoint file")
+	fs.Bool(
+		"loader.runner.resume",
+		false,
+		"Skip the items loaded according to checkpoint-file and keep the existing database, if the file exists",
+	)
 }
 
 func addDataSourceFlags(fs *pflag.FlagSet) {
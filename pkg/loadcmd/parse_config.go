@@ -1,4 +1,4 @@
-package main
+package loadcmd
 
 import (
 	"errors"
@@ -74,6 +74,14 @@ func convertRunnerConfigToInternalRep(r *RunnerConfig) *load.BenchmarkRunnerConf
 		InsertIntervals: r.InsertIntervals,
 		NoFlowControl:   !r.FlowControl,
 		ChannelCapacity: r.ChannelCapacity,
+		MetricsAddr:     r.MetricsAddr,
+		BatchStatsCSV:   r.BatchStatsCSV,
+		RateLimit:       r.RateLimit,
+		RateUnit:        r.RateUnit,
+
+		CheckpointFile:     r.CheckpointFile,
+		CheckpointInterval: r.CheckpointInterval,
+		Resume:             r.Resume,
 	}
 }
 
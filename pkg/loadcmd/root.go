@@ -0,0 +1,45 @@
+// Package loadcmd implements the tsbs_load command. It offers every target
+// registered with targets.Register, so a target maintained outside this
+// repository can be loaded into by a main package that imports it and calls
+// Main.
+package loadcmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var cfgFile string
+
+// newRootCmd returns the tsbs_load command. It is created when run rather
+// than on init so that it offers the targets registered by packages that are
+// initialized after this one.
+func newRootCmd() (*cobra.Command, error) {
+	rootCmd := &cobra.Command{
+		Use:   "tsbs_load",
+		Short: "Load data inside a db",
+	}
+	loadCmd, err := initLoadCMD()
+	if err != nil {
+		return nil, err
+	}
+	rootCmd.AddCommand(loadCmd)
+	configCmd := initConfigCMD()
+	rootCmd.AddCommand(configCmd)
+	return rootCmd, nil
+}
+
+// Main runs tsbs_load with the command line arguments and the registered
+// targets, and exits non-zero on error.
+func Main() {
+	rootCmd, err := newRootCmd()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
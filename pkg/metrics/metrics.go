@@ -0,0 +1,314 @@
+// Package metrics exposes live benchmark metrics over HTTP in the Prometheus
+// text exposition format, so long runs can be scraped and plotted next to the
+// metrics of the database and the cache server.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	errKindMismatchFmt = "metric %s registered as %s, not %s"
+	errOddLabelsFmt    = "metric %s: labels must be key value pairs, got %d strings"
+)
+
+// LatencyBuckets are the default upper bounds, in seconds, of latency
+// histograms.
+var LatencyBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// DefaultRegistry is the registry served by Serve.
+var DefaultRegistry = NewRegistry()
+
+// Registry holds metric families by name. Each family holds one series per
+// distinct set of label values.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    string
+	buckets []float64
+	series  map[string]series
+}
+
+type series interface {
+	write(w io.Writer, name, labels string) error
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// get returns the series of the named family with the given labels, creating
+// both with newFn if needed.
+func (r *Registry) get(name, help, kind string, buckets []float64, labels []string, newFn func(f *family) series) series {
+	key := formatLabels(name, labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{name: name, help: help, kind: kind, buckets: buckets, series: make(map[string]series)}
+		r.families[name] = f
+	} else if f.kind != kind {
+		panic(fmt.Sprintf(errKindMismatchFmt, name, f.kind, kind))
+	}
+	s, ok := f.series[key]
+	if !ok {
+		s = newFn(f)
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter returns the counter with the given name and label key value pairs,
+// registering it on first use.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return r.get(name, help, kindCounter, nil, labels, func(*family) series { return &Counter{} }).(*Counter)
+}
+
+// Gauge returns the gauge with the given name and label key value pairs,
+// registering it on first use.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return r.get(name, help, kindGauge, nil, labels, func(*family) series { return &Gauge{} }).(*Gauge)
+}
+
+// CounterFunc registers a counter whose value is read from fn on every scrape.
+func (r *Registry) CounterFunc(name, help string, fn func() float64, labels ...string) {
+	r.get(name, help, kindCounter, nil, labels, func(*family) series { return valueFunc(fn) })
+}
+
+// GaugeFunc registers a gauge whose value is read from fn on every scrape.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64, labels ...string) {
+	r.get(name, help, kindGauge, nil, labels, func(*family) series { return valueFunc(fn) })
+}
+
+// Histogram returns the histogram with the given name and label key value
+// pairs, registering it on first use. All series of a family share the
+// buckets it was first registered with.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return r.get(name, help, kindHistogram, buckets, labels, func(f *family) series {
+		return newHistogram(f.buckets)
+	}).(*Histogram)
+}
+
+// WriteText writes all metrics to w in the Prometheus text format, families
+// and series sorted by name.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	families := make([]family, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		f := *r.families[name]
+		f.series = make(map[string]series, len(r.families[name].series))
+		for k, s := range r.families[name].series {
+			f.series[k] = s
+		}
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+
+	for _, f := range families {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, helpEscaper.Replace(f.help), f.name, f.kind); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := f.series[k].write(w, f.name, k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ServeHTTP writes the metrics of r as the response.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	if err := r.WriteText(w); err != nil {
+		log.Printf("metrics: %v", err)
+	}
+}
+
+// Serve starts serving DefaultRegistry on /metrics of addr, e.g. ":9100",
+// in the background. It returns an error if addr cannot be listened on.
+func Serve(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", DefaultRegistry)
+	go func() {
+		if err := http.Serve(ln, mux); err != nil {
+			log.Printf("metrics: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	bits uint64
+}
+
+// Inc adds 1 to the counter.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds v, which must not be negative, to the counter.
+func (c *Counter) Add(v float64) {
+	addFloat(&c.bits, v)
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.bits))
+}
+
+func (c *Counter) write(w io.Writer, name, labels string) error {
+	return writeSample(w, name, labels, c.Value())
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	bits uint64
+}
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v float64) {
+	atomic.StoreUint64(&g.bits, math.Float64bits(v))
+}
+
+// Add adds v to the gauge.
+func (g *Gauge) Add(v float64) {
+	addFloat(&g.bits, v)
+}
+
+// Value returns the current value of the gauge.
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.bits))
+}
+
+func (g *Gauge) write(w io.Writer, name, labels string) error {
+	return writeSample(w, name, labels, g.Value())
+}
+
+type valueFunc func() float64
+
+func (f valueFunc) write(w io.Writer, name, labels string) error {
+	return writeSample(w, name, labels, f())
+}
+
+// Histogram counts observations in cumulative buckets.
+type Histogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     uint64
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+// Observe records v.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	if i < len(h.counts) {
+		atomic.AddUint64(&h.counts[i], 1)
+	}
+	atomic.AddUint64(&h.count, 1)
+	addFloat(&h.sum, v)
+}
+
+func (h *Histogram) write(w io.Writer, name, labels string) error {
+	cumulative := uint64(0)
+	for i, b := range h.buckets {
+		cumulative += atomic.LoadUint64(&h.counts[i])
+		le := withLabel(labels, "le", strconv.FormatFloat(b, 'g', -1, 64))
+		if err := writeSample(w, name+"_bucket", le, float64(cumulative)); err != nil {
+			return err
+		}
+	}
+	count := float64(atomic.LoadUint64(&h.count))
+	if err := writeSample(w, name+"_bucket", withLabel(labels, "le", "+Inf"), count); err != nil {
+		return err
+	}
+	if err := writeSample(w, name+"_sum", labels, math.Float64frombits(atomic.LoadUint64(&h.sum))); err != nil {
+		return err
+	}
+	return writeSample(w, name+"_count", labels, count)
+}
+
+func addFloat(bits *uint64, v float64) {
+	for {
+		old := atomic.LoadUint64(bits)
+		if atomic.CompareAndSwapUint64(bits, old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func writeSample(w io.Writer, name, labels string, v float64) error {
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	_, err := fmt.Fprintf(w, "%s%s %s\n", name, labels, strconv.FormatFloat(v, 'g', -1, 64))
+	return err
+}
+
+// formatLabels renders key value pairs as they appear between the braces of
+// a sample.
+func formatLabels(name string, labels []string) string {
+	if len(labels)%2 != 0 {
+		panic(fmt.Sprintf(errOddLabelsFmt, name, len(labels)))
+	}
+	parts := make([]string, 0, len(labels)/2)
+	for i := 0; i < len(labels); i += 2 {
+		parts = append(parts, labels[i]+`="`+labelEscaper.Replace(labels[i+1])+`"`)
+	}
+	return strings.Join(parts, ",")
+}
+
+func withLabel(labels, key, value string) string {
+	l := key + `="` + labelEscaper.Replace(value) + `"`
+	if labels == "" {
+		return l
+	}
+	return labels + "," + l
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteText(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("queries_total", "Queries run.").Add(3)
+	r.Counter("hits_total", "Cache hits.", "kind", "full").Inc()
+	r.Counter("hits_total", "Cache hits.", "kind", "partial").Add(2)
+	r.Gauge("workers", "Workers.").Set(4)
+	r.GaugeFunc("answer", "Read on scrape.", func() float64 { return 42 })
+	h := r.Histogram("latency_seconds", "Latency.", []float64{0.1, 1}, "label", `a "b"`)
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(5)
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `# HELP answer Read on scrape.
+# TYPE answer gauge
+answer 42
+# HELP hits_total Cache hits.
+# TYPE hits_total counter
+hits_total{kind="full"} 1
+hits_total{kind="partial"} 2
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{label="a \"b\"",le="0.1"} 1
+latency_seconds_bucket{label="a \"b\"",le="1"} 2
+latency_seconds_bucket{label="a \"b\"",le="+Inf"} 3
+latency_seconds_sum{label="a \"b\""} 5.55
+latency_seconds_count{label="a \"b\""} 3
+# HELP queries_total Queries run.
+# TYPE queries_total counter
+queries_total 3
+# HELP workers Workers.
+# TYPE workers gauge
+workers 4
+`
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect output:\ngot\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRegistrySameSeries(t *testing.T) {
+	r := NewRegistry()
+	if r.Counter("c", "", "k", "v") != r.Counter("c", "", "k", "v") {
+		t.Errorf("same name and labels should return the same counter")
+	}
+	if r.Counter("c", "", "k", "v") == r.Counter("c", "", "k", "w") {
+		t.Errorf("different labels should return different counters")
+	}
+}
+
+func TestRegistryKindMismatch(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("m", "")
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic for a gauge registered under a counter's name")
+		}
+	}()
+	r.Gauge("m", "")
+}
+
+func TestRegistryServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("c", "").Inc()
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("incorrect content type: %s", resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), "\nc 1\n") {
+		t.Errorf("incorrect body: %s", body)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/spf13/pflag"
+	"github.com/timescale/tsbs/pkg/metrics"
 	"golang.org/x/time/rate"
 )
 
@@ -27,19 +28,22 @@ const (
 
 // BenchmarkRunnerConfig is the configuration of the benchmark runner.
 type BenchmarkRunnerConfig struct {
-	DBName           string `mapstructure:"db-name"`         // 数据库名称，用于指定要执行基准测试的数据库。
-	Limit            uint64 `mapstructure:"max-queries"`     // 最大查询数量，表示要执行的查询的最大数量。
-	LimitRPS         uint64 `mapstructure:"max-rps"`         // 最大每秒查询率，表示每秒钟执行的最大查询数量。
-	MemProfile       string `mapstructure:"memprofile"`      // 内存分析文件，用于指定内存分析文件的名称或路径。
-	HDRLatenciesFile string `mapstructure:"hdr-latencies"`   // HDR 延迟文件，用于指定 HDR 延迟的文件名称或路径。
-	Workers          uint   `mapstructure:"workers"`         // 工作线程数，表示并发执行基准测试的工作线程数量。
-	PrintResponses   bool   `mapstructure:"print-responses"` // 是否打印响应，表示是否打印每个查询的响应结果。
-	Debug            int    `mapstructure:"debug"`           // 调试级别，用于指定基准测试的调试级别。
-	FileName         string `mapstructure:"file"`            // 文件名，表示文件的名称或路径。
-	BurnIn           uint64 `mapstructure:"burn-in"`         // 预热查询数，表示执行基准测试前执行的预热查询数量。	不计入统计结果
-	PrintInterval    uint64 `mapstructure:"print-interval"`  // 打印间隔，表示打印时间统计的时间间隔。
-	PrewarmQueries   bool   `mapstructure:"prewarm-queries"` // 预热查询，表示是否在执行基准测试前执行预热查询。
-	ResultsFile      string `mapstructure:"results-file"`    // 结果文件，用于指定基准测试结果的文件名称或路径。
+	DBName           string  `mapstructure:"db-name"`         // 数据库名称，用于指定要执行基准测试的数据库。
+	Limit            uint64  `mapstructure:"max-queries"`     // 最大查询数量，表示要执行的查询的最大数量。
+	LimitRPS         uint64  `mapstructure:"max-rps"`         // 最大每秒查询率，表示每秒钟执行的最大查询数量。
+	MemProfile       string  `mapstructure:"memprofile"`      // 内存分析文件，用于指定内存分析文件的名称或路径。
+	HDRLatenciesFile string  `mapstructure:"hdr-latencies"`   // HDR 延迟文件，用于指定 HDR 延迟的文件名称或路径。
+	Workers          uint    `mapstructure:"workers"`         // 工作线程数，表示并发执行基准测试的工作线程数量。
+	PrintResponses   bool    `mapstructure:"print-responses"` // 是否打印响应，表示是否打印每个查询的响应结果。
+	Debug            int     `mapstructure:"debug"`           // 调试级别，用于指定基准测试的调试级别。
+	FileName         string  `mapstructure:"file"`            // 文件名，表示文件的名称或路径。
+	BurnIn           uint64  `mapstructure:"burn-in"`         // 预热查询数，表示执行基准测试前执行的预热查询数量。	不计入统计结果
+	PrintInterval    uint64  `mapstructure:"print-interval"`  // 打印间隔，表示打印时间统计的时间间隔。
+	PrewarmQueries   bool    `mapstructure:"prewarm-queries"` // 预热查询，表示是否在执行基准测试前执行预热查询。
+	ResultsFile      string  `mapstructure:"results-file"`    // 结果文件，用于指定基准测试结果的文件名称或路径。
+	InputFormat      string  `mapstructure:"input-format"`    // 查询文件格式：gob, text, csv, tsv, ndjson
+	ReplaySpeed      float64 `mapstructure:"replay-speed"`    // 按查询时间戳的原始节奏重放的倍速，0 表示忽略时间戳
+	MetricsAddr      string  `mapstructure:"metrics-addr"`    // Prometheus 指标的 HTTP 监听地址，为空则不开启
 	//
 	CacheURL string `mapstructure:"cache-url"`
 	UseCache string `mapstructure:"use-cache"`
@@ -64,6 +68,9 @@ func (c BenchmarkRunnerConfig) AddToFlagSet(fs *pflag.FlagSet) {
 	fs.Int("debug", 0, "Whether to print debug messages.")
 	fs.String("file", "", "File name to read queries from")
 	fs.String("results-file", "", "Write the test results summary json to this file")
+	fs.String("input-format", FormatGob, "Format of the query file: gob (from tsbs_generate_queries), or one InfluxQL statement per record as text, csv, tsv or ndjson")
+	fs.Float64("replay-speed", 0, "Replay timestamped text queries at this multiple of their original pacing, 0 = ignore timestamps")
+	fs.String("metrics-addr", "", "Serve Prometheus metrics on /metrics of this address, e.g. ':9100' (empty to disable)")
 	//
 	fs.String("cache-url", "http://localhost:11211", "STsCache url")
 	fs.String("use-cache", "db", "use STsCache , fatcache ,otherwise use database")
@@ -84,7 +91,7 @@ type BenchmarkRunner struct {
 // common functionality to be used by query benchmarker programs
 func NewBenchmarkRunner(config BenchmarkRunnerConfig) *BenchmarkRunner {
 	runner := &BenchmarkRunner{BenchmarkRunnerConfig: config}
-	runner.scanner = newScanner(&runner.Limit)
+	runner.scanner = newScanner(&runner.Limit).setFormat(config.InputFormat, config.ReplaySpeed)
 	spArgs := &statProcessorArgs{
 		limit:            &runner.Limit,
 		printInterval:    runner.PrintInterval,
@@ -92,6 +99,9 @@ func NewBenchmarkRunner(config BenchmarkRunnerConfig) *BenchmarkRunner {
 		burnIn:           runner.BurnIn,
 		hdrLatenciesFile: runner.HDRLatenciesFile,
 	}
+	if config.MetricsAddr != "" {
+		spArgs.metrics = newQueryMetrics(metrics.DefaultRegistry, config.Workers)
+	}
 	// todo cache启动参数
 	client.DB = config.DBName
 
@@ -112,6 +122,18 @@ func NewBenchmarkRunner(config BenchmarkRunnerConfig) *BenchmarkRunner {
 	return runner
 }
 
+// QueryListener is notified of every query the runner completes, including
+// burn-in and warm queries, with its latency in milliseconds and how the cache
+// answered it: 0 not at all, 1 partially, 2 fully. It is always called from
+// the same goroutine.
+type QueryListener func(label string, latencyMillis float64, hitKind uint8)
+
+// SetQueryListener makes the runner call fn for every completed query, e.g.
+// to build a timeline of a benchmark. It must be called before Run.
+func (b *BenchmarkRunner) SetQueryListener(fn QueryListener) {
+	b.sp.getArgs().listener = fn
+}
+
 // SetLimit changes the number of queries to run, with 0 being all of them
 func (b *BenchmarkRunner) SetLimit(limit uint64) {
 	b.Limit = limit
@@ -176,6 +198,12 @@ func (b *BenchmarkRunner) Run(queryPool *sync.Pool, processorCreateFn ProcessorC
 	}
 	b.ch = make(chan Query, b.Workers)
 
+	if len(b.MetricsAddr) > 0 {
+		if err := metrics.Serve(b.MetricsAddr); err != nil {
+			log.Fatalf("cannot serve metrics on %s: %v", b.MetricsAddr, err)
+		}
+	}
+
 	// Launch the stats processor:
 	go b.sp.process(b.Workers)
 
@@ -246,10 +274,14 @@ func (b *BenchmarkRunner) saveTestResult(took time.Duration, start time.Time, en
 
 func (b *BenchmarkRunner) processorHandler(wg *sync.WaitGroup, rateLimiter *rate.Limiter, queryPool *sync.Pool, processor Processor, workerNum int) {
 	processor.Init(workerNum)
+	m := b.sp.getArgs().metrics
 	for query := range b.ch {
 		r := rateLimiter.Reserve()
 		time.Sleep(r.Delay())
 
+		if m != nil {
+			m.busyWorkers.Add(1)
+		}
 		stats, err := processor.ProcessQuery(query, false, workerNum)
 		if err != nil {
 			panic(err)
@@ -268,6 +300,9 @@ func (b *BenchmarkRunner) processorHandler(wg *sync.WaitGroup, rateLimiter *rate
 			}
 			b.sp.sendWarm(stats)
 		}
+		if m != nil {
+			m.busyWorkers.Add(-1)
+		}
 		queryPool.Put(query)
 	}
 	wg.Done()
@@ -2,12 +2,20 @@ package config
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/spf13/pflag"
 	"github.com/timescale/tsbs/internal/utils"
 	"github.com/timescale/tsbs/pkg/data/usecases/common"
 )
 
-const ErrEmptyQueryType = "query type cannot be empty"
+const (
+	ErrEmptyQueryType       = "query type cannot be empty"
+	ErrBadDashboardRefresh  = "dashboard refresh period must be positive"
+	ErrEmptyDashboardRanges = "dashboard ranges cannot be empty"
+	ErrNegativeLast         = "last duration cannot be negative"
+	ErrLastDashboards       = "cannot query the last duration with dashboards"
+)
 
 // QueryGeneratorConfig is the GeneratorConfig that should be used with a
 // QueryGenerator. It includes all the fields from a BaseConfig, as well as
@@ -34,6 +42,17 @@ type QueryGeneratorConfig struct {
 	TruckScale string `mapstructure:truck-scale`
 	RandomTag  bool   `mapstructure:"random-tag"`
 	TagNum     int    `mapstructure:"tag-num"`
+
+	// Dashboards switches generation to the sliding-window dashboard mode
+	// when non-zero; see DashboardPanels, DashboardRanges and DashboardRefresh.
+	Dashboards       uint          `mapstructure:"dashboards"`
+	DashboardPanels  string        `mapstructure:"dashboard-panels"`
+	DashboardRanges  string        `mapstructure:"dashboard-ranges"`
+	DashboardRefresh time.Duration `mapstructure:"dashboard-refresh"`
+
+	// Last, when non-zero, makes every query cover the last Last of the time
+	// range instead of a random interval.
+	Last time.Duration `mapstructure:"last"`
 }
 
 // Validate checks that the values of the QueryGeneratorConfig are reasonable.
@@ -43,10 +62,27 @@ func (c *QueryGeneratorConfig) Validate() error {
 		return err
 	}
 
-	if c.QueryType == "" {
+	if c.Dashboards > 0 {
+		if c.QueryType == "" && c.DashboardPanels == "" {
+			return fmt.Errorf(ErrEmptyQueryType)
+		}
+		if c.DashboardRanges == "" {
+			return fmt.Errorf(ErrEmptyDashboardRanges)
+		}
+		if c.DashboardRefresh <= 0 {
+			return fmt.Errorf(ErrBadDashboardRefresh)
+		}
+	} else if c.QueryType == "" {
 		return fmt.Errorf(ErrEmptyQueryType)
 	}
 
+	if c.Last < 0 {
+		return fmt.Errorf(ErrNegativeLast)
+	}
+	if c.Last > 0 && c.Dashboards > 0 {
+		return fmt.Errorf(ErrLastDashboards)
+	}
+
 	err = utils.ValidateGroups(c.InterleavedGroupID, c.InterleavedNumGroups)
 	return err
 }
@@ -72,4 +108,11 @@ func (c *QueryGeneratorConfig) AddToFlagSet(fs *pflag.FlagSet) {
 	fs.String("truck-scale", "small", "Specify query truck scale: small, medium or large")
 	fs.Bool("random-tag", true, "generate random or sequential tag")
 	fs.Int("tag-num", 10, "tag number count")
+
+	fs.Uint("dashboards", 0, "Number of simulated dashboards. When non-zero, queries slide forward with a virtual clock instead of using random intervals.")
+	fs.String("dashboard-panels", "", "Comma-separated query types shown on every dashboard. Defaults to --query-type.")
+	fs.String("dashboard-ranges", "now-6h", "Comma-separated relative ranges assigned round-robin to dashboards, e.g. now-1h,now-6h,now-7d")
+	fs.Duration("dashboard-refresh", 5*time.Minute, "Virtual time between refreshes of a dashboard")
+
+	fs.Duration("last", 0, "Make every query cover the last duration before --timestamp-end, e.g. 15m with --timestamp-end=now to query data generated in real time. 0 means random intervals")
 }
@@ -0,0 +1,58 @@
+package query
+
+import (
+	"sync/atomic"
+
+	client "github.com/timescale/tsbs/InfluxDB-client/v2"
+	"github.com/timescale/tsbs/pkg/metrics"
+)
+
+// queryMetrics exposes the progress of a query benchmark as Prometheus metrics.
+type queryMetrics struct {
+	reg         *metrics.Registry
+	queries     *metrics.Counter
+	fullHits    *metrics.Counter
+	partialHits *metrics.Counter
+	busyWorkers *metrics.Gauge
+	latencies   map[string]*metrics.Histogram // only used by the stat processor goroutine
+}
+
+func newQueryMetrics(reg *metrics.Registry, workers uint) *queryMetrics {
+	m := &queryMetrics{
+		reg:         reg,
+		queries:     reg.Counter("tsbs_queries_total", "Queries executed."),
+		fullHits:    reg.Counter("tsbs_query_cache_hits_total", "Queries answered by the cache.", "kind", "full"),
+		partialHits: reg.Counter("tsbs_query_cache_hits_total", "Queries answered by the cache.", "kind", "partial"),
+		busyWorkers: reg.Gauge("tsbs_query_workers_busy", "Workers executing a query."),
+		latencies:   make(map[string]*metrics.Histogram),
+	}
+	reg.Gauge("tsbs_query_workers", "Configured query workers.").Set(float64(workers))
+	reg.CounterFunc("tsbs_query_result_bytes_total", "Bytes of query results by source.", func() float64 {
+		return float64(atomic.LoadUint64(&client.CacheBytes))
+	}, "source", "cache")
+	reg.CounterFunc("tsbs_query_result_bytes_total", "Bytes of query results by source.", func() float64 {
+		return float64(atomic.LoadUint64(&client.DatabaseBytes))
+	}, "source", "database")
+	return m
+}
+
+// observe records a Stat, including those of burn-in queries.
+func (m *queryMetrics) observe(stat *Stat) {
+	label := string(stat.label)
+	h, ok := m.latencies[label]
+	if !ok {
+		h = m.reg.Histogram("tsbs_query_duration_seconds", "Query latency by query type.", metrics.LatencyBuckets, "label", label)
+		m.latencies[label] = h
+	}
+	h.Observe(stat.value / 1e3)
+
+	if stat.isPartial {
+		return
+	}
+	m.queries.Inc()
+	if stat.hitKind == 2 {
+		m.fullHits.Inc()
+	} else if stat.hitKind == 1 {
+		m.partialHits.Inc()
+	}
+}
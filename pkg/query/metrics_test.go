@@ -0,0 +1,37 @@
+package query
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/metrics"
+)
+
+func TestQueryMetricsObserve(t *testing.T) {
+	reg := metrics.NewRegistry()
+	m := newQueryMetrics(reg, 4)
+	m.observe(GetStat().Init([]byte("q"), 2, 0, 2))
+	m.observe(GetStat().Init([]byte("q"), 20, 0, 1))
+	m.observe(GetStat().Init([]byte("q"), 200, 0, 0))
+	m.observe(GetPartialStat().Init([]byte("part"), 1, 0, 2))
+
+	var buf bytes.Buffer
+	if err := reg.WriteText(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"tsbs_queries_total 3\n",
+		`tsbs_query_cache_hits_total{kind="full"} 1` + "\n",
+		`tsbs_query_cache_hits_total{kind="partial"} 1` + "\n",
+		`tsbs_query_duration_seconds_bucket{label="q",le="0.0025"} 1` + "\n",
+		`tsbs_query_duration_seconds_bucket{label="q",le="0.25"} 3` + "\n",
+		`tsbs_query_duration_seconds_count{label="part"} 1` + "\n",
+		"tsbs_query_workers 4\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package query
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Input formats understood by the scanner. FormatGob is the output of
+// tsbs_generate_queries; the others hold one InfluxQL statement per record and
+// can only be decoded into HTTP queries.
+const (
+	FormatGob    = "gob"
+	FormatText   = "text"
+	FormatCSV    = "csv"
+	FormatTSV    = "tsv"
+	FormatNDJSON = "ndjson"
+
+	// DefaultTextLabel is the label of text queries that do not carry one.
+	DefaultTextLabel = "InfluxQL query"
+
+	maxTextQuerySize = 16 << 20 // 16 MB
+
+	errUnknownInputFormatFmt = "unknown query input format '%s'"
+	errTextQueryTypeFmt      = "input format '%s' requires HTTP queries, got %T"
+	errTextQueryLineFmt      = "line %d: %v"
+	errNoStatement           = "missing query statement"
+	errBadTimestampFmt       = "cannot parse timestamp '%s'"
+	errSplitStatementFmt     = "first column '%s' is the start of a statement; quote statements containing '%s' or add a header"
+)
+
+// statementKeywords are the first words of InfluxQL statements. A positional
+// record whose first column starts with one is a statement that was split on
+// the separator, not a label or timestamp.
+var statementKeywords = map[string]struct{}{
+	"ALTER":   {},
+	"CREATE":  {},
+	"DELETE":  {},
+	"DROP":    {},
+	"EXPLAIN": {},
+	"GRANT":   {},
+	"KILL":    {},
+	"REVOKE":  {},
+	"SELECT":  {},
+	"SHOW":    {},
+}
+
+// queryDecoder reads the next Query from an input. The returned time is the
+// original issue time of the query, or the zero time if the input has none.
+type queryDecoder interface {
+	decode(q Query) (time.Time, error)
+}
+
+// newQueryDecoder returns the queryDecoder for format reading from r.
+func newQueryDecoder(format string, r io.Reader) (queryDecoder, error) {
+	switch format {
+	case "", FormatGob:
+		return &gobDecoder{dec: gob.NewDecoder(r)}, nil
+	case FormatText:
+		return newLineDecoder(format, r, parseTextLine), nil
+	case FormatTSV:
+		p := &columnParser{sep: "\t", first: true}
+		return newLineDecoder(format, r, p.parseLine), nil
+	case FormatCSV:
+		return newCSVDecoder(r), nil
+	case FormatNDJSON:
+		return newLineDecoder(format, r, parseNDJSONLine), nil
+	default:
+		return nil, fmt.Errorf(errUnknownInputFormatFmt, format)
+	}
+}
+
+// textQuery is a single InfluxQL statement read from a text input.
+type textQuery struct {
+	Statement string
+	Label     string
+	Timestamp time.Time
+}
+
+// fill sets q up the same way the Influx query generator does, so text
+// queries run through the same client paths as generated ones.
+func (tq *textQuery) fill(format string, q Query) error {
+	hq, ok := q.(*HTTP)
+	if !ok {
+		return fmt.Errorf(errTextQueryTypeFmt, format, q)
+	}
+	label := tq.Label
+	if label == "" {
+		label = DefaultTextLabel
+	}
+	v := url.Values{}
+	v.Set("q", tq.Statement)
+	hq.HumanLabel = append(hq.HumanLabel[:0], label...)
+	hq.HumanDescription = append(hq.HumanDescription[:0], tq.Statement...)
+	hq.Method = append(hq.Method[:0], "POST"...)
+	hq.Path = append(hq.Path[:0], "/query?"+v.Encode()...)
+	hq.Body = hq.Body[:0]
+	hq.RawQuery = append(hq.RawQuery[:0], tq.Statement...)
+	hq.StartTimestamp = 0
+	hq.EndTimestamp = 0
+	return nil
+}
+
+// gobDecoder decodes queries encoded by tsbs_generate_queries.
+type gobDecoder struct {
+	dec *gob.Decoder
+}
+
+func (d *gobDecoder) decode(q Query) (time.Time, error) {
+	return time.Time{}, d.dec.Decode(q)
+}
+
+// lineParser parses one non-empty line of a text input. ok is false for lines
+// that hold no query, such as a header.
+type lineParser func(line string) (tq textQuery, ok bool, err error)
+
+// lineDecoder decodes text inputs that hold one query per line. Empty lines
+// and lines starting with '#' are skipped.
+type lineDecoder struct {
+	format string
+	sc     *bufio.Scanner
+	parse  lineParser
+	line   int
+}
+
+func newLineDecoder(format string, r io.Reader, parse lineParser) *lineDecoder {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxTextQuerySize)
+	return &lineDecoder{format: format, sc: sc, parse: parse}
+}
+
+func (d *lineDecoder) decode(q Query) (time.Time, error) {
+	for d.sc.Scan() {
+		d.line++
+		line := strings.TrimSpace(d.sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tq, ok, err := d.parse(line)
+		if err != nil {
+			return time.Time{}, fmt.Errorf(errTextQueryLineFmt, d.line, err)
+		}
+		if !ok {
+			continue
+		}
+		return tq.Timestamp, tq.fill(d.format, q)
+	}
+	if err := d.sc.Err(); err != nil {
+		return time.Time{}, err
+	}
+	return time.Time{}, io.EOF
+}
+
+// parseTextLine treats the whole line as the statement.
+func parseTextLine(line string) (textQuery, bool, error) {
+	return textQuery{Statement: line}, true, nil
+}
+
+// ndjsonQuery is a line of an NDJSON input. Timestamp is either an RFC3339
+// string or a Unix epoch number.
+type ndjsonQuery struct {
+	Query     string          `json:"query"`
+	Label     string          `json:"label"`
+	Timestamp json.RawMessage `json:"timestamp"`
+}
+
+func parseNDJSONLine(line string) (textQuery, bool, error) {
+	var nq ndjsonQuery
+	if err := json.Unmarshal([]byte(line), &nq); err != nil {
+		return textQuery{}, false, err
+	}
+	tq := textQuery{Statement: strings.TrimSpace(nq.Query), Label: nq.Label}
+	if tq.Statement == "" {
+		return tq, false, fmt.Errorf(errNoStatement)
+	}
+	if ts := strings.Trim(string(nq.Timestamp), `"`); ts != "" && ts != "null" {
+		t, err := parseTextTimestamp(ts)
+		if err != nil {
+			return tq, false, err
+		}
+		tq.Timestamp = t
+	}
+	return tq, true, nil
+}
+
+// columnParser parses delimited records. Without a header the columns are
+// positional: "query", "label,query" or "timestamp,label,query", where any
+// further fields are joined back into the statement, and a first column that
+// starts like a statement is an error as the statement was split. A first
+// record naming a "query" column is a header, and its "label" and
+// "timestamp" columns are used if present.
+type columnParser struct {
+	sep                 string
+	header              bool
+	first               bool
+	query, label, tsCol int
+}
+
+func (p *columnParser) parseLine(line string) (textQuery, bool, error) {
+	return p.parseFields(strings.Split(line, p.sep))
+}
+
+func (p *columnParser) parseFields(fields []string) (textQuery, bool, error) {
+	if p.first {
+		p.first = false
+		if p.parseHeader(fields) {
+			return textQuery{}, false, nil
+		}
+	}
+
+	get := func(i int) string {
+		if i < 0 || i >= len(fields) {
+			return ""
+		}
+		return strings.TrimSpace(fields[i])
+	}
+
+	var tq textQuery
+	var ts string
+	if p.header {
+		tq.Statement, tq.Label, ts = get(p.query), get(p.label), get(p.tsCol)
+	} else {
+		if len(fields) > 1 && isStatementStart(get(0)) {
+			return tq, false, fmt.Errorf(errSplitStatementFmt, get(0), p.sep)
+		}
+		switch len(fields) {
+		case 1:
+			tq.Statement = get(0)
+		case 2:
+			tq.Label, tq.Statement = get(0), get(1)
+		default:
+			ts, tq.Label = get(0), get(1)
+			tq.Statement = strings.TrimSpace(strings.Join(fields[2:], p.sep))
+		}
+	}
+	if tq.Statement == "" {
+		return tq, false, fmt.Errorf(errNoStatement)
+	}
+	if ts != "" {
+		t, err := parseTextTimestamp(ts)
+		if err != nil {
+			return tq, false, err
+		}
+		tq.Timestamp = t
+	}
+	return tq, true, nil
+}
+
+// isStatementStart returns whether s starts with the keyword of an InfluxQL
+// statement.
+func isStatementStart(s string) bool {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return false
+	}
+	_, ok := statementKeywords[strings.ToUpper(words[0])]
+	return ok
+}
+
+func (p *columnParser) parseHeader(fields []string) bool {
+	p.query, p.label, p.tsCol = -1, -1, -1
+	for i, f := range fields {
+		switch strings.ToLower(strings.TrimSpace(f)) {
+		case "query":
+			p.query = i
+		case "label":
+			p.label = i
+		case "timestamp", "time":
+			p.tsCol = i
+		}
+	}
+	p.header = p.query >= 0
+	return p.header
+}
+
+// csvDecoder decodes CSV inputs. Statements containing commas must be quoted
+// as usual for CSV unless they are the last of three positional columns;
+// unquoted ones in a single column are rejected rather than split.
+type csvDecoder struct {
+	r      *csv.Reader
+	parser *columnParser
+}
+
+func newCSVDecoder(r io.Reader) *csvDecoder {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.Comment = '#'
+	cr.LazyQuotes = true
+	return &csvDecoder{r: cr, parser: &columnParser{sep: ",", first: true}}
+}
+
+func (d *csvDecoder) decode(q Query) (time.Time, error) {
+	for {
+		fields, err := d.r.Read()
+		if err != nil {
+			return time.Time{}, err
+		}
+		line, _ := d.r.FieldPos(0)
+		tq, ok, err := d.parser.parseFields(fields)
+		if err != nil {
+			return time.Time{}, fmt.Errorf(errTextQueryLineFmt, line, err)
+		}
+		if !ok {
+			continue
+		}
+		return tq.Timestamp, tq.fill(FormatCSV, q)
+	}
+}
+
+// parseTextTimestamp parses an RFC3339 time or a Unix epoch whose unit is
+// inferred from its number of digits (s, ms, us or ns).
+func parseTextTimestamp(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !strings.ContainsAny(s, "eE") {
+		if strings.Contains(s, ".") {
+			sec := int64(f)
+			return time.Unix(sec, int64((f-float64(sec))*1e9)).UTC(), nil
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf(errBadTimestampFmt, s)
+		}
+		switch digits := len(strings.TrimPrefix(s, "-")); {
+		case digits <= 10:
+			return time.Unix(n, 0).UTC(), nil
+		case digits <= 13:
+			return time.Unix(0, n*int64(time.Millisecond)).UTC(), nil
+		case digits <= 16:
+			return time.Unix(0, n*int64(time.Microsecond)).UTC(), nil
+		default:
+			return time.Unix(0, n).UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf(errBadTimestampFmt, s)
+}
+
+// replayPacer delays queries so that they are issued with the same spacing
+// as their timestamps, scaled by speed. Queries without a timestamp, and all
+// queries when speed is 0, are not delayed.
+type replayPacer struct {
+	speed     float64
+	firstTS   time.Time
+	wallStart time.Time
+	sleep     func(time.Duration)
+	now       func() time.Time
+}
+
+func newReplayPacer(speed float64) *replayPacer {
+	return &replayPacer{speed: speed, sleep: time.Sleep, now: time.Now}
+}
+
+// wait blocks until the query with timestamp ts is due.
+func (p *replayPacer) wait(ts time.Time) {
+	if p.speed <= 0 || ts.IsZero() {
+		return
+	}
+	if p.firstTS.IsZero() {
+		p.firstTS = ts
+		p.wallStart = p.now()
+		return
+	}
+	due := p.wallStart.Add(time.Duration(float64(ts.Sub(p.firstTS)) / p.speed))
+	if d := due.Sub(p.now()); d > 0 {
+		p.sleep(d)
+	}
+}
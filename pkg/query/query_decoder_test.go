@@ -0,0 +1,247 @@
+package query
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decodeAllHTTP(t *testing.T, format, input string) ([]*HTTP, []time.Time) {
+	dec, err := newQueryDecoder(format, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error creating decoder: %v", err)
+	}
+	queries := []*HTTP{}
+	timestamps := []time.Time{}
+	for {
+		q := &HTTP{}
+		ts, err := dec.decode(q)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected decode error: %v", format, err)
+		}
+		queries = append(queries, q)
+		timestamps = append(timestamps, ts)
+	}
+	return queries, timestamps
+}
+
+func TestQueryDecoderText(t *testing.T) {
+	stmt := `SELECT mean("usage_user") FROM "cpu" WHERE time >= '2016-01-01T00:00:00Z' GROUP BY time(1m)`
+	input := "# comment\n\n" + stmt + "\nSHOW DATABASES\n"
+
+	queries, timestamps := decodeAllHTTP(t, FormatText, input)
+	if got := len(queries); got != 2 {
+		t.Fatalf("incorrect number of queries: got %d want %d", got, 2)
+	}
+	q := queries[0]
+	if got := string(q.RawQuery); got != stmt {
+		t.Errorf("incorrect raw query: got %s want %s", got, stmt)
+	}
+	if got := string(q.HumanLabel); got != DefaultTextLabel {
+		t.Errorf("incorrect label: got %s want %s", got, DefaultTextLabel)
+	}
+	if got := string(q.Method); got != "POST" {
+		t.Errorf("incorrect method: got %s", got)
+	}
+	v := url.Values{}
+	v.Set("q", stmt)
+	if got, want := string(q.Path), "/query?"+v.Encode(); got != want {
+		t.Errorf("incorrect path: got %s want %s", got, want)
+	}
+	if !timestamps[0].IsZero() {
+		t.Errorf("text query should have no timestamp: got %v", timestamps[0])
+	}
+}
+
+func TestQueryDecoderColumns(t *testing.T) {
+	ts := time.Date(2016, time.January, 1, 0, 0, 1, 0, time.UTC)
+	cases := []struct {
+		desc      string
+		format    string
+		input     string
+		wantStmt  string
+		wantLabel string
+		wantTS    time.Time
+	}{
+		{
+			desc:      "csv query only",
+			format:    FormatCSV,
+			input:     `"SELECT a,b FROM cpu"` + "\n",
+			wantStmt:  "SELECT a,b FROM cpu",
+			wantLabel: DefaultTextLabel,
+		},
+		{
+			desc:      "csv label and query",
+			format:    FormatCSV,
+			input:     "lbl,SELECT a FROM cpu\n",
+			wantStmt:  "SELECT a FROM cpu",
+			wantLabel: "lbl",
+		},
+		{
+			desc:      "csv positional with unquoted commas",
+			format:    FormatCSV,
+			input:     "1451606401,lbl,SELECT a,b FROM cpu\n",
+			wantStmt:  "SELECT a,b FROM cpu",
+			wantLabel: "lbl",
+			wantTS:    ts,
+		},
+		{
+			desc:      "csv header",
+			format:    FormatCSV,
+			input:     "query,timestamp,label\n\"SELECT \"\"a\"\" FROM cpu\",2016-01-01T00:00:01Z,lbl\n",
+			wantStmt:  `SELECT "a" FROM cpu`,
+			wantLabel: "lbl",
+			wantTS:    ts,
+		},
+		{
+			desc:      "tsv positional",
+			format:    FormatTSV,
+			input:     "1451606401000\tlbl\tSELECT \"a\",b FROM cpu\n",
+			wantStmt:  `SELECT "a",b FROM cpu`,
+			wantLabel: "lbl",
+			wantTS:    ts,
+		},
+		{
+			desc:      "tsv header",
+			format:    FormatTSV,
+			input:     "label\tquery\nlbl\tSELECT a FROM cpu\n",
+			wantStmt:  "SELECT a FROM cpu",
+			wantLabel: "lbl",
+		},
+		{
+			desc:      "ndjson",
+			format:    FormatNDJSON,
+			input:     `{"query": "SELECT \"a\" FROM cpu", "label": "lbl", "timestamp": "2016-01-01T00:00:01Z"}` + "\n",
+			wantStmt:  `SELECT "a" FROM cpu`,
+			wantLabel: "lbl",
+			wantTS:    ts,
+		},
+		{
+			desc:      "ndjson epoch",
+			format:    FormatNDJSON,
+			input:     `{"query": "SELECT a FROM cpu", "timestamp": 1451606401000000000}` + "\n",
+			wantStmt:  "SELECT a FROM cpu",
+			wantLabel: DefaultTextLabel,
+			wantTS:    ts,
+		},
+	}
+
+	for _, c := range cases {
+		queries, timestamps := decodeAllHTTP(t, c.format, c.input)
+		if got := len(queries); got != 1 {
+			t.Errorf("%s: incorrect number of queries: got %d want %d", c.desc, got, 1)
+			continue
+		}
+		if got := string(queries[0].RawQuery); got != c.wantStmt {
+			t.Errorf("%s: incorrect statement: got %s want %s", c.desc, got, c.wantStmt)
+		}
+		if got := string(queries[0].HumanLabel); got != c.wantLabel {
+			t.Errorf("%s: incorrect label: got %s want %s", c.desc, got, c.wantLabel)
+		}
+		if got := timestamps[0]; !got.Equal(c.wantTS) {
+			t.Errorf("%s: incorrect timestamp: got %v want %v", c.desc, got, c.wantTS)
+		}
+	}
+}
+
+func TestQueryDecoderErrors(t *testing.T) {
+	cases := []struct {
+		desc   string
+		format string
+		input  string
+	}{
+		{desc: "unknown format", format: "xml", input: ""},
+		{desc: "ndjson without query", format: FormatNDJSON, input: `{"label": "lbl"}`},
+		{desc: "ndjson malformed", format: FormatNDJSON, input: `{"query": `},
+		{desc: "bad timestamp", format: FormatTSV, input: "yesterday\tlbl\tSELECT a FROM cpu"},
+		{desc: "empty statement", format: FormatCSV, input: "lbl,"},
+		{desc: "csv unquoted statement with comma", format: FormatCSV, input: "SELECT a,b FROM cpu\n"},
+		{desc: "csv unquoted statement with commas", format: FormatCSV, input: "select a,b,c FROM cpu\n"},
+		{desc: "tsv statement with tab", format: FormatTSV, input: "SELECT a\tFROM cpu\n"},
+	}
+	for _, c := range cases {
+		dec, err := newQueryDecoder(c.format, strings.NewReader(c.input))
+		if err == nil {
+			_, err = dec.decode(&HTTP{})
+		}
+		if err == nil || err == io.EOF {
+			t.Errorf("%s: expected error, got %v", c.desc, err)
+		}
+	}
+}
+
+func TestQueryDecoderRequiresHTTP(t *testing.T) {
+	dec, err := newQueryDecoder(FormatText, strings.NewReader("SHOW DATABASES\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := dec.decode(&testQuery{}); err == nil {
+		t.Errorf("expected error decoding text into a non-HTTP query")
+	}
+}
+
+func TestScanText(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteString("a\tSELECT a FROM cpu\nb\tSELECT b FROM cpu\na\tSELECT c FROM cpu\n")
+	wantLabels := []string{"a", "b", "a"}
+
+	limit := uint64(0)
+	queryChan := make(chan Query, len(wantLabels))
+	newScanner(&limit).setFormat(FormatTSV, 0).setReader(&b).scan(&HTTPPool, queryChan)
+	close(queryChan)
+
+	i := 0
+	for q := range queryChan {
+		if got := string(q.HumanLabelName()); got != wantLabels[i] {
+			t.Errorf("wrong label for query %d: got %s want %s", i, got, wantLabels[i])
+		}
+		if got := q.GetID(); got != uint64(i) {
+			t.Errorf("wrong id for query %d: got %d", i, got)
+		}
+		i++
+	}
+	if i != len(wantLabels) {
+		t.Errorf("incorrect num of queries scanned: got %d want %d", i, len(wantLabels))
+	}
+}
+
+func TestReplayPacer(t *testing.T) {
+	start := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	slept := []time.Duration{}
+	p := newReplayPacer(2)
+	p.now = func() time.Time { return now }
+	p.sleep = func(d time.Duration) {
+		slept = append(slept, d)
+		now = now.Add(d)
+	}
+
+	ts := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	p.wait(ts)
+	p.wait(time.Time{})
+	p.wait(ts.Add(10 * time.Second))
+	now = now.Add(time.Second)
+	p.wait(ts.Add(14 * time.Second))
+	p.wait(ts.Add(12 * time.Second))
+
+	want := []time.Duration{5 * time.Second, time.Second}
+	if len(slept) != len(want) {
+		t.Fatalf("incorrect number of sleeps: got %v want %v", slept, want)
+	}
+	for i := range want {
+		if slept[i] != want[i] {
+			t.Errorf("incorrect sleep %d: got %v want %v", i, slept[i], want[i])
+		}
+	}
+
+	p = newReplayPacer(0)
+	p.sleep = func(d time.Duration) { t.Errorf("unexpected sleep with speed 0: %v", d) }
+	p.wait(ts)
+	p.wait(ts.Add(time.Hour))
+}
@@ -1,7 +1,6 @@
 package query
 
 import (
-	"encoding/gob"
 	"io"
 	"log"
 	"sync"
@@ -10,8 +9,10 @@ import (
 // scanner is used to read in Queries from a Reader where they are
 // Go-encoded and then distribute them to workers
 type scanner struct {
-	r     io.Reader
-	limit *uint64
+	r           io.Reader
+	limit       *uint64
+	format      string
+	replaySpeed float64
 }
 
 // newScanner returns a new scanner for a given Reader and its limit
@@ -25,9 +26,21 @@ func (s *scanner) setReader(r io.Reader) *scanner {
 	return s
 }
 
+// setFormat sets the input format and the speed at which timestamped queries
+// are replayed relative to their original pacing (0 to ignore timestamps)
+func (s *scanner) setFormat(format string, replaySpeed float64) *scanner {
+	s.format = format
+	s.replaySpeed = replaySpeed
+	return s
+}
+
 // scan reads encoded Queries and places them into a channel
 func (s *scanner) scan(pool *sync.Pool, c chan Query) {
-	decoder := gob.NewDecoder(s.r)
+	decoder, err := newQueryDecoder(s.format, s.r)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pacer := newReplayPacer(s.replaySpeed)
 
 	n := uint64(0)
 	for {
@@ -37,7 +50,7 @@ func (s *scanner) scan(pool *sync.Pool, c chan Query) {
 		}
 
 		q := pool.Get().(Query)
-		err := decoder.Decode(q)
+		ts, err := decoder.decode(q)
 		if err == io.EOF {
 			// EOF, all done
 			break
@@ -47,7 +60,8 @@ func (s *scanner) scan(pool *sync.Pool, c chan Query) {
 			log.Fatal(err)
 		}
 
-		// We have a query, send it to the runner
+		// We have a query, wait until it is due and send it to the runner
+		pacer.wait(ts)
 		q.SetID(n)
 		c <- q
 
@@ -25,11 +25,13 @@ type statProcessor interface {
 }
 
 type statProcessorArgs struct {
-	prewarmQueries   bool    // PrewarmQueries tells the StatProcessor whether we're running each query twice to prewarm the cache
-	limit            *uint64 // limit is the number of statistics to analyze before stopping
-	burnIn           uint64  // burnIn is the number of statistics to ignore before analyzing
-	printInterval    uint64  // printInterval is how often print intermediate stats (number of queries)
-	hdrLatenciesFile string  // hdrLatenciesFile is the filename to Write the High Dynamic Range (HDR) Histogram of Response Latencies to
+	prewarmQueries   bool          // PrewarmQueries tells the StatProcessor whether we're running each query twice to prewarm the cache
+	limit            *uint64       // limit is the number of statistics to analyze before stopping
+	burnIn           uint64        // burnIn is the number of statistics to ignore before analyzing
+	printInterval    uint64        // printInterval is how often print intermediate stats (number of queries)
+	hdrLatenciesFile string        // hdrLatenciesFile is the filename to Write the High Dynamic Range (HDR) Histogram of Response Latencies to
+	metrics          *queryMetrics // metrics, if not nil, is updated with every Stat
+	listener         QueryListener // listener, if not nil, is called with every complete query
 
 }
 
@@ -105,6 +107,12 @@ func (sp *defaultStatProcessor) process(workers uint) {
 	prevRequestCount := uint64(0)
 
 	for stat := range sp.c {
+		if sp.args.metrics != nil {
+			sp.args.metrics.observe(stat)
+		}
+		if sp.args.listener != nil && !stat.isPartial {
+			sp.args.listener(string(stat.label), stat.value, stat.hitKind)
+		}
 		atomic.AddUint64(&sp.opsCount, 1)
 		atomic.AddUint64(&sp.totalByteLength, stat.byteLength)
 		if stat.hitKind == 2 {
@@ -27,7 +27,7 @@ func TestGetPartialStat(t *testing.T) {
 
 func TestStatInit(t *testing.T) {
 	s := GetStat()
-	s.Init([]byte("foo"), 11.0)
+	s.Init([]byte("foo"), 11.0, 0, 0)
 
 	if s.isPartial {
 		t.Errorf("Init() failed - isPartial = true")
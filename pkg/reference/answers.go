@@ -0,0 +1,244 @@
+package reference
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/timescale/tsbs/InfluxDB-client/models"
+)
+
+const (
+	// floatTolerance is the relative difference allowed between two floats,
+	// which aggregates summing values in another order may differ by
+	floatTolerance = 1e-9
+
+	errSeriesCountFmt   = "%d series, want %d"
+	errMissingSeriesFmt = "missing series %s"
+	errRowCountFmt      = "series %s: %d rows, want %d"
+	errColumnCountFmt   = "series %s: %d columns, want %d"
+	errValueFmt         = "series %s: row %d, column %s: %v, want %v"
+	errBadAnswerFmt     = "line %d: %v"
+)
+
+// Answer is the expected answer of a query, or the error that kept it from
+// being evaluated.
+type Answer struct {
+	// ID is the index of the query in the query file, as the query runner
+	// numbers them
+	ID     uint64       `json:"id"`
+	Label  string       `json:"label"`
+	Query  string       `json:"query"`
+	Series []models.Row `json:"series"`
+	Err    string       `json:"error,omitempty"`
+}
+
+// WriteAnswer writes a as a line of JSON to w.
+func WriteAnswer(w io.Writer, a *Answer) error {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// Answers are expected answers by query ID, against which the results of
+// the queries are checked. Check can be called concurrently.
+type Answers struct {
+	// Precision is the unit of the numeric time values of the results
+	Precision time.Duration
+
+	answers    map[uint64]*Answer
+	checked    uint64
+	mismatched uint64
+	unchecked  uint64
+}
+
+// ReadAnswers reads the answers written by WriteAnswer from r.
+func ReadAnswers(r io.Reader, precision time.Duration) (*Answers, error) {
+	a := &Answers{Precision: precision, answers: make(map[uint64]*Answer)}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		dec := json.NewDecoder(strings.NewReader(scanner.Text()))
+		dec.UseNumber()
+		answer := &Answer{}
+		if err := dec.Decode(answer); err != nil {
+			return nil, fmt.Errorf(errBadAnswerFmt, line, err)
+		}
+		a.answers[answer.ID] = answer
+	}
+	return a, scanner.Err()
+}
+
+// Check checks the series of the result of the query id against its
+// expected answer. It returns nil if they match, or if there is no expected
+// answer for the query.
+func (a *Answers) Check(id uint64, got []models.Row) error {
+	answer, ok := a.answers[id]
+	if !ok || answer.Err != "" {
+		atomic.AddUint64(&a.unchecked, 1)
+		return nil
+	}
+	atomic.AddUint64(&a.checked, 1)
+	if err := Compare(answer.Series, got, a.Precision); err != nil {
+		atomic.AddUint64(&a.mismatched, 1)
+		return err
+	}
+	return nil
+}
+
+// WriteSummary writes the number of checked and mismatched results to w.
+func (a *Answers) WriteSummary(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "reference answers: %d results checked, %d mismatched, %d without answer\n",
+		atomic.LoadUint64(&a.checked), atomic.LoadUint64(&a.mismatched), atomic.LoadUint64(&a.unchecked))
+	return err
+}
+
+// Compare compares the series of a result with the expected ones, matching
+// them by name and tags, and returns the first difference. Rows whose values
+// are all null or zero, such as empty time intervals, are left out, and so
+// are series without other rows. Numeric time values are in units of
+// precision.
+func Compare(want, got []models.Row, precision time.Duration) error {
+	want, got = nonEmpty(want), nonEmpty(got)
+	if len(want) != len(got) {
+		return fmt.Errorf(errSeriesCountFmt, len(got), len(want))
+	}
+	bySeries := make(map[string]models.Row, len(got))
+	for _, r := range got {
+		bySeries[seriesKey(r)] = r
+	}
+	for _, w := range want {
+		key := seriesKey(w)
+		g, ok := bySeries[key]
+		if !ok {
+			return fmt.Errorf(errMissingSeriesFmt, key)
+		}
+		if len(g.Values) != len(w.Values) {
+			return fmt.Errorf(errRowCountFmt, key, len(g.Values), len(w.Values))
+		}
+		for i := range w.Values {
+			if len(g.Values[i]) != len(w.Values[i]) {
+				return fmt.Errorf(errColumnCountFmt, key, len(g.Values[i]), len(w.Values[i]))
+			}
+			for j := range w.Values[i] {
+				wv, gv := w.Values[i][j], g.Values[i][j]
+				same := false
+				if j == 0 {
+					wv, gv = toTime(wv, precision), toTime(gv, precision)
+					same = wv == gv
+				} else {
+					same = equal(wv, gv)
+				}
+				if !same {
+					column := fmt.Sprint(j)
+					if j < len(w.Columns) {
+						column = w.Columns[j]
+					}
+					return fmt.Errorf(errValueFmt, key, i, column, gv, wv)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func seriesKey(r models.Row) string {
+	keys := make([]string, 0, len(r.Tags))
+	for k := range r.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	sb.WriteString(r.Name)
+	for _, k := range keys {
+		sb.WriteString("," + k + "=" + r.Tags[k])
+	}
+	return sb.String()
+}
+
+// nonEmpty returns the series of rows without their empty rows.
+func nonEmpty(rows []models.Row) []models.Row {
+	var result []models.Row
+	for _, r := range rows {
+		var values [][]interface{}
+		for _, v := range r.Values {
+			if !emptyRow(v) {
+				values = append(values, v)
+			}
+		}
+		if len(values) > 0 {
+			r.Values = values
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+func emptyRow(values []interface{}) bool {
+	for _, v := range values[1:] {
+		if f, ok := number(v); v != nil && (!ok || f != 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// number returns v as a float64, if it is a number.
+func number(v interface{}) (float64, bool) {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	if f, ok := toFloat(v); ok {
+		return f, true
+	}
+	if f, ok := v.(float32); ok {
+		return float64(f), true
+	}
+	return 0, false
+}
+
+// toTime returns the time value v in nanoseconds since the epoch.
+func toTime(v interface{}, precision time.Duration) interface{} {
+	switch t := v.(type) {
+	case time.Time:
+		return t.UnixNano()
+	case string:
+		if parsed, err := time.Parse(time.RFC3339Nano, t); err == nil {
+			return parsed.UnixNano()
+		}
+		return v
+	case int64:
+		return t * int64(precision)
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i * int64(precision)
+		}
+	}
+	if f, ok := number(v); ok {
+		return int64(math.Round(f)) * int64(precision)
+	}
+	return v
+}
+
+func equal(a, b interface{}) bool {
+	fa, okA := number(a)
+	fb, okB := number(b)
+	if okA && okB {
+		diff := math.Abs(fa - fb)
+		return diff == 0 || diff <= floatTolerance*math.Max(math.Abs(fa), math.Abs(fb))
+	}
+	if okA || okB {
+		return false
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
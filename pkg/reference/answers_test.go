@@ -0,0 +1,133 @@
+package reference
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/InfluxDB-client/models"
+)
+
+func TestCompare(t *testing.T) {
+	want := []models.Row{
+		{Name: "cpu", Tags: map[string]string{"hostname": "host_0"}, Columns: []string{"time", "max"},
+			Values: [][]interface{}{{ts(0), 20.0}, {ts(60), nil}, {ts(120), 30.0}}},
+		{Name: "cpu", Tags: map[string]string{"hostname": "host_1"}, Columns: []string{"time", "max"},
+			Values: [][]interface{}{{ts(0), nil}}},
+	}
+	// a result as the database returns it, in seconds, without the empty
+	// interval and with a float summed in another order
+	got := func() []models.Row {
+		return []models.Row{
+			{Name: "cpu", Tags: map[string]string{"hostname": "host_0"}, Columns: []string{"time", "max"},
+				Values: [][]interface{}{{json.Number("0"), json.Number("20.000000000000004")}, {json.Number("120"), json.Number("30")}}},
+		}
+	}
+	if err := Compare(want, got(), time.Second); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		desc   string
+		change func(rows []models.Row) []models.Row
+		errMsg string
+	}{
+		{
+			desc:   "value",
+			change: func(rows []models.Row) []models.Row { rows[0].Values[1][1] = json.Number("31"); return rows },
+			errMsg: "series cpu,hostname=host_0: row 1, column max: 31, want 30",
+		},
+		{
+			desc:   "time",
+			change: func(rows []models.Row) []models.Row { rows[0].Values[1][0] = json.Number("130"); return rows },
+			errMsg: "series cpu,hostname=host_0: row 1, column time: 130000000000, want 120000000000",
+		},
+		{
+			desc:   "rows",
+			change: func(rows []models.Row) []models.Row { rows[0].Values = rows[0].Values[:1]; return rows },
+			errMsg: "series cpu,hostname=host_0: 1 rows, want 2",
+		},
+		{
+			desc:   "tags",
+			change: func(rows []models.Row) []models.Row { rows[0].Tags["hostname"] = "host_1"; return rows },
+			errMsg: "missing series cpu,hostname=host_0",
+		},
+		{
+			desc:   "series",
+			change: func(rows []models.Row) []models.Row { return nil },
+			errMsg: "0 series, want 1",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			err := Compare(want, c.change(got()), time.Second)
+			if err == nil || err.Error() != c.errMsg {
+				t.Errorf("incorrect error: got %v want %s", err, c.errMsg)
+			}
+		})
+	}
+}
+
+func TestAnswersCheck(t *testing.T) {
+	d := newTestDataset(t)
+	var buf bytes.Buffer
+	queries := []string{
+		`SELECT max(usage_user) FROM cpu GROUP BY hostname`,
+		`SELECT percentile(usage_user, 50) FROM cpu`,
+	}
+	for i, q := range queries {
+		a := &Answer{ID: uint64(i), Label: "test", Query: q}
+		var err error
+		if a.Series, err = d.Evaluate(q); err != nil {
+			a.Err = err.Error()
+		}
+		if err := WriteAnswer(&buf, a); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	answers, err := ReadAnswers(&buf, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := []models.Row{
+		{Name: "cpu", Tags: map[string]string{"hostname": "host_0"}, Columns: []string{"time", "max"},
+			Values: [][]interface{}{{json.Number("70"), json.Number("30")}}},
+		{Name: "cpu", Tags: map[string]string{"hostname": "host_1"}, Columns: []string{"time", "max"},
+			Values: [][]interface{}{{json.Number("65"), json.Number("50")}}},
+		{Name: "cpu", Tags: map[string]string{"hostname": "host_2"}, Columns: []string{"time", "max"},
+			Values: [][]interface{}{{json.Number("5"), json.Number("5")}}},
+	}
+	if err := answers.Check(0, result); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	result[2].Values[0][1] = json.Number("6")
+	if err := answers.Check(0, result); err == nil {
+		t.Errorf("expected error for a different result")
+	}
+	// unsupported and unknown queries are not checked
+	if err := answers.Check(1, nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := answers.Check(2, nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var summary strings.Builder
+	if err := answers.WriteSummary(&summary); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "reference answers: 2 results checked, 1 mismatched, 2 without answer\n"
+	if summary.String() != want {
+		t.Errorf("incorrect summary: got %q want %q", summary.String(), want)
+	}
+}
+
+func TestReadAnswersError(t *testing.T) {
+	if _, err := ReadAnswers(strings.NewReader("{\"id\":0}\nnot json\n"), time.Second); err == nil ||
+		!strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("incorrect error: got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+// Package reference computes the expected answers of InfluxQL queries over a
+// generated data set held in memory, so that the results of a database or of
+// a cache in front of it can be checked without a second database.
+package reference
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/timescale/tsbs/InfluxDB-client/models"
+)
+
+const (
+	errParseFmt  = "line %d: %v"
+	errFieldsFmt = "cannot read fields of %s: %v"
+	maxLineSize  = 16 << 20
+)
+
+// Dataset is a data set held in memory, with the points of each series
+// sorted by time. Like InfluxDB, it keeps a single point per series and
+// timestamp, whose fields are the last values written at that timestamp.
+type Dataset struct {
+	measurements map[string]*measurement
+	lines        uint64
+	sorted       bool
+}
+
+// measurement holds the series of a measurement.
+type measurement struct {
+	name      string
+	fieldKeys []string
+	// fields are the indexes of the field keys in the values of the points
+	fields map[string]int
+	series map[string]*series
+}
+
+// series holds the points of a series.
+type series struct {
+	key    string
+	tags   map[string]string
+	points []point
+}
+
+// point has the values of the fields of a point, by index of their key in
+// the measurement, and nil for the fields it does not have.
+type point struct {
+	time   int64
+	values []interface{}
+}
+
+// NewDataset returns an empty Dataset.
+func NewDataset() *Dataset {
+	return &Dataset{measurements: make(map[string]*measurement)}
+}
+
+// Read adds the points of the line protocol read from r.
+func (d *Dataset) Read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		d.lines++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		points, err := models.ParsePoints(line)
+		if err != nil {
+			return fmt.Errorf(errParseFmt, d.lines, err)
+		}
+		for _, p := range points {
+			if err := d.Add(p); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// Add adds p.
+func (d *Dataset) Add(p models.Point) error {
+	fields, err := p.Fields()
+	if err != nil {
+		return fmt.Errorf(errFieldsFmt, p.Key(), err)
+	}
+	name := string(p.Name())
+	m, ok := d.measurements[name]
+	if !ok {
+		m = &measurement{name: name, fields: make(map[string]int), series: make(map[string]*series)}
+		d.measurements[name] = m
+	}
+	key := string(p.Key())
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{key: key, tags: p.Tags().Map()}
+		m.series[key] = s
+	}
+
+	values := make([]interface{}, len(m.fieldKeys), len(m.fieldKeys)+len(fields))
+	for k, v := range fields {
+		i, ok := m.fields[k]
+		if !ok {
+			i = len(m.fieldKeys)
+			m.fields[k] = i
+			m.fieldKeys = append(m.fieldKeys, k)
+			values = append(values, nil)
+		}
+		values[i] = v
+	}
+	s.points = append(s.points, point{time: p.UnixNano(), values: values})
+	d.sorted = false
+	return nil
+}
+
+// sort sorts the points of every series by time, merging the points of a
+// series with the same timestamp.
+func (d *Dataset) sort() {
+	if d.sorted {
+		return
+	}
+	for _, m := range d.measurements {
+		for _, s := range m.series {
+			sort.SliceStable(s.points, func(i, j int) bool { return s.points[i].time < s.points[j].time })
+			merged := s.points[:0]
+			for _, p := range s.points {
+				last := len(merged) - 1
+				if last < 0 || merged[last].time != p.time {
+					merged = append(merged, p)
+					continue
+				}
+				merged[last] = mergePoints(merged[last], p)
+			}
+			s.points = merged
+		}
+	}
+	d.sorted = true
+}
+
+// mergePoints returns the point of the fields of a overwritten by the ones
+// of b.
+func mergePoints(a, b point) point {
+	n := len(a.values)
+	if len(b.values) > n {
+		n = len(b.values)
+	}
+	values := make([]interface{}, n)
+	copy(values, a.values)
+	for i, v := range b.values {
+		if v != nil {
+			values[i] = v
+		}
+	}
+	return point{time: a.time, values: values}
+}
+
+// value returns the value of the field or, if none, the tag key of p of s.
+func (m *measurement) value(s *series, p *point, key string) (interface{}, bool) {
+	if i, ok := m.fields[key]; ok && i < len(p.values) && p.values[i] != nil {
+		return p.values[i], true
+	}
+	if v, ok := s.tags[key]; ok {
+		return v, true
+	}
+	return nil, false
+}
+
+// field returns the value of the field key of p, nil if none.
+func (m *measurement) field(p *point, key string) interface{} {
+	if i, ok := m.fields[key]; ok && i < len(p.values) {
+		return p.values[i]
+	}
+	return nil
+}
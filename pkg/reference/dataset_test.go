@@ -0,0 +1,70 @@
+package reference
+
+import (
+	"strings"
+	"testing"
+)
+
+// testData has cpu series of two regions, one of them with two points at
+// the same timestamp, and a mem series.
+const testData = `# comment
+cpu,hostname=host_0,region=a usage_user=10,usage_system=1 0
+cpu,hostname=host_0,region=a usage_user=20,usage_system=2 10000000000
+cpu,hostname=host_1,region=a usage_user=40,usage_system=4 0
+cpu,hostname=host_0,region=a usage_user=30,usage_system=3 70000000000
+cpu,hostname=host_1,region=a usage_user=50 65000000000
+
+cpu,hostname=host_1,region=a usage_system=9 65000000000
+cpu,hostname=host_2,region=b usage_user=5,usage_system=5 5000000000
+mem,hostname=host_0 used=1i 0
+`
+
+func newTestDataset(t *testing.T) *Dataset {
+	d := NewDataset()
+	if err := d.Read(strings.NewReader(testData)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return d
+}
+
+func TestDatasetRead(t *testing.T) {
+	d := newTestDataset(t)
+	d.sort()
+	cpu := d.measurements["cpu"]
+	if len(cpu.series) != 3 || len(d.measurements["mem"].series) != 1 {
+		t.Fatalf("incorrect series: got %d cpu, %d mem", len(cpu.series), len(d.measurements["mem"].series))
+	}
+
+	s := cpu.series["cpu,hostname=host_0,region=a"]
+	var times []int64
+	for _, p := range s.points {
+		times = append(times, p.time)
+	}
+	if len(times) != 3 || times[0] != 0 || times[1] != 10e9 || times[2] != 70e9 {
+		t.Errorf("incorrect times: got %v", times)
+	}
+
+	// the points at the same timestamp are merged
+	s = cpu.series["cpu,hostname=host_1,region=a"]
+	if len(s.points) != 2 {
+		t.Fatalf("incorrect points: got %d want %d", len(s.points), 2)
+	}
+	p := &s.points[1]
+	if cpu.field(p, "usage_user") != 50.0 || cpu.field(p, "usage_system") != 9.0 {
+		t.Errorf("incorrect merged point: got %v", p.values)
+	}
+	if v, ok := cpu.value(s, p, "region"); !ok || v != "a" {
+		t.Errorf("incorrect tag value: got %v", v)
+	}
+	if v, ok := cpu.value(s, p, "usage_guest"); ok {
+		t.Errorf("unexpected value: got %v", v)
+	}
+}
+
+func TestDatasetReadError(t *testing.T) {
+	d := NewDataset()
+	err := d.Read(strings.NewReader("cpu usage=1 0\ncpu usage= 0\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("incorrect error: got %v", err)
+	}
+}
@@ -0,0 +1,471 @@
+package reference
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxql"
+	"github.com/timescale/tsbs/InfluxDB-client/models"
+)
+
+const (
+	errUnsupportedFmt = "unsupported query: %s"
+	errNotSelect      = "not a SELECT statement"
+)
+
+// Aggregates are the functions of the queries a Dataset evaluates.
+var Aggregates = []string{"mean", "max", "min", "count", "last"}
+
+// selectQuery is a SELECT statement reduced to what Evaluate supports.
+type selectQuery struct {
+	measurement string
+	// calls are the aggregates of the columns, empty for raw queries
+	calls   []string
+	fields  []string
+	columns []string
+	// wildcard selects all fields and tags, other than the dimensions
+	wildcard bool
+
+	condition influxql.Expr
+	min, max  int64
+	bounded   bool
+	interval  int64
+	dims      []string
+
+	fill      influxql.FillOption
+	fillValue interface{}
+	limit     int
+	offset    int
+	ascending bool
+}
+
+func unsupported(format string, args ...interface{}) error {
+	return fmt.Errorf(errUnsupportedFmt, fmt.Sprintf(format, args...))
+}
+
+func isAggregate(name string) bool {
+	for _, a := range Aggregates {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQuery parses query, which has to select fields, or aggregates of
+// fields, of a single measurement, with conditions on tags, fields and time,
+// grouped by tags and time.
+func parseQuery(query string) (*selectQuery, error) {
+	stmt, err := influxql.ParseStatement(query)
+	if err != nil {
+		return nil, err
+	}
+	sel, ok := stmt.(*influxql.SelectStatement)
+	if !ok {
+		return nil, errors.New(errNotSelect)
+	}
+
+	q := &selectQuery{
+		columns:   sel.ColumnNames(),
+		fill:      sel.Fill,
+		fillValue: sel.FillValue,
+		limit:     sel.Limit,
+		offset:    sel.Offset,
+		ascending: sel.TimeAscending(),
+	}
+	if len(sel.Sources) != 1 {
+		return nil, unsupported("%d sources", len(sel.Sources))
+	}
+	m, ok := sel.Sources[0].(*influxql.Measurement)
+	if !ok || m.Regex != nil || m.Name == "" {
+		return nil, unsupported("source %s", sel.Sources[0])
+	}
+	q.measurement = m.Name
+
+	raw := 0
+	for _, f := range sel.Fields {
+		switch e := f.Expr.(type) {
+		case *influxql.Wildcard:
+			if len(sel.Fields) != 1 || e.Type != influxql.ILLEGAL {
+				return nil, unsupported("field %s", f)
+			}
+			raw++
+			q.wildcard = true
+			q.calls = append(q.calls, "")
+		case *influxql.VarRef:
+			raw++
+			q.calls = append(q.calls, "")
+			q.fields = append(q.fields, e.Val)
+		case *influxql.Call:
+			if !isAggregate(e.Name) || len(e.Args) != 1 {
+				return nil, unsupported("field %s", e)
+			}
+			ref, ok := e.Args[0].(*influxql.VarRef)
+			if !ok {
+				return nil, unsupported("field %s", e)
+			}
+			q.calls = append(q.calls, e.Name)
+			q.fields = append(q.fields, ref.Val)
+		default:
+			return nil, unsupported("field %s", f)
+		}
+	}
+	if raw == len(q.calls) {
+		q.calls = nil
+	} else if raw > 0 {
+		return nil, unsupported("fields mixing aggregates and raw values")
+	}
+
+	for _, d := range sel.Dimensions {
+		switch e := d.Expr.(type) {
+		case *influxql.VarRef:
+			q.dims = append(q.dims, e.Val)
+		case *influxql.Call:
+			interval, err := sel.GroupByInterval()
+			if e.Name != "time" || len(e.Args) != 1 || err != nil {
+				return nil, unsupported("dimension %s", e)
+			}
+			q.interval = int64(interval)
+		default:
+			return nil, unsupported("dimension %s", d)
+		}
+	}
+	sort.Strings(q.dims)
+	if q.interval > 0 && q.calls == nil {
+		return nil, unsupported("GROUP BY time of raw values")
+	}
+
+	if sel.SLimit != 0 || sel.SOffset != 0 {
+		return nil, unsupported("SLIMIT or SOFFSET")
+	}
+	switch sel.Fill {
+	case influxql.NullFill, influxql.NoFill, influxql.NumberFill:
+	default:
+		return nil, unsupported("fill option")
+	}
+
+	condition, tr, err := influxql.ConditionExpr(sel.Condition, nil)
+	if err != nil {
+		return nil, unsupported("condition: %v", err)
+	}
+	q.condition = condition
+	q.min, q.max = tr.MinTimeNano(), tr.MaxTimeNano()
+	q.bounded = !tr.Min.IsZero() && !tr.Max.IsZero()
+	return q, nil
+}
+
+// Evaluate returns the series InfluxDB would answer query with, given the
+// points of d. Its time values are time.Time.
+//
+// Evaluate supports the queries of fields, all fields and tags (*), or of
+// aggregates of fields (see Aggregates), of a single measurement, with
+// conditions on tags, fields and time, grouped by tags and time intervals, and
+// with LIMIT, OFFSET, ORDER BY time and fill(null|none|<number>). Empty
+// intervals are only filled if the time range has both bounds.
+func (d *Dataset) Evaluate(query string) ([]models.Row, error) {
+	q, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	d.sort()
+	m, ok := d.measurements[q.measurement]
+	if !ok {
+		return nil, nil
+	}
+	if q.wildcard {
+		q.fields = m.keys(q.dims)
+		q.columns = append([]string{"time"}, q.fields...)
+	}
+
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	groups := make(map[string]*group)
+	var groupKeys []string
+	for _, key := range keys {
+		s := m.series[key]
+		tags := make(map[string]string, len(q.dims))
+		parts := make([]string, len(q.dims))
+		for i, dim := range q.dims {
+			tags[dim] = s.tags[dim]
+			parts[i] = dim + "=" + s.tags[dim]
+		}
+		groupKey := strings.Join(parts, ",")
+		g := groups[groupKey]
+
+		// the points of the time range
+		lo := sort.Search(len(s.points), func(i int) bool { return s.points[i].time >= q.min })
+		hi := sort.Search(len(s.points), func(i int) bool { return s.points[i].time > q.max })
+		for i := lo; i < hi; i++ {
+			p := &s.points[i]
+			if q.condition != nil {
+				eval := influxql.ValuerEval{Valuer: pointValuer{m: m, s: s, p: p}}
+				if !eval.EvalBool(q.condition) {
+					continue
+				}
+			}
+			if g == nil {
+				g = &group{tags: tags, buckets: make(map[int64][]*aggregator)}
+				groups[groupKey] = g
+				groupKeys = append(groupKeys, groupKey)
+			}
+			g.add(q, m, s, p)
+		}
+	}
+
+	sort.Strings(groupKeys)
+	rows := make([]models.Row, 0, len(groupKeys))
+	for _, key := range groupKeys {
+		g := groups[key]
+		values := g.rows(q)
+		if !q.ascending {
+			for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+				values[i], values[j] = values[j], values[i]
+			}
+		}
+		values = page(values, q.offset, q.limit)
+		if len(values) == 0 {
+			continue
+		}
+		row := models.Row{Name: q.measurement, Columns: q.columns, Values: values}
+		if len(q.dims) > 0 {
+			row.Tags = g.tags
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func page(values [][]interface{}, offset, limit int) [][]interface{} {
+	if offset >= len(values) {
+		return nil
+	}
+	values = values[offset:]
+	if limit > 0 && limit < len(values) {
+		values = values[:limit]
+	}
+	return values
+}
+
+// keys returns the field and tag keys of m, other than exclude, sorted as
+// the columns of a wildcard query.
+func (m *measurement) keys(exclude []string) []string {
+	set := make(map[string]bool, len(m.fieldKeys))
+	for _, k := range m.fieldKeys {
+		set[k] = true
+	}
+	for _, s := range m.series {
+		for k := range s.tags {
+			set[k] = true
+		}
+	}
+	for _, k := range exclude {
+		delete(set, k)
+	}
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// pointValuer is the influxql.Valuer of the fields and tags of a point.
+type pointValuer struct {
+	m *measurement
+	s *series
+	p *point
+}
+
+func (v pointValuer) Value(key string) (interface{}, bool) {
+	return v.m.value(v.s, v.p, key)
+}
+
+// group holds the points of a group of series: the aggregates of each time
+// interval, or the raw rows.
+type group struct {
+	tags    map[string]string
+	buckets map[int64][]*aggregator
+	raw     []rawRow
+}
+
+type rawRow struct {
+	time   int64
+	values []interface{}
+}
+
+func (g *group) add(q *selectQuery, m *measurement, s *series, p *point) {
+	if q.calls == nil {
+		row := rawRow{time: p.time, values: make([]interface{}, len(q.fields))}
+		hasField := false
+		for i, f := range q.fields {
+			row.values[i], _ = m.value(s, p, f)
+			hasField = hasField || m.field(p, f) != nil
+		}
+		// like InfluxDB, rows without any selected field are left out
+		if hasField {
+			g.raw = append(g.raw, row)
+		}
+		return
+	}
+
+	bucket := q.min
+	if q.interval > 0 {
+		bucket = floor(p.time, q.interval)
+	} else if bucket == influxql.MinTime {
+		bucket = 0
+	}
+	aggs, ok := g.buckets[bucket]
+	if !ok {
+		aggs = make([]*aggregator, len(q.calls))
+		for i, call := range q.calls {
+			aggs[i] = &aggregator{call: call}
+		}
+		g.buckets[bucket] = aggs
+	}
+	for i, f := range q.fields {
+		aggs[i].add(m.field(p, f), p.time)
+	}
+}
+
+func floor(t, interval int64) int64 {
+	b := t - t%interval
+	if t < 0 && t%interval != 0 {
+		b -= interval
+	}
+	return b
+}
+
+// rows returns the rows of g, in ascending time order.
+func (g *group) rows(q *selectQuery) [][]interface{} {
+	if q.calls == nil {
+		sort.SliceStable(g.raw, func(i, j int) bool { return g.raw[i].time < g.raw[j].time })
+		rows := make([][]interface{}, len(g.raw))
+		for i, r := range g.raw {
+			rows[i] = append([]interface{}{time.Unix(0, r.time).UTC()}, r.values...)
+		}
+		return rows
+	}
+
+	var times []int64
+	if q.interval > 0 && q.fill != influxql.NoFill && q.bounded {
+		for t := floor(q.min, q.interval); t <= q.max; t += q.interval {
+			times = append(times, t)
+		}
+	} else {
+		for t := range g.buckets {
+			times = append(times, t)
+		}
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	}
+
+	// a single selector without GROUP BY time has the time of the point
+	// it selects
+	selectorTime := q.interval == 0 && len(q.calls) == 1 && q.calls[0] != "mean" && q.calls[0] != "count"
+
+	rows := make([][]interface{}, 0, len(times))
+	for _, t := range times {
+		row := make([]interface{}, 1+len(q.calls))
+		row[0] = time.Unix(0, t).UTC()
+		aggs, ok := g.buckets[t]
+		if !ok {
+			for i := range q.calls {
+				if q.fill == influxql.NumberFill {
+					row[1+i] = q.fillValue
+				}
+			}
+			rows = append(rows, row)
+			continue
+		}
+		for i, a := range aggs {
+			row[1+i] = a.result()
+		}
+		if selectorTime && aggs[0].has {
+			row[0] = time.Unix(0, aggs[0].time).UTC()
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// aggregator computes an aggregate of the values of a field.
+type aggregator struct {
+	call  string
+	count int64
+	// sum and numeric are the sum and number of the numeric values
+	sum     float64
+	numeric int64
+	// value is the value selected by max, min or last, at time
+	value interface{}
+	time  int64
+	has   bool
+}
+
+func (a *aggregator) add(v interface{}, t int64) {
+	if v == nil {
+		return
+	}
+	a.count++
+	switch a.call {
+	case "mean":
+		if f, ok := toFloat(v); ok {
+			a.sum += f
+			a.numeric++
+		}
+	case "max", "min":
+		f, ok := toFloat(v)
+		if !ok {
+			return
+		}
+		cur, _ := toFloat(a.value)
+		better := f > cur
+		if a.call == "min" {
+			better = f < cur
+		}
+		// the earliest of equal values is selected
+		if !a.has || better || f == cur && t < a.time {
+			a.value, a.time, a.has = v, t, true
+		}
+	case "last":
+		if !a.has || t >= a.time {
+			a.value, a.time, a.has = v, t, true
+		}
+	}
+}
+
+func (a *aggregator) result() interface{} {
+	switch a.call {
+	case "count":
+		return a.count
+	case "mean":
+		if a.numeric == 0 {
+			return nil
+		}
+		return a.sum / float64(a.numeric)
+	default:
+		return a.value
+	}
+}
+
+// toFloat returns v as a float64, if it is a number.
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	default:
+		return math.NaN(), false
+	}
+}
@@ -0,0 +1,136 @@
+package reference
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/InfluxDB-client/models"
+)
+
+func ts(sec int64) time.Time {
+	return time.Unix(sec, 0).UTC()
+}
+
+func TestDatasetEvaluate(t *testing.T) {
+	const bounds = `time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:02:00Z'`
+	cases := []struct {
+		desc  string
+		query string
+		want  []models.Row
+	}{
+		{
+			desc:  "max by time and tag",
+			query: `SELECT max(usage_user) FROM cpu WHERE ` + bounds + ` GROUP BY time(1m), hostname`,
+			want: []models.Row{
+				{Name: "cpu", Tags: map[string]string{"hostname": "host_0"}, Columns: []string{"time", "max"},
+					Values: [][]interface{}{{ts(0), 20.0}, {ts(60), 30.0}}},
+				{Name: "cpu", Tags: map[string]string{"hostname": "host_1"}, Columns: []string{"time", "max"},
+					Values: [][]interface{}{{ts(0), 40.0}, {ts(60), 50.0}}},
+				{Name: "cpu", Tags: map[string]string{"hostname": "host_2"}, Columns: []string{"time", "max"},
+					Values: [][]interface{}{{ts(0), 5.0}, {ts(60), nil}}},
+			},
+		},
+		{
+			desc:  "mean and count by tag",
+			query: `SELECT mean(usage_user), count(usage_system) FROM cpu GROUP BY region`,
+			want: []models.Row{
+				{Name: "cpu", Tags: map[string]string{"region": "a"}, Columns: []string{"time", "mean", "count"},
+					Values: [][]interface{}{{ts(0), 30.0, int64(5)}}},
+				{Name: "cpu", Tags: map[string]string{"region": "b"}, Columns: []string{"time", "mean", "count"},
+					Values: [][]interface{}{{ts(0), 5.0, int64(1)}}},
+			},
+		},
+		{
+			desc:  "last has the time of its point",
+			query: `SELECT last(usage_user) FROM cpu WHERE hostname = 'host_0'`,
+			want: []models.Row{
+				{Name: "cpu", Columns: []string{"time", "last"}, Values: [][]interface{}{{ts(70), 30.0}}},
+			},
+		},
+		{
+			desc:  "min with tag condition",
+			query: `SELECT min(usage_system) FROM cpu WHERE region = 'a'`,
+			want: []models.Row{
+				{Name: "cpu", Columns: []string{"time", "min"}, Values: [][]interface{}{{ts(0), 1.0}}},
+			},
+		},
+		{
+			desc:  "count fill none",
+			query: `SELECT count(usage_user) FROM cpu WHERE ` + bounds + ` GROUP BY time(30s) fill(none)`,
+			want: []models.Row{
+				{Name: "cpu", Columns: []string{"time", "count"}, Values: [][]interface{}{{ts(0), int64(4)}, {ts(60), int64(2)}}},
+			},
+		},
+		{
+			desc:  "raw with field condition",
+			query: `SELECT usage_user, usage_system FROM cpu WHERE usage_user > 15 AND hostname = 'host_1'`,
+			want: []models.Row{
+				{Name: "cpu", Columns: []string{"time", "usage_user", "usage_system"},
+					Values: [][]interface{}{{ts(0), 40.0, 4.0}, {ts(65), 50.0, 9.0}}},
+			},
+		},
+		{
+			desc:  "raw descending with limit",
+			query: `SELECT usage_user FROM cpu WHERE hostname = 'host_0' ORDER BY time DESC LIMIT 2`,
+			want: []models.Row{
+				{Name: "cpu", Columns: []string{"time", "usage_user"}, Values: [][]interface{}{{ts(70), 30.0}, {ts(10), 20.0}}},
+			},
+		},
+		{
+			desc:  "wildcard of the last point by tag",
+			query: `SELECT * FROM cpu GROUP BY hostname ORDER BY time DESC LIMIT 1`,
+			want: []models.Row{
+				{Name: "cpu", Tags: map[string]string{"hostname": "host_0"}, Columns: []string{"time", "region", "usage_system", "usage_user"},
+					Values: [][]interface{}{{ts(70), "a", 3.0, 30.0}}},
+				{Name: "cpu", Tags: map[string]string{"hostname": "host_1"}, Columns: []string{"time", "region", "usage_system", "usage_user"},
+					Values: [][]interface{}{{ts(65), "a", 9.0, 50.0}}},
+				{Name: "cpu", Tags: map[string]string{"hostname": "host_2"}, Columns: []string{"time", "region", "usage_system", "usage_user"},
+					Values: [][]interface{}{{ts(5), "b", 5.0, 5.0}}},
+			},
+		},
+		{
+			desc:  "time range without points",
+			query: `SELECT max(usage_user) FROM cpu WHERE time > '1970-01-01T00:05:00Z'`,
+			want:  []models.Row{},
+		},
+		{
+			desc:  "unknown measurement",
+			query: `SELECT max(usage_user) FROM disk`,
+		},
+	}
+	d := newTestDataset(t)
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			got, err := d.Evaluate(c.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("incorrect result:\ngot  %v\nwant %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestDatasetEvaluateUnsupported(t *testing.T) {
+	queries := []string{
+		`SHOW DATABASES`,
+		`SELECT mean(usage_user) FROM (SELECT usage_user FROM cpu)`,
+		`SELECT percentile(usage_user, 50) FROM cpu`,
+		`SELECT usage_user, max(usage_system) FROM cpu`,
+		`SELECT *, usage_user FROM cpu`,
+		`SELECT usage_user FROM cpu GROUP BY time(1m)`,
+		`SELECT max(usage_user) FROM cpu GROUP BY time(1m) fill(previous)`,
+		`SELECT max(usage_user) FROM cpu GROUP BY hostname SLIMIT 1`,
+	}
+	d := newTestDataset(t)
+	for _, q := range queries {
+		if _, err := d.Evaluate(q); err == nil {
+			t.Errorf("%s: expected error", q)
+		} else if q != queries[0] && !strings.HasPrefix(err.Error(), "unsupported query") {
+			t.Errorf("%s: incorrect error: got %v", q, err)
+		}
+	}
+}
@@ -14,6 +14,9 @@ const (
 	FormatVictoriaMetrics = "victoriametrics"
 	FormatTimestream      = "timestream"
 	FormatQuestDB         = "questdb"
+	// FormatColumnar is the target-independent binary format of package
+	// columnar, which the loaders of some targets read as well
+	FormatColumnar = "columnar"
 )
 
 func SupportedFormats() []string {
@@ -30,5 +33,6 @@ func SupportedFormats() []string {
 		FormatVictoriaMetrics,
 		FormatTimestream,
 		FormatQuestDB,
+		FormatColumnar,
 	}
 }
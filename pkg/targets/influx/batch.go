@@ -0,0 +1,91 @@
+package influx
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+const errNotThreeTuplesFmt = "parse error: line does not have 3 tuples, has %d"
+
+var newLine = []byte("\n")
+
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		return bytes.NewBuffer(make([]byte, 0, 4*1024*1024))
+	},
+}
+
+type batch struct {
+	buf     *bytes.Buffer
+	rows    uint
+	metrics uint64
+}
+
+func (b *batch) Len() uint {
+	return b.rows
+}
+
+func (b *batch) Append(item data.LoadedPoint) {
+	that := item.Data.([]byte)
+	thatStr := string(that)
+	b.rows++
+	// Each influx line is format "csv-tags csv-fields timestamp", so we split by space
+	// and then on the middle element, we split by comma to count number of fields added
+	args := strings.Split(thatStr, " ")
+	if len(args) != 3 {
+		fatal(errNotThreeTuplesFmt, len(args))
+		return
+	}
+	b.metrics += uint64(len(strings.Split(args[1], ",")))
+
+	b.buf.Write(that)
+	b.buf.Write(newLine)
+}
+
+// groupBySeries reorders the lines of the batch so that the lines of each
+// series are adjacent, with the series in the order of their first line. The
+// lines of a series keep their order.
+func (b *batch) groupBySeries() {
+	lines := bytes.SplitAfter(b.buf.Bytes(), newLine)
+	if n := len(lines); n > 0 && len(lines[n-1]) == 0 {
+		lines = lines[:n-1]
+	}
+	group := make(map[string]int)
+	groups := make([]int, len(lines))
+	for i, line := range lines {
+		key := string(seriesKey(bytes.TrimSuffix(line, newLine)))
+		g, ok := group[key]
+		if !ok {
+			g = len(group)
+			group[key] = g
+		}
+		groups[i] = g
+	}
+	if len(group) == len(lines) {
+		return // every line is a series of its own
+	}
+	order := make([]int, len(lines))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return groups[order[i]] < groups[order[j]] })
+
+	grouped := bufPool.Get().(*bytes.Buffer)
+	for _, i := range order {
+		grouped.Write(lines[i])
+	}
+	b.buf.Reset()
+	bufPool.Put(b.buf)
+	b.buf = grouped
+}
+
+type factory struct{}
+
+func (f *factory) New() targets.Batch {
+	return &batch{buf: bufPool.Get().(*bytes.Buffer)}
+}
@@ -0,0 +1,223 @@
+package influx
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/blagojts/viper"
+	"github.com/timescale/tsbs/internal/inputs"
+	"github.com/timescale/tsbs/pkg/data/source"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+const (
+	errInvalidConsistencyFmt = "invalid consistency '%s', must be one of: any, one, quorum, all"
+	errNoFileConfig          = "file data source selected, but no file config provided"
+	errNoSimulatorConfig     = "simulator data source selected, but no simulator config provided"
+	errCreateSimulatorFmt    = "cannot create simulator: %v"
+	errUnknownSourceTypeFmt  = "data source type '%s' unrecognized; allowed: %v"
+	errDeadLetterFileFmt     = "cannot create dead-letter file: %v"
+	errInvalidAPIVersionFmt  = "invalid api-version %d, must be 1 or 2"
+	errInvalidPrecisionFmt   = "invalid precision '%s', must be one of: ns, us, ms, s"
+	errAPIv2NoOrg            = "api-version 2 requires an org"
+	errAPIv2NoToken          = "api-version 2 requires a token"
+
+	apiVersion1 = 1
+	apiVersion2 = 2
+)
+
+var precisionChoices = map[string]struct{}{
+	"ns": {},
+	"us": {},
+	"ms": {},
+	"s":  {},
+}
+
+var consistencyChoices = map[string]struct{}{
+	"any":    {},
+	"one":    {},
+	"quorum": {},
+	"all":    {},
+}
+
+// SpecificConfig holds the InfluxDB specific settings of a load, i.e. the
+// flags added by TargetSpecificFlags.
+type SpecificConfig struct {
+	URLs              []string      `yaml:"urls" mapstructure:"urls"`
+	ReplicationFactor int           `yaml:"replication-factor" mapstructure:"replication-factor"`
+	Consistency       string        `yaml:"consistency" mapstructure:"consistency"`
+	Backoff           time.Duration `yaml:"backoff" mapstructure:"backoff"`
+	BackoffMax        time.Duration `yaml:"backoff-max" mapstructure:"backoff-max"`
+	UseGzip           bool          `yaml:"gzip" mapstructure:"gzip"`
+	// RetryMaxAttempts is the number of times a batch is written before it
+	// is given up on, 0 to retry until it succeeds.
+	RetryMaxAttempts uint `yaml:"retry-max-attempts" mapstructure:"retry-max-attempts"`
+	// RetryStatusCodes are the HTTP status codes of writes that are retried,
+	// besides backpressure.
+	RetryStatusCodes []int `yaml:"retry-status-codes" mapstructure:"retry-status-codes"`
+	// DeadLetterFile receives the batches the server refused or that ran
+	// out of retries, instead of aborting the load.
+	DeadLetterFile string `yaml:"dead-letter-file" mapstructure:"dead-letter-file"`
+	// GeneratorWorkers is the number of goroutines simulating and
+	// serializing points with a SIMULATOR data source, 0 for one per CPU.
+	GeneratorWorkers uint `yaml:"generator-workers" mapstructure:"generator-workers"`
+	// APIVersion selects the HTTP API of the server: 1 writes to /write and
+	// creates databases, 2 writes to /api/v2/write and creates buckets.
+	APIVersion uint `yaml:"api-version" mapstructure:"api-version"`
+	// Org is the organization of the bucket, with API version 2.
+	Org string `yaml:"org" mapstructure:"org"`
+	// Token, if set, is sent as 'Authorization: Token' with every request.
+	Token string `yaml:"token" mapstructure:"token"`
+	// Precision of the written timestamps, with API version 2.
+	Precision string `yaml:"precision" mapstructure:"precision"`
+}
+
+// ParseSpecificConfig reads a SpecificConfig from v, where urls is a
+// comma-separated list.
+func ParseSpecificConfig(v *viper.Viper) (*SpecificConfig, error) {
+	conf := &SpecificConfig{
+		ReplicationFactor: v.GetInt("replication-factor"),
+		Consistency:       v.GetString("consistency"),
+		Backoff:           v.GetDuration("backoff"),
+		BackoffMax:        v.GetDuration("backoff-max"),
+		UseGzip:           v.GetBool("gzip"),
+		RetryMaxAttempts:  v.GetUint("retry-max-attempts"),
+		DeadLetterFile:    v.GetString("dead-letter-file"),
+		GeneratorWorkers:  v.GetUint("generator-workers"),
+		APIVersion:        v.GetUint("api-version"),
+		Org:               v.GetString("org"),
+		Token:             v.GetString("token"),
+		Precision:         v.GetString("precision"),
+	}
+	codes, err := parseStatusCodes(v.GetString("retry-status-codes"))
+	if err != nil {
+		return nil, err
+	}
+	conf.RetryStatusCodes = codes
+	for _, u := range strings.Split(v.GetString("urls"), ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			conf.URLs = append(conf.URLs, u)
+		}
+	}
+	if len(conf.URLs) == 0 {
+		return nil, errors.New("missing 'urls' flag")
+	}
+	if _, ok := consistencyChoices[conf.Consistency]; !ok {
+		return nil, fmt.Errorf(errInvalidConsistencyFmt, conf.Consistency)
+	}
+	if conf.APIVersion == 0 {
+		conf.APIVersion = apiVersion1
+	}
+	if conf.Precision == "" {
+		conf.Precision = "ns"
+	}
+	switch conf.APIVersion {
+	case apiVersion1:
+	case apiVersion2:
+		if conf.Org == "" {
+			return nil, errors.New(errAPIv2NoOrg)
+		}
+		if conf.Token == "" {
+			return nil, errors.New(errAPIv2NoToken)
+		}
+		if _, ok := precisionChoices[conf.Precision]; !ok {
+			return nil, fmt.Errorf(errInvalidPrecisionFmt, conf.Precision)
+		}
+	default:
+		return nil, fmt.Errorf(errInvalidAPIVersionFmt, conf.APIVersion)
+	}
+	return conf, nil
+}
+
+// NewBenchmark returns a Benchmark that loads the points of dataSourceConfig
+// into the database dbName.
+func NewBenchmark(dbName string, opts *SpecificConfig, dataSourceConfig *source.DataSourceConfig) (targets.Benchmark, error) {
+	switch dataSourceConfig.Type {
+	case source.FileDataSourceType:
+		if dataSourceConfig.File == nil {
+			return nil, errors.New(errNoFileConfig)
+		}
+	case source.SimulatorDataSourceType:
+		if dataSourceConfig.Simulator == nil {
+			return nil, errors.New(errNoSimulatorConfig)
+		}
+	default:
+		return nil, fmt.Errorf(errUnknownSourceTypeFmt, dataSourceConfig.Type, source.ValidDataSourceTypes)
+	}
+	b := &benchmark{dbName: dbName, opts: opts, ds: dataSourceConfig}
+	if dataSourceConfig.Type == source.SimulatorDataSourceType {
+		if err := b.initSimulator(); err != nil {
+			return nil, err
+		}
+	}
+	if opts.DeadLetterFile != "" {
+		w, err := newDeadLetterWriter(opts.DeadLetterFile)
+		if err != nil {
+			return nil, fmt.Errorf(errDeadLetterFileFmt, err)
+		}
+		b.deadLetter = w
+	}
+	return b, nil
+}
+
+type benchmark struct {
+	dbName     string
+	opts       *SpecificConfig
+	ds         *source.DataSourceConfig
+	deadLetter *deadLetterWriter
+	// sim and workers are the simulator and the number of goroutines
+	// serializing its points, with a SIMULATOR data source
+	sim     common.Simulator
+	workers uint
+}
+
+// initSimulator resolves the number of generator workers and creates the
+// simulator of the data source config, without modifying that config.
+func (b *benchmark) initSimulator() error {
+	b.workers = b.opts.GeneratorWorkers
+	if b.workers == 0 {
+		b.workers = uint(runtime.NumCPU())
+	}
+	simConf := *b.ds.Simulator
+	if simConf.Workers <= 1 && simConf.InterleavedNumGroups <= 1 {
+		// simulators that cannot be partitioned, like the IoT one, then
+		// simulate their entities in parallel themselves
+		simConf.Workers = b.workers
+	}
+	sim, err := (&inputs.DataGenerator{}).CreateSimulator(&simConf)
+	if err != nil {
+		return fmt.Errorf(errCreateSimulatorFmt, err)
+	}
+	b.sim = sim
+	return nil
+}
+
+func (b *benchmark) GetDataSource() targets.DataSource {
+	if b.ds.Type == source.FileDataSourceType {
+		return newFileDataSource(b.ds.File.Location)
+	}
+	return newSimulationDataSource(b.sim, b.workers)
+}
+
+func (b *benchmark) GetBatchFactory() targets.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(maxPartitions uint) targets.PointIndexer {
+	return newSeriesIndexer(maxPartitions)
+}
+
+func (b *benchmark) GetProcessor() targets.Processor {
+	return &processor{dbName: b.dbName, opts: b.opts, deadLetter: b.deadLetter}
+}
+
+func (b *benchmark) GetDBCreator() targets.DBCreator {
+	if b.opts.APIVersion == apiVersion2 {
+		return &bucketCreator{opts: b.opts}
+	}
+	return &dbCreator{opts: b.opts}
+}
@@ -0,0 +1,216 @@
+package influx
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/blagojts/viper"
+	"github.com/timescale/tsbs/pkg/data/source"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"github.com/timescale/tsbs/pkg/targets/constants"
+)
+
+func TestParseSpecificConfig(t *testing.T) {
+	v := viper.New()
+	v.Set("urls", "http://a:8086, http://b:8086")
+	v.Set("replication-factor", 2)
+	v.Set("consistency", "one")
+	v.Set("backoff", "2s")
+	v.Set("gzip", true)
+	conf, err := ParseSpecificConfig(v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(conf.URLs) != 2 || conf.URLs[1] != "http://b:8086" {
+		t.Errorf("incorrect urls: %v", conf.URLs)
+	}
+	if conf.ReplicationFactor != 2 || conf.Consistency != "one" || conf.Backoff != 2*time.Second || !conf.UseGzip {
+		t.Errorf("incorrect config: %+v", conf)
+	}
+
+	v.Set("consistency", "most")
+	if _, err := ParseSpecificConfig(v); err == nil {
+		t.Errorf("expected error for invalid consistency")
+	}
+	v.Set("consistency", "all")
+	v.Set("urls", "")
+	if _, err := ParseSpecificConfig(v); err == nil {
+		t.Errorf("expected error for missing urls")
+	}
+}
+
+func TestParseSpecificConfigAPIv2(t *testing.T) {
+	v := viper.New()
+	v.Set("urls", "http://a:8086")
+	v.Set("consistency", "all")
+	conf, err := ParseSpecificConfig(v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if conf.APIVersion != apiVersion1 || conf.Precision != "ns" {
+		t.Errorf("incorrect defaults: got api-version %d precision %s", conf.APIVersion, conf.Precision)
+	}
+
+	v.Set("api-version", 2)
+	if _, err := ParseSpecificConfig(v); err == nil || err.Error() != errAPIv2NoOrg {
+		t.Errorf("incorrect error without org: got %v", err)
+	}
+	v.Set("org", "tsbs")
+	if _, err := ParseSpecificConfig(v); err == nil || err.Error() != errAPIv2NoToken {
+		t.Errorf("incorrect error without token: got %v", err)
+	}
+	v.Set("token", "secret")
+	v.Set("precision", "h")
+	if _, err := ParseSpecificConfig(v); err == nil {
+		t.Errorf("expected error for invalid precision")
+	}
+	v.Set("precision", "ms")
+	conf, err = ParseSpecificConfig(v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if conf.APIVersion != apiVersion2 || conf.Org != "tsbs" || conf.Token != "secret" || conf.Precision != "ms" {
+		t.Errorf("incorrect config: %+v", conf)
+	}
+	if _, ok := (&benchmark{opts: conf}).GetDBCreator().(*bucketCreator); !ok {
+		t.Errorf("api-version 2 does not create buckets")
+	}
+
+	v.Set("api-version", 3)
+	if _, err := ParseSpecificConfig(v); err == nil {
+		t.Errorf("expected error for invalid api-version")
+	}
+}
+
+func TestNewBenchmarkDataSourceValidation(t *testing.T) {
+	opts := &SpecificConfig{URLs: []string{"http://localhost:8086"}, Consistency: "all"}
+	cases := []struct {
+		desc    string
+		ds      *source.DataSourceConfig
+		wantErr bool
+	}{
+		{
+			desc: "file",
+			ds:   &source.DataSourceConfig{Type: source.FileDataSourceType, File: &source.FileDataSourceConfig{Location: "x"}},
+		},
+		{
+			desc:    "file without config",
+			ds:      &source.DataSourceConfig{Type: source.FileDataSourceType},
+			wantErr: true,
+		},
+		{
+			desc: "simulator",
+			ds:   testSimulatorConfig(),
+		},
+		{
+			desc:    "invalid simulator config",
+			ds:      &source.DataSourceConfig{Type: source.SimulatorDataSourceType, Simulator: &common.DataGeneratorConfig{}},
+			wantErr: true,
+		},
+		{
+			desc:    "simulator without config",
+			ds:      &source.DataSourceConfig{Type: source.SimulatorDataSourceType},
+			wantErr: true,
+		},
+		{
+			desc:    "unknown",
+			ds:      &source.DataSourceConfig{Type: "KAFKA"},
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		_, err := NewBenchmark("benchmark", opts, c.ds)
+		if c.wantErr && err == nil {
+			t.Errorf("%s: expected error", c.desc)
+		} else if !c.wantErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+		}
+	}
+}
+
+func testSimulatorConfig() *source.DataSourceConfig {
+	return &source.DataSourceConfig{
+		Type: source.SimulatorDataSourceType,
+		Simulator: &common.DataGeneratorConfig{
+			BaseConfig: common.BaseConfig{
+				Format:    constants.FormatInflux,
+				Use:       common.UseCaseCPUOnly,
+				Scale:     2,
+				TimeStart: "2016-01-01T00:00:00Z",
+				TimeEnd:   "2016-01-01T00:01:00Z",
+				Seed:      123,
+			},
+			LogInterval:          10 * time.Second,
+			InterleavedNumGroups: 1,
+		},
+	}
+}
+
+func TestSimulationDataSource(t *testing.T) {
+	opts := &SpecificConfig{URLs: []string{"u"}, Consistency: "all", GeneratorWorkers: 1}
+	ds := testSimulatorConfig()
+	b, err := NewBenchmark("benchmark", opts, ds)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := testSimulatorConfig(); !reflect.DeepEqual(ds, got) {
+		t.Errorf("simulator config was modified: got %+v want %+v", ds.Simulator, got.Simulator)
+	}
+	src := b.GetDataSource()
+	if src.Headers() == nil {
+		t.Errorf("simulator data source has no headers")
+	}
+
+	bat := b.GetBatchFactory().New().(*batch)
+	for p := src.NextItem(); p.Data != nil; p = src.NextItem() {
+		line := p.Data.([]byte)
+		if !bytes.HasPrefix(line, []byte("cpu,hostname=host_")) || bytes.HasSuffix(line, newLine) {
+			t.Fatalf("incorrect line: %q", line)
+		}
+		bat.Append(p)
+	}
+	// 2 hosts with a reading every 10 seconds for a minute.
+	if bat.Len() != 12 {
+		t.Errorf("incorrect number of rows: got %d want %d", bat.Len(), 12)
+	}
+	if bat.metrics != 12*10 {
+		t.Errorf("incorrect number of metrics: got %d want %d", bat.metrics, 12*10)
+	}
+}
+
+func TestSimulationDataSourceWorkers(t *testing.T) {
+	readAll := func(use string, workers uint) [][]byte {
+		opts := &SpecificConfig{URLs: []string{"u"}, Consistency: "all", GeneratorWorkers: workers}
+		ds := testSimulatorConfig()
+		ds.Simulator.Use = use
+		ds.Simulator.Scale = 5
+		b, err := NewBenchmark("benchmark", opts, ds)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		src := b.GetDataSource()
+		var lines [][]byte
+		for p := src.NextItem(); p.Data != nil; p = src.NextItem() {
+			lines = append(lines, p.Data.([]byte))
+		}
+		return lines
+	}
+
+	for _, use := range []string{common.UseCaseCPUOnly, common.UseCaseDevops, common.UseCaseIoT} {
+		want := readAll(use, 1)
+		for _, workers := range []uint{2, 5} {
+			got := readAll(use, workers)
+			if len(got) != len(want) {
+				t.Fatalf("%s, %d workers: incorrect number of lines: got %d want %d", use, workers, len(got), len(want))
+			}
+			for i := range want {
+				if !bytes.Equal(got[i], want[i]) {
+					t.Errorf("%s, %d workers: line %d differs:\ngot  %s\nwant %s", use, workers, i, got[i], want[i])
+					break
+				}
+			}
+		}
+	}
+}
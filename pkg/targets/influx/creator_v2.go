@@ -0,0 +1,181 @@
+package influx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	pathBuckets = "/api/v2/buckets"
+	pathOrgs    = "/api/v2/orgs"
+	pathDBRPs   = "/api/v2/dbrps"
+
+	// retention policy of the DBRP mapping of created buckets, which the
+	// InfluxQL queries of the query runners use through /query
+	dbrpRetentionPolicy = "autogen"
+)
+
+// bucketCreator is the DBCreator of InfluxDB 2.x servers, which stores data
+// in buckets of an organization instead of databases. Every created bucket
+// is mapped to a database of the same name, so that it can be queried with
+// InfluxQL through the 1.x compatible /query endpoint.
+type bucketCreator struct {
+	opts      *SpecificConfig
+	daemonURL string
+	client    *http.Client
+}
+
+// apiError is returned when the server answers a request of the
+// bucketCreator with an unexpected status code.
+type apiError struct {
+	request    string
+	statusCode int
+	body       string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s returned status %d: %s", e.request, e.statusCode, e.body)
+}
+
+type bucketListing struct {
+	Buckets []bucketInfo `json:"buckets"`
+}
+
+type bucketInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type orgListing struct {
+	Orgs []struct {
+		ID string `json:"id"`
+	} `json:"orgs"`
+}
+
+func (d *bucketCreator) Init() {
+	d.daemonURL = d.opts.URLs[0] // pick first one since it always exists
+	d.client = &http.Client{}
+}
+
+func (d *bucketCreator) DBExists(dbName string) bool {
+	id, err := d.bucketID(dbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return id != ""
+}
+
+func (d *bucketCreator) RemoveOldDB(dbName string) error {
+	id, err := d.bucketID(dbName)
+	if err != nil {
+		return fmt.Errorf("drop bucket error: %v", err)
+	}
+	if id == "" {
+		return nil
+	}
+	if err := d.do(http.MethodDelete, pathBuckets+"/"+url.PathEscape(id), nil, nil, http.StatusNoContent, nil); err != nil {
+		return fmt.Errorf("drop bucket error: %v", err)
+	}
+	time.Sleep(time.Second)
+	return nil
+}
+
+func (d *bucketCreator) CreateDB(dbName string) error {
+	var orgs orgListing
+	if err := d.do(http.MethodGet, pathOrgs, url.Values{"org": {d.opts.Org}}, nil, http.StatusOK, &orgs); err != nil {
+		return fmt.Errorf("cannot find org %s: %v", d.opts.Org, err)
+	}
+	if len(orgs.Orgs) == 0 {
+		return fmt.Errorf("cannot find org %s", d.opts.Org)
+	}
+	orgID := orgs.Orgs[0].ID
+
+	var bucket bucketInfo
+	req := map[string]interface{}{
+		"orgID":          orgID,
+		"name":           dbName,
+		"retentionRules": []interface{}{},
+	}
+	if err := d.do(http.MethodPost, pathBuckets, nil, req, http.StatusCreated, &bucket); err != nil {
+		return fmt.Errorf("bad bucket create: %v", err)
+	}
+
+	req = map[string]interface{}{
+		"orgID":            orgID,
+		"bucketID":         bucket.ID,
+		"database":         dbName,
+		"retention_policy": dbrpRetentionPolicy,
+		"default":          true,
+	}
+	if err := d.do(http.MethodPost, pathDBRPs, nil, req, http.StatusCreated, nil); err != nil {
+		return fmt.Errorf("cannot map database %s to bucket: %v", dbName, err)
+	}
+	time.Sleep(time.Second)
+	return nil
+}
+
+// bucketID returns the ID of the bucket name of the org, or "" if there is
+// no such bucket.
+func (d *bucketCreator) bucketID(name string) (string, error) {
+	var listing bucketListing
+	q := url.Values{"org": {d.opts.Org}, "name": {name}}
+	// the server answers 404 if the name does not match any bucket
+	if err := d.do(http.MethodGet, pathBuckets, q, nil, http.StatusOK, &listing); err != nil {
+		if e, ok := err.(*apiError); ok && e.statusCode == http.StatusNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+	for _, b := range listing.Buckets {
+		if b.Name == name {
+			return b.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// do sends a request with the JSON encoding of body, if not nil, to path of
+// the server and decodes the response into out, if not nil. Responses other
+// than wantStatus are returned as an *apiError.
+func (d *bucketCreator) do(method, path string, query url.Values, body interface{}, wantStatus int, out interface{}) error {
+	u := d.daemonURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	reqBody := bytes.NewReader(nil)
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(headerAuthorization, headerTokenPrefix+d.opts.Token)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != wantStatus {
+		return &apiError{request: method + " " + path, statusCode: resp.StatusCode, body: string(respBody)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
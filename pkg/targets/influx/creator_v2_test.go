@@ -0,0 +1,131 @@
+package influx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testServerV2 is a stand-in of the bucket API of an InfluxDB 2.x server
+// with the org "tsbs" and token "secret".
+type testServerV2 struct {
+	mu      sync.Mutex
+	buckets map[string]string // name -> ID
+	dbrps   []map[string]interface{}
+}
+
+func (s *testServerV2) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Header.Get(headerAuthorization) != "Token secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == pathOrgs:
+		if r.URL.Query().Get("org") != "tsbs" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"orgs":[{"id":"org1"}]}`))
+	case r.Method == http.MethodGet && r.URL.Path == pathBuckets:
+		name := r.URL.Query().Get("name")
+		id, ok := s.buckets[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(bucketListing{Buckets: []bucketInfo{{ID: id, Name: name}}})
+	case r.Method == http.MethodPost && r.URL.Path == pathBuckets:
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["orgID"] != "org1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		name := req["name"].(string)
+		s.buckets[name] = "id-" + name
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(bucketInfo{ID: s.buckets[name], Name: name})
+	case r.Method == http.MethodPost && r.URL.Path == pathDBRPs:
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		s.dbrps = append(s.dbrps, req)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, pathBuckets+"/"):
+		id := strings.TrimPrefix(r.URL.Path, pathBuckets+"/")
+		for name, bid := range s.buckets {
+			if bid == id {
+				delete(s.buckets, name)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestBucketCreator(t *testing.T) {
+	s := &testServerV2{buckets: map[string]string{}}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	d := &bucketCreator{opts: &SpecificConfig{URLs: []string{ts.URL}, Org: "tsbs", Token: "secret"}}
+	d.Init()
+	if d.DBExists("benchmark") {
+		t.Errorf("bucket exists before it was created")
+	}
+	if err := d.RemoveOldDB("benchmark"); err != nil {
+		t.Errorf("unexpected error removing missing bucket: %v", err)
+	}
+	if err := d.CreateDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !d.DBExists("benchmark") {
+		t.Errorf("bucket does not exist after it was created")
+	}
+	if len(s.dbrps) != 1 {
+		t.Fatalf("incorrect DBRP mappings: got %d want 1", len(s.dbrps))
+	}
+	want := map[string]interface{}{
+		"orgID":            "org1",
+		"bucketID":         "id-benchmark",
+		"database":         "benchmark",
+		"retention_policy": dbrpRetentionPolicy,
+		"default":          true,
+	}
+	for k, v := range want {
+		if got := s.dbrps[0][k]; got != v {
+			t.Errorf("incorrect DBRP mapping %s: got %v want %v", k, got, v)
+		}
+	}
+	if err := d.RemoveOldDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.DBExists("benchmark") {
+		t.Errorf("bucket exists after it was removed")
+	}
+}
+
+func TestBucketCreatorErrors(t *testing.T) {
+	ts := httptest.NewServer(&testServerV2{buckets: map[string]string{}})
+	defer ts.Close()
+
+	d := &bucketCreator{opts: &SpecificConfig{URLs: []string{ts.URL}, Org: "other", Token: "secret"}}
+	d.Init()
+	if err := d.CreateDB("benchmark"); err == nil {
+		t.Errorf("expected error for unknown org")
+	}
+
+	d = &bucketCreator{opts: &SpecificConfig{URLs: []string{ts.URL}, Org: "tsbs", Token: "wrong"}}
+	d.Init()
+	_, err := d.bucketID("benchmark")
+	if e, ok := err.(*apiError); !ok || e.statusCode != http.StatusUnauthorized {
+		t.Errorf("incorrect error for wrong token: got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package influx
+
+import (
+	"os"
+	"sync"
+)
+
+// deadLetterWriter appends the line protocol of batches the server refused
+// to a file shared by all workers, so they can be inspected or loaded again
+// later. Writes are not buffered, so nothing is lost if the load aborts.
+type deadLetterWriter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func newDeadLetterWriter(path string) (*deadLetterWriter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &deadLetterWriter{file: f}, nil
+}
+
+// write appends lines, which must end with a newline.
+func (w *deadLetterWriter) write(lines []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.file.Write(lines)
+	return err
+}
@@ -0,0 +1,62 @@
+package influx
+
+import (
+	"bufio"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/columnar"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+// newFileDataSource reads line protocol, or columnar data, from fileName, or
+// stdin if empty.
+func newFileDataSource(fileName string) targets.DataSource {
+	return newReaderDataSource(load.GetBufferedReader(fileName))
+}
+
+// newReaderDataSource reads line protocol from br, unless br starts with
+// columnar.Magic, in which case it reads columnar data and serializes it to
+// line protocol.
+func newReaderDataSource(br *bufio.Reader) targets.DataSource {
+	if magic, _ := br.Peek(len(columnar.Magic)); columnar.IsColumnar(magic) {
+		ds, err := columnar.NewDataSource(br, &Serializer{})
+		if err != nil {
+			fatal("cannot read columnar data: %v", err)
+			return nil
+		}
+		return &columnarDataSource{ds}
+	}
+	return &fileDataSource{scanner: bufio.NewScanner(br)}
+}
+
+type fileDataSource struct {
+	scanner *bufio.Scanner
+}
+
+func (d *fileDataSource) NextItem() data.LoadedPoint {
+	ok := d.scanner.Scan()
+	if !ok && d.scanner.Err() == nil { // nothing scanned & no error = EOF
+		return data.LoadedPoint{}
+	} else if !ok {
+		fatal("scan error: %v", d.scanner.Err())
+		return data.LoadedPoint{}
+	}
+	return data.NewLoadedPoint(d.scanner.Bytes())
+}
+
+func (d *fileDataSource) Headers() *common.GeneratedDataHeaders { return nil }
+
+// columnarDataSource reads columnar data, and is fatal on corrupt data.
+type columnarDataSource struct {
+	*columnar.DataSource
+}
+
+func (d *columnarDataSource) NextItem() data.LoadedPoint {
+	p := d.DataSource.NextItem()
+	if p.Data == nil && d.Err() != nil {
+		fatal("columnar read error: %v", d.Err())
+	}
+	return p
+}
@@ -1,4 +1,4 @@
-package main
+package influx
 
 import (
 	"bufio"
@@ -8,6 +8,8 @@ import (
 	"testing"
 
 	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/columnar"
+	"github.com/timescale/tsbs/pkg/data/serialize"
 )
 
 func TestBatch(t *testing.T) {
@@ -103,3 +105,37 @@ func TestDecodeEOF(t *testing.T) {
 		t.Errorf("expected p to be nil, got %v", p)
 	}
 }
+
+func TestReaderDataSourceColumnar(t *testing.T) {
+	points := []*data.Point{serialize.TestPointDefault(), serialize.TestPointMultiField(), serialize.TestPointWithNilTag()}
+	var want [][]byte
+	var buf bytes.Buffer
+	w, err := columnar.NewWriter(&buf, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, p := range points {
+		var line bytes.Buffer
+		if err := (&Serializer{}).Serialize(p, &line); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want = append(want, bytes.TrimSuffix(line.Bytes(), []byte("\n")))
+		if err := w.Write(p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ds := newReaderDataSource(bufio.NewReader(&buf))
+	for i, line := range want {
+		p := ds.NextItem()
+		if got, _ := p.Data.([]byte); !bytes.Equal(got, line) {
+			t.Errorf("incorrect point %d: got\n%s\nwant\n%s", i, got, line)
+		}
+	}
+	if p := ds.NextItem(); p.Data != nil {
+		t.Errorf("expected p to be nil, got %v", p)
+	}
+}
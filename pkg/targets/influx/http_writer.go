@@ -1,4 +1,4 @@
-package main
+package influx
 
 // This file lifted wholesale from mountainflux by Mark Rushakoff.
 
@@ -6,6 +6,8 @@ import (
 	"bytes"
 	"fmt"
 	"net/url"
+	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/valyala/fasthttp"
@@ -15,6 +17,8 @@ const (
 	httpClientName        = "tsbs_load_influx"
 	headerContentEncoding = "Content-Encoding"
 	headerGzip            = "gzip"
+	headerAuthorization   = "Authorization"
+	headerTokenPrefix     = "Token "
 )
 
 var (
@@ -28,6 +32,34 @@ var (
 	backoffMagicWords5  = []byte("write failed: can not exceed max connections of 500")
 )
 
+// writeError is returned when the server answers a write with an unexpected
+// status code.
+type writeError struct {
+	debugInfo  string
+	statusCode int
+	body       string
+}
+
+func (e *writeError) Error() string {
+	return fmt.Sprintf("[DebugInfo: %s] Invalid write response (status %d): %s", e.debugInfo, e.statusCode, e.body)
+}
+
+// partialWriteDropped matches the number of points InfluxDB dropped in its
+// answer to a partial write, e.g. "partial write: field type conflict: ...
+// dropped=2".
+var partialWriteDropped = regexp.MustCompile(`partial write.*dropped=(\d+)`)
+
+// dropped returns the number of points the server dropped if it wrote the
+// other points of the batch, or false if it wrote none of them.
+func (e *writeError) dropped() (uint64, bool) {
+	m := partialWriteDropped.FindStringSubmatch(e.body)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(m[1], 10, 64)
+	return n, err == nil
+}
+
 // HTTPWriterConfig is the configuration used to create an HTTPWriter.
 type HTTPWriterConfig struct {
 	// URL of the host, in form "http://example.com:8086"
@@ -38,19 +70,32 @@ type HTTPWriterConfig struct {
 
 	// Debug label for more informative errors.
 	DebugInfo string
+
+	// APIVersion selects the write endpoint, 1 for /write and 2 for
+	// /api/v2/write, where Database is the bucket.
+	APIVersion uint
+
+	// Organization of the bucket and precision of the timestamps, only used
+	// with API version 2.
+	Org       string
+	Precision string
+
+	// Token, if set, is sent in the Authorization header.
+	Token string
 }
 
 // HTTPWriter is a Writer that writes to an InfluxDB HTTP server.
 type HTTPWriter struct {
 	client fasthttp.Client
 
-	c   HTTPWriterConfig
-	url []byte
+	c    HTTPWriterConfig
+	url  []byte
+	auth []byte
 }
 
 // NewHTTPWriter returns a new HTTPWriter from the supplied HTTPWriterConfig.
 func NewHTTPWriter(c HTTPWriterConfig, consistency string) *HTTPWriter {
-	return &HTTPWriter{
+	w := &HTTPWriter{
 		client: fasthttp.Client{
 			Name: httpClientName,
 		},
@@ -58,6 +103,13 @@ func NewHTTPWriter(c HTTPWriterConfig, consistency string) *HTTPWriter {
 		c:   c,
 		url: []byte(c.Host + "/write?consistency=" + consistency + "&db=" + url.QueryEscape(c.Database)),
 	}
+	if c.APIVersion == apiVersion2 {
+		w.url = []byte(c.Host + "/api/v2/write?org=" + url.QueryEscape(c.Org) + "&bucket=" + url.QueryEscape(c.Database) + "&precision=" + c.Precision)
+	}
+	if c.Token != "" {
+		w.auth = []byte(headerTokenPrefix + c.Token)
+	}
+	return w
 }
 
 var (
@@ -72,6 +124,9 @@ func (w *HTTPWriter) initializeReq(req *fasthttp.Request, body []byte, isGzip bo
 	if isGzip {
 		req.Header.Add(headerContentEncoding, headerGzip)
 	}
+	if w.auth != nil {
+		req.Header.SetBytesV(headerAuthorization, w.auth)
+	}
 	req.SetBody(body)
 }
 
@@ -84,7 +139,7 @@ func (w *HTTPWriter) executeReq(req *fasthttp.Request, resp *fasthttp.Response)
 		if sc == 500 && backpressurePred(resp.Body()) {
 			err = errBackoff
 		} else if sc != fasthttp.StatusNoContent {
-			err = fmt.Errorf("[DebugInfo: %s] Invalid write response (status %d): %s", w.c.DebugInfo, sc, resp.Body())
+			err = &writeError{debugInfo: w.c.DebugInfo, statusCode: sc, body: string(resp.Body())}
 		}
 	}
 	return lat, err
@@ -1,4 +1,4 @@
-package main
+package influx
 
 import (
 	"context"
@@ -16,6 +16,7 @@ import (
 const (
 	shouldBackoffParam = "shouldErr"
 	shouldInvalidParam = "shouldInvalid"
+	shouldPartialParam = "shouldPartial"
 	httpServerPort     = ":8080"
 	httpDelay          = 50 * time.Millisecond
 )
@@ -42,6 +43,9 @@ func runHTTPServer(c chan struct{}) {
 				w.WriteHeader(http.StatusNoContent)
 				fmt.Fprintf(w, "")
 			}
+		} else if strings.Contains(r.URL.RawQuery, shouldPartialParam) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error":"partial write: field type conflict: input field \"usage_user\" on measurement \"cpu\" is type string, already exists as type float dropped=1"}`)
 		} else if strings.Contains(r.URL.RawQuery, shouldInvalidParam) {
 			fmt.Fprintf(w, "success should be an empty msg")
 		} else {
@@ -135,6 +139,36 @@ func TestHTTPWriterInitializeReq(t *testing.T) {
 	}
 }
 
+func TestHTTPWriterAPIv2(t *testing.T) {
+	conf := HTTPWriterConfig{
+		Host:       "http://localhost:8086",
+		Database:   "my bucket",
+		APIVersion: apiVersion2,
+		Org:        "tsbs",
+		Precision:  "ms",
+		Token:      "secret",
+	}
+	w := NewHTTPWriter(conf, testConsistency)
+	want := "http://localhost:8086/api/v2/write?org=tsbs&bucket=my+bucket&precision=ms"
+	if got := string(w.url); got != want {
+		t.Errorf("incorrect url: got %s want %s", got, want)
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	w.initializeReq(req, []byte("body"), false)
+	if got := string(req.Header.Peek(headerAuthorization)); got != "Token secret" {
+		t.Errorf("incorrect Authorization header: got '%s' want 'Token secret'", got)
+	}
+
+	w = NewHTTPWriter(testConf, testConsistency)
+	req.Reset()
+	w.initializeReq(req, []byte("body"), false)
+	if got := string(req.Header.Peek(headerAuthorization)); got != "" {
+		t.Errorf("Authorization header without token: got '%s'", got)
+	}
+}
+
 func TestHTTPWriterExecuteReq(t *testing.T) {
 	c := launchHTTPServer()
 
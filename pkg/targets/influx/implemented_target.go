@@ -10,6 +10,10 @@ import (
 	"time"
 )
 
+func init() {
+	targets.Register(constants.FormatInflux, NewTarget)
+}
+
 func NewTarget() targets.ImplementedTarget {
 	return &influxTarget{}
 }
@@ -21,8 +25,17 @@ func (t *influxTarget) TargetSpecificFlags(flagPrefix string, flagSet *pflag.Fla
 	flagSet.String(flagPrefix+"urls", "http://localhost:8086", "InfluxDB URLs, comma-separated. Will be used in a round-robin fashion.")
 	flagSet.Int(flagPrefix+"replication-factor", 1, "Cluster replication factor (only applies to clustered databases).")
 	flagSet.String(flagPrefix+"consistency", "all", "Write consistency. Must be one of: any, one, quorum, all.")
-	flagSet.Duration(flagPrefix+"backoff", time.Second, "Time to sleep between requests when server indicates backpressure is needed.")
+	flagSet.Duration(flagPrefix+"backoff", time.Second, "Time to sleep before the first retry of a write; doubles with each further retry.")
+	flagSet.Duration(flagPrefix+"backoff-max", 30*time.Second, "Maximum time to sleep between retries of a write.")
+	flagSet.Uint(flagPrefix+"retry-max-attempts", 0, "Times a batch is written before giving up on it, 0 = retry until it succeeds.")
+	flagSet.String(flagPrefix+"retry-status-codes", "429,503", "Comma-separated HTTP status codes of writes to retry, besides backpressure.")
+	flagSet.String(flagPrefix+"dead-letter-file", "", "Write batches the server refused or that ran out of retries to this file instead of aborting.")
 	flagSet.Bool(flagPrefix+"gzip", true, "Whether to gzip encode requests (default true).")
+	flagSet.Uint(flagPrefix+"generator-workers", 0, "Goroutines simulating and serializing data when loading from the simulator, 0 = one per CPU.")
+	flagSet.Uint(flagPrefix+"api-version", 1, "InfluxDB HTTP API: 1 writes to /write and creates a database, 2 writes to /api/v2/write and creates a bucket.")
+	flagSet.String(flagPrefix+"org", "", "Organization of the bucket, with api-version 2.")
+	flagSet.String(flagPrefix+"token", "", "API token sent as 'Authorization: Token', required with api-version 2.")
+	flagSet.String(flagPrefix+"precision", "ns", "Precision of the written timestamps with api-version 2: ns, us, ms or s.")
 }
 
 func (t *influxTarget) TargetName() string {
@@ -33,6 +46,10 @@ func (t *influxTarget) Serializer() serialize.PointSerializer {
 	return &Serializer{}
 }
 
-func (t *influxTarget) Benchmark(string, *source.DataSourceConfig, *viper.Viper) (targets.Benchmark, error) {
-	panic("not implemented")
+func (t *influxTarget) Benchmark(targetDB string, dataSourceConfig *source.DataSourceConfig, v *viper.Viper) (targets.Benchmark, error) {
+	opts, err := ParseSpecificConfig(v)
+	if err != nil {
+		return nil, err
+	}
+	return NewBenchmark(targetDB, opts, dataSourceConfig)
 }
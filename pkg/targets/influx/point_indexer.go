@@ -0,0 +1,34 @@
+package influx
+
+import (
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/targets"
+	"github.com/timescale/tsbs/pkg/targets/common"
+)
+
+// seriesKey returns the series key of the line protocol line, i.e. the
+// measurement and tag set up to the first unescaped space.
+func seriesKey(line []byte) []byte {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++ // skip the escaped character
+		case ' ':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func seriesKeyOfPoint(point *data.LoadedPoint) []byte {
+	return seriesKey(point.Data.([]byte))
+}
+
+// newSeriesIndexer returns a PointIndexer that sends all points of a series
+// to the same one of maxPartitions workers.
+func newSeriesIndexer(maxPartitions uint) targets.PointIndexer {
+	if maxPartitions <= 1 {
+		return &targets.ConstantIndexer{}
+	}
+	return common.NewGenericPointIndexer(maxPartitions, seriesKeyOfPoint)
+}
@@ -0,0 +1,90 @@
+package influx
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+func TestSeriesKey(t *testing.T) {
+	cases := []struct {
+		line string
+		want string
+	}{
+		{line: "cpu,hostname=host_0 usage_user=1 140", want: "cpu,hostname=host_0"},
+		{line: "cpu usage_user=1 140", want: "cpu"},
+		{line: `cpu,region=us\ east,host=a\\ usage_user=1 140`, want: `cpu,region=us\ east,host=a\\`},
+		{line: "cpu", want: "cpu"},
+	}
+	for _, c := range cases {
+		if got := string(seriesKey([]byte(c.line))); got != c.want {
+			t.Errorf("incorrect series key of %s: got %s want %s", c.line, got, c.want)
+		}
+	}
+}
+
+func TestSeriesIndexer(t *testing.T) {
+	if _, ok := newSeriesIndexer(1).(*targets.ConstantIndexer); !ok {
+		t.Errorf("single partition does not use the constant indexer")
+	}
+
+	const partitions = 4
+	indexer := newSeriesIndexer(partitions)
+	index := map[string]uint{}
+	used := map[uint]bool{}
+	for ts := 0; ts < 3; ts++ {
+		for h := 0; h < 100; h++ {
+			line := fmt.Sprintf("cpu,hostname=host_%d usage_user=%d %d", h, ts, ts)
+			got := indexer.GetIndex(data.LoadedPoint{Data: []byte(line)})
+			if got >= partitions {
+				t.Fatalf("index out of range: got %d", got)
+			}
+			key := fmt.Sprintf("host_%d", h)
+			if want, ok := index[key]; ok && got != want {
+				t.Errorf("series %s sent to partitions %d and %d", key, want, got)
+			}
+			index[key] = got
+			used[got] = true
+		}
+	}
+	if len(used) != partitions {
+		t.Errorf("series not spread over all partitions: got %d want %d", len(used), partitions)
+	}
+}
+
+func TestBatchGroupBySeries(t *testing.T) {
+	b := (&factory{}).New().(*batch)
+	for _, line := range []string{
+		"cpu,hostname=host_1 u=1 10",
+		"cpu,hostname=host_0 u=1 10",
+		"mem,hostname=host_1 u=1 10",
+		"cpu,hostname=host_1 u=2 20",
+		"cpu,hostname=host_0 u=2 20",
+		"cpu,hostname=host_1 u=3 30",
+	} {
+		b.Append(data.LoadedPoint{Data: []byte(line)})
+	}
+	b.groupBySeries()
+	want := "cpu,hostname=host_1 u=1 10\n" +
+		"cpu,hostname=host_1 u=2 20\n" +
+		"cpu,hostname=host_1 u=3 30\n" +
+		"cpu,hostname=host_0 u=1 10\n" +
+		"cpu,hostname=host_0 u=2 20\n" +
+		"mem,hostname=host_1 u=1 10\n"
+	if got := b.buf.String(); got != want {
+		t.Errorf("incorrect grouped batch:\ngot\n%swant\n%s", got, want)
+	}
+	if b.rows != 6 || b.metrics != 6 {
+		t.Errorf("incorrect counts after grouping: got %d rows %d metrics", b.rows, b.metrics)
+	}
+
+	distinct := "cpu,hostname=host_0 u=1 10\ncpu,hostname=host_1 u=1 10\n"
+	b = &batch{buf: bytes.NewBufferString(distinct)}
+	b.groupBySeries()
+	if got := b.buf.String(); got != distinct {
+		t.Errorf("batch of distinct series changed: got %s", got)
+	}
+}
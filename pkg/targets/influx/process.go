@@ -0,0 +1,178 @@
+package influx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/metrics"
+	"github.com/timescale/tsbs/pkg/targets"
+	"github.com/valyala/fasthttp"
+)
+
+const backingOffChanCap = 100
+
+// allows for testing
+var (
+	printFn = fmt.Printf
+	fatal   = log.Fatalf
+)
+
+var backoffSeconds = metrics.DefaultRegistry.Counter("tsbs_load_backoff_seconds_total", "Time spent backing off because the server indicated backpressure.")
+
+type processor struct {
+	dbName         string
+	opts           *SpecificConfig
+	backingOffChan chan bool
+	backingOffDone chan struct{}
+	httpWriter     *HTTPWriter
+	deadLetter     *deadLetterWriter
+	rand           *rand.Rand
+	// hashWorkers is set with --hash-workers, in which case batches are
+	// grouped by series before they are written
+	hashWorkers bool
+
+	rejectedMetrics uint64
+	rejectedRows    uint64
+}
+
+func (p *processor) Init(numWorker int, _, hashWorkers bool) {
+	p.hashWorkers = hashWorkers
+	daemonURL := p.opts.URLs[numWorker%len(p.opts.URLs)]
+	cfg := HTTPWriterConfig{
+		DebugInfo: fmt.Sprintf("worker #%d, dest url: %s", numWorker, daemonURL),
+		Host:      daemonURL,
+		Database:  p.dbName,
+
+		APIVersion: p.opts.APIVersion,
+		Org:        p.opts.Org,
+		Precision:  p.opts.Precision,
+		Token:      p.opts.Token,
+	}
+	w := NewHTTPWriter(cfg, p.opts.Consistency)
+	p.initWithHTTPWriter(numWorker, w)
+}
+
+func (p *processor) initWithHTTPWriter(numWorker int, w *HTTPWriter) {
+	p.backingOffChan = make(chan bool, backingOffChanCap)
+	p.backingOffDone = make(chan struct{})
+	p.httpWriter = w
+	p.rand = rand.New(rand.NewSource(time.Now().UnixNano() + int64(numWorker)))
+	go p.processBackoffMessages(numWorker)
+}
+
+func (p *processor) Close(_ bool) {
+	close(p.backingOffChan)
+	<-p.backingOffDone
+}
+
+func (p *processor) ProcessBatch(b targets.Batch, doLoad bool) (uint64, uint64) {
+	metricCnt, rowCnt, _ := p.ProcessBatchWithStats(b, doLoad)
+	return metricCnt, rowCnt
+}
+
+func (p *processor) ProcessBatchWithStats(b targets.Batch, doLoad bool) (uint64, uint64, targets.BatchStats) {
+	batch := b.(*batch)
+	metricCnt := batch.metrics
+	rowCnt := uint64(batch.rows)
+
+	var stats targets.BatchStats
+	if doLoad {
+		if p.hashWorkers {
+			batch.groupBySeries()
+		}
+		var err error
+		stats, err = p.write(batch.buf.Bytes())
+		if err != nil {
+			var we *writeError
+			if p.deadLetter == nil || (err != errBackoff && !errors.As(err, &we)) {
+				fatal("Error writing: %s\n", err.Error())
+			} else if dlErr := p.deadLetter.write(batch.buf.Bytes()); dlErr != nil {
+				fatal("Error writing to dead-letter file: %s\n", dlErr.Error())
+			} else {
+				rejectedMetrics, rejectedRows := metricCnt, rowCnt
+				if we != nil {
+					if dropped, ok := we.dropped(); ok && dropped < rowCnt {
+						// the server wrote the other rows; the metrics of
+						// the dropped ones are the average of the batch
+						rejectedRows = dropped
+						rejectedMetrics = metricCnt * dropped / rowCnt
+					}
+				}
+				printFn("rejected %d of %d rows: %s\n", rejectedRows, rowCnt, err.Error())
+				p.rejectedMetrics += rejectedMetrics
+				p.rejectedRows += rejectedRows
+				metricCnt -= rejectedMetrics
+				rowCnt -= rejectedRows
+			}
+		}
+	}
+
+	// Return the batch buffer to the pool.
+	batch.buf.Reset()
+	bufPool.Put(batch.buf)
+	return metricCnt, rowCnt, stats
+}
+
+// write writes the line protocol in body, retrying as allowed by the retry
+// settings, and returns the error of the last attempt. The latency in stats is
+// that of the last attempt.
+func (p *processor) write(body []byte) (targets.BatchStats, error) {
+	var stats targets.BatchStats
+	for attempts := uint(1); ; attempts++ {
+		var lat int64
+		var err error
+		if p.opts.UseGzip {
+			compressedBatch := bufPool.Get().(*bytes.Buffer)
+			fasthttp.WriteGzip(compressedBatch, body)
+			stats.Bytes += uint64(compressedBatch.Len())
+			lat, err = p.httpWriter.WriteLineProtocol(compressedBatch.Bytes(), true)
+			// Return the compressed batch buffer to the pool.
+			compressedBatch.Reset()
+			bufPool.Put(compressedBatch)
+		} else {
+			stats.Bytes += uint64(len(body))
+			lat, err = p.httpWriter.WriteLineProtocol(body, false)
+		}
+		stats.Latency = time.Duration(lat)
+
+		if err == nil || !p.opts.retryable(err) || !p.opts.canRetry(attempts) {
+			p.backingOffChan <- false
+			return stats, err
+		}
+		// only backpressure counts as backing off; other retried errors
+		// are failures of the write
+		p.backingOffChan <- err == errBackoff
+		time.Sleep(p.opts.retryDelay(attempts, p.rand))
+	}
+}
+
+// Rejected returns the metrics and rows of the batches written to the
+// dead-letter file.
+func (p *processor) Rejected() (uint64, uint64) {
+	return p.rejectedMetrics, p.rejectedRows
+}
+
+func (p *processor) processBackoffMessages(workerID int) {
+	var totalBackoffSecs float64
+	var start time.Time
+	last := false
+	for this := range p.backingOffChan {
+		if this && !last {
+			start = time.Now()
+			last = true
+		} else if !this && last {
+			took := time.Now().Sub(start)
+			printFn("[worker %d] backoff took %.02fsec\n", workerID, took.Seconds())
+			totalBackoffSecs += took.Seconds()
+			backoffSeconds.Add(took.Seconds())
+			last = false
+			start = time.Now()
+		}
+	}
+	printFn("[worker %d] backoffs took a total of %fsec of runtime\n", workerID, totalBackoffSecs)
+	p.backingOffDone <- struct{}{}
+}
@@ -1,8 +1,12 @@
-package main
+package influx
 
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -17,26 +21,27 @@ func emptyLog(_ string, _ ...interface{}) (int, error) {
 }
 
 func TestProcessorInit(t *testing.T) {
-	daemonURLs = []string{"url1", "url2"}
+	opts := &SpecificConfig{URLs: []string{"url1", "url2"}, Consistency: testConsistency}
+	daemonURLs := opts.URLs
 	printFn = emptyLog
-	p := &processor{}
+	p := &processor{dbName: "benchmark", opts: opts}
 	p.Init(0, false, false)
 	p.Close(true)
 	if got := p.httpWriter.c.Host; got != daemonURLs[0] {
 		t.Errorf("incorrect host: got %s want %s", got, daemonURLs[0])
 	}
-	if got := p.httpWriter.c.Database; got != loader.DatabaseName() {
-		t.Errorf("incorrect database: got %s want %s", got, loader.DatabaseName())
+	if got := p.httpWriter.c.Database; got != "benchmark" {
+		t.Errorf("incorrect database: got %s want %s", got, "benchmark")
 	}
 
-	p = &processor{}
+	p = &processor{opts: opts}
 	p.Init(1, false, false)
 	p.Close(true)
 	if got := p.httpWriter.c.Host; got != daemonURLs[1] {
 		t.Errorf("incorrect host: got %s want %s", got, daemonURLs[1])
 	}
 
-	p = &processor{}
+	p = &processor{opts: opts}
 	p.Init(len(daemonURLs), false, false)
 	p.Close(true)
 	if got := p.httpWriter.c.Host; got != daemonURLs[0] {
@@ -142,7 +147,7 @@ func TestProcessorProcessBatch(t *testing.T) {
 			ch = launchHTTPServer()
 		}
 
-		p := &processor{}
+		p := &processor{opts: &SpecificConfig{UseGzip: c.useGzip}}
 		w := NewHTTPWriter(testConf, testConsistency)
 
 		// If the case should backoff, we tell our dummy server to do so by
@@ -154,7 +159,6 @@ func TestProcessorProcessBatch(t *testing.T) {
 		}
 
 		p.initWithHTTPWriter(0, w)
-		useGzip = c.useGzip
 		mCnt, rCnt := p.ProcessBatch(b, c.doLoad)
 		if c.shouldFatal {
 			if !fatalCalled {
@@ -240,3 +244,114 @@ func TestProcessorProcessBackoffMessages(t *testing.T) {
 		}
 	}
 }
+
+func TestProcessorWriteBackingOff(t *testing.T) {
+	ch := launchHTTPServer()
+	defer shutdownHTTPServer(ch)
+
+	cases := []struct {
+		desc  string
+		param string
+		want  []bool
+	}{
+		{desc: "backpressure", param: shouldBackoffParam, want: []bool{true, false}},
+		{desc: "retried status code", param: shouldPartialParam, want: []bool{false, false, false}},
+	}
+	for _, c := range cases {
+		opts := &SpecificConfig{RetryMaxAttempts: 3, RetryStatusCodes: []int{400}, Backoff: time.Millisecond}
+		w := NewHTTPWriter(testConf, testConsistency)
+		w.url = []byte(fmt.Sprintf("%s&%s=true", string(w.url), c.param))
+		p := &processor{
+			opts:           opts,
+			httpWriter:     w,
+			backingOffChan: make(chan bool, backingOffChanCap),
+			rand:           rand.New(rand.NewSource(1)),
+		}
+		p.write([]byte("cpu,hostname=h1 usage_user=1 140"))
+		close(p.backingOffChan)
+		got := make([]bool, 0)
+		for b := range p.backingOffChan {
+			got = append(got, b)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: incorrect backing off messages: got %v want %v", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestProcessorProcessBatchDeadLetter(t *testing.T) {
+	printFn = emptyLog
+	fatal = func(format string, args ...interface{}) {
+		t.Errorf("fatal called unexpectedly: "+format, args...)
+	}
+	path := filepath.Join(t.TempDir(), "rejected.txt")
+	deadLetter, err := newDeadLetterWriter(path)
+	if err != nil {
+		t.Fatalf("could not create dead-letter file: %v", err)
+	}
+
+	ch := launchHTTPServer()
+	defer shutdownHTTPServer(ch)
+	opts := &SpecificConfig{RetryMaxAttempts: 2, RetryStatusCodes: []int{500}}
+	p := &processor{opts: opts, deadLetter: deadLetter}
+	w := NewHTTPWriter(testConf, testConsistency)
+	// The server answers 200 with a message instead of 204.
+	w.url = []byte(fmt.Sprintf("%s&%s=true", string(w.url), shouldInvalidParam))
+	p.initWithHTTPWriter(0, w)
+
+	line := "cpu,hostname=h1 usage_user=1,usage_system=2 140"
+	b := (&factory{}).New().(*batch)
+	b.Append(data.LoadedPoint{Data: []byte(line)})
+	wantMetrics := b.metrics
+	mCnt, rCnt := p.ProcessBatch(b, true)
+	p.Close(true)
+	if mCnt != 0 || rCnt != 0 {
+		t.Errorf("rejected batch counted as loaded: got %d metrics, %d rows", mCnt, rCnt)
+	}
+	if m, r := p.Rejected(); m != wantMetrics || r != 1 {
+		t.Errorf("incorrect rejected counts: got %d metrics, %d rows, want %d, %d", m, r, wantMetrics, 1)
+	}
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read dead-letter file: %v", err)
+	}
+	if string(got) != line+"\n" {
+		t.Errorf("incorrect dead-letter file: got %q want %q", got, line+"\n")
+	}
+}
+
+func TestProcessorProcessBatchPartialWrite(t *testing.T) {
+	printFn = emptyLog
+	fatal = func(format string, args ...interface{}) {
+		t.Errorf("fatal called unexpectedly: "+format, args...)
+	}
+	deadLetter, err := newDeadLetterWriter(filepath.Join(t.TempDir(), "rejected.txt"))
+	if err != nil {
+		t.Fatalf("could not create dead-letter file: %v", err)
+	}
+
+	ch := launchHTTPServer()
+	defer shutdownHTTPServer(ch)
+	p := &processor{opts: &SpecificConfig{}, deadLetter: deadLetter}
+	w := NewHTTPWriter(testConf, testConsistency)
+	// The server answers 400 with a partial write dropping 1 point.
+	w.url = []byte(fmt.Sprintf("%s&%s=true", string(w.url), shouldPartialParam))
+	p.initWithHTTPWriter(0, w)
+
+	b := (&factory{}).New().(*batch)
+	for _, line := range []string{
+		"cpu,hostname=h1 usage_user=1,usage_system=2 140",
+		"cpu,hostname=h1 usage_user=\"a\",usage_system=2 150",
+		"cpu,hostname=h1 usage_user=3,usage_system=4 160",
+	} {
+		b.Append(data.LoadedPoint{Data: []byte(line)})
+	}
+	mCnt, rCnt := p.ProcessBatch(b, true)
+	p.Close(true)
+	if mCnt != 4 || rCnt != 2 {
+		t.Errorf("incorrect loaded counts of a partial write: got %d metrics, %d rows, want 4, 2", mCnt, rCnt)
+	}
+	if m, r := p.Rejected(); m != 2 || r != 1 {
+		t.Errorf("incorrect rejected counts of a partial write: got %d metrics, %d rows, want 2, 1", m, r)
+	}
+}
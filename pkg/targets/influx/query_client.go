@@ -1,7 +1,10 @@
-package main
+package influx
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
@@ -14,13 +17,18 @@ import (
 
 var bytesSlash = []byte("/") // heap optimization
 
+const fluxQueryPath = "/api/v2/query"
+
 // HTTPClient is a reusable HTTP Client.
 type HTTPClient struct {
 	//client     fasthttp.Client
 	client     *http.Client
+	conn       client.Client
 	Host       []byte
 	HostString string
 	uri        []byte
+	// response is the response of the last InfluxQL query, nil for Flux
+	response *client.Response
 }
 
 // HTTPClientDoOptions wraps options uses when calling `Do`.
@@ -29,6 +37,9 @@ type HTTPClientDoOptions struct {
 	PrettyPrintResponses bool
 	chunkSize            uint64
 	database             string
+	language             string
+	org                  string
+	token                string
 }
 
 var httpClientOnce = sync.Once{}
@@ -44,10 +55,12 @@ func getHttpClient() *http.Client {
 	return httpClient
 }
 
-// NewHTTPClient creates a new HTTPClient.
-func NewHTTPClient(host string) *HTTPClient {
+// NewHTTPClient creates a new HTTPClient that sends queries to host through
+// conn.
+func NewHTTPClient(host string, conn client.Client) *HTTPClient {
 	return &HTTPClient{
 		client:     getHttpClient(),
+		conn:       conn,
 		Host:       []byte(host),
 		HostString: host,
 		uri:        []byte{}, // heap optimization
@@ -56,7 +69,7 @@ func NewHTTPClient(host string) *HTTPClient {
 
 // Do performs the action specified by the given Query. It uses fasthttp, and
 // tries to minimize heap allocations.
-func (w *HTTPClient) Do(q *query.HTTP, opts *HTTPClientDoOptions, workerNum int) (float64, uint64, uint8, error) {
+func (w *HTTPClient) Do(q *query.HTTP, opts *HTTPClientDoOptions) (float64, uint64, uint8, error) {
 	// populate uri from the reusable byte slice:
 	w.uri = w.uri[:0]
 	w.uri = append(w.uri, w.Host...)
@@ -72,27 +85,32 @@ func (w *HTTPClient) Do(q *query.HTTP, opts *HTTPClientDoOptions, workerNum int)
 	byteLength := uint64(0)
 	hitKind := uint8(0)
 	err := error(nil)
+	w.response = nil
 
 	// Perform the request while tracking latency:
 	start := time.Now() // 发送请求之前的时间
 
 	//log.Println(string(q.RawQuery))
-	if strings.EqualFold(client.UseCache, "stscache") {
+	if opts.language == QueryLanguageFlux {
+
+		byteLength, err = w.doFlux(q, opts)
 
-		_, byteLength, hitKind = client.STsCacheClient(DBConn[workerNum%len(DBConn)], string(q.RawQuery))
+	} else if strings.EqualFold(client.UseCache, "stscache") {
+
+		w.response, byteLength, hitKind = client.STsCacheClient(w.conn, string(q.RawQuery))
 
 	} else if strings.EqualFold(client.UseCache, "tscache") {
 
-		_, byteLength, hitKind = client.TSCacheClient(DBConn[workerNum%len(DBConn)], string(q.RawQuery))
+		w.response, byteLength, hitKind = client.TSCacheClient(w.conn, string(q.RawQuery))
 
 	} else { // database
 
 		qry := client.NewQuery(string(q.RawQuery), client.DB, "s")
-		//resp, err := DBConn[workerNum%len(DBConn)].Query(qry)
-		_, err := DBConn[workerNum%len(DBConn)].Query(qry)
+		resp, err := w.conn.Query(qry)
 		if err != nil {
 			panic(err)
 		}
+		w.response = resp
 		//values := client.ResponseToByteArray(resp, string(q.RawQuery))
 		////client.TotalGetByteLength += uint64(len(values))
 		//log.Println(len(values))
@@ -170,3 +188,44 @@ func (w *HTTPClient) Do(q *query.HTTP, opts *HTTPClientDoOptions, workerNum int)
 
 	return lag, byteLength, hitKind, err
 }
+
+// Response returns the response of the last InfluxQL query done, nil if
+// none.
+func (w *HTTPClient) Response() *client.Response {
+	return w.response
+}
+
+// doFlux sends the Flux query q to the /api/v2/query endpoint and returns the
+// length of the CSV response.
+func (w *HTTPClient) doFlux(q *query.HTTP, opts *HTTPClientDoOptions) (uint64, error) {
+	body, err := json.Marshal(map[string]string{"query": string(q.RawQuery), "type": "flux"})
+	if err != nil {
+		return 0, err
+	}
+	u := w.HostString + fluxQueryPath + "?org=" + url.QueryEscape(opts.org)
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/csv")
+	if opts.token != "" {
+		req.Header.Set(headerAuthorization, headerTokenPrefix+opts.token)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("flux query returned status %d: %s", resp.StatusCode, respBody)
+	}
+	if opts.PrettyPrintResponses {
+		fmt.Printf("ID %d: %s\n%s\n", q.GetID(), q.RawQuery, respBody)
+	}
+	return uint64(len(respBody)), nil
+}
@@ -0,0 +1,142 @@
+package influx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/timescale/tsbs/InfluxDB-client/models"
+	client "github.com/timescale/tsbs/InfluxDB-client/v2"
+	"github.com/timescale/tsbs/pkg/query"
+	"github.com/timescale/tsbs/pkg/reference"
+)
+
+// QueryProcessorConfig holds the settings of the processors that run InfluxQL
+// queries, i.e. the flags of tsbs_run_queries_influx.
+type QueryProcessorConfig struct {
+	URLs                 []string
+	ChunkSize            uint64
+	Database             string
+	Debug                int
+	PrettyPrintResponses bool
+	// Language of the queries, QueryLanguageInfluxQL or QueryLanguageFlux.
+	Language string
+	// Org is the organization Flux queries run in.
+	Org string
+	// Token, if set, is sent as 'Authorization: Token' with every query.
+	Token string
+	// Answers, if set, are the expected answers the results of InfluxQL
+	// queries are checked against.
+	Answers *reference.Answers
+}
+
+const (
+	// QueryLanguageInfluxQL queries are sent to the /query endpoint, which
+	// InfluxDB 2.x serves for buckets mapped to a database.
+	QueryLanguageInfluxQL = "influxql"
+	// QueryLanguageFlux queries are sent to the /api/v2/query endpoint of
+	// InfluxDB 2.x, bypassing any cache.
+	QueryLanguageFlux = "flux"
+
+	errInvalidQueryLanguageFmt = "invalid query language '%s', must be one of: influxql, flux"
+	errFluxCache               = "flux queries cannot be cached: use --use-cache=db"
+	errFluxNoOrg               = "flux queries require an org"
+)
+
+// Validate checks conf for queries run through the cache selected with
+// useCache.
+func (conf QueryProcessorConfig) Validate(useCache string) error {
+	switch conf.Language {
+	case QueryLanguageInfluxQL:
+	case QueryLanguageFlux:
+		if !strings.EqualFold(useCache, "db") {
+			return errors.New(errFluxCache)
+		}
+		if conf.Org == "" {
+			return errors.New(errFluxNoOrg)
+		}
+	default:
+		return fmt.Errorf(errInvalidQueryLanguageFmt, conf.Language)
+	}
+	return nil
+}
+
+// NewQueryConns returns a client connection for each of urls, which sends
+// token, if not empty, with every request.
+func NewQueryConns(urls []string, token string) ([]client.Client, error) {
+	conns := make([]client.Client, len(urls))
+	for i, u := range urls {
+		conn, err := client.NewHTTPClient(client.HTTPConfig{Addr: u, Token: token})
+		if err != nil {
+			return nil, fmt.Errorf("cannot connect to %s: %v", u, err)
+		}
+		conns[i] = conn
+	}
+	return conns, nil
+}
+
+// LoadQuerySchema reads the tag keys and values and the field keys of db,
+// which the cache clients need to split and merge query results. It has to be
+// called once the database holds data for all series.
+func LoadQuerySchema(conn client.Client, db string) {
+	client.TagKV = client.GetTagKV(conn, db)
+	client.Fields = client.GetFieldKeys(conn, db)
+}
+
+// NewQueryProcessorCreate returns a query.ProcessorCreate for processors that
+// run queries against conf.URLs, one per worker in a round-robin fashion,
+// through the cache selected with the runner's --use-cache. conns are the
+// connections to conf.URLs returned by NewQueryConns.
+func NewQueryProcessorCreate(conf QueryProcessorConfig, conns []client.Client) query.ProcessorCreate {
+	return func() query.Processor {
+		return &queryProcessor{conf: conf, conns: conns}
+	}
+}
+
+type queryProcessor struct {
+	conf  QueryProcessorConfig
+	conns []client.Client
+	w     *HTTPClient
+	opts  *HTTPClientDoOptions
+}
+
+func (p *queryProcessor) Init(workerNumber int) {
+	p.opts = &HTTPClientDoOptions{
+		Debug:                p.conf.Debug,
+		PrettyPrintResponses: p.conf.PrettyPrintResponses,
+		chunkSize:            p.conf.ChunkSize,
+		database:             p.conf.Database,
+		language:             p.conf.Language,
+		org:                  p.conf.Org,
+		token:                p.conf.Token,
+	}
+	i := workerNumber % len(p.conf.URLs)
+	p.w = NewHTTPClient(p.conf.URLs[i], p.conns[i%len(p.conns)])
+}
+
+func (p *queryProcessor) ProcessQuery(q query.Query, _ bool, _ int) ([]*query.Stat, error) {
+	hq := q.(*query.HTTP)
+	lag, byteLength, hitKind, err := p.w.Do(hq, p.opts)
+	if err != nil {
+		return nil, err
+	}
+	if p.conf.Answers != nil && p.conf.Language == QueryLanguageInfluxQL {
+		p.check(hq)
+	}
+	stat := query.GetStat()
+	stat.Init(q.HumanLabelName(), lag, byteLength, hitKind)
+	return []*query.Stat{stat}, nil
+}
+
+// check checks the result of q against its expected answer, and reports a
+// mismatch on stderr.
+func (p *queryProcessor) check(q *query.HTTP) {
+	var rows []models.Row
+	if resp := p.w.Response(); resp != nil && len(resp.Results) > 0 {
+		rows = resp.Results[0].Series
+	}
+	if err := p.conf.Answers.Check(q.GetID(), rows); err != nil {
+		fmt.Fprintf(os.Stderr, "query %d (%s): result differs from the reference answer: %v\n", q.GetID(), q.HumanLabel, err)
+	}
+}
@@ -0,0 +1,117 @@
+package influx
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/InfluxDB-client/models"
+	client "github.com/timescale/tsbs/InfluxDB-client/v2"
+	"github.com/timescale/tsbs/pkg/query"
+	"github.com/timescale/tsbs/pkg/reference"
+)
+
+func TestQueryProcessorConfigValidate(t *testing.T) {
+	cases := []struct {
+		desc     string
+		conf     QueryProcessorConfig
+		useCache string
+		wantErr  bool
+	}{
+		{desc: "influxql", conf: QueryProcessorConfig{Language: QueryLanguageInfluxQL}, useCache: "stscache"},
+		{desc: "flux", conf: QueryProcessorConfig{Language: QueryLanguageFlux, Org: "tsbs"}, useCache: "db"},
+		{desc: "flux with cache", conf: QueryProcessorConfig{Language: QueryLanguageFlux, Org: "tsbs"}, useCache: "stscache", wantErr: true},
+		{desc: "flux without org", conf: QueryProcessorConfig{Language: QueryLanguageFlux}, useCache: "db", wantErr: true},
+		{desc: "unknown language", conf: QueryProcessorConfig{Language: "sql"}, useCache: "db", wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			if err := c.conf.Validate(c.useCache); (err != nil) != c.wantErr {
+				t.Errorf("incorrect error: got %v want error %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPClientDoFlux(t *testing.T) {
+	const csv = "result,table,_value\n,0,1\n"
+	var gotQuery map[string]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != fluxQueryPath || r.URL.Query().Get("org") != "tsbs" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get(headerAuthorization) != "Token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&gotQuery)
+		w.Write([]byte(csv))
+	}))
+	defer ts.Close()
+
+	q := &query.HTTP{RawQuery: []byte(`from(bucket: "benchmark") |> range(start: -1h)`)}
+	opts := &HTTPClientDoOptions{language: QueryLanguageFlux, org: "tsbs", token: "secret"}
+	w := NewHTTPClient(ts.URL, nil)
+	_, byteLength, hitKind, err := w.Do(q, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if byteLength != uint64(len(csv)) || hitKind != 0 {
+		t.Errorf("incorrect result: got %d bytes, hit kind %d want %d bytes, 0", byteLength, hitKind, len(csv))
+	}
+	if gotQuery["query"] != string(q.RawQuery) || gotQuery["type"] != "flux" {
+		t.Errorf("incorrect request body: %v", gotQuery)
+	}
+
+	opts.token = "wrong"
+	if _, _, _, err := w.Do(q, opts); err == nil {
+		t.Errorf("expected error for unauthorized query")
+	}
+}
+
+func TestQueryProcessorCheck(t *testing.T) {
+	// the database answers max with the time in seconds
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","max"],"values":[[10,20]]}]}]}`))
+	}))
+	defer ts.Close()
+	conn, err := client.NewHTTPClient(client.HTTPConfig{Addr: ts.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	for i, max := range []float64{20, 30} {
+		reference.WriteAnswer(&buf, &reference.Answer{ID: uint64(i), Series: []models.Row{
+			{Name: "cpu", Columns: []string{"time", "max"}, Values: [][]interface{}{{time.Unix(10, 0), max}}},
+		}})
+	}
+	answers, err := reference.ReadAnswers(&buf, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	conf := QueryProcessorConfig{URLs: []string{ts.URL}, Language: QueryLanguageInfluxQL, Answers: answers}
+	p := NewQueryProcessorCreate(conf, []client.Client{conn})()
+	p.Init(0)
+	for i := uint64(0); i < 2; i++ {
+		q := &query.HTTP{RawQuery: []byte("SELECT max(usage_user) FROM cpu")}
+		q.SetID(i)
+		if _, err := p.ProcessQuery(q, false, 0); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	var summary strings.Builder
+	answers.WriteSummary(&summary)
+	want := "reference answers: 2 results checked, 1 mismatched, 0 without answer\n"
+	if summary.String() != want {
+		t.Errorf("incorrect summary: got %q want %q", summary.String(), want)
+	}
+}
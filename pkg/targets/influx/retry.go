@@ -0,0 +1,75 @@
+package influx
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const errInvalidStatusCodeFmt = "invalid retry status code '%s', must be an HTTP status code"
+
+// parseStatusCodes parses a comma-separated list of HTTP status codes.
+func parseStatusCodes(s string) ([]int, error) {
+	var codes []int
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c == "" {
+			continue
+		}
+		code, err := strconv.Atoi(c)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf(errInvalidStatusCodeFmt, c)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// retryable reports whether a write that failed with err should be retried:
+// the server asked for backpressure, or answered with one of the retry status
+// codes. Transport errors, e.g. a refused connection, are not retried.
+func (o *SpecificConfig) retryable(err error) bool {
+	if err == errBackoff {
+		return true
+	}
+	var we *writeError
+	if !errors.As(err, &we) {
+		return false
+	}
+	for _, code := range o.RetryStatusCodes {
+		if we.statusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// canRetry reports whether a write may be attempted again after attempts
+// failed attempts.
+func (o *SpecificConfig) canRetry(attempts uint) bool {
+	return o.RetryMaxAttempts == 0 || attempts < o.RetryMaxAttempts
+}
+
+// retryDelay returns the time to wait after attempts failed attempts. The
+// delay doubles with each attempt, starting at Backoff and capped at
+// BackoffMax, and is then drawn uniformly from its upper half so workers
+// backing off together do not retry in lockstep.
+func (o *SpecificConfig) retryDelay(attempts uint, rnd *rand.Rand) time.Duration {
+	d := o.Backoff
+	for i := uint(1); i < attempts && d > 0; i++ {
+		if o.BackoffMax > 0 && d >= o.BackoffMax {
+			break
+		}
+		d *= 2
+	}
+	if o.BackoffMax > 0 && d > o.BackoffMax {
+		d = o.BackoffMax
+	}
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + time.Duration(rnd.Int63n(int64(d-half)+1))
+}
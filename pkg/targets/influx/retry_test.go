@@ -0,0 +1,84 @@
+package influx
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestParseStatusCodes(t *testing.T) {
+	codes, err := parseStatusCodes(" 429, 503,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(codes) != 2 || codes[0] != 429 || codes[1] != 503 {
+		t.Errorf("incorrect codes: %v", codes)
+	}
+	if codes, err := parseStatusCodes(""); err != nil || len(codes) != 0 {
+		t.Errorf("incorrect result for empty list: %v, %v", codes, err)
+	}
+	for _, s := range []string{"abc", "99", "600"} {
+		if _, err := parseStatusCodes(s); err == nil {
+			t.Errorf("expected error for %s", s)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	o := &SpecificConfig{RetryStatusCodes: []int{503}}
+	cases := []struct {
+		desc string
+		err  error
+		want bool
+	}{
+		{desc: "backpressure", err: errBackoff, want: true},
+		{desc: "retry status code", err: &writeError{statusCode: 503}, want: true},
+		{desc: "other status code", err: &writeError{statusCode: 400}, want: false},
+		{desc: "transport error", err: errors.New("dial tcp: connection refused"), want: false},
+	}
+	for _, c := range cases {
+		if got := o.retryable(c.err); got != c.want {
+			t.Errorf("%s: got %v want %v", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestCanRetry(t *testing.T) {
+	o := &SpecificConfig{}
+	if !o.canRetry(1000) {
+		t.Errorf("unlimited attempts should always retry")
+	}
+	o.RetryMaxAttempts = 3
+	if !o.canRetry(2) || o.canRetry(3) {
+		t.Errorf("3 attempts: incorrect canRetry")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	o := &SpecificConfig{Backoff: time.Second, BackoffMax: 5 * time.Second}
+	rnd := rand.New(rand.NewSource(1))
+	cases := []struct {
+		attempts uint
+		max      time.Duration
+	}{
+		{attempts: 1, max: time.Second},
+		{attempts: 2, max: 2 * time.Second},
+		{attempts: 3, max: 4 * time.Second},
+		{attempts: 4, max: 5 * time.Second},
+		{attempts: 100, max: 5 * time.Second},
+	}
+	for _, c := range cases {
+		for i := 0; i < 100; i++ {
+			got := o.retryDelay(c.attempts, rnd)
+			if got < c.max/2 || got > c.max {
+				t.Fatalf("attempt %d: delay %v not in [%v, %v]", c.attempts, got, c.max/2, c.max)
+			}
+		}
+	}
+
+	o = &SpecificConfig{}
+	if got := o.retryDelay(5, rnd); got != 0 {
+		t.Errorf("zero backoff: got %v want 0", got)
+	}
+}
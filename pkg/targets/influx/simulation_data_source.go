@@ -0,0 +1,203 @@
+package influx
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+)
+
+// simulationGroupCapacity is the number of points buffered per interleaved
+// group, which bounds the memory used ahead of the loader.
+const simulationGroupCapacity = 1024
+
+var pointPool = sync.Pool{
+	New: func() interface{} {
+		return data.NewPoint()
+	},
+}
+
+// simulationDataSource generates points with a Simulator and serializes them
+// to line protocol in memory, so data can be loaded without generating a file
+// first.
+//
+// With more than one worker, a simulator that can be partitioned simulates and
+// serializes its partitions in separate goroutines, whose lines are read back
+// in the order of the simulator. Other simulators run in a single goroutine,
+// and their points are dealt round-robin to interleaved groups, as with
+// tsbs_generate_data --interleaved-generation-groups, each serialized by its
+// own goroutine and read back in the same round-robin order. Either way, the
+// loaded data is identical to the output of tsbs_generate_data for the same
+// seed.
+type simulationDataSource struct {
+	simulator common.Simulator
+	// headers are read before the simulation starts, since they are read
+	// from the entities that churn replaces
+	headers *common.GeneratedDataHeaders
+
+	// used with a single worker
+	serializer *Serializer
+	point      *data.Point
+	buf        bytes.Buffer
+
+	// used with several workers and a simulator that can be partitioned
+	steps *common.PartitionSteps
+	lines [][]byte
+
+	// used with several workers otherwise
+	groups []chan []byte
+	next   int
+}
+
+func newSimulationDataSource(sim common.Simulator, workers uint) *simulationDataSource {
+	d := &simulationDataSource{simulator: sim, headers: sim.Headers()}
+	if workers <= 1 {
+		d.serializer = &Serializer{}
+		d.point = data.NewPoint()
+		return d
+	}
+	if p, ok := sim.(common.Partitioner); ok {
+		d.steps = common.RunPartitions(p, int(workers), serializeStep)
+		return d
+	}
+
+	in := make([]chan *data.Point, workers)
+	d.groups = make([]chan []byte, workers)
+	for i := range in {
+		in[i] = make(chan *data.Point, simulationGroupCapacity)
+		d.groups[i] = make(chan []byte, simulationGroupCapacity)
+		go serializeGroup(in[i], d.groups[i])
+	}
+	go d.simulate(in)
+	return d
+}
+
+// serializeStep returns the common.StepFunc of a partition, which returns the
+// lines of the points of each step that have a non-nil field.
+func serializeStep() common.StepFunc {
+	serializer := &Serializer{}
+	point := data.NewPoint()
+	var buf bytes.Buffer
+	return func(sim common.Simulator, stepSize int) interface{} {
+		lines := make([][]byte, 0, stepSize)
+		for i := 0; i < stepSize && !sim.Finished(); i++ {
+			if sim.Next(point) {
+				buf.Reset()
+				if err := serializer.Serialize(point, &buf); err != nil {
+					fatal("can not serialize point: %v", err)
+					break
+				}
+				if buf.Len() > 0 {
+					lines = append(lines, append([]byte(nil), bytes.TrimSuffix(buf.Bytes(), newLine)...))
+				}
+			}
+			point.Reset()
+		}
+		return lines
+	}
+}
+
+// simulate deals the points of the simulator round-robin to the groups.
+func (d *simulationDataSource) simulate(groups []chan *data.Point) {
+	g := 0
+	for !d.simulator.Finished() {
+		p := pointPool.Get().(*data.Point)
+		if !d.simulator.Next(p) {
+			p.Reset()
+			pointPool.Put(p)
+			continue
+		}
+		// Simulators point the timestamp at their own state, which the
+		// next call to Next advances before the group serializes p.
+		ts := *p.Timestamp()
+		p.SetTimestamp(&ts)
+		groups[g] <- p
+		g = (g + 1) % len(groups)
+	}
+	for _, c := range groups {
+		close(c)
+	}
+}
+
+// serializeGroup serializes the points of one group in order. A point without
+// any non-nil field is sent as an empty line to keep the groups in step.
+func serializeGroup(in <-chan *data.Point, out chan<- []byte) {
+	serializer := &Serializer{}
+	var buf bytes.Buffer
+	for p := range in {
+		buf.Reset()
+		err := serializer.Serialize(p, &buf)
+		p.Reset()
+		pointPool.Put(p)
+		if err != nil {
+			fatal("can not serialize point: %v", err)
+			break
+		}
+		out <- append([]byte(nil), bytes.TrimSuffix(buf.Bytes(), newLine)...)
+	}
+	close(out)
+}
+
+func (d *simulationDataSource) NextItem() data.LoadedPoint {
+	if d.steps != nil {
+		return d.nextFromSteps()
+	}
+	if d.groups != nil {
+		return d.nextFromGroups()
+	}
+	for !d.simulator.Finished() {
+		write := d.simulator.Next(d.point)
+		if !write {
+			d.point.Reset()
+			continue
+		}
+
+		d.buf.Reset()
+		err := d.serializer.Serialize(d.point, &d.buf)
+		d.point.Reset()
+		if err != nil {
+			fatal("can not serialize point: %v", err)
+			return data.LoadedPoint{}
+		}
+		// Points without any non-nil field are not written.
+		if d.buf.Len() == 0 {
+			continue
+		}
+		line := bytes.TrimSuffix(d.buf.Bytes(), newLine)
+		return data.NewLoadedPoint(append([]byte(nil), line...))
+	}
+	return data.LoadedPoint{}
+}
+
+func (d *simulationDataSource) nextFromSteps() data.LoadedPoint {
+	for len(d.lines) == 0 {
+		step, ok := d.steps.Next()
+		if !ok {
+			return data.LoadedPoint{}
+		}
+		d.lines = step.([][]byte)
+	}
+	line := d.lines[0]
+	d.lines = d.lines[1:]
+	return data.NewLoadedPoint(line)
+}
+
+func (d *simulationDataSource) nextFromGroups() data.LoadedPoint {
+	for {
+		line, ok := <-d.groups[d.next]
+		if !ok {
+			// Points are dealt round-robin, so the first exhausted group
+			// in turn means all of them are.
+			return data.LoadedPoint{}
+		}
+		d.next = (d.next + 1) % len(d.groups)
+		if len(line) > 0 {
+			return data.NewLoadedPoint(line)
+		}
+	}
+}
+
+func (d *simulationDataSource) Headers() *common.GeneratedDataHeaders {
+	return d.headers
+}
@@ -0,0 +1,64 @@
+package influx
+
+import (
+	"bytes"
+	"time"
+)
+
+// streamBackoff is the time a StreamWriter waits before writing again when
+// the server asks for backpressure.
+const streamBackoff = time.Second
+
+// StreamWriter is an io.Writer that writes the line protocol written to it
+// to InfluxDB, a batch of the complete lines of each Write, so that points
+// can be streamed to the database as they are generated.
+type StreamWriter struct {
+	w *HTTPWriter
+	// pending is the incomplete last line of the previous writes
+	pending []byte
+	sleep   func(time.Duration)
+}
+
+// NewStreamWriter returns a StreamWriter that writes through w.
+func NewStreamWriter(w *HTTPWriter) *StreamWriter {
+	return &StreamWriter{w: w, sleep: time.Sleep}
+}
+
+// Write writes the complete lines of p, after the incomplete line of the
+// previous writes, keeping its own incomplete last line for the next Write.
+func (s *StreamWriter) Write(p []byte) (int, error) {
+	end := bytes.LastIndexByte(p, '\n') + 1
+	if end == 0 {
+		s.pending = append(s.pending, p...)
+		return len(p), nil
+	}
+	body := p[:end]
+	if len(s.pending) > 0 {
+		body = append(s.pending, body...)
+	}
+	if err := s.write(body); err != nil {
+		return 0, err
+	}
+	s.pending = append(s.pending[:0], p[end:]...)
+	return len(p), nil
+}
+
+// Close writes the incomplete last line, if any.
+func (s *StreamWriter) Close() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	err := s.write(s.pending)
+	s.pending = s.pending[:0]
+	return err
+}
+
+func (s *StreamWriter) write(body []byte) error {
+	for {
+		_, err := s.w.WriteLineProtocol(body, false)
+		if err != errBackoff {
+			return err
+		}
+		s.sleep(streamBackoff)
+	}
+}
@@ -0,0 +1,61 @@
+package influx
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStreamWriter(t *testing.T) {
+	var bodies []string
+	backoffs := 1
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if backoffs > 0 {
+			backoffs--
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(backoffMagicWords0)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	s := NewStreamWriter(NewHTTPWriter(HTTPWriterConfig{Host: ts.URL, Database: "test"}, "one"))
+	var slept time.Duration
+	s.sleep = func(d time.Duration) { slept += d }
+
+	// the writes split lines, as a bufio.Writer flushing a full buffer does
+	writes := []string{"cpu usage=1 0\ncpu us", "age=2 10", "\ncpu usage=3 20\n", "cpu usage=4 30"}
+	for _, w := range writes {
+		if n, err := s.Write([]byte(w)); err != nil || n != len(w) {
+			t.Fatalf("incorrect write: got %d, %v want %d", n, err, len(w))
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"cpu usage=1 0\n", "cpu usage=2 10\ncpu usage=3 20\n", "cpu usage=4 30"}
+	if !reflect.DeepEqual(bodies, want) {
+		t.Errorf("incorrect batches: got %q want %q", bodies, want)
+	}
+	if slept != streamBackoff {
+		t.Errorf("incorrect backoff: got %v want %v", slept, streamBackoff)
+	}
+}
+
+func TestStreamWriterError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	s := NewStreamWriter(NewHTTPWriter(HTTPWriterConfig{Host: ts.URL, Database: "test"}, "one"))
+	if _, err := s.Write([]byte("cpu usage=1 0\n")); err == nil {
+		t.Errorf("expected error for a failed write")
+	}
+}
@@ -1,19 +1,14 @@
+// Package initializers registers the targets implemented in this repository.
+// Importing it makes them available through targets.GetTarget.
 package initializers
 
 import (
-	"fmt"
 	"github.com/timescale/tsbs/pkg/targets"
-	"github.com/timescale/tsbs/pkg/targets/constants"
-	"github.com/timescale/tsbs/pkg/targets/influx"
-	"strings"
+	_ "github.com/timescale/tsbs/pkg/targets/influx" // registers the influx target
 )
 
-func GetTarget(format string) targets.ImplementedTarget {
-	switch format {
-	case constants.FormatInflux:
-		return influx.NewTarget()
-	}
-
-	supportedFormatsStr := strings.Join(constants.SupportedFormats(), ",")
-	panic(fmt.Sprintf("Unrecognized format %s, supported: %s", format, supportedFormatsStr))
+// GetTarget returns a new target for format, or the registry error if no
+// target is registered for format.
+func GetTarget(format string) (targets.ImplementedTarget, error) {
+	return targets.GetTarget(format)
 }
@@ -1,5 +1,7 @@
 package targets
 
+import "time"
+
 // Processor is a type that processes the work for a loading worker
 type Processor interface {
 	// Init does per-worker setup needed before receiving data
@@ -14,3 +16,30 @@ type ProcessorCloser interface {
 	// Close cleans up after a Processor
 	Close(doLoad bool)
 }
+
+// ProcessorRejecter is a Processor that can give up on batches the database
+// permanently refused instead of aborting the load. The metrics and rows of
+// such batches are not included in what ProcessBatch returns.
+type ProcessorRejecter interface {
+	Processor
+	// Rejected returns the number of metrics and rows rejected so far
+	Rejected() (metricCount, rowCount uint64)
+}
+
+// BatchStats are the statistics of writing a single batch
+type BatchStats struct {
+	// Latency is the time the database took to acknowledge the write
+	Latency time.Duration
+	// Bytes is the number of bytes sent to the database, including retries
+	Bytes uint64
+}
+
+// ProcessorWithStats is a Processor that reports the statistics of each
+// batch it writes. The load runner uses ProcessBatchWithStats instead of
+// ProcessBatch for such processors.
+type ProcessorWithStats interface {
+	Processor
+	// ProcessBatchWithStats handles a single batch of data like ProcessBatch
+	// and also returns how it was written
+	ProcessBatchWithStats(b Batch, doLoad bool) (metricCount, rowCount uint64, stats BatchStats)
+}
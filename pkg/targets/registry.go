@@ -0,0 +1,63 @@
+package targets
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	errTargetRegisteredTwiceFmt = "target for format %s registered twice"
+	errNilTargetConstructorFmt  = "nil constructor registered for format %s"
+	errTargetNotRegisteredFmt   = "no target registered for format %s, registered: %s"
+)
+
+// TargetConstructor returns a new ImplementedTarget.
+type TargetConstructor func() ImplementedTarget
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]TargetConstructor)
+)
+
+// Register makes the target returned by constructor available for format.
+// Target packages call it from an init function, so importing a package,
+// even only for its side effects, is enough to make its target available to
+// tsbs_load. This also works for targets that live outside this repository.
+// Register panics if format is registered twice or constructor is nil.
+func Register(format string, constructor TargetConstructor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if constructor == nil {
+		panic(fmt.Sprintf(errNilTargetConstructorFmt, format))
+	}
+	if _, ok := registry[format]; ok {
+		panic(fmt.Sprintf(errTargetRegisteredTwiceFmt, format))
+	}
+	registry[format] = constructor
+}
+
+// GetTarget returns a new target for format, or an error if no target is
+// registered for it.
+func GetTarget(format string) (ImplementedTarget, error) {
+	registryMu.RLock()
+	constructor, ok := registry[format]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf(errTargetNotRegisteredFmt, format, strings.Join(RegisteredFormats(), ","))
+	}
+	return constructor(), nil
+}
+
+// RegisteredFormats returns the formats with a registered target, sorted.
+func RegisteredFormats() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	formats := make([]string, 0, len(registry))
+	for f := range registry {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
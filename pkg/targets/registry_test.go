@@ -0,0 +1,63 @@
+package targets
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/blagojts/viper"
+	"github.com/spf13/pflag"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/source"
+)
+
+type testTarget struct{ name string }
+
+func (t *testTarget) Benchmark(string, *source.DataSourceConfig, *viper.Viper) (Benchmark, error) {
+	return nil, nil
+}
+func (t *testTarget) Serializer() serialize.PointSerializer      { return nil }
+func (t *testTarget) TargetSpecificFlags(string, *pflag.FlagSet) {}
+func (t *testTarget) TargetName() string                         { return t.name }
+
+func TestRegistry(t *testing.T) {
+	Register("test-b", func() ImplementedTarget { return &testTarget{name: "test-b"} })
+	Register("test-a", func() ImplementedTarget { return &testTarget{name: "test-a"} })
+	defer func() {
+		delete(registry, "test-a")
+		delete(registry, "test-b")
+	}()
+
+	target, err := GetTarget("test-a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if target.TargetName() != "test-a" {
+		t.Errorf("incorrect target: got %s want %s", target.TargetName(), "test-a")
+	}
+	if _, err := GetTarget("test-c"); err == nil {
+		t.Errorf("expected error for unregistered format")
+	}
+	if got := RegisteredFormats(); !reflect.DeepEqual(got, []string{"test-a", "test-b"}) {
+		t.Errorf("incorrect formats: got %v", got)
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	Register("test-twice", func() ImplementedTarget { return &testTarget{} })
+	defer delete(registry, "test-twice")
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic when registering a format twice")
+		}
+	}()
+	Register("test-twice", func() ImplementedTarget { return &testTarget{} })
+}
+
+func TestRegisterNil(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic for a nil constructor")
+		}
+	}()
+	Register("test-nil", nil)
+}
@@ -0,0 +1,186 @@
+// Package timeline aggregates the writes and queries of a mixed read/write
+// benchmark into aligned time buckets.
+package timeline
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+const (
+	// query latencies are recorded in microseconds, up to an hour, with two
+	// significant figures to keep the per-bucket histograms small
+	maxLatencyMicros = int64(time.Hour / time.Microsecond)
+	latencySigFigs   = 2
+
+	// hit kinds as reported by the query stat processor
+	hitKindPartial = 1
+	hitKindFull    = 2
+)
+
+// LatencySummary summarizes the query latencies of a bucket, in milliseconds.
+type LatencySummary struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// Bucket holds the writes and queries that completed in one time bucket.
+// Rates are per second of the bucket duration.
+type Bucket struct {
+	// Start of the bucket in seconds since the start of the timeline.
+	Start       float64         `json:"start"`
+	Metrics     uint64          `json:"metrics"`
+	Rows        uint64          `json:"rows"`
+	MetricRate  float64         `json:"metricRate"`
+	RowRate     float64         `json:"rowRate"`
+	Queries     uint64          `json:"queries"`
+	QueryRate   float64         `json:"queryRate"`
+	Latency     *LatencySummary `json:"latency,omitempty"`
+	FullHits    uint64          `json:"fullHits"`
+	PartialHits uint64          `json:"partialHits"`
+	// HitRate is the share of queries that were answered from the cache,
+	// fully or partially.
+	HitRate     float64 `json:"hitRate"`
+	FullHitRate float64 `json:"fullHitRate"`
+}
+
+type bucket struct {
+	metrics, rows         uint64
+	queries               uint64
+	fullHits, partialHits uint64
+	latencies             *hdrhistogram.Histogram
+}
+
+// Timeline collects writes and queries by the bucket they completed in. It
+// is safe for concurrent use.
+type Timeline struct {
+	mu      sync.Mutex
+	start   time.Time
+	size    time.Duration
+	buckets []*bucket
+}
+
+// New returns a Timeline with buckets of size starting at start.
+func New(start time.Time, size time.Duration) *Timeline {
+	if size <= 0 {
+		panic(fmt.Sprintf("timeline: invalid bucket size %v", size))
+	}
+	return &Timeline{start: start, size: size}
+}
+
+// BucketSize returns the duration of the buckets.
+func (t *Timeline) BucketSize() time.Duration {
+	return t.size
+}
+
+// bucketAt returns the bucket at, growing the timeline as needed. Times
+// before the start fall into the first bucket. t.mu must be held.
+func (t *Timeline) bucketAt(at time.Time) *bucket {
+	i := 0
+	if at.After(t.start) {
+		i = int(at.Sub(t.start) / t.size)
+	}
+	for len(t.buckets) <= i {
+		t.buckets = append(t.buckets, &bucket{})
+	}
+	return t.buckets[i]
+}
+
+// AddWrite records a batch of metrics and rows written at at.
+func (t *Timeline) AddWrite(at time.Time, metrics, rows uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := t.bucketAt(at)
+	b.metrics += metrics
+	b.rows += rows
+}
+
+// AddQuery records a query completed at at, which took latency and was a
+// cache miss (hitKind 0), partial (1) or full (2) cache hit.
+func (t *Timeline) AddQuery(at time.Time, latency time.Duration, hitKind uint8) {
+	lat := latency.Microseconds()
+	if lat < 1 {
+		lat = 1
+	} else if lat > maxLatencyMicros {
+		lat = maxLatencyMicros
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := t.bucketAt(at)
+	if b.latencies == nil {
+		b.latencies = hdrhistogram.New(1, maxLatencyMicros, latencySigFigs)
+	}
+	// RecordValue only fails for values out of range, which are clamped above
+	_ = b.latencies.RecordValue(lat)
+	b.queries++
+	switch hitKind {
+	case hitKindFull:
+		b.fullHits++
+	case hitKindPartial:
+		b.partialHits++
+	}
+}
+
+// Buckets returns the buckets from the start up to the last one that
+// anything was recorded in.
+func (t *Timeline) Buckets() []Bucket {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	secs := t.size.Seconds()
+	res := make([]Bucket, len(t.buckets))
+	for i, b := range t.buckets {
+		r := Bucket{
+			Start:       (time.Duration(i) * t.size).Seconds(),
+			Metrics:     b.metrics,
+			Rows:        b.rows,
+			MetricRate:  float64(b.metrics) / secs,
+			RowRate:     float64(b.rows) / secs,
+			Queries:     b.queries,
+			QueryRate:   float64(b.queries) / secs,
+			FullHits:    b.fullHits,
+			PartialHits: b.partialHits,
+		}
+		if b.queries > 0 {
+			r.HitRate = float64(b.fullHits+b.partialHits) / float64(b.queries)
+			r.FullHitRate = float64(b.fullHits) / float64(b.queries)
+			ms := func(micros int64) float64 { return float64(micros) / 1e3 }
+			r.Latency = &LatencySummary{
+				Mean: b.latencies.Mean() / 1e3,
+				P50:  ms(b.latencies.ValueAtQuantile(50)),
+				P95:  ms(b.latencies.ValueAtQuantile(95)),
+				P99:  ms(b.latencies.ValueAtQuantile(99)),
+				Max:  ms(b.latencies.Max()),
+			}
+		}
+		res[i] = r
+	}
+	return res
+}
+
+// WriteTable writes buckets as a human readable table to w.
+func WriteTable(w io.Writer, buckets []Bucket) error {
+	_, err := fmt.Fprintf(w, "%10s %14s %14s %10s %10s %10s %8s\n",
+		"time", "metrics/sec", "rows/sec", "queries/s", "p50 ms", "p99 ms", "hit %")
+	if err != nil {
+		return err
+	}
+	for _, b := range buckets {
+		var p50, p99 float64
+		if b.Latency != nil {
+			p50, p99 = b.Latency.P50, b.Latency.P99
+		}
+		_, err = fmt.Fprintf(w, "%9.0fs %14.2f %14.2f %10.2f %10.2f %10.2f %8.2f\n",
+			b.Start, b.MetricRate, b.RowRate, b.QueryRate, p50, p99, 100*b.HitRate)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
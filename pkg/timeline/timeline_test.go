@@ -0,0 +1,62 @@
+package timeline
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	start := time.Unix(1000, 0)
+	tl := New(start, 10*time.Second)
+	tl.AddWrite(start.Add(-time.Second), 10, 1)
+	tl.AddWrite(start.Add(5*time.Second), 90, 9)
+	tl.AddWrite(start.Add(25*time.Second), 200, 20)
+	tl.AddQuery(start.Add(time.Second), 10*time.Millisecond, 2)
+	tl.AddQuery(start.Add(2*time.Second), 20*time.Millisecond, 1)
+	tl.AddQuery(start.Add(3*time.Second), 30*time.Millisecond, 0)
+	tl.AddQuery(start.Add(4*time.Second), 40*time.Millisecond, 0)
+
+	got := tl.Buckets()
+	if len(got) != 3 {
+		t.Fatalf("incorrect number of buckets: got %d want 3", len(got))
+	}
+	b := got[0]
+	if b.Start != 0 || b.Metrics != 100 || b.Rows != 10 || b.MetricRate != 10 || b.RowRate != 1 {
+		t.Errorf("incorrect writes in first bucket: %+v", b)
+	}
+	if b.Queries != 4 || b.QueryRate != 0.4 || b.FullHits != 1 || b.PartialHits != 1 {
+		t.Errorf("incorrect queries in first bucket: %+v", b)
+	}
+	if b.HitRate != 0.5 || b.FullHitRate != 0.25 {
+		t.Errorf("incorrect hit rates: got %f, %f want 0.5, 0.25", b.HitRate, b.FullHitRate)
+	}
+	if b.Latency == nil || b.Latency.Mean < 24.5 || b.Latency.Mean > 25.5 || b.Latency.Max < 39.5 || b.Latency.Max > 40.5 {
+		t.Errorf("incorrect latencies: %+v", b.Latency)
+	}
+
+	if empty := got[1]; empty.Start != 10 || empty.Metrics != 0 || empty.Queries != 0 || empty.Latency != nil {
+		t.Errorf("incorrect empty bucket: %+v", empty)
+	}
+	if last := got[2]; last.Start != 20 || last.Metrics != 200 || last.HitRate != 0 {
+		t.Errorf("incorrect last bucket: %+v", last)
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	tl := New(time.Unix(0, 0), time.Second)
+	tl.AddWrite(time.Unix(0, 0), 100, 10)
+	tl.AddQuery(time.Unix(1, 0), time.Millisecond, 2)
+	var buf bytes.Buffer
+	if err := WriteTable(&buf, tl.Buckets()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("incorrect number of lines: got %d want 3\n%s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[2], "100.00") {
+		t.Errorf("hit rate missing from row: %s", lines[2])
+	}
+}